@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ErrAPIKeyMissing 表示环境变量、密钥文件与 Keychain 中都没有找到 API Key
+var ErrAPIKeyMissing = &Error{
+	Type:       ErrTypeConfig,
+	Message:    "LLM API key not found",
+	Suggestion: "set CATMIT_LLM_API_KEY or CATMIT_LLM_API_KEY_FILE, or store it in the macOS Keychain under the service name " + keychainService,
+}
+
+// keychainService 是 macOS Keychain 中存放 API Key 的通用密码条目的服务名
+const keychainService = "catmit"
+
+// keychainTimeout 限制 security 命令的执行时间，避免 Keychain 弹窗长时间阻塞
+const keychainTimeout = 5 * time.Second
+
+// apiKeySources 描述 API Key 的候选来源，按字段顺序决定优先级
+type apiKeySources struct {
+	Env      string                 // CATMIT_LLM_API_KEY 的值
+	File     string                 // CATMIT_LLM_API_KEY_FILE 指向的文件路径
+	Keychain func() (string, error) // 查询 Keychain，nil 表示不查询
+}
+
+// resolveAPIKey 依次从环境变量、密钥文件与 Keychain 取得 API Key。
+// 密钥文件无法读取时返回配置错误；所有来源都为空时返回 ErrAPIKeyMissing。
+func resolveAPIKey(src apiKeySources) (string, error) {
+	if key := strings.TrimSpace(src.Env); key != "" {
+		return key, nil
+	}
+	if src.File != "" {
+		data, err := os.ReadFile(src.File)
+		if err != nil {
+			return "", &Error{
+				Type:       ErrTypeConfig,
+				Message:    "failed to read CATMIT_LLM_API_KEY_FILE",
+				Suggestion: "check that the file exists and is readable",
+				Err:        err,
+			}
+		}
+		// 文件通常以换行结尾，只去掉行尾的换行
+		if key := strings.TrimRight(string(data), "\r\n"); key != "" {
+			return key, nil
+		}
+	}
+	if src.Keychain != nil {
+		if key, err := src.Keychain(); err == nil && key != "" {
+			return key, nil
+		}
+	}
+	return "", ErrAPIKeyMissing
+}
+
+// keychainLookup 查询 Keychain 中的 API Key；测试时可替换
+var keychainLookup = lookupKeychain
+
+// lookupKeychain 在 macOS 上通过 security find-generic-password 读取 API Key，其他平台不支持
+func lookupKeychain() (string, error) {
+	if runtime.GOOS != "darwin" {
+		return "", fmt.Errorf("keychain lookup is only supported on macOS")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keychainTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "security", "find-generic-password", "-s", keychainService, "-w").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// apiKeyFromEnv 按 CATMIT_LLM_API_KEY、CATMIT_LLM_API_KEY_FILE、Keychain 的顺序解析 API Key。
+// 没有找到 Key 时不视为错误：本地模型服务等端点无需鉴权，缺少 Key 时由服务端返回 401。
+func apiKeyFromEnv() (string, error) {
+	key, err := resolveAPIKey(apiKeySources{
+		Env:      os.Getenv("CATMIT_LLM_API_KEY"),
+		File:     os.Getenv("CATMIT_LLM_API_KEY_FILE"),
+		Keychain: keychainLookup,
+	})
+	if errors.Is(err, ErrAPIKeyMissing) {
+		return "", nil
+	}
+	return key, err
+}
+
+// HasAPIKey 报告能否从环境变量、密钥文件或 Keychain 中取得 API Key，供 catmit doctor 检查
+func HasAPIKey() bool {
+	key, err := apiKeyFromEnv()
+	return err == nil && key != ""
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAPIKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("sk-from-file\n"), 0o600))
+	keychain := func() (string, error) { return "sk-from-keychain", nil }
+	noKeychain := func() (string, error) { return "", errors.New("not found") }
+
+	tests := []struct {
+		name    string
+		src     apiKeySources
+		want    string
+		wantErr error
+	}{
+		{name: "env_wins", src: apiKeySources{Env: "sk-env", File: keyFile, Keychain: keychain}, want: "sk-env"},
+		{name: "file_trims_newline", src: apiKeySources{File: keyFile, Keychain: keychain}, want: "sk-from-file"},
+		{name: "keychain", src: apiKeySources{Keychain: keychain}, want: "sk-from-keychain"},
+		{name: "missing", src: apiKeySources{Keychain: noKeychain}, wantErr: ErrAPIKeyMissing},
+		{name: "missing_without_keychain", src: apiKeySources{}, wantErr: ErrAPIKeyMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveAPIKey(tt.src)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.True(t, IsType(err, ErrTypeConfig))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("unreadable_file", func(t *testing.T) {
+		_, err := resolveAPIKey(apiKeySources{File: filepath.Join(t.TempDir(), "missing"), Keychain: keychain})
+		require.True(t, IsType(err, ErrTypeConfig))
+		require.ErrorContains(t, err, "CATMIT_LLM_API_KEY_FILE")
+	})
+}
+
+func TestNewClient_APIKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("sk-from-file\n"), 0o600))
+	t.Setenv("CATMIT_LLM_PROVIDER", "")
+	t.Setenv("CATMIT_LLM_API_URL", "https://llm.example.com/v1/chat/completions")
+	t.Setenv("CATMIT_LLM_API_KEY", "")
+	t.Setenv("CATMIT_LLM_API_KEY_FILE", keyFile)
+	t.Setenv("CATMIT_LLM_MODEL", "example-model")
+	t.Setenv("CATMIT_LLM_CA_CERT", "")
+
+	var auth string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		auth = req.Header.Get("Authorization")
+		return nil, errors.New("stop")
+	})
+	_, _ = NewClientWithTransport(transport, nil).GetCommitMessage(context.Background(), "sys", "user")
+	require.Equal(t, "Bearer sk-from-file", auth)
+
+	t.Setenv("CATMIT_LLM_API_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	require.True(t, IsType(NewClient(nil).Validate(), ErrTypeConfig))
+}
@@ -3,21 +3,42 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// defaultLogBodyLimit 是 debug 日志中请求/响应体的默认最大长度（字节）
+const defaultLogBodyLimit = 2000
+
 // LLMProvider 定义 LLM 服务提供商的通用接口
 // 支持不同的 LLM API（OpenAI 兼容和非兼容）
 type LLMProvider interface {
 	GetCompletion(ctx context.Context, systemPrompt, userPrompt string) (string, error)
 }
 
+// Info 描述 Client 实际使用的 Provider、模型与端点，不包含 API Key 等敏感信息
+type Info struct {
+	Provider string // Provider 名称
+	Model    string // 模型名称
+	Endpoint string // API 端点（已移除查询参数与认证信息）
+}
+
+// infoReporter 由能够描述自身配置的 Provider 实现
+type infoReporter interface {
+	Info() Info
+}
+
 // Client 负责与 LLM API 进行交互。
 // 该结构体通过注入的 LLMProvider 支持多种 LLM 服务商。
 // English log messages should be emitted by callers; 此处仅返回错误。
@@ -45,19 +66,109 @@ type OpenAICompatibleProvider struct {
 	apiURL     string       // 完整的 API 端点 URL
 	apiKey     string       // 鉴权所需的 API Key
 	model      string       // 模型名称
+	headers    http.Header  // 通过 CATMIT_LLM_HEADERS 配置的附加请求头
 	httpClient *http.Client // 可注入自定义 http.Client，用于超时与测试
+	configErr  error        // 解析环境变量时遇到的配置错误，调用时返回
+	logger     *zap.Logger  // 可选，debug 级别时记录原始请求与响应
+	logLimit   int          // debug 日志中请求/响应体的最大长度
 }
 
 // NewClient 创建一个 LLM Client。
 // 所有超时控制通过传入的 context.Context 实现，确保信号处理的即时响应。
+// Provider 由 CATMIT_LLM_PROVIDER 选择，默认为 OpenAI 兼容接口。
 func NewClient(logger *zap.Logger) *Client {
-	provider := NewOpenAICompatibleProvider()
+	return NewClientWithModel("", logger)
+}
+
+// NewClientWithModel 创建一个使用指定模型的 Client，通常对应 --model 参数。
+// model 为空时依次回退到 CATMIT_LLM_MODEL 与 Provider 的默认模型。
+func NewClientWithModel(model string, logger *zap.Logger) *Client {
 	return &Client{
-		provider: provider,
+		provider: newProviderFromEnv(model, nil, logger),
+		logger:   logger,
+	}
+}
+
+// NewClientWithTransport 创建一个使用指定 Transport 的 Client，主要用于测试。
+// 其余配置仍从环境变量读取。
+func NewClientWithTransport(transport http.RoundTripper, logger *zap.Logger) *Client {
+	return &Client{
+		provider: newProviderFromEnv("", transport, logger),
 		logger:   logger,
 	}
 }
 
+// newProviderFromEnv 根据 CATMIT_LLM_PROVIDER 创建 Provider；
+// model 非空时覆盖环境变量，transport 非空时替换默认 Transport。
+// 同时设置 CATMIT_LLM_FALLBACK_URL 与 CATMIT_LLM_FALLBACK_API_KEY 时，
+// 主 Provider 出现网络错误或 5xx 后改用备用端点。
+func newProviderFromEnv(model string, transport http.RoundTripper, logger *zap.Logger) LLMProvider {
+	nameVar, name := "CATMIT_LLM_PROVIDER", os.Getenv("CATMIT_LLM_PROVIDER")
+	apiKey, keyErr := apiKeyFromEnv()
+	primary := newNamedProvider(nameVar, name, os.Getenv("CATMIT_LLM_API_URL"), apiKey, keyErr, model, transport, logger)
+
+	fallbackURL := os.Getenv("CATMIT_LLM_FALLBACK_URL")
+	if fallbackURL == "" {
+		return primary
+	}
+	// 备用端点必须单独配置 API Key，避免把主端点的凭据发送到另一台主机
+	fallbackKey := os.Getenv("CATMIT_LLM_FALLBACK_API_KEY")
+	if fallbackKey == "" {
+		if logger != nil {
+			logger.Warn("CATMIT_LLM_FALLBACK_URL is set without CATMIT_LLM_FALLBACK_API_KEY; fallback disabled",
+				zap.String("fallback", fallbackURL))
+		}
+		return primary
+	}
+	// 备用端点默认与主端点使用相同的 Provider 协议
+	if fallbackName := os.Getenv("CATMIT_LLM_FALLBACK_PROVIDER"); fallbackName != "" {
+		nameVar, name = "CATMIT_LLM_FALLBACK_PROVIDER", fallbackName
+	}
+	fallback := newNamedProvider(nameVar, name, fallbackURL, fallbackKey, nil, model, transport, logger)
+	return NewFallbackProvider(primary, fallback, logger)
+}
+
+// newNamedProvider 按 Provider 名称（大小写不敏感，空串表示 OpenAI 兼容）创建使用指定端点与 API Key 的 Provider；
+// nameVar 是名称来源的环境变量，用于错误信息；keyErr 是解析 API Key 时的错误，调用时返回
+func newNamedProvider(nameVar, name, apiURL, apiKey string, keyErr error, model string, transport http.RoundTripper, logger *zap.Logger) LLMProvider {
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case "", "openai", "openai-compatible":
+		provider := newOpenAICompatibleProviderFor(apiURL, apiKey, keyErr, model)
+		if transport != nil {
+			provider.httpClient.Transport = transport
+		}
+		provider.logger = logger
+		return provider
+	case "gemini":
+		provider := newGeminiProviderFor(apiURL, apiKey, keyErr, model)
+		if transport != nil {
+			provider.httpClient.Transport = transport
+		}
+		provider.logger = logger
+		return provider
+	default:
+		return invalidProvider{err: &Error{
+			Type:       ErrTypeConfig,
+			Message:    fmt.Sprintf("unknown %s %q", nameVar, name),
+			Suggestion: "use openai or gemini",
+		}}
+	}
+}
+
+// invalidProvider 在配置无效时代替真实 Provider，所有调用都返回配置错误
+type invalidProvider struct {
+	err error
+}
+
+func (p invalidProvider) GetCompletion(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return "", p.err
+}
+
+// Validate 返回导致 Provider 无效的配置错误
+func (p invalidProvider) Validate() error {
+	return p.err
+}
+
 // NewClientWithProvider 创建一个使用指定 Provider 的 Client。
 func NewClientWithProvider(provider LLMProvider, logger *zap.Logger) *Client {
 	return &Client{
@@ -66,31 +177,210 @@ func NewClientWithProvider(provider LLMProvider, logger *zap.Logger) *Client {
 	}
 }
 
+// openAIDefaultModels 记录已知 OpenAI 兼容端点（按主机名）的默认模型。
+// 自定义端点没有默认模型，必须显式通过 --model 或 CATMIT_LLM_MODEL 指定。
+var openAIDefaultModels = map[string]string{
+	"api.deepseek.com": "deepseek-chat",
+	"api.openai.com":   "gpt-4o-mini",
+}
+
 // NewOpenAICompatibleProvider 创建一个 OpenAI 兼容的 Provider
 // 从环境变量读取配置，支持 DeepSeek, Volcengine 等 OpenAI 兼容 API
 func NewOpenAICompatibleProvider() *OpenAICompatibleProvider {
-	apiURL := os.Getenv("CATMIT_LLM_API_URL")
+	return newOpenAICompatibleProvider("")
+}
+
+func newOpenAICompatibleProvider(model string) *OpenAICompatibleProvider {
+	apiKey, keyErr := apiKeyFromEnv()
+	return newOpenAICompatibleProviderFor(os.Getenv("CATMIT_LLM_API_URL"), apiKey, keyErr, model)
+}
+
+// newOpenAICompatibleProviderFor 使用指定的端点与 API Key 创建 Provider，其余配置仍从环境变量读取
+func newOpenAICompatibleProviderFor(apiURL, apiKey string, keyErr error, model string) *OpenAICompatibleProvider {
 	if apiURL == "" {
 		apiURL = "https://api.deepseek.com/v1/chat/completions"
 	}
 	
-	apiKey := os.Getenv("CATMIT_LLM_API_KEY")
-	
-	model := os.Getenv("CATMIT_LLM_MODEL")
-	if model == "" {
-		model = "deepseek-chat"
+	var defaultModel string
+	if u, err := url.Parse(apiURL); err == nil {
+		defaultModel = openAIDefaultModels[u.Hostname()]
 	}
+	model, err := resolveModel(model, defaultModel, redactEndpoint(apiURL))
 	
+	headers, headersErr := parseHeaders(os.Getenv("CATMIT_LLM_HEADERS"))
+	transport, transportErr := newTransport(os.Getenv("CATMIT_LLM_CA_CERT"))
+	err = firstError(keyErr, err, headersErr, transportErr)
+
 	return &OpenAICompatibleProvider{
-		apiURL: apiURL,
-		apiKey: apiKey,
-		model:  model,
+		apiURL:  apiURL,
+		apiKey:  apiKey,
+		model:   model,
+		headers: headers,
 		httpClient: &http.Client{
 			// 不设置 Timeout，完全依赖 context 控制超时和取消
+			Transport: transport,
 		},
+		configErr: err,
+		logLimit:  logBodyLimitFromEnv(),
 	}
 }
 
+// resolveModel 按 --model、CATMIT_LLM_MODEL、Provider 默认值的顺序选择模型；
+// 三者皆为空时返回 ErrTypeConfig。
+func resolveModel(override, defaultModel, endpoint string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	if model := os.Getenv("CATMIT_LLM_MODEL"); model != "" {
+		return model, nil
+	}
+	if defaultModel != "" {
+		return defaultModel, nil
+	}
+	return "", &Error{
+		Type:       ErrTypeConfig,
+		Message:    fmt.Sprintf("no default model for %s", endpoint),
+		Suggestion: "set --model or CATMIT_LLM_MODEL",
+	}
+}
+
+// firstError 返回第一个非 nil 的错误
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logBodyLimitFromEnv 读取 CATMIT_LLM_LOG_BODY_LIMIT，无效时使用默认值
+func logBodyLimitFromEnv() int {
+	if v, err := strconv.Atoi(os.Getenv("CATMIT_LLM_LOG_BODY_LIMIT")); err == nil && v > 0 {
+		return v
+	}
+	return defaultLogBodyLimit
+}
+
+// parseHeaders 解析 "Key1:Val1;Key2:Val2" 格式的附加请求头。
+// 空字符串返回 nil；任何格式错误都返回 ErrTypeConfig，避免静默丢弃请求头。
+func parseHeaders(spec string) (http.Header, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	headers := make(http.Header)
+	for _, pair := range strings.Split(spec, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, &Error{
+				Type:       ErrTypeConfig,
+				Message:    fmt.Sprintf("malformed header %q in CATMIT_LLM_HEADERS", strings.TrimSpace(pair)),
+				Suggestion: "use the format Key1:Val1;Key2:Val2",
+			}
+		}
+		headers.Set(key, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// newTransport 构建 HTTP Transport：遵循 HTTPS_PROXY/HTTP_PROXY/NO_PROXY，
+// 并在 caCertPath 非空时将其中的证书追加到系统根证书池，以支持企业代理的自签证书。
+func newTransport(caCertPath string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if caCertPath == "" {
+		return transport, nil
+	}
+
+	pem, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return transport, &Error{
+			Type:       ErrTypeConfig,
+			Message:    "failed to read CATMIT_LLM_CA_CERT",
+			Suggestion: "check that the file exists and is readable",
+			Err:        err,
+		}
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return transport, &Error{
+			Type:       ErrTypeConfig,
+			Message:    fmt.Sprintf("no PEM certificates found in CATMIT_LLM_CA_CERT (%s)", caCertPath),
+			Suggestion: "provide a PEM-encoded CA certificate",
+		}
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	transport.TLSClientConfig.RootCAs = pool
+	return transport, nil
+}
+
+// wrapRequestError 将 http.Client.Do 的错误映射为 ErrTypeNetwork。
+// 如果是 context 取消或超时，直接返回原始错误以便调用方区分。
+func wrapRequestError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return &Error{
+		Type:       ErrTypeNetwork,
+		Message:    "failed to connect to LLM API",
+		Suggestion: "check network connectivity and HTTPS_PROXY/HTTP_PROXY settings",
+		Err:        err,
+	}
+}
+
+// Validate 报告 Provider 在读取配置时遇到的错误
+func (p *OpenAICompatibleProvider) Validate() error {
+	return p.configErr
+}
+
+// Info 返回 Provider 解析后的配置
+func (p *OpenAICompatibleProvider) Info() Info {
+	return Info{
+		Provider: "openai-compatible",
+		Model:    p.model,
+		Endpoint: redactEndpoint(p.apiURL),
+	}
+}
+
+// Info 返回当前使用的模型与端点；Provider 未提供信息时返回零值
+func (c *Client) Info() Info {
+	if reporter, ok := c.provider.(infoReporter); ok {
+		return reporter.Info()
+	}
+	return Info{}
+}
+
+// Validate 检查 Client 配置是否有效，供调用方在启动时尽早失败
+func (c *Client) Validate() error {
+	if v, ok := c.provider.(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// redactEndpoint 移除 URL 中可能携带密钥的查询参数与认证信息
+func redactEndpoint(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "<invalid url>"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
 // chatRequest 定义了 DeepSeek Chat API 请求体结构。
 // 私有结构体仅用于序列化。
 type chatRequest struct {
@@ -134,6 +424,11 @@ func maskAPIKey(apiKey string) string {
 	return apiKey[:4] + "***" + apiKey[len(apiKey)-4:]
 }
 
+// debugEnabled reports whether raw request/response logging is active
+func debugEnabled(logger *zap.Logger) bool {
+	return logger != nil && logger.Core().Enabled(zapcore.DebugLevel)
+}
+
 // truncateForLog truncates content for logging with UTF-8 awareness
 func truncateForLog(content string, maxLen int) string {
 	if len(content) <= maxLen {
@@ -154,6 +449,10 @@ func isValidUTF8Start(b byte) bool {
 
 // GetCompletion 实现 OpenAI 兼容的 API 调用
 func (p *OpenAICompatibleProvider) GetCompletion(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	if p.configErr != nil {
+		return "", p.configErr
+	}
+
 	// 构建请求体，使用 system 和 user 消息分离
 	messages := []chatMessage{
 		{Role: "system", Content: systemPrompt},
@@ -179,15 +478,32 @@ func (p *OpenAICompatibleProvider) GetCompletion(ctx context.Context, systemProm
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if p.apiKey != "" {
+	for key, values := range p.headers {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	// CATMIT_LLM_HEADERS 中显式配置的 Authorization 优先（如网关使用其他鉴权方案），不被 API Key 覆盖
+	authorization := "Bearer " + maskAPIKey(p.apiKey)
+	if p.headers.Get("Authorization") != "" {
+		authorization = "custom (CATMIT_LLM_HEADERS)"
+	} else if p.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	}
 
+	if debugEnabled(p.logger) {
+		p.logger.Debug("LLM raw request",
+			zap.String("method", req.Method),
+			zap.String("url", redactEndpoint(p.apiURL)),
+			zap.String("authorization", authorization),
+			zap.Int("body_length", len(data)),
+			zap.String("body", truncateForLog(string(data), p.logLimit)))
+	}
+
 	// 发送请求
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		// 如果是 context 取消或超时，直接返回原始错误以便调用方区分。
-		return "", err
+		return "", wrapRequestError(ctx, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
@@ -197,10 +513,17 @@ func (p *OpenAICompatibleProvider) GetCompletion(ctx context.Context, systemProm
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if debugEnabled(p.logger) {
+		p.logger.Debug("LLM raw response",
+			zap.Int("status", resp.StatusCode),
+			zap.Int("body_length", len(bodyBytes)),
+			zap.String("body", truncateForLog(string(bodyBytes), p.logLimit)))
+	}
+
 	// 非 200 统一处理为错误输出，包含状态码但不包含响应体以防泄露敏感信息。
 	if resp.StatusCode != http.StatusOK {
 		// 只记录状态码，不记录响应体内容以防泄露 API 密钥等敏感信息
-		return "", fmt.Errorf("API error: status %d", resp.StatusCode)
+		return "", &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	var chatResp chatResponse
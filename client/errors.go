@@ -0,0 +1,68 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorType 对 Client 返回的错误进行分类，便于调用方给出针对性提示
+type ErrorType string
+
+const (
+	// ErrTypeNetwork 表示无法连接到 LLM API（DNS、代理、TLS 等）
+	ErrTypeNetwork ErrorType = "network"
+	// ErrTypeConfig 表示环境变量等配置无效
+	ErrTypeConfig ErrorType = "config"
+	// ErrTypeValidation 表示请求内容未通过本地校验（如 prompt 超出 --max-prompt-tokens），未发送请求
+	ErrTypeValidation ErrorType = "validation"
+)
+
+// Error 是 Client 返回的结构化错误
+type Error struct {
+	Type       ErrorType // 错误分类
+	Message    string    // 面向用户的错误描述
+	Suggestion string    // 可选的修复建议
+	Err        error     // 底层错误
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	if e.Suggestion != "" {
+		msg += " (" + e.Suggestion + ")"
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// StatusError 表示 LLM API 返回了非 200 状态码；不包含响应体以防泄露敏感信息
+type StatusError struct {
+	StatusCode int
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API error: status %d", e.StatusCode)
+}
+
+// AsError 提取 err 链中的 *Error
+func AsError(err error) (*Error, bool) {
+	var clientErr *Error
+	if errors.As(err, &clientErr) {
+		return clientErr, true
+	}
+	return nil, false
+}
+
+// IsType 判断 err 是否为指定类型的 Client 错误
+func IsType(err error, errType ErrorType) bool {
+	clientErr, ok := AsError(err)
+	return ok && clientErr.Type == errType
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// FallbackProvider 先调用主 Provider，出现网络错误或 5xx 时改用备用 Provider。
+// 由 CATMIT_LLM_FALLBACK_URL、CATMIT_LLM_FALLBACK_API_KEY 与可选的
+// CATMIT_LLM_FALLBACK_PROVIDER 配置。
+type FallbackProvider struct {
+	primary  LLMProvider
+	fallback LLMProvider
+	logger   *zap.Logger // 可选，记录最终提供响应的端点
+}
+
+// NewFallbackProvider 创建在 primary 不可用时改用 fallback 的 Provider
+func NewFallbackProvider(primary, fallback LLMProvider, logger *zap.Logger) *FallbackProvider {
+	return &FallbackProvider{primary: primary, fallback: fallback, logger: logger}
+}
+
+// GetCompletion 调用主 Provider，仅在 shouldFallback 判定端点不可用时调用备用 Provider
+func (p *FallbackProvider) GetCompletion(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	result, err := p.primary.GetCompletion(ctx, systemPrompt, userPrompt)
+	if err == nil {
+		if p.logger != nil {
+			p.logger.Debug("LLM response served by primary endpoint", zap.String("endpoint", endpointOf(p.primary)))
+		}
+		return result, nil
+	}
+	if !shouldFallback(ctx, err) {
+		return "", err
+	}
+
+	result, fallbackErr := p.fallback.GetCompletion(ctx, systemPrompt, userPrompt)
+	if fallbackErr != nil {
+		// 两个端点都失败时返回主端点的错误，备用端点的错误只记录日志
+		if p.logger != nil {
+			p.logger.Debug("Fallback LLM endpoint failed",
+				zap.String("endpoint", endpointOf(p.fallback)),
+				zap.Error(fallbackErr))
+		}
+		return "", err
+	}
+	if p.logger != nil {
+		p.logger.Warn("Primary LLM endpoint unavailable, response served by fallback endpoint",
+			zap.String("primary", endpointOf(p.primary)),
+			zap.String("fallback", endpointOf(p.fallback)),
+			zap.Error(err))
+	}
+	return result, nil
+}
+
+// Validate 报告主 Provider 或备用 Provider 的配置错误
+func (p *FallbackProvider) Validate() error {
+	for _, provider := range []LLMProvider{p.primary, p.fallback} {
+		if v, ok := provider.(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Info 返回主 Provider 的配置
+func (p *FallbackProvider) Info() Info {
+	if reporter, ok := p.primary.(infoReporter); ok {
+		return reporter.Info()
+	}
+	return Info{}
+}
+
+// shouldFallback 判断错误是否表示端点不可用：网络错误或 5xx。
+// context 已取消或超时、配置错误与 4xx 不切换端点。
+func shouldFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if IsType(err, ErrTypeNetwork) {
+		return true
+	}
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode >= http.StatusInternalServerError
+}
+
+// endpointOf 返回 Provider 的端点，用于日志
+func endpointOf(provider LLMProvider) string {
+	if reporter, ok := provider.(infoReporter); ok {
+		return reporter.Info().Endpoint
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// stubProvider 返回固定结果并记录调用次数
+type stubProvider struct {
+	result string
+	err    error
+	calls  int
+}
+
+func (s *stubProvider) GetCompletion(_ context.Context, _, _ string) (string, error) {
+	s.calls++
+	return s.result, s.err
+}
+
+func TestFallbackProvider(t *testing.T) {
+	networkErr := &Error{Type: ErrTypeNetwork, Message: "failed to connect to LLM API"}
+
+	tests := []struct {
+		name          string
+		primaryErr    error
+		wantResult    string
+		wantFallback  bool
+		wantErrSubstr string
+	}{
+		{name: "primary_ok", wantResult: "primary"},
+		{name: "network_error", primaryErr: networkErr, wantResult: "fallback", wantFallback: true},
+		{name: "server_error", primaryErr: &StatusError{StatusCode: 503}, wantResult: "fallback", wantFallback: true},
+		{name: "client_error", primaryErr: &StatusError{StatusCode: 401}, wantErrSubstr: "status 401"},
+		{name: "config_error", primaryErr: &Error{Type: ErrTypeConfig, Message: "bad config"}, wantErrSubstr: "bad config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &stubProvider{result: "primary", err: tt.primaryErr}
+			if tt.primaryErr != nil {
+				primary.result = ""
+			}
+			fallback := &stubProvider{result: "fallback"}
+			got, err := NewFallbackProvider(primary, fallback, nil).GetCompletion(context.Background(), "sys", "user")
+			if tt.wantErrSubstr != "" {
+				require.ErrorContains(t, err, tt.wantErrSubstr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantResult, got)
+			}
+			require.Equal(t, tt.wantFallback, fallback.calls == 1)
+		})
+	}
+}
+
+func TestFallbackProvider_BothFail(t *testing.T) {
+	primary := &stubProvider{err: &StatusError{StatusCode: 500}}
+	fallback := &stubProvider{err: errors.New("fallback down")}
+	_, err := NewFallbackProvider(primary, fallback, nil).GetCompletion(context.Background(), "sys", "user")
+	require.ErrorContains(t, err, "status 500")
+}
+
+func TestFallbackProvider_CanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fallback := &stubProvider{result: "fallback"}
+	_, err := NewFallbackProvider(&stubProvider{err: context.Canceled}, fallback, nil).GetCompletion(ctx, "sys", "user")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, fallback.calls)
+}
+
+func TestNewClient_FallbackFromEnv(t *testing.T) {
+	t.Setenv("CATMIT_LLM_PROVIDER", "")
+	t.Setenv("CATMIT_LLM_API_URL", "https://primary.example.com/v1/chat/completions")
+	t.Setenv("CATMIT_LLM_API_KEY", "sk-primary")
+	t.Setenv("CATMIT_LLM_FALLBACK_URL", "https://backup.example.com/v1/chat/completions")
+	t.Setenv("CATMIT_LLM_FALLBACK_API_KEY", "sk-backup")
+	t.Setenv("CATMIT_LLM_FALLBACK_PROVIDER", "")
+	t.Setenv("CATMIT_LLM_MODEL", "example-model")
+	t.Setenv("CATMIT_LLM_CA_CERT", "")
+
+	var auths []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		auths = append(auths, req.Header.Get("Authorization"))
+		if req.URL.Host == "primary.example.com" {
+			return &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader("")), Header: make(http.Header)}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"choices":[{"message":{"role":"assistant","content":"feat: ok"}}]}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	c := NewClientWithTransport(transport, zap.New(core))
+	require.Equal(t, "https://primary.example.com/v1/chat/completions", c.Info().Endpoint)
+
+	msg, err := c.GetCommitMessage(context.Background(), "sys", "user")
+	require.NoError(t, err)
+	require.Equal(t, "feat: ok", msg)
+	require.Equal(t, []string{"Bearer sk-primary", "Bearer sk-backup"}, auths)
+
+	entries := logs.FilterMessageSnippet("served by fallback").All()
+	require.Len(t, entries, 1)
+	require.Equal(t, "https://backup.example.com/v1/chat/completions", entries[0].ContextMap()["fallback"])
+}
+
+func TestNewClient_FallbackProviderOverride(t *testing.T) {
+	t.Setenv("CATMIT_LLM_PROVIDER", "")
+	t.Setenv("CATMIT_LLM_MODEL", "example-model")
+	t.Setenv("CATMIT_LLM_FALLBACK_URL", "https://backup.example.com")
+	t.Setenv("CATMIT_LLM_FALLBACK_API_KEY", "sk-backup")
+	t.Setenv("CATMIT_LLM_FALLBACK_PROVIDER", "unknown")
+
+	err := NewClient(nil).Validate()
+	require.True(t, IsType(err, ErrTypeConfig))
+	require.ErrorContains(t, err, `unknown CATMIT_LLM_FALLBACK_PROVIDER "unknown"`)
+}
+
+func TestNewClient_FallbackRequiresOwnKey(t *testing.T) {
+	t.Setenv("CATMIT_LLM_PROVIDER", "")
+	t.Setenv("CATMIT_LLM_API_URL", "https://primary.example.com/v1/chat/completions")
+	t.Setenv("CATMIT_LLM_API_KEY", "sk-primary")
+	t.Setenv("CATMIT_LLM_FALLBACK_URL", "https://backup.example.com/v1/chat/completions")
+	t.Setenv("CATMIT_LLM_FALLBACK_API_KEY", "")
+	t.Setenv("CATMIT_LLM_FALLBACK_PROVIDER", "")
+	t.Setenv("CATMIT_LLM_MODEL", "example-model")
+	t.Setenv("CATMIT_LLM_CA_CERT", "")
+
+	var hosts []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		return &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader("")), Header: make(http.Header)}, nil
+	})
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	c := NewClientWithTransport(transport, zap.New(core))
+	_, err := c.GetCommitMessage(context.Background(), "sys", "user")
+	require.Error(t, err)
+	require.NotContains(t, hosts, "backup.example.com")
+	require.Len(t, logs.FilterMessageSnippet("fallback disabled").All(), 1)
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// geminiDefaultBaseURL 是 Gemini generateContent 接口的默认前缀，模型名称拼接在其后
+const geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta/models/"
+
+// GeminiProvider 实现 Google Gemini generateContent API 调用
+type GeminiProvider struct {
+	apiURL     string       // 完整的 generateContent 端点 URL（不含 key）
+	apiKey     string       // 通过 key 查询参数传递的 API Key
+	model      string       // 模型名称
+	headers    http.Header  // 通过 CATMIT_LLM_HEADERS 配置的附加请求头
+	httpClient *http.Client // 可注入自定义 http.Client，用于超时与测试
+	configErr  error        // 解析环境变量时遇到的配置错误，调用时返回
+	logger     *zap.Logger  // 可选，debug 级别时记录原始请求与响应
+	logLimit   int          // debug 日志中请求/响应体的最大长度
+}
+
+// NewGeminiProvider 创建一个 Gemini Provider
+// 从环境变量读取配置；CATMIT_LLM_API_URL 未设置时根据模型拼接默认端点
+func NewGeminiProvider() *GeminiProvider {
+	return newGeminiProvider("")
+}
+
+func newGeminiProvider(model string) *GeminiProvider {
+	apiKey, keyErr := apiKeyFromEnv()
+	return newGeminiProviderFor(os.Getenv("CATMIT_LLM_API_URL"), apiKey, keyErr, model)
+}
+
+// newGeminiProviderFor 使用指定的端点与 API Key 创建 Provider，apiURL 为空时根据模型拼接默认端点
+func newGeminiProviderFor(apiURL, apiKey string, keyErr error, model string) *GeminiProvider {
+	model, err := resolveModel(model, "gemini-1.5-flash", "gemini")
+
+	if apiURL == "" {
+		apiURL = geminiDefaultBaseURL + url.PathEscape(model) + ":generateContent"
+	}
+
+	headers, headersErr := parseHeaders(os.Getenv("CATMIT_LLM_HEADERS"))
+	transport, transportErr := newTransport(os.Getenv("CATMIT_LLM_CA_CERT"))
+	err = firstError(keyErr, err, headersErr, transportErr)
+
+	return &GeminiProvider{
+		apiURL:  apiURL,
+		apiKey:  apiKey,
+		model:   model,
+		headers: headers,
+		httpClient: &http.Client{
+			// 不设置 Timeout，完全依赖 context 控制超时和取消
+			Transport: transport,
+		},
+		configErr: err,
+		logLimit:  logBodyLimitFromEnv(),
+	}
+}
+
+// Validate 报告 Provider 在读取配置时遇到的错误
+func (p *GeminiProvider) Validate() error {
+	return p.configErr
+}
+
+// Info 返回 Provider 解析后的配置
+func (p *GeminiProvider) Info() Info {
+	return Info{
+		Provider: "gemini",
+		Model:    p.model,
+		Endpoint: redactEndpoint(p.apiURL),
+	}
+}
+
+// geminiRequest 定义了 generateContent 请求体结构。
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens int     `json:"maxOutputTokens"`
+	Temperature     float64 `json:"temperature"`
+}
+
+// geminiResponse 仅包含解析 commit message 所需的字段。
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+}
+
+// GetCompletion 实现 Gemini generateContent API 调用
+func (p *GeminiProvider) GetCompletion(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	if p.configErr != nil {
+		return "", p.configErr
+	}
+
+	reqBody := geminiRequest{
+		Contents: []geminiContent{
+			{Role: "user", Parts: []geminiPart{{Text: userPrompt}}},
+		},
+		GenerationConfig: geminiGenerationConfig{
+			MaxOutputTokens: 128,
+			Temperature:     0.7,
+		},
+	}
+	if systemPrompt != "" {
+		reqBody.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: systemPrompt}}}
+	}
+
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint, err := url.Parse(p.apiURL)
+	if err != nil {
+		return "", &Error{
+			Type:       ErrTypeConfig,
+			Message:    "invalid CATMIT_LLM_API_URL",
+			Suggestion: "set a full generateContent endpoint URL",
+			Err:        err,
+		}
+	}
+	if p.apiKey != "" {
+		query := endpoint.Query()
+		query.Set("key", p.apiKey)
+		endpoint.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range p.headers {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+
+	if debugEnabled(p.logger) {
+		p.logger.Debug("LLM raw request",
+			zap.String("method", req.Method),
+			zap.String("url", redactEndpoint(p.apiURL)),
+			zap.String("key", maskAPIKey(p.apiKey)),
+			zap.Int("body_length", len(data)),
+			zap.String("body", truncateForLog(string(data), p.logLimit)))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		// *url.Error 的消息包含完整 URL，其中带有 key 查询参数，需要先脱敏
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = redactEndpoint(urlErr.URL)
+		}
+		return "", wrapRequestError(ctx, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if debugEnabled(p.logger) {
+		p.logger.Debug("LLM raw response",
+			zap.Int("status", resp.StatusCode),
+			zap.Int("body_length", len(bodyBytes)),
+			zap.String("body", truncateForLog(string(bodyBytes), p.logLimit)))
+	}
+
+	// 与 OpenAI 兼容 Provider 一致，只返回状态码，不包含响应体
+	if resp.StatusCode != http.StatusOK {
+		return "", &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(bodyBytes, &geminiResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("invalid response: empty candidates")
+	}
+
+	text := geminiResp.Candidates[0].Content.Parts[0].Text
+	if text == "" {
+		return "", fmt.Errorf("invalid response: empty message content")
+	}
+
+	return text, nil
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiProvider_GetCompletion(t *testing.T) {
+	t.Setenv("CATMIT_LLM_PROVIDER", "gemini")
+	t.Setenv("CATMIT_LLM_API_URL", "")
+	t.Setenv("CATMIT_LLM_API_KEY", "gm-key")
+	t.Setenv("CATMIT_LLM_MODEL", "")
+	t.Setenv("CATMIT_LLM_CA_CERT", "")
+
+	var gotURL string
+	var gotBody geminiRequest
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		gotURL = req.URL.String()
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&gotBody))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"candidates":[{"content":{"role":"model","parts":[{"text":"feat: gemini"}]}}]}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := NewClientWithTransport(transport, nil)
+	require.NoError(t, c.Validate())
+	msg, err := c.GetCommitMessage(context.Background(), "sys", "user")
+	require.NoError(t, err)
+	require.Equal(t, "feat: gemini", msg)
+	require.Equal(t, "https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:generateContent?key=gm-key", gotURL)
+	require.Equal(t, "sys", gotBody.SystemInstruction.Parts[0].Text)
+	require.Equal(t, "user", gotBody.Contents[0].Parts[0].Text)
+
+	info := c.Info()
+	require.Equal(t, "gemini", info.Provider)
+	require.NotContains(t, info.Endpoint, "gm-key")
+}
+
+func TestGeminiProvider_NetworkErrorRedactsKey(t *testing.T) {
+	t.Setenv("CATMIT_LLM_PROVIDER", "gemini")
+	t.Setenv("CATMIT_LLM_API_KEY", "gm-secret")
+	t.Setenv("CATMIT_LLM_CA_CERT", "")
+
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	_, err := NewClientWithTransport(transport, nil).GetCommitMessage(context.Background(), "sys", "user")
+	require.True(t, IsType(err, ErrTypeNetwork))
+	require.NotContains(t, err.Error(), "gm-secret")
+}
+
+func TestGeminiProvider_EmptyCandidates(t *testing.T) {
+	t.Setenv("CATMIT_LLM_PROVIDER", "gemini")
+	t.Setenv("CATMIT_LLM_CA_CERT", "")
+
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"candidates":[]}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	_, err := NewClientWithTransport(transport, nil).GetCommitMessage(context.Background(), "sys", "user")
+	require.ErrorContains(t, err, "empty candidates")
+}
+
+func TestNewClient_UnknownProvider(t *testing.T) {
+	t.Setenv("CATMIT_LLM_PROVIDER", "bogus")
+	c := NewClient(nil)
+	require.True(t, IsType(c.Validate(), ErrTypeConfig))
+	_, err := c.GetCommitMessage(context.Background(), "sys", "user")
+	require.True(t, IsType(err, ErrTypeConfig))
+}
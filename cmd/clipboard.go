@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/penwyp/catmit/internal/clipboard"
+	"github.com/spf13/cobra"
+)
+
+// copyToClipboard 将文本写入系统剪贴板；测试时可替换
+var copyToClipboard = clipboard.Copy
+
+// copyPRURL 在 --copy-pr-url 时复制 PR URL；复制失败只警告，PR 已创建成功
+func copyPRURL(ctx context.Context, cmd *cobra.Command, prURL string) {
+	if !flagCopyPRURL || prURL == "" {
+		return
+	}
+	if err := copyToClipboard(ctx, prURL); err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to copy PR URL: %v\n", err)
+		return
+	}
+	_, _ = fmt.Fprintln(statusWriter(cmd), "Copied PR URL to clipboard")
+}
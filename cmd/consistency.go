@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/penwyp/catmit/internal/commitlint"
+	"go.uber.org/zap"
+)
+
+// maxConsistency 是 --consistency 的上限：每条候选都是一次 LLM 调用，过大的值只会放大费用与限流风险
+const maxConsistency = 5
+
+// consistencyClient 并发生成 n 条候选 message，按 scoreCandidate 自动选出得分最高的一条（--consistency）。
+// 交互模式下 Review 界面预填该结果，用户仍可编辑或重新生成。
+type consistencyClient struct {
+	inner clientInterface
+	n     int
+	lint  commitlint.Config
+}
+
+func (c consistencyClient) GetCommitMessage(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	messages := make([]string, c.n)
+	errs := make([]error, c.n)
+	var wg sync.WaitGroup
+	for i := 0; i < c.n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			messages[i], errs[i] = c.inner.GetCommitMessage(ctx, systemPrompt, userPrompt)
+		}(i)
+	}
+	wg.Wait()
+
+	// 只要有一条候选成功即可投票，全部失败时返回第一个错误
+	var candidates []string
+	for i, err := range errs {
+		if err == nil {
+			candidates = append(candidates, strings.TrimSpace(messages[i]))
+		}
+	}
+	if len(candidates) == 0 {
+		return "", errs[0]
+	}
+	best := pickCandidate(candidates, c.lint)
+	verbosef("Picked candidate %d of %d", best+1, len(candidates))
+	if appLogger != nil {
+		appLogger.Debug("Self-consistency vote",
+			zap.Int("candidates", len(candidates)),
+			zap.Int("failed", c.n-len(candidates)),
+			zap.Int("picked", best))
+	}
+	return candidates[best], nil
+}
+
+// pickCandidate 返回得分最高的候选下标，同分时取靠前的一条
+func pickCandidate(candidates []string, cfg commitlint.Config) int {
+	best, bestScore := 0, 0
+	for i, candidate := range candidates {
+		score := scoreCandidate(candidate, candidates, cfg)
+		if i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// scoreCandidate 为候选打分：commitlint 违规越少越好，违规数相同时与越多候选的
+// 标题头（type 与 scope）一致越好，即对 type 与 scope 做多数投票。
+// 每条违规扣除的分数大于一致票数的上限，因此规则符合度总是优先于投票结果。
+func scoreCandidate(candidate string, candidates []string, cfg commitlint.Config) int {
+	votes := 0
+	header := subjectHeader(candidate)
+	for _, other := range candidates {
+		if header != "" && subjectHeader(other) == header {
+			votes++
+		}
+	}
+	return votes - len(commitlint.Lint(candidate, cfg))*(len(candidates)+1)
+}
+
+// subjectHeader 返回标题行冒号前的部分（如 "fix(ui)"），统一小写并去掉 "!"；没有冒号时为空
+func subjectHeader(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	header, _, found := strings.Cut(subject, ":")
+	if !found {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(header), "!"))
+}
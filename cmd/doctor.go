@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/penwyp/catmit/client"
+	"github.com/spf13/cobra"
+)
+
+// doctorRequestTimeout 限制检查 LLM 端点时最小请求的等待时间
+const doctorRequestTimeout = 15 * time.Second
+
+// doctorCheck 是 catmit doctor 的一项检查结果
+type doctorCheck struct {
+	Name       string
+	OK         bool
+	Detail     string // 检查通过或失败时的说明，可为空
+	Suggestion string // 失败时的修复建议
+	Critical   bool   // 失败时 doctor 以非零退出码退出
+}
+
+// doctorLookPath 查找可执行文件；测试时可替换
+var doctorLookPath = exec.LookPath
+
+// doctorRun 执行外部命令（如 gh auth status）；测试时可替换
+var doctorRun = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return realRunner{}.Run(ctx, name, args...)
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that git, the LLM endpoint, the GitHub CLI and config files are set up",
+	Long: `Check the environment catmit needs and print a checklist with suggestions.
+
+Checks: git is installed and the current directory is a repository, the config
+files parse, the LLM configuration is valid, an API key is available, a minimal
+authenticated request to the LLM endpoint succeeds (telling a rejected key, a
+refused connection and a timeout apart), and the GitHub CLI (used by
+--create-pr) is installed and logged in. Exits with code 1 when a critical
+check fails.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	checks := runDoctorChecks(cmd.Context())
+	out := cmd.OutOrStdout()
+	failed := 0
+	for _, check := range checks {
+		mark := "✓"
+		if !check.OK {
+			mark = "✗"
+			if check.Critical {
+				failed++
+			}
+		}
+		line := mark + " " + check.Name
+		if check.Detail != "" {
+			line += ": " + check.Detail
+		}
+		_, _ = fmt.Fprintln(out, line)
+		if !check.OK && check.Suggestion != "" {
+			_, _ = fmt.Fprintf(out, "    → %s\n", check.Suggestion)
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	// 检查结果已输出，避免 cobra 再打印用法与错误
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &ExitError{Code: 1, Err: fmt.Errorf("%d critical check(s) failed", failed)}
+}
+
+// runDoctorChecks 依次执行全部检查；前置条件不满足的检查（如端点无效时的可达性）会被跳过
+func runDoctorChecks(ctx context.Context) []doctorCheck {
+	var checks []doctorCheck
+
+	git := doctorCheck{Name: "git installed", Critical: true, Suggestion: "install git and make sure it is on PATH"}
+	if path, err := doctorLookPath("git"); err == nil {
+		git.OK, git.Detail = true, path
+	}
+	checks = append(checks, git)
+
+	repo := doctorCheck{Name: "inside a git repository", Critical: true, Suggestion: "run catmit inside a repository, or run 'git init'"}
+	if git.OK {
+		if root, err := locateRepoRoot(ctx); err == nil {
+			repo.OK, repo.Detail = true, root
+		}
+	}
+	checks = append(checks, repo)
+
+	cfg := doctorCheck{Name: "config files valid", Critical: true, Suggestion: "fix or remove the config file named above"}
+	if _, err := loadConfigFiles(ctx); err != nil {
+		cfg.Detail = err.Error()
+	} else {
+		cfg.OK = true
+	}
+	checks = append(checks, cfg)
+
+	checks = append(checks, llmDoctorChecks(ctx)...)
+	checks = append(checks, ghDoctorChecks(ctx)...)
+	return checks
+}
+
+// llmDoctorChecks 检查 LLM 配置、API Key 与端点可达性
+func llmDoctorChecks(ctx context.Context) []doctorCheck {
+	cli := clientProvider()
+	llm := doctorCheck{Name: "LLM configuration valid", Critical: true, OK: true}
+	if v, ok := cli.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			llm.OK, llm.Detail = false, err.Error()
+			llm.Suggestion = "check the CATMIT_LLM_* environment variables"
+		}
+	}
+	var info client.Info
+	if reporter, ok := cli.(interface{ Info() client.Info }); ok {
+		info = reporter.Info()
+		if llm.OK {
+			llm.Detail = fmt.Sprintf("%s via %s", info.Model, info.Endpoint)
+		}
+	}
+
+	// 本地模型服务等端点无需鉴权，缺少 Key 不视为致命错误
+	key := doctorCheck{Name: "API key present", OK: client.HasAPIKey(),
+		Suggestion: "set CATMIT_LLM_API_KEY or CATMIT_LLM_API_KEY_FILE (not needed for endpoints without authentication)"}
+
+	reach := doctorCheck{Name: "LLM endpoint accepts requests", Critical: true}
+	if !llm.OK {
+		reach.Detail = "skipped: invalid LLM configuration"
+	} else {
+		// 发送一次最小的鉴权请求，在真正运行前区分 Key 错误、地址错误与超时
+		reqCtx, cancel := context.WithTimeout(ctx, doctorRequestTimeout)
+		_, err := cli.GetCommitMessage(reqCtx, "Reply with OK.", "ping")
+		cancel()
+		if err == nil {
+			reach.OK, reach.Detail = true, info.Endpoint
+		} else {
+			reach.Detail, reach.Suggestion = diagnoseLLMError(err)
+		}
+	}
+	return []doctorCheck{llm, key, reach}
+}
+
+// diagnoseLLMError 将最小请求的错误归类为 Key 被拒绝、连接被拒绝、超时等，返回说明与修复建议
+func diagnoseLLMError(err error) (string, string) {
+	var statusErr *client.StatusError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("timed out after %s", doctorRequestTimeout),
+			"the endpoint is slow or unreachable; check HTTPS_PROXY/HTTP_PROXY or try again with a larger --timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused",
+			"nothing is listening at CATMIT_LLM_API_URL; check the host and port"
+	case errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden):
+		return fmt.Sprintf("status %d: the API key was rejected", statusErr.StatusCode),
+			"check CATMIT_LLM_API_KEY (or CATMIT_LLM_API_KEY_FILE / the Keychain entry) for this endpoint"
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound:
+		return "status 404: no API at this URL",
+			"check the path in CATMIT_LLM_API_URL, e.g. .../v1/chat/completions"
+	case client.IsType(err, client.ErrTypeNetwork):
+		return err.Error(), "check network connectivity, HTTPS_PROXY/HTTP_PROXY and CATMIT_LLM_API_URL"
+	default:
+		return err.Error(), "check CATMIT_LLM_API_URL, CATMIT_LLM_MODEL and the API key"
+	}
+}
+
+// ghDoctorChecks 检查 --create-pr 依赖的 GitHub CLI；未安装或未登录只影响创建 PR
+func ghDoctorChecks(ctx context.Context) []doctorCheck {
+	installed := doctorCheck{Name: "GitHub CLI installed", Suggestion: "install gh from https://cli.github.com to use --create-pr"}
+	if path, err := doctorLookPath("gh"); err == nil {
+		installed.OK, installed.Detail = true, path
+	}
+	if !installed.OK {
+		return []doctorCheck{installed}
+	}
+	auth := doctorCheck{Name: "GitHub CLI logged in", Suggestion: "run 'gh auth login'"}
+	if _, err := doctorRun(ctx, "gh", "auth", "status"); err == nil {
+		auth.OK = true
+	}
+	return []doctorCheck{installed, auth}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/penwyp/catmit/collector"
+	"go.uber.org/zap"
+)
+
+// readCommitTemplate 返回 `git config commit.template` 指向的模板（例如 .gitmessage）的内容，
+// 未配置或读取失败时返回空串。git commit 在工作区根目录执行，因此相对路径相对于工作区根目录解析
+func readCommitTemplate(ctx context.Context) string {
+	col := collector.New(runnerProvider())
+	path, err := col.CommitTemplatePath(ctx)
+	if err != nil || path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		root, err := col.RepoRoot(ctx)
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(root, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if appLogger != nil {
+			appLogger.Warn("Failed to read commit.template", zap.String("path", path), zap.Error(err))
+		}
+		return ""
+	}
+	verbosef("Using commit template %s", path)
+	return string(data)
+}
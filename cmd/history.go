@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/penwyp/catmit/internal/history"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// historyProvider 返回记录 commit message 的历史存储，nil 表示不可用；测试时可替换
+var historyProvider = defaultHistoryProvider
+
+func defaultHistoryProvider() *history.Store {
+	path, err := history.DefaultPath()
+	if err != nil {
+		return nil
+	}
+	return history.New(path, history.DefaultMaxEntries)
+}
+
+// currentRepo 返回当前工作树根目录，作为历史记录的仓库标识与配置查找起点。
+// 通过 runner 执行 git rev-parse --show-toplevel，linked worktree 会得到其自身的根目录；
+// 不在 Git 仓库中时退回工作目录。
+func currentRepo(ctx context.Context) string {
+	if root, err := locateRepoRoot(ctx); err == nil {
+		return root
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// recordMessage 将 message 写入历史文件；写入失败不影响提交流程，仅记录 debug 日志
+func recordMessage(repo, message string, edited bool) {
+	store := historyProvider()
+	if store == nil || strings.TrimSpace(message) == "" {
+		return
+	}
+	err := store.Append(history.Entry{
+		Time:    time.Now(),
+		Repo:    repo,
+		Message: message,
+		Edited:  edited,
+	})
+	if err != nil && appLogger != nil {
+		appLogger.Debug("Failed to record commit message history", zap.Error(err))
+	}
+}
+
+// lastMessage 返回当前仓库最近一次记录的 message，供 --reuse-last 使用
+func lastMessage(repo string) (string, error) {
+	store := historyProvider()
+	if store == nil {
+		return "", fmt.Errorf("commit message history is unavailable")
+	}
+	entry, ok, err := store.Last(repo)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("no recorded commit message for %s", repo)
+	}
+	return entry.Message, nil
+}
+
+var flagHistoryLimit int
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recently generated commit messages",
+	Long: `List commit messages recorded by catmit, newest first.
+
+Every generated or edited message is appended to ~/.config/catmit/history.jsonl
+(or $XDG_CONFIG_HOME/catmit/history.jsonl). Use --reuse-last to commit the most
+recent message for the current repository again.`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&flagHistoryLimit, "limit", "n", 20, "number of entries to show (0 shows all)")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, _ []string) error {
+	store := historyProvider()
+	if store == nil {
+		return fmt.Errorf("commit message history is unavailable")
+	}
+	entries, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No history yet.")
+		return nil
+	}
+
+	out := cmd.OutOrStdout()
+	shown := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		if flagHistoryLimit > 0 && shown >= flagHistoryLimit {
+			break
+		}
+		_, _ = fmt.Fprintln(out, formatHistoryEntry(entries[i]))
+		shown++
+	}
+	return nil
+}
+
+// formatHistoryEntry 以单行形式展示一条记录：时间、仓库与 message 标题
+func formatHistoryEntry(e history.Entry) string {
+	subject := strings.TrimSpace(e.Message)
+	if idx := strings.IndexByte(subject, '\n'); idx >= 0 {
+		subject = strings.TrimSpace(subject[:idx])
+	}
+	mark := ""
+	if e.Edited {
+		mark = " (edited)"
+	}
+	return fmt.Sprintf("%s  %s  %s%s", e.Time.Local().Format("2006-01-02 15:04"), e.Repo, subject, mark)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// seedIntent 是 seed 中以关键字表达的特殊意图
+type seedIntent int
+
+const (
+	seedIntentNone  seedIntent = iota
+	seedIntentWIP              // "wip"：生成的 message 以 "WIP: " 开头
+	seedIntentFixup            // "fixup"：生成 "fixup! <目标提交标题>" 供 rebase --autosquash 使用
+)
+
+// fixupSearchDepth 是查找 fixup 目标时检查的最近提交数
+const fixupSearchDepth = 50
+
+// 由 seed 解析的意图，run 开始时设置
+var (
+	wipMessages bool // 为生成的 message 加上 "WIP: " 前缀
+	fixupSeed   bool // 不调用 LLM，以 fixup! 指向匹配的最近提交
+)
+
+// parseSeedIntent 识别 seed 中的 wip/fixup 关键字（不区分大小写），返回意图与剩余的 seed。
+// 关键字须单独出现，或后跟 ":"/"!" 与其余文本（如 "wip: login form"、"fixup! parser"），
+// 因此 "wipe cache"、"fixup the parser" 等普通 seed 不受影响
+func parseSeedIntent(seed string) (seedIntent, string) {
+	trimmed := strings.TrimSpace(seed)
+	for keyword, intent := range map[string]seedIntent{"wip": seedIntentWIP, "fixup": seedIntentFixup} {
+		if len(trimmed) < len(keyword) || !strings.EqualFold(trimmed[:len(keyword)], keyword) {
+			continue
+		}
+		rest := trimmed[len(keyword):]
+		if rest == "" {
+			return intent, ""
+		}
+		if rest[0] == ':' || rest[0] == '!' {
+			return intent, strings.TrimSpace(rest[1:])
+		}
+	}
+	return seedIntentNone, seed
+}
+
+// fixupTarget 返回 commits（由新到旧的标题）中最近一个匹配 hint 的提交标题；
+// hint 为空时取最近的提交。已有的 fixup!/squash! 提交不作为目标
+func fixupTarget(commits []string, hint string) (string, error) {
+	hint = strings.ToLower(strings.TrimSpace(hint))
+	for _, subject := range commits {
+		subject = strings.TrimSpace(subject)
+		if subject == "" || strings.HasPrefix(subject, "fixup! ") || strings.HasPrefix(subject, "squash! ") {
+			continue
+		}
+		if strings.Contains(strings.ToLower(subject), hint) {
+			return subject, nil
+		}
+	}
+	if hint == "" {
+		return "", fmt.Errorf("no commit to fix up")
+	}
+	return "", fmt.Errorf("no recent commit matches %q to fix up", hint)
+}
+
+// fixupMessage 生成指向 hint 匹配的最近提交的 fixup! message
+func fixupMessage(ctx context.Context, col collectorInterface, hint string) (string, error) {
+	commits, err := col.RecentCommits(ctx, fixupSearchDepth)
+	if err != nil {
+		return "", fmt.Errorf("failed to read recent commits: %w", err)
+	}
+	target, err := fixupTarget(commits, hint)
+	if err != nil {
+		return "", err
+	}
+	verbosef("Fixing up %q", target)
+	return "fixup! " + target, nil
+}
+
+// wipMessage 为 message 加上 "WIP: " 前缀，已有前缀时保持不变
+func wipMessage(message string) string {
+	message = strings.TrimSpace(message)
+	if len(message) >= 4 && strings.EqualFold(message[:4], "wip:") {
+		return message
+	}
+	return "WIP: " + message
+}
+
+// wipClient 为生成的 message 加上 "WIP: " 前缀（seed 为 "wip"）
+type wipClient struct {
+	inner clientInterface
+}
+
+func (c wipClient) GetCommitMessage(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	message, err := c.inner.GetCommitMessage(ctx, systemPrompt, userPrompt)
+	if err != nil {
+		return "", err
+	}
+	return wipMessage(message), nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/penwyp/catmit/internal/commitlint"
+	"github.com/spf13/cobra"
+)
+
+// ExitCodeLintFailed 是 catmit lint 发现违规时的进程退出码
+const ExitCodeLintFailed = 5
+
+// maxStdinMessageSize 限制从标准输入读取的 commit message 大小
+const maxStdinMessageSize = 64 * 1024
+
+// lintMessageProvider 读取 ref 对应提交的完整 message；测试时可替换
+var lintMessageProvider = func(ctx context.Context, ref string) (string, error) {
+	// 防止 ref 被 git 解析为选项
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid commit ref %q", ref)
+	}
+	out, err := runnerProvider().Run(ctx, "git", "log", "-1", "--pretty=%B", ref, "--")
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit message of %s: %s", ref, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+var (
+	flagLintMaxSubject int
+	flagLintDisable    []string
+	flagLintEnable     []string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [<commit-ref>]",
+	Short: "Check a commit message against Conventional Commits",
+	Long: `Check a commit message against the Conventional Commits rules catmit follows.
+
+The message is read from <commit-ref> (default HEAD). When no ref is given and
+stdin is not a terminal, or the ref is "-", the message is read from stdin, so
+the command also works as a commit-msg hook:
+
+  catmit lint - < "$1"
+
+Rules: subject-length, type-prefix, body-leading-blank, subject-imperative.
+Disable rules with --disable or lint_disable in the config file.
+The footer rules footer-leading-blank, footer-format and breaking-change-marker
+are off by default; turn them on with --enable or lint_enable in the config file.
+Exits with code 5 when violations are found.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLint,
+}
+
+func init() {
+	lintCmd.Flags().IntVar(&flagLintMaxSubject, "max-subject", 0, "maximum subject length (default from max_subject in the config file, or 72)")
+	lintCmd.Flags().StringSliceVar(&flagLintDisable, "disable", nil, "comma-separated rules to skip")
+	lintCmd.Flags().StringSliceVar(&flagLintEnable, "enable", nil, "comma-separated opt-in rules to check: "+strings.Join(commitlint.OptInRules, ", "))
+	rootCmd.AddCommand(lintCmd)
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := loadConfigFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	lintCfg := commitlint.Config{MaxSubject: cfg.MaxSubject, Disabled: cfg.LintDisable, Enabled: cfg.LintEnable}
+	if cmd.Flags().Changed("max-subject") {
+		lintCfg.MaxSubject = flagLintMaxSubject
+	}
+	if cmd.Flags().Changed("disable") {
+		lintCfg.Disabled = flagLintDisable
+	}
+	if cmd.Flags().Changed("enable") {
+		lintCfg.Enabled = flagLintEnable
+	}
+	if err := commitlint.ValidateRules(lintCfg.Disabled); err != nil {
+		return err
+	}
+	if err := commitlint.ValidateRules(lintCfg.Enabled); err != nil {
+		return err
+	}
+
+	message, err := readLintMessage(ctx, cmd, args)
+	if err != nil {
+		return err
+	}
+
+	violations := commitlint.Lint(message, lintCfg)
+	out := cmd.OutOrStdout()
+	if len(violations) == 0 {
+		_, _ = fmt.Fprintln(out, "✔ commit message follows Conventional Commits")
+		return nil
+	}
+	for _, v := range violations {
+		_, _ = fmt.Fprintf(out, "✖ %s\n", v)
+	}
+	// 违规已输出，避免 cobra 再打印用法与错误
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &ExitError{Code: ExitCodeLintFailed, Err: fmt.Errorf("commit message has %d lint violation(s)", len(violations))}
+}
+
+// readLintMessage 按参数确定 message 来源：ref 为 "-" 或未指定 ref 且标准输入为非空管道时读取标准输入，
+// 否则读取 ref（默认 HEAD）对应提交的 message
+func readLintMessage(ctx context.Context, cmd *cobra.Command, args []string) (string, error) {
+	ref := "HEAD"
+	if len(args) > 0 {
+		ref = args[0]
+	}
+	implicit := len(args) == 0 && !stdinIsTerminal() && stdinIsPiped()
+	if ref == "-" || implicit {
+		data, err := io.ReadAll(io.LimitReader(cmd.InOrStdin(), maxStdinMessageSize))
+		if err != nil {
+			return "", fmt.Errorf("failed to read commit message from stdin: %w", err)
+		}
+		// 隐式读取到空输入（如 CI 中的 </dev/null）时回退到 HEAD
+		if ref == "-" || strings.TrimSpace(string(data)) != "" {
+			return string(data), nil
+		}
+	}
+	return lintMessageProvider(ctx, ref)
+}
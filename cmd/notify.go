@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/penwyp/catmit/internal/notify"
+	"go.uber.org/zap"
+)
+
+// notifyTimeout 限制发送提交通知的耗时，避免慢速 webhook 拖住命令退出
+const notifyTimeout = 5 * time.Second
+
+// notifierProvider 返回提交后的通知目标，nil 表示未配置；测试时可替换
+var notifierProvider = defaultNotifierProvider
+
+// defaultNotifierProvider 在设置了 CATMIT_WEBHOOK_URL 时返回向其 POST JSON 的 Webhook
+func defaultNotifierProvider() notify.Notifier {
+	url := strings.TrimSpace(os.Getenv("CATMIT_WEBHOOK_URL"))
+	if url == "" {
+		return nil
+	}
+	return notify.NewWebhook(url)
+}
+
+// notifyCommit 在提交（以及推送、创建 PR）完成后发送通知；
+// 通知失败只记录警告，不影响提交结果。
+func notifyCommit(ctx context.Context, col collectorInterface, repo, message, sha, prURL string) {
+	n := notifierProvider()
+	if n == nil {
+		return
+	}
+	branch, _ := col.BranchName(ctx)
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	err := n.Notify(ctx, notify.Event{
+		Repo:    filepath.Base(repo),
+		Branch:  branch,
+		Subject: subject,
+		SHA:     sha,
+		PRURL:   prURL,
+	})
+	if err != nil && appLogger != nil {
+		appLogger.Warn("Failed to send commit notification", zap.Error(err))
+	}
+}
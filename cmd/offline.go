@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/penwyp/catmit/collector"
+	"github.com/penwyp/catmit/prompt"
+)
+
+// offlineMaxListedFiles 是 --offline 标题中最多列出的文件名数量，超过时只写文件数
+const offlineMaxListedFiles = 3
+
+// offlineGenerate 不调用 LLM，根据变更分析生成规则化的 commit message（--offline）
+func offlineGenerate(ctx context.Context, col collectorInterface) (string, error) {
+	summary, err := col.AnalyzeChanges(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to analyze changes: %w", err)
+	}
+	if len(summary.FilesByPriority) == 0 {
+		return "", collector.ErrNoDiff
+	}
+	message := offlineMessage(summary)
+	if useGitmoji {
+		message = prompt.ApplyGitmoji(message)
+	}
+	if wipMessages {
+		message = wipMessage(message)
+	}
+	verbosef("Built offline commit message from %d files", len(summary.FilesByPriority))
+	return message, nil
+}
+
+// offlineMessage 由 SuggestedPrefix、主要影响区域与文件列表拼出 message，例如
+// "chore(config): update providers.yaml"。文件超过 offlineMaxListedFiles 个时
+// 标题只写文件数，并在正文中逐行列出全部路径。
+func offlineMessage(summary *collector.ChangesSummary) string {
+	prefix := summary.SuggestedPrefix
+	if prefix == "" {
+		prefix = "chore"
+	}
+	files := summary.FilesByPriority
+	if scope := dominantArea(files); scope != "" {
+		prefix += "(" + scope + ")"
+	}
+
+	verb := ""
+	for _, f := range files {
+		v := offlineVerb(f)
+		if verb != "" && v != verb {
+			// 混合了多种变更时统一描述为 update
+			verb = "update"
+			break
+		}
+		verb = v
+	}
+
+	if len(files) > offlineMaxListedFiles {
+		lines := make([]string, 0, len(files))
+		for _, f := range files {
+			lines = append(lines, "- "+f.Path)
+		}
+		return fmt.Sprintf("%s: %s %d files\n\n%s", prefix, verb, len(files), strings.Join(lines, "\n"))
+	}
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, path.Base(f.Path))
+	}
+	list := names[0]
+	if len(names) > 1 {
+		list = strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	}
+	return fmt.Sprintf("%s: %s %s", prefix, verb, list)
+}
+
+// dominantArea 返回变更文件最多的顶层目录作为 scope，数量相同时取优先级更高者；
+// 变更主要位于仓库根目录时返回空串
+func dominantArea(files []collector.FileStatus) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, f := range files {
+		area := f.AffectedArea
+		if area == "" {
+			if dir := path.Dir(f.Path); dir != "." {
+				area = strings.SplitN(dir, "/", 2)[0]
+			} else {
+				area = "root"
+			}
+		}
+		counts[area]++
+		if best == "" || counts[area] > counts[best] {
+			best = area
+		}
+	}
+	if best == "root" {
+		return ""
+	}
+	return best
+}
+
+// offlineVerb 根据文件状态选择标题中的动词
+func offlineVerb(f collector.FileStatus) string {
+	switch {
+	case f.IsUntracked || f.IndexStatus == 'A' || f.IndexStatus == '?':
+		return "add"
+	case f.IndexStatus == 'D' || f.WorkStatus == 'D':
+		return "remove"
+	case f.IsRenamed || f.IndexStatus == 'R':
+		return "rename"
+	}
+	return "update"
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/penwyp/catmit/collector"
+)
+
+// onlyPaths 是 --only 指定的路径（相对仓库根目录），defaultCollectorProvider 据此限制 diff，
+// commitArgs 据此以 git commit --only 只提交这些路径
+var onlyPaths []string
+
+// applyOnly 校验 --only 的每个路径都有变更，并将其转换为相对仓库根目录的路径。
+// 不改动暂存区：提交时由 git commit --only 只提交这些路径，其他已暂存的变更保持不变
+func applyOnly(ctx context.Context, paths []string) error {
+	runner := runnerProvider()
+	out, err := runner.Run(ctx, "git", "rev-parse", "--show-prefix")
+	if err != nil {
+		return fmt.Errorf("git rev-parse --show-prefix failed: %w", err)
+	}
+	prefix := strings.TrimSpace(string(out))
+
+	staged, err := gitLines(ctx, runner, collector.DiffArgs("--cached", "--name-only")...)
+	if err != nil {
+		return err
+	}
+	unstaged, err := gitLines(ctx, runner, collector.DiffArgs("--name-only")...)
+	if err != nil {
+		return err
+	}
+	untracked, err := gitLines(ctx, runner, "ls-files", "--others", "--exclude-standard", "--full-name", "--", ":/")
+	if err != nil {
+		return err
+	}
+	changed := uniqueSorted(append(append(append([]string(nil), staged...), unstaged...), untracked...))
+
+	rels := make([]string, 0, len(paths))
+	var missing []string
+	for _, p := range paths {
+		rel := path.Clean(path.Join(prefix, filepath.ToSlash(p)))
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("--only %s: path is outside the repository", p)
+		}
+		matched := false
+		for _, f := range changed {
+			if collector.InPaths(f, []string{rel}) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			missing = append(missing, p)
+		}
+		rels = append(rels, rel)
+	}
+	if len(missing) > 0 {
+		list := "  (none)"
+		if len(changed) > 0 {
+			list = "  " + strings.Join(changed, "\n  ")
+		}
+		return fmt.Errorf("--only: no changes in %s; changed files:\n%s", strings.Join(missing, ", "), list)
+	}
+	onlyPaths = rels
+	verbosef("Restricting the commit to %s", strings.Join(rels, ", "))
+	return nil
+}
+
+// onlyPathspecs 返回 onlyPaths 对应的 pathspec；使用 :(top,literal) 避免受当前目录与通配符影响
+func onlyPathspecs() []string {
+	specs := make([]string, 0, len(onlyPaths))
+	for _, p := range onlyPaths {
+		specs = append(specs, ":(top,literal)"+p)
+	}
+	return specs
+}
+
+// intentToAddOnly 在用户确认提交后、git commit --only 之前，将 --only 路径下的未跟踪文件
+// 记为 intent-to-add：git commit 的路径参数只匹配 git 已知的文件
+func intentToAddOnly(ctx context.Context) error {
+	if len(onlyPaths) == 0 {
+		return nil
+	}
+	args := append([]string{"add", "--intent-to-add", "--"}, onlyPathspecs()...)
+	if out, err := runnerProvider().Run(ctx, "git", args...); err != nil {
+		return fmt.Errorf("git add --intent-to-add failed: %w\n%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// gitLines 运行 git 命令并返回非空的输出行
+func gitLines(ctx context.Context, runner collector.Runner, args ...string) ([]string, error) {
+	out, err := runner.Run(ctx, "git", args...)
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// uniqueSorted 返回排序并去重后的字符串列表
+func uniqueSorted(items []string) []string {
+	sort.Strings(items)
+	unique := items[:0]
+	for i, item := range items {
+		if i == 0 || item != items[i-1] {
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
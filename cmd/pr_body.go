@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/penwyp/catmit/collector"
+	"github.com/penwyp/catmit/prompt"
+	"go.uber.org/zap"
+)
+
+// prTemplatePaths 是按优先级查找的 GitHub PR 模板位置（相对仓库根目录）
+var prTemplatePaths = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+}
+
+// readPRTemplate 返回仓库的 PR 模板内容，不存在时返回空串
+func readPRTemplate(ctx context.Context) string {
+	root, err := locateRepoRoot(ctx)
+	if err != nil {
+		return ""
+	}
+	for _, rel := range prTemplatePaths {
+		if data, err := os.ReadFile(filepath.Join(root, rel)); err == nil {
+			verbosef("Using pull request template %s", rel)
+			return string(data)
+		}
+	}
+	return ""
+}
+
+// prPromptBuilder 是可选能力：支持生成 PR 描述提示词的 prompt builder
+type prPromptBuilder interface {
+	BuildPRSystemPrompt(template string) string
+	BuildPRUserPrompt(title string, commits []string, diff string, files []string) string
+}
+
+// prBodySource 提供生成 PR 描述所需的分支 diff、分支提交与 HEAD 提交信息
+type prBodySource interface {
+	branchDiffer
+	HeadMessage(ctx context.Context) (string, error)
+	CommitsOnBranch(ctx context.Context, baseRef string) ([]string, error)
+}
+
+// diffStatter 是可选能力：统计相对 PR base 的变更，用于 PR 模板的 {{.DiffStat}}
+type diffStatter interface {
+	DiffStatAgainstRef(ctx context.Context, baseRef string) (string, error)
+}
+
+// prTemplateData 收集填充 PR 模板占位符所需的数据；变更统计获取失败时留空
+func prTemplateData(ctx context.Context, col prBodySource, baseRef string, commits []string) prompt.PRTemplateData {
+	data := prompt.PRTemplateData{Commits: commits}
+	if statter, ok := col.(diffStatter); ok {
+		stat, err := statter.DiffStatAgainstRef(ctx, baseRef)
+		if err != nil && appLogger != nil {
+			appLogger.Debug("Failed to compute the branch diff stat", zap.Error(err))
+		}
+		data.DiffStat = stat
+	}
+	return data
+}
+
+// generatePRBody 以 HEAD 的标题行作为 PR 标题，并让 LLM 根据 baseRef 以来的分支 diff
+// 与提交列表撰写 PR 描述（--pr-generate-body）。仓库存在 PR 模板时按模板结构填写，
+// 模板中的 {{.BranchCommits}}/{{.RecentCommits}} 等占位符先替换为分支提交列表与变更统计。
+func generatePRBody(ctx context.Context, col prBodySource, baseRef string) (string, string, error) {
+	diff, err := col.DiffAgainstRef(ctx, baseRef)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to diff against %s: %w", baseRef, err)
+	}
+	if strings.TrimSpace(diff) == "" {
+		return "", "", fmt.Errorf("no changes since %s to describe", baseRef)
+	}
+	head, err := col.HeadMessage(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	title, _, _ := strings.Cut(head, "\n")
+
+	var files []string
+	if changes, err := col.AnalyzeChangesAgainstRef(ctx, baseRef); err == nil {
+		for _, file := range changes.FilesByPriority {
+			files = append(files, file.Path)
+		}
+	}
+	// 分支提交列表只用于补充描述，获取失败时仍可仅凭 diff 生成
+	commits, err := col.CommitsOnBranch(ctx, baseRef)
+	if err != nil && appLogger != nil {
+		appLogger.Debug("Failed to list branch commits", zap.Error(err))
+	}
+	verbosef("Generating pull request body from %d commits and %d files changed since %s", len(commits), len(files), baseRef)
+
+	builder, ok := promptProvider(currentLang()).(prPromptBuilder)
+	if !ok {
+		return "", "", fmt.Errorf("--pr-generate-body is not supported by this prompt builder")
+	}
+	template := prompt.FillPRTemplateData(readPRTemplate(ctx), prTemplateData(ctx, col, baseRef, commits))
+	warnUnfilledPlaceholders("PR template references unknown variables", templateVariables(template))
+	systemPrompt := builder.BuildPRSystemPrompt(template)
+	// BuildPRUserPrompt 会按预算截断 diff，因此按截断前的内容检查 --max-prompt-tokens
+	if err := checkPromptBudget(systemPrompt, title, strings.Join(commits, "\n"), strings.Join(files, ", "), diff); err != nil {
+		return "", "", err
+	}
+	// PR 描述不是 commit message，不经过 gitmoji、WIP、--consistency 等后处理
+	cli, err := newRawClient()
+	if err != nil {
+		return "", "", err
+	}
+	apiCtx, apiCancel := context.WithTimeout(ctx, time.Duration(flagTimeout)*time.Second)
+	defer apiCancel()
+	body, err := cli.GetCommitMessage(apiCtx, systemPrompt, builder.BuildPRUserPrompt(title, commits, diff, files))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate pull request body: %w", err)
+	}
+	warnUnfilledPlaceholders("Generated PR body contains unfilled placeholders", prompt.UnfilledPlaceholders(body))
+	return strings.TrimSpace(title), strings.TrimSpace(body), nil
+}
+
+// updateExistingPRBody 将生成的描述 body 合并到已存在的 PR url 中：有内容的小节保持原样，
+// 为空的小节与缺少的小节由 body 填充（prompt.MergeTemplate）。返回是否更新了 PR；
+// 读取或更新失败只记录日志，不影响已完成的提交与推送
+func updateExistingPRBody(ctx context.Context, runner collector.Runner, url, body string) bool {
+	existing, err := runner.Run(ctx, "gh", "pr", "view", url, "--json", "body", "--jq", ".body")
+	if err != nil {
+		if appLogger != nil {
+			appLogger.Debug("Failed to read the existing pull request body", zap.Error(err))
+		}
+		return false
+	}
+	current := strings.TrimRight(string(existing), "\n")
+	merged := prompt.MergeTemplate(current, body)
+	if merged == current {
+		return false
+	}
+	if _, err := runner.Run(ctx, "gh", "pr", "edit", url, "--body", merged); err != nil {
+		if appLogger != nil {
+			appLogger.Debug("Failed to update the existing pull request body", zap.Error(err))
+		}
+		return false
+	}
+	return true
+}
+
+// checkPromptBudget 在设置了 --max-prompt-tokens 且未指定 --truncate 时，
+// 估算 parts 组成的提示词，超出上限则返回与 commit message 路径相同的 ErrTypeValidation 错误
+func checkPromptBudget(parts ...string) error {
+	if flagMaxPromptTokens <= 0 || flagTruncate {
+		return nil
+	}
+	if estimated := prompt.EstimateTokens(strings.Join(parts, "\n\n")); estimated > flagMaxPromptTokens {
+		return promptSizeError(&prompt.BudgetExceededError{Estimated: estimated, Limit: flagMaxPromptTokens})
+	}
+	return nil
+}
+
+// templateVariables 返回填充后的 PR 模板中残留的 {{.Foo}} 变量；[Foo] 与 <Foo> 是留给模型填写的提示，不在此列
+func templateVariables(template string) []string {
+	var vars []string
+	for _, p := range prompt.UnfilledPlaceholders(template) {
+		if strings.HasPrefix(p, "{{") {
+			vars = append(vars, p)
+		}
+	}
+	return vars
+}
+
+// warnUnfilledPlaceholders 在 placeholders 非空时记录警告
+func warnUnfilledPlaceholders(msg string, placeholders []string) {
+	if len(placeholders) > 0 && appLogger != nil {
+		appLogger.Warn(msg, zap.Strings("placeholders", placeholders))
+	}
+}
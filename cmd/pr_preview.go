@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/penwyp/catmit/prompt"
+)
+
+// prPreviewIn 与 prPreviewOut 是 --pr-preview 预览与确认使用的输入输出；测试时可替换
+var (
+	prPreviewIn  io.Reader = os.Stdin
+	prPreviewOut io.Writer = os.Stdout
+)
+
+// prPreviewConfirm 为 true 时 --pr-preview 在预览后询问是否创建，由 run 在交互模式下设置
+var prPreviewConfirm bool
+
+// errPRDeclined 表示用户在 --pr-preview 的预览后没有确认创建 PR
+var errPRDeclined = errors.New("pull request creation declined")
+
+// previewPR 输出即将创建的 PR 的标题与描述，并提示残留的模板占位符（--pr-preview）。
+// prPreviewConfirm 时读取一行回答，只有 y 或 yes 才继续，否则返回 errPRDeclined
+func previewPR(title, body string) error {
+	out := prPreviewOut
+	_, _ = fmt.Fprintln(out, "Pull request preview:")
+	if title == "" {
+		_, _ = fmt.Fprintln(out, "(title and body filled in by gh from the branch commits)")
+	} else {
+		_, _ = fmt.Fprintf(out, "Title: %s\n\n%s\n", title, body)
+	}
+	if placeholders := prompt.UnfilledPlaceholders(body); len(placeholders) > 0 {
+		_, _ = fmt.Fprintf(out, "Warning: unfilled template placeholders: %s\n", strings.Join(placeholders, ", "))
+	}
+	if !prPreviewConfirm {
+		return nil
+	}
+	_, _ = fmt.Fprint(out, "Create this pull request? [y/N] ")
+	answer, _ := bufio.NewReader(prPreviewIn).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errPRDeclined
+}
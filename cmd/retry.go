@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/penwyp/catmit/client"
+	"github.com/spf13/cobra"
+)
+
+// retryBaseDelay 是 --max-retries 第一次重试前的等待时间，之后每次翻倍；测试时可替换
+var retryBaseDelay = time.Second
+
+// withRetries 执行 fn，遇到可重试的错误时以指数退避重新执行，最多重试 maxRetries 次。
+// 只用于 message 生成：重新构建 prompt 并请求 LLM，单个 git 命令的重试由 collector 的 RetryConfig 负责。
+func withRetries(ctx context.Context, cmd *cobra.Command, maxRetries int, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxRetries || !isRetryable(ctx, err) {
+			return err
+		}
+		delay := retryBaseDelay << attempt
+		if !flagQuiet {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Attempt %d of %d failed: %v; retrying in %s\n", attempt+1, maxRetries+1, err, delay)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// isRetryable 报告错误是否可能是暂时性的：LLM 请求超时、网络错误、429 与 5xx。
+// 不在 git 仓库中、参数校验、鉴权（401/403）等错误重试也不会成功；用户中断时不再重试。
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || client.IsType(err, client.ErrTypeNetwork) {
+		return true
+	}
+	var statusErr *client.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
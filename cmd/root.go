@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"os/exec"
@@ -15,11 +20,15 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/penwyp/catmit/client"
 	"github.com/penwyp/catmit/collector"
+	"github.com/penwyp/catmit/config"
+	"github.com/penwyp/catmit/internal/commitlint"
 	"github.com/penwyp/catmit/internal/logger"
+	"github.com/penwyp/catmit/internal/msgdiff"
 	"github.com/penwyp/catmit/prompt"
 	"github.com/penwyp/catmit/ui"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // version holds the current version of catmit
@@ -33,7 +42,8 @@ func GetVersionString() string {
 
 // ErrPRAlreadyExists is returned when a PR already exists for the branch
 type ErrPRAlreadyExists struct {
-	URL string
+	URL     string
+	Updated bool // 生成的描述已填入现有 PR 中为空的小节
 }
 
 func (e *ErrPRAlreadyExists) Error() string {
@@ -43,13 +53,28 @@ func (e *ErrPRAlreadyExists) Error() string {
 // 将关键依赖抽象为接口以便测试时注入 Mock。
 // 若在运行时未被替换，则使用默认实现。
 var (
+	runnerProvider    func() collector.Runner                     = defaultRunnerProvider // Collector 执行 git 命令所用的 Runner
 	collectorProvider func() collectorInterface                   = defaultCollectorProvider
 	promptProvider    func(lang string) promptInterface           = defaultPromptProvider
 	clientProvider    func() clientInterface                      = defaultClientProvider
 	committer         commitInterface                             = defaultCommitter{}
 	appLogger         *zap.Logger                                 // 全局日志记录器
+	verboseOut        io.Writer                                   // --verbose 进度输出目标，nil 表示关闭
+	bodyMode          prompt.BodyMode                             // 由 --body/--no-body/CATMIT_BODY 解析的正文要求
+	useGitmoji        bool                                        // 由 --gitmoji/CATMIT_GITMOJI/配置文件解析
+	runConfig         *config.Config                              // 本次运行合并后的配置，run 开始时解析
+	plainOutput       bool                                        // 不使用 lipgloss 样式输出状态行
+	cacheTTL          = collector.DefaultCacheTTL                 // 由 --cache-ttl/CATMIT_CACHE_TTL 解析的 git 命令缓存时长
 )
 
+// verbosef 输出一行面向用户的简洁进度信息，仅在 --verbose 生效时输出
+func verbosef(format string, args ...interface{}) {
+	if verboseOut == nil {
+		return
+	}
+	_, _ = fmt.Fprintf(verboseOut, "› "+format+"\n", args...)
+}
+
 type collectorInterface interface {
 	RecentCommits(ctx context.Context, n int) ([]string, error)
 	Diff(ctx context.Context) (string, error)
@@ -79,21 +104,92 @@ type commitInterface interface {
 	HasStagedChanges(ctx context.Context) bool
 	CreatePullRequest(ctx context.Context) (string, error)
 	NeedsPush(ctx context.Context) (bool, error)
+	// LastCommitSHA 返回刚完成的提交的 SHA，用于在提交后显示；不支持时返回空串
+	LastCommitSHA(ctx context.Context) (string, error)
 }
 
 // ---------------- 默认实现 ------------------
+func defaultRunnerProvider() collector.Runner {
+	return realRunner{debug: flagDebug}
+}
+
 func defaultCollectorProvider() collectorInterface {
-	// 使用真实 Runner（os/exec）实现，后续补充。
-	return collector.New(realRunner{debug: flagDebug})
+	// 通过 runnerProvider 获取 Runner，测试可替换为不启动 git 的 Runner
+	col := collector.NewWithCache(runnerProvider(), cacheTTL)
+	if flagMaxFileSize > 0 {
+		col.SetMaxFileSize(flagMaxFileSize, warnOversizedFile)
+	}
+	col.SetSkipUntracked(flagNoUntracked)
+	col.SetAmend(flagAmend)
+	col.SetDiffStatHeader(flagDiffStat)
+	col.SetMaxLineLength(flagMaxLineLength)
+	col.SetCollapseDeletions(flagCollapseDeletions)
+	col.SetIgnoreWhitespace(flagIgnoreWhitespace)
+	col.SetSlowCommandThreshold(flagSlowGitThreshold, warnSlowCommand)
+	col.SetOnlyPaths(onlyPaths)
+	if runConfig != nil {
+		col.SetExcludePatterns(runConfig.Exclude)
+		col.SetPrefixMap(runConfig.PrefixMap)
+	}
+	return col
+}
+
+// warnOversizedFile 提示某个文件因超过 --max-file-size 而未纳入 diff
+func warnOversizedFile(path string, size int64) {
+	if appLogger != nil {
+		appLogger.Warn("Skipping file larger than --max-file-size",
+			zap.String("path", path),
+			zap.Int64("size", size),
+			zap.Int64("limit", flagMaxFileSize))
+	}
+}
+
+// warnSlowCommand 提示某个 git 命令耗时超过 --slow-git-threshold
+func warnSlowCommand(timing collector.CommandTiming) {
+	if appLogger != nil {
+		appLogger.Warn("Slow git command",
+			zap.String("command", timing.Command),
+			zap.Duration("duration", timing.Duration),
+			zap.Duration("threshold", flagSlowGitThreshold))
+	}
 }
 
 func defaultPromptProvider(lang string) promptInterface {
-	return prompt.NewBuilder(lang, 0)
+	b := prompt.NewBuilder(lang, 0)
+	b.SetBodyMode(bodyMode)
+	b.SetGitmoji(useGitmoji)
+	if runConfig != nil {
+		if preset, err := prompt.LookupPreset(runConfig.Preset); err == nil {
+			b.SetPreset(preset)
+		}
+		b.SetMaxSubject(runConfig.MaxSubject)
+		b.SetTemplate(runConfig.CommitTemplate)
+		if len(runConfig.PrefixMap) > 0 {
+			b.SetPrefixMap(collector.ResolvePrefixMap(runConfig.PrefixMap))
+		}
+	}
+	if flagMaxPromptTokens > 0 {
+		b.SetTokenBudget(flagMaxPromptTokens, !flagTruncate)
+	}
+	return b
+}
+
+// promptSizeError 将超出 --max-prompt-tokens 的预算错误转换为 ErrTypeValidation，其他错误原样返回
+func promptSizeError(err error) error {
+	var exceeded *prompt.BudgetExceededError
+	if !errors.As(err, &exceeded) {
+		return err
+	}
+	return &client.Error{
+		Type:       client.ErrTypeValidation,
+		Message:    fmt.Sprintf("prompt too large: about %d tokens, --max-prompt-tokens is %d", exceeded.Estimated, exceeded.Limit),
+		Suggestion: "stage fewer changes, or pass --truncate to cut the diff down to the limit",
+	}
 }
 
 func defaultClientProvider() clientInterface {
 	// 使用新的通用 Client，它会自动从环境变量读取配置
-	return client.NewClient(appLogger)
+	return client.NewClientWithModel(flagModel, appLogger)
 }
 
 // realRunner 实际执行系统命令；仅在生产模式使用。
@@ -103,12 +199,22 @@ type realRunner struct {
 
 func (r realRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
 	cmd := exec.CommandContext(ctx, name, args...)
+	verbosef("$ %s", quoteCommand(name, args...))
 	if r.debug {
 		appLogger.Debug("Running command",
 			zap.String("command", name),
 			zap.Strings("args", args))
 	}
-	output, err := cmd.CombinedOutput()
+	// 返回合并输出供调用方解析与展示；失败时另外保留分开的 stdout 与 stderr
+	var combined logBuffer
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&combined, &stdout)
+	cmd.Stderr = io.MultiWriter(&combined, &stderr)
+	err := cmd.Run()
+	output := combined.buf.Bytes()
+	if err != nil {
+		err = &collector.CommandError{Err: err, Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+	}
 	if r.debug {
 		appLogger.Debug("Command output",
 			zap.Int("output_length", len(output)),
@@ -123,22 +229,34 @@ func (r realRunner) Run(ctx context.Context, name string, args ...string) ([]byt
 	return output, err
 }
 
-// defaultCommitter 使用 git commit -m 执行提交。
+// defaultCommitter 使用 git commit -m 执行提交，指定 --amend 时改写 HEAD。
 
 type defaultCommitter struct{}
 
 func (defaultCommitter) Commit(ctx context.Context, message string) error {
-	cmd := exec.CommandContext(ctx, "git", "commit", "-m", message)
+	if err := intentToAddOnly(ctx); err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "git", commitArgs(message)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
+// LastCommitSHA 返回 HEAD 的完整 SHA，用于在提交后显示
+func (defaultCommitter) LastCommitSHA(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 func (d defaultCommitter) Push(ctx context.Context) error {
 	if appLogger != nil {
 		appLogger.Debug("Executing git push command")
 	}
-	cmd := exec.CommandContext(ctx, "git", "push")
+	cmd := exec.CommandContext(ctx, "git", pushArgs()...)
 	// Capture output instead of connecting directly to terminal
 	// This prevents error messages from bypassing the TUI
 	output, err := cmd.CombinedOutput()
@@ -177,14 +295,45 @@ func (d defaultCommitter) CreatePullRequest(ctx context.Context) (string, error)
 	if appLogger != nil {
 		appLogger.Debug("Creating GitHub pull request")
 	}
-	
+
+	col := collector.NewWithCache(runnerProvider(), cacheTTL)
+	base := resolvePRBase(ctx, col)
+	var title, body string
+	var err error
+	switch {
+	case flagPRDryRun && (flagBaseRef != "" || flagPRGenerateBody):
+		// --pr-dry-run 只输出命令，不调用 LLM
+		verbosef("Skipping pull request body generation under --pr-dry-run")
+	case flagBaseRef != "":
+		title, body, err = summarizeBranch(ctx, col)
+	case flagPRGenerateBody:
+		// 与 PR 的 base 分支比较，而不是仅描述刚创建的提交
+		title, body, err = generatePRBody(ctx, col, prRemote()+"/"+base)
+	}
+	if err != nil {
+		return "", err
+	}
+	// --dry-run --create-pr 不提交新变更，创建的 PR 为草稿
+	args := prCreateArgs(base, prHead(ctx, col), title, body, flagDryRun)
+
+	// Dry-run: only record the command that would be executed; the URL stays empty
+	if flagPRDryRun {
+		prDryRunCommand = quoteCommand("gh", args...)
+		return "", nil
+	}
+	if flagPRPreview {
+		if err := previewPR(title, body); err != nil {
+			return "", err
+		}
+	}
+
 	// Check if gh CLI is available
 	if _, err := exec.LookPath("gh"); err != nil {
 		return "", fmt.Errorf("gh CLI not found: %w. Please install GitHub CLI: https://cli.github.com/", err)
 	}
-	
+
 	// Execute gh pr create command
-	cmd := exec.CommandContext(ctx, "gh", "pr", "create", "--fill", "--base", "main", "--draft=false")
+	cmd := exec.CommandContext(ctx, "gh", args...)
 	output, err := cmd.CombinedOutput()
 	
 	if appLogger != nil {
@@ -204,8 +353,14 @@ func (d defaultCommitter) CreatePullRequest(ctx context.Context) (string, error)
 		if strings.Contains(outputStr, "already exists") {
 			// Extract the existing PR URL
 			existingPRURL := extractPRURL(outputStr)
+			if existingPRURL == "" {
+				// Some gh versions print the message without a URL; ask gh for it
+				existingPRURL = lookupExistingPRURL(ctx, realRunner{debug: flagDebug})
+			}
 			if existingPRURL != "" {
-				return "", &ErrPRAlreadyExists{URL: existingPRURL}
+				// 生成了描述时只填充现有 PR 中为空的小节，保留手动编辑的内容
+				updated := body != "" && updateExistingPRBody(ctx, runnerProvider(), existingPRURL, body)
+				return "", &ErrPRAlreadyExists{URL: existingPRURL, Updated: updated}
 			}
 		}
 		return "", fmt.Errorf("failed to create pull request: %w\nOutput: %s", err, outputStr)
@@ -217,6 +372,141 @@ func (d defaultCommitter) CreatePullRequest(ctx context.Context) (string, error)
 	return prURL, nil
 }
 
+// prDryRunCommand 是 --pr-dry-run 时 CreatePullRequest 记录的 gh 命令，由 createPullRequest 输出；
+// CreatePullRequest 本身返回空 URL，避免命令文本被当作 PR URL 显示、复制或发送到 webhook
+var prDryRunCommand string
+
+// defaultPRBase is used when the base branch can be neither configured nor detected
+const defaultPRBase = "main"
+
+// defaultBranchResolver resolves the default branch of a remote
+type defaultBranchResolver interface {
+	DefaultBranch(ctx context.Context, remote string) (string, error)
+}
+
+// defaultRemote is the remote used for PR base detection when --remote is not set
+const defaultRemote = "origin"
+
+// prRemote returns the remote whose default branch is used as the PR base
+func prRemote() string {
+	if flagRemote != "" {
+		return flagRemote
+	}
+	return defaultRemote
+}
+
+// resolvePRBase returns the base branch for PR creation.
+// An explicit --pr-base wins, otherwise the remote's default branch is used,
+// falling back to "main" when detection fails.
+func resolvePRBase(ctx context.Context, resolver defaultBranchResolver) string {
+	if flagPRBase != "" {
+		return flagPRBase
+	}
+	branch, err := resolver.DefaultBranch(ctx, prRemote())
+	if err != nil {
+		if appLogger != nil {
+			appLogger.Debug("Failed to detect default branch, using fallback",
+				zap.Error(err),
+				zap.String("fallback", defaultPRBase))
+		}
+		return defaultPRBase
+	}
+	return branch
+}
+
+// headResolver resolves what `gh pr create --head` needs for a branch pushed with --remote
+type headResolver interface {
+	RemoteOwner(ctx context.Context, remote string) (string, error)
+	BranchName(ctx context.Context) (string, error)
+}
+
+// prHead returns "<owner>:<branch>" for the remote given by --remote, so that gh opens
+// the PR from the branch just pushed to e.g. a fork rather than guessing the head repository.
+// Without --remote, or when the owner cannot be determined, it returns "" and gh decides.
+func prHead(ctx context.Context, resolver headResolver) string {
+	if flagRemote == "" {
+		return ""
+	}
+	owner, err := resolver.RemoteOwner(ctx, flagRemote)
+	if err == nil {
+		var branch string
+		if branch, err = resolver.BranchName(ctx); err == nil {
+			return owner + ":" + branch
+		}
+	}
+	if appLogger != nil {
+		appLogger.Debug("Failed to resolve pull request head", zap.Error(err), zap.String("remote", flagRemote))
+	}
+	return ""
+}
+
+// prCreateArgs builds the arguments passed to `gh` for PR creation.
+// Without a title, gh fills the title and body from the branch commits.
+// A non-empty head ("<owner>:<branch>") is passed as --head.
+func prCreateArgs(base, head, title, body string, draft bool) []string {
+	args := []string{"pr", "create"}
+	if title == "" {
+		args = append(args, "--fill")
+	} else {
+		args = append(args, "--title", title, "--body", body)
+	}
+	args = append(args, "--base", base)
+	if head != "" {
+		args = append(args, "--head", head)
+	}
+	return append(args, "--draft="+strconv.FormatBool(draft))
+}
+
+// branchDiffer collects the changes of the current branch against a base ref
+type branchDiffer interface {
+	DiffAgainstRef(ctx context.Context, baseRef string) (string, error)
+	AnalyzeChangesAgainstRef(ctx context.Context, baseRef string) (*collector.ChangesSummary, error)
+}
+
+// summarizeBranch describes every change since --base-ref with the PR prompt,
+// rather than only the commit that was just created. The title is HEAD's subject.
+func summarizeBranch(ctx context.Context, col prBodySource) (string, string, error) {
+	return generatePRBody(ctx, col, flagBaseRef)
+}
+
+// prViewArgs builds the arguments used to query the PR of the current branch
+func prViewArgs() []string {
+	return []string{"pr", "view", "--json", "url", "--jq", ".url"}
+}
+
+// lookupExistingPRURL queries the URL of the PR associated with the current branch.
+// Returns an empty string if the URL cannot be determined.
+func lookupExistingPRURL(ctx context.Context, runner collector.Runner) string {
+	output, err := runner.Run(ctx, "gh", prViewArgs()...)
+	if err != nil {
+		if appLogger != nil {
+			appLogger.Debug("Failed to query existing PR URL", zap.Error(err))
+		}
+		return ""
+	}
+	url := strings.TrimSpace(string(output))
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return extractPRURL(url)
+	}
+	return url
+}
+
+// shellSafeArg matches arguments that can be printed without quoting
+var shellSafeArg = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// quoteCommand renders a command line that can be safely pasted into a POSIX shell
+func quoteCommand(name string, args ...string) string {
+	parts := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{name}, args...) {
+		if shellSafeArg.MatchString(arg) {
+			parts = append(parts, arg)
+			continue
+		}
+		parts = append(parts, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+	}
+	return strings.Join(parts, " ")
+}
+
 // extractPRURL extracts the PR URL from gh command output
 func extractPRURL(output string) string {
 	lines := strings.Split(output, "\n")
@@ -234,19 +524,85 @@ func extractPRURL(output string) string {
 	return ""
 }
 
+// commitArgs 返回 git commit 的参数，附带 --amend、--author、--date 与 --no-verify。
+// 使用 --author=<值> 形式，避免值被 git 解析为其他选项
+func commitArgs(message string) []string {
+	args := []string{"commit", "-m", message}
+	if flagAmend {
+		args = append(args, "--amend")
+	}
+	if flagAuthor != "" {
+		args = append(args, "--author="+flagAuthor)
+	}
+	if flagDate != "" {
+		args = append(args, "--date="+flagDate)
+	}
+	if flagNoVerify {
+		args = append(args, "--no-verify")
+	}
+	// --only：只提交这些路径的工作区内容，暂存区中的其他变更保留到下次提交
+	if len(onlyPaths) > 0 {
+		args = append(append(args, "--only", "--"), onlyPathspecs()...)
+	}
+	return args
+}
+
+// identityPattern 匹配 git 身份 "Name <email>"
+var identityPattern = regexp.MustCompile(`^[^<>\n]+ <[^<>\s@]+@[^<>\s@]+>$`)
+
+// validateIdentity 校验 "Name <email>" 格式的身份，如 --author 的值
+func validateIdentity(identity string) error {
+	if !identityPattern.MatchString(identity) {
+		return fmt.Errorf("invalid identity %q: want \"Name <email>\"", identity)
+	}
+	return nil
+}
+
+// pushArgs 返回 git push 的参数：未指定 --remote 时推送到当前分支的 upstream，
+// 否则将当前分支推送到该 remote 的同名分支。--no-verify 时跳过 pre-push hook
+func pushArgs() []string {
+	args := []string{"push"}
+	if flagNoVerify {
+		args = append(args, "--no-verify")
+	}
+	if flagRemote == "" {
+		return args
+	}
+	return append(args, flagRemote, "HEAD")
+}
+
+// pushedRef 返回用于比较未推送提交的远程引用：未指定 --remote 时为 @{u}，
+// 否则为 <remote>/<branch>。远程引用不存在时返回错误
+func pushedRef(ctx context.Context) (string, error) {
+	if flagRemote == "" {
+		if err := exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}").Run(); err != nil {
+			return "", err
+		}
+		return "@{u}", nil
+	}
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return "", err
+	}
+	ref := flagRemote + "/" + strings.TrimSpace(string(out))
+	if err := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", "refs/remotes/"+ref).Run(); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
 func (defaultCommitter) NeedsPush(ctx context.Context) (bool, error) {
 	// Check if the current branch has unpushed commits
-	// First, check if we have an upstream branch
-	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
-	_, err := cmd.CombinedOutput()
+	// First, check if the branch exists on the remote (its upstream, or --remote)
+	ref, err := pushedRef(ctx)
 	if err != nil {
 		// No upstream branch set, so we need to push
 		return true, nil
 	}
 	
 	// Check if there are commits to push
-	// git rev-list --count @{u}..HEAD
-	cmd = exec.CommandContext(ctx, "git", "rev-list", "--count", "@{u}..HEAD")
+	// git rev-list --count <ref>..HEAD
+	cmd := exec.CommandContext(ctx, "git", "rev-list", "--count", ref+"..HEAD")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return false, fmt.Errorf("failed to check unpushed commits: %w", err)
@@ -262,8 +618,26 @@ func (defaultCommitter) NeedsPush(ctx context.Context) (bool, error) {
 	return count > 0, nil
 }
 
+// statusWriter 返回状态与结果提示的输出目标，--quiet 时丢弃。
+// 显式请求的输出（--dry-run 的 message、--pr-dry-run 的命令）不经过这里。
+func statusWriter(cmd *cobra.Command) io.Writer {
+	if flagQuiet {
+		return io.Discard
+	}
+	return cmd.OutOrStdout()
+}
+
 // renderStatusBar 渲染带样式的状态条
 func renderStatusBar(message string, isSuccess bool) string {
+	// 创建进度指示符
+	indicator := "▶"
+	if isSuccess {
+		indicator = "✓"
+	}
+	if plainOutput {
+		return indicator + " " + message
+	}
+
 	var style lipgloss.Style
 	if isSuccess {
 		style = lipgloss.NewStyle().
@@ -279,25 +653,33 @@ func renderStatusBar(message string, isSuccess bool) string {
 			Padding(0, 1)
 	}
 	
-	// 创建进度指示符
-	indicator := "▶"
-	if isSuccess {
-		indicator = "✓"
-	}
-	
 	styledMessage := style.Render(indicator + " " + message)
 	return styledMessage
 }
 
+// repoLocator 在进程内共享，使配置查找、仓库检查与历史记录只执行一次 git rev-parse --show-toplevel。
+// 首次使用时通过 runnerProvider 构建；测试可将其置为 nil 以重新查找
+var (
+	repoLocator   *collector.Collector
+	repoLocatorMu sync.Mutex
+)
+
+// locateRepoRoot 返回当前工作树的根目录，结果由 repoLocator 缓存
+func locateRepoRoot(ctx context.Context) (string, error) {
+	repoLocatorMu.Lock()
+	if repoLocator == nil {
+		repoLocator = collector.New(runnerProvider())
+	}
+	locator := repoLocator
+	repoLocatorMu.Unlock()
+	return locator.RepoRoot(ctx)
+}
+
 // checkGitRepository performs a quick check to see if we're in a git repository
 // Returns a user-friendly error if not
 func checkGitRepository(ctx context.Context) error {
-	// Quick test: try to run a simple git command that would fail in non-git directories
-	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--git-dir")
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	
-	if err := cmd.Run(); err != nil {
+	// The memoized root lookup fails in non-git directories
+	if _, err := locateRepoRoot(ctx); err != nil {
 		return collector.ErrNotGitRepository
 	}
 	
@@ -332,6 +714,8 @@ Features:
 - Smart token budgeting for large changesets
 - Interactive review and editing capabilities
 - Multiple language support (English/Chinese)`,
+	// 存在子命令时 cobra 默认把未知参数视为子命令，这里显式允许 SEED_TEXT
+	Args:  cobra.ArbitraryArgs,
 	RunE:  run,
 }
 
@@ -341,24 +725,126 @@ var (
 	flagYes      bool
 	flagDryRun   bool
 	flagDebug    bool
+	flagVerbose  bool
 	flagPush     bool
 	flagStageAll bool
 	flagVersion  bool
 	flagCreatePR bool
+	flagPRDryRun bool
+	flagPRBase   string
+	flagModel    string
+
+	flagMaxFileSize int64
+	flagMaxLineLength int
+	flagBaseRef     string
+	flagReuseLast   bool
+	flagSeed        string
+	flagBody        bool
+	flagNoBody      bool
+	flagGitmoji     bool
+	flagPreset      string
+	flagTUI         bool
+	flagNoTUI       bool
+	flagForcePush   bool
+	flagNoUntracked bool
+	flagQuiet       bool
+	flagAmend       bool
+	flagRemote      string
+	flagOffline     bool
+
+	flagPRGenerateBody bool
+	flagSince          string
+	flagMaxRetries     int
+	flagSignature      bool
+	flagDiffStat       bool
+	flagRequireTests   bool
+	flagMaxPromptTokens int
+	flagTruncate       bool
+	flagAuthor         string
+	flagDate           string
+	flagKeepOpen       bool
+	flagCopyPRURL      bool
+	flagNoVerify       bool
+	flagSlowGitThreshold time.Duration
+	flagCacheTTL         time.Duration
+	flagConsistency      int
+	flagCollapseDeletions bool
+	flagIgnoreWhitespace  bool
+	flagPRPreview         bool
+	flagInteractiveStage  bool
+	flagOnly              []string
 )
 
 func init() {
 	rootCmd.Flags().StringVarP(&flagLang, "lang", "l", "en", "commit message language (ISO 639-1)")
 	rootCmd.Flags().IntVarP(&flagTimeout, "timeout", "t", 20, "API timeout in seconds")
+	rootCmd.Flags().IntVar(&flagConsistency, "consistency", 0, "generate N messages and pick the one with the fewest lint violations, breaking ties by majority vote on type and scope (costs N LLM calls, at most "+strconv.Itoa(maxConsistency)+")")
+	rootCmd.Flags().IntVar(&flagMaxRetries, "max-retries", 0, "retry the whole generate-and-commit attempt up to N times on timeouts, network errors, 429 or 5xx, with exponential backoff (requires -y or --dry-run)")
+	rootCmd.Flags().IntVar(&flagMaxPromptTokens, "max-prompt-tokens", 0, "fail instead of calling the LLM when the estimated prompt exceeds N tokens (0 disables)")
+	rootCmd.Flags().BoolVar(&flagTruncate, "truncate", false, "with --max-prompt-tokens, truncate the diff to fit N tokens instead of failing")
 	rootCmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "skip confirmation and commit immediately")
 	rootCmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "print message but do not commit")
 	rootCmd.Flags().BoolVar(&flagDebug, "debug", false, "enable debug output for troubleshooting")
+	rootCmd.Flags().BoolVarP(&flagVerbose, "verbose", "v", false, "print concise progress to stderr (with -y or --dry-run)")
+	rootCmd.Flags().BoolVarP(&flagQuiet, "quiet", "q", false, "print nothing but errors (requires -y or --dry-run; --dry-run still prints the message)")
 	rootCmd.Flags().BoolVarP(&flagPush, "push", "p", true, "automatically push after successful commit")
 	rootCmd.Flags().BoolVar(&flagStageAll, "stage-all", true, "automatically stage all changes (tracked and untracked) if none are staged")
+	rootCmd.Flags().BoolVar(&flagKeepOpen, "keep-open", false, "keep the TUI open on a summary of the commit SHA and PR URL until a key is pressed (default from keep_open in the config file)")
 	rootCmd.Flags().BoolVar(&flagVersion, "version", false, "show version information")
-	rootCmd.Flags().BoolVar(&flagCreatePR, "create-pr", false, "create GitHub pull request after successful push")
+	rootCmd.Flags().BoolVar(&flagCreatePR, "create-pr", false, "create GitHub pull request after successful push; with --dry-run, create a draft PR from the already-committed branch without committing")
+	rootCmd.Flags().BoolVar(&flagCopyPRURL, "copy-pr-url", false, "copy the created pull request URL to the clipboard")
+	rootCmd.Flags().BoolVar(&flagPRPreview, "pr-preview", false, "print the pull request title and body (warning about unfilled template placeholders) before creating it; asks for confirmation in interactive mode")
+	rootCmd.Flags().BoolVar(&flagPRDryRun, "pr-dry-run", false, "print the push and pull request commands instead of executing them")
+	rootCmd.Flags().Int64Var(&flagMaxFileSize, "max-file-size", 0, "exclude files larger than this many bytes from the diff (0 disables)")
+	rootCmd.Flags().DurationVar(&flagCacheTTL, "cache-ttl", collector.DefaultCacheTTL, "how long git command results are cached within a run; 0 disables caching (default from CATMIT_CACHE_TTL)")
+	rootCmd.Flags().DurationVar(&flagSlowGitThreshold, "slow-git-threshold", 2*time.Second, "log a warning for git commands that take at least this long, e.g. on network filesystems (0 disables)")
+	rootCmd.Flags().BoolVar(&flagIgnoreWhitespace, "ignore-whitespace", false, "ignore whitespace changes in the diff sent to the LLM (git diff --ignore-all-space), e.g. for commits mixing reformatting with real changes")
+	rootCmd.Flags().BoolVar(&flagCollapseDeletions, "collapse-deletions", false, "send deleted files to the LLM as a one-line \"deleted file <path> (N lines)\" summary instead of their full content")
+	rootCmd.Flags().IntVar(&flagMaxLineLength, "max-line-length", collector.DefaultMaxLineLength, "collapse diff lines longer than this many characters, e.g. minified files (0 disables)")
+	rootCmd.Flags().StringVar(&flagModel, "model", "", "LLM model name (overrides CATMIT_LLM_MODEL)")
+	rootCmd.Flags().BoolVar(&flagPRGenerateBody, "pr-generate-body", false, "write the pull request body with the LLM from the branch diff against the PR base, following the repository's PR template if present")
+	rootCmd.Flags().StringVar(&flagSince, "since", "", "print one message summarizing everything committed since a ref (e.g. a tag) or a duration such as 12h, 2d or 1w; never commits")
+	rootCmd.Flags().StringVar(&flagBaseRef, "base-ref", "", "describe all changes since this ref (e.g. origin/main) in the pull request body; the title is the HEAD commit subject")
+	rootCmd.Flags().BoolVar(&flagBody, "body", false, "require an explanatory body paragraph (default from CATMIT_BODY)")
+	rootCmd.Flags().BoolVar(&flagNoBody, "no-body", false, "generate a subject line only")
+	rootCmd.Flags().BoolVar(&flagGitmoji, "gitmoji", false, "prefix the subject with a gitmoji for its type (default from CATMIT_GITMOJI)")
+	rootCmd.Flags().StringVar(&flagPreset, "preset", "", "prompt preset: "+strings.Join(prompt.PresetNames(), ", ")+" (default from preset in the config file, or conventional)")
+	rootCmd.Flags().BoolVar(&flagTUI, "tui", false, "always use the interactive TUI, even when stdout is not a terminal")
+	rootCmd.Flags().BoolVar(&flagNoTUI, "no-tui", false, "never use the TUI; print plain progress lines and commit like -y")
+	rootCmd.Flags().BoolVar(&flagDiffStat, "diff-stat", false, "start the diff sent to the LLM with git's --stat summary of staged and unstaged changes")
+	rootCmd.Flags().BoolVar(&flagInteractiveStage, "interactive-stage", false, "pick the files to commit from a checklist in the TUI; only the selected files are staged and described")
+	rootCmd.Flags().StringSliceVar(&flagOnly, "only", nil, "describe and commit only these files or directories (repeatable or comma-separated), like git commit --only; other staged changes stay staged, and each path must have changes")
+	rootCmd.Flags().BoolVar(&flagNoUntracked, "no-untracked", false, "ignore untracked files; skips the expensive untracked-file scan in large repositories")
+	rootCmd.Flags().BoolVar(&flagAmend, "amend", false, "regenerate the message of the last commit and amend it with the staged changes, comparing the old and new message (never pushes)")
+	rootCmd.Flags().BoolVar(&flagNoVerify, "no-verify", false, "bypass the pre-commit, commit-msg and pre-push hooks (skips their safety checks)")
+	rootCmd.Flags().BoolVar(&flagForcePush, "force-push", false, "push to protected branches (main, master, release/*) without confirmation")
+	rootCmd.Flags().StringVar(&flagSeed, "seed", "", "seed text for the message; use - to read it from stdin")
+	rootCmd.Flags().BoolVar(&flagOffline, "offline", false, "build a rule-based message from the changed files without calling the LLM")
+	rootCmd.Flags().BoolVar(&flagRequireTests, "require-tests", false, "run the project's tests before committing and abort if they fail (test_command in the config, else go test ./... or npm test)")
+	rootCmd.Flags().BoolVar(&flagSignature, "signature", false, "append a \"Generated-by: catmit <version>\" trailer to the message (ignored when disable_signature is set in the config)")
+	rootCmd.Flags().BoolVar(&flagReuseLast, "reuse-last", false, "reuse the last recorded commit message for this repository instead of generating one")
+	rootCmd.Flags().StringVar(&flagAuthor, "author", "", "override the commit author, as \"Name <email>\"")
+	rootCmd.Flags().StringVar(&flagDate, "date", "", "override the author date; accepts any date git parses (e.g. 2024-05-01T12:00:00, \"2 days ago\")")
+	rootCmd.Flags().StringVar(&flagRemote, "remote", "", "remote to push to and to detect the pull request base from (default: the branch's upstream for pushing, origin for the base)")
+	rootCmd.Flags().StringVar(&flagPRBase, "pr-base", "", "base branch for the pull request (default: the remote's default branch)")
+}
+
+// ExitCodePushSkipped 表示提交已完成，但 -y 模式下跳过了对受保护分支的推送。
+// 3（网络错误）、4（配置错误）与 5（ExitCodeLintFailed）已有含义，因此使用 6
+const ExitCodePushSkipped = 6
+
+// ExitError 携带进程退出码，main 据此退出而不是统一使用 1。
+// Silent 表示错误已向用户说明，main 只设置退出码，不再输出错误信息
+type ExitError struct {
+	Code   int
+	Err    error
+	Silent bool
 }
 
+func (e *ExitError) Error() string { return e.Err.Error() }
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
 func Execute() error { return rootCmd.Execute() }
 
 func ExecuteContext(ctx context.Context) error { return rootCmd.ExecuteContext(ctx) }
@@ -378,9 +864,153 @@ func run(cmd *cobra.Command, args []string) error {
 	}
 	defer func() { _ = appLogger.Sync() }()
 
-	seedText := ""
-	if len(args) > 0 {
-		seedText = args[0]
+	runConfig, err = resolveConfig(cmd.Context(), cmd)
+	if err != nil {
+		return err
+	}
+	// 配置文件未指定 commit_template 时沿用 git 的 commit.template
+	if runConfig.CommitTemplate == "" {
+		runConfig.CommitTemplate = readCommitTemplate(cmd.Context())
+	}
+	// resolveConfig 已校验预设名称
+	preset, _ := prompt.LookupPreset(runConfig.Preset)
+	bodyMode, err = resolveBodyMode(preset.BodyMode)
+	if err != nil {
+		return err
+	}
+	if cacheTTL, err = resolveCacheTTL(cmd); err != nil {
+		return err
+	}
+	useGitmoji = *runConfig.Gitmoji
+	stageAllEnabled := *runConfig.StageAll
+	pushEnabled := *runConfig.Push
+	if flagOffline && flagReuseLast {
+		return fmt.Errorf("--offline and --reuse-last cannot be used together")
+	}
+	if flagSince != "" {
+		conflicts := []struct {
+			name string
+			set  bool
+		}{{"--amend", flagAmend}, {"--reuse-last", flagReuseLast}, {"--offline", flagOffline}, {"--create-pr", flagCreatePR}}
+		for _, c := range conflicts {
+			if c.set {
+				return fmt.Errorf("--since and %s cannot be used together", c.name)
+			}
+		}
+	}
+	if strings.HasPrefix(flagRemote, "-") {
+		return fmt.Errorf("invalid remote %q", flagRemote)
+	}
+	if flagAuthor != "" {
+		if err := validateIdentity(flagAuthor); err != nil {
+			return fmt.Errorf("--author: %w", err)
+		}
+	}
+	if flagAmend {
+		if flagCreatePR {
+			return fmt.Errorf("--amend and --create-pr cannot be used together")
+		}
+		// amend 只改写 HEAD 与已暂存的变更；改写后的提交可能需要强制推送，交由用户决定
+		stageAllEnabled, pushEnabled = false, false
+	}
+
+	seedText, seedFromStdin, err := resolveSeed(cmd, args)
+	if err != nil {
+		return err
+	}
+	// seed 为 "wip"/"fixup" 时生成 WIP: 或 fixup! 格式的 message，其余文本作为 seed 或 fixup 目标
+	intent, seedRest := parseSeedIntent(seedText)
+	wipMessages, fixupSeed = intent == seedIntentWIP, intent == seedIntentFixup
+	if intent != seedIntentNone {
+		seedText = seedRest
+	}
+	useTUI, err := resolveTUI()
+	if err != nil {
+		return err
+	}
+	// 标准输入已被读取时 TUI 无法再接收按键；--no-tui 或标准输出不是终端（如 catmit | tee log）时
+	// 不渲染 TUI，改为逐行输出纯文本进度。均退化为 -y 语义，提交、推送与创建 PR 照常进行
+	nonInteractive := flagDryRun || flagYes || seedFromStdin || !useTUI || flagSince != ""
+	plainOutput = !useTUI
+	prPreviewConfirm = !nonInteractive
+	if flagMaxRetries < 0 {
+		return fmt.Errorf("--max-retries must not be negative")
+	}
+	if flagPRPreview && !flagCreatePR {
+		return fmt.Errorf("--pr-preview requires --create-pr")
+	}
+	if flagInteractiveStage {
+		// --amend 只描述已暂存的变更，--offline 在选择文件前就已生成 message
+		for _, conflict := range []struct {
+			name string
+			set  bool
+		}{{"--amend", flagAmend}, {"--offline", flagOffline}} {
+			if conflict.set {
+				return fmt.Errorf("--interactive-stage and %s cannot be used together", conflict.name)
+			}
+		}
+		if nonInteractive {
+			return fmt.Errorf("--interactive-stage requires the interactive TUI")
+		}
+		stageAllEnabled = false
+	}
+	if len(flagOnly) > 0 {
+		// --amend 只描述已暂存的变更，--interactive-stage 另行选择文件
+		for _, conflict := range []struct {
+			name string
+			set  bool
+		}{{"--amend", flagAmend}, {"--interactive-stage", flagInteractiveStage}} {
+			if conflict.set {
+				return fmt.Errorf("--only and %s cannot be used together", conflict.name)
+			}
+		}
+		stageAllEnabled = false
+	}
+	if flagConsistency < 0 {
+		return fmt.Errorf("--consistency must not be negative")
+	}
+	if flagConsistency > maxConsistency {
+		return fmt.Errorf("--consistency must be at most %d, got %d", maxConsistency, flagConsistency)
+	}
+	if flagSlowGitThreshold < 0 {
+		return fmt.Errorf("--slow-git-threshold must not be negative")
+	}
+	if flagMaxLineLength < 0 {
+		return fmt.Errorf("--max-line-length must not be negative")
+	}
+	if flagMaxPromptTokens < 0 {
+		return fmt.Errorf("--max-prompt-tokens must not be negative")
+	}
+	if flagTruncate && flagMaxPromptTokens == 0 {
+		return fmt.Errorf("--truncate requires --max-prompt-tokens")
+	}
+	if flagMaxRetries > 0 && !nonInteractive {
+		return fmt.Errorf("--max-retries cannot be used with the interactive TUI; add -y to commit without review")
+	}
+	if flagQuiet {
+		if flagVerbose {
+			return fmt.Errorf("--quiet and --verbose cannot be used together")
+		}
+		if !nonInteractive {
+			return fmt.Errorf("--quiet cannot be used with the interactive TUI; add -y to commit without review")
+		}
+		// 只保留错误日志，警告（如超大文件被跳过）不再输出
+		appLogger = appLogger.WithOptions(zap.IncreaseLevel(zapcore.ErrorLevel))
+	}
+
+	// TUI 占用终端时日志先写入内存，退出后再输出到 stderr，避免破坏 bubbletea 绘制的画面
+	if !nonInteractive {
+		var logs *logBuffer
+		if appLogger, logs, err = interactiveLogger(appLogger, flagDebug); err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer logs.flushTo(cmd.ErrOrStderr())
+	}
+
+	// --verbose 仅作用于非交互路径，交互模式由 TUI 展示进度
+	verboseOut = nil
+	if flagVerbose && nonInteractive {
+		verboseOut = cmd.ErrOrStderr()
 	}
 
 	ctx := cmd.Context()
@@ -392,7 +1022,7 @@ func run(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
 			cmd.SilenceErrors = true
 			// Print user-friendly error message directly
-			_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(flagLang))
+			_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(runConfig.Lang))
 			os.Exit(1) // Exit directly to avoid any additional error output
 		}
 		// For other errors, let the normal error handling proceed
@@ -401,179 +1031,113 @@ func run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// --since 只输出汇总 message，不提交
+	if flagSince != "" {
+		return runSince(ctx, cmd, collectorProvider())
+	}
+
+	// --only 只描述并提交指定路径；确认提交前不改动暂存区
+	onlyPaths = nil
+	if len(flagOnly) > 0 {
+		if err := applyOnly(ctx, flagOnly); err != nil {
+			return err
+		}
+	}
+
 	// Dry-run 与 -y 快速路径，保留同步逻辑
-	if flagDryRun || flagYes {
-		// 执行同步流程
-		col := collectorProvider()
-		
-		// Use ComprehensiveDiff to include untracked files
-		diffText, err := col.ComprehensiveDiff(ctx)
-		if err != nil {
-			if err == collector.ErrNoDiff {
-				if flagCreatePR {
-					// Check if we need to push first
-					needsPush, err := committer.NeedsPush(ctx)
-					if err != nil {
-						if flagDebug {
-							appLogger.Debug("Failed to check if push is needed", zap.Error(err))
-						}
-						// Continue anyway, let the PR creation fail if needed
-						needsPush = false
-					}
-					
-					if needsPush {
-						_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Pushing branch...", false))
-						if err := committer.Push(ctx); err != nil {
-							return fmt.Errorf("failed to push branch: %w", err)
-						}
-						_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Branch pushed successfully", true))
-					}
-					
-					// Even with no changes, allow creating a PR if explicitly requested
-					_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Creating pull request...", false))
-					prURL, err := committer.CreatePullRequest(ctx)
-					if err != nil {
-						var prExists *ErrPRAlreadyExists
-						if errors.As(err, &prExists) {
-							_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Pull request already exists", true))
-							_, _ = fmt.Fprintf(cmd.OutOrStdout(), "PR URL: %s\n", prExists.URL)
-							return nil
-						}
-						return fmt.Errorf("failed to create pull request: %w", err)
-					}
-					_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Pull request created successfully", true))
-					if prURL != "" {
-						_, _ = fmt.Fprintf(cmd.OutOrStdout(), "PR URL: %s\n", prURL)
-					}
-					return nil
-				}
-				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Nothing to commit.")
-				if flagDebug {
-					appLogger.Debug("No staged, unstaged, or untracked changes detected")
-				}
-				return nil
-			}
-			if errors.Is(err, collector.ErrNotGitRepository) {
-				cmd.SilenceUsage = true
-				cmd.SilenceErrors = true
-				_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(flagLang))
-				os.Exit(1)
-			}
-			if flagDebug {
-				appLogger.Debug("Comprehensive diff collection failed, trying fallback", zap.Error(err))
-			}
-			// Fallback to legacy diff for backward compatibility
-			diffText, err = col.Diff(ctx)
-			if err != nil {
-				if err == collector.ErrNoDiff {
-					_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Nothing to commit.")
-					return nil
-				}
-				if errors.Is(err, collector.ErrNotGitRepository) {
-					cmd.SilenceUsage = true
-					cmd.SilenceErrors = true
-					_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(flagLang))
-					return fmt.Errorf("git repository required")
-				}
-				return fmt.Errorf("failed to collect git diff: %w", err)
-			}
+	if nonInteractive {
+		return runNonInteractive(ctx, cmd, seedText, stageAllEnabled, pushEnabled)
+	}
+
+	// 交互模式：使用统一的MainModel
+	repo := currentRepo(ctx)
+	var cli clientInterface
+	initial := ""
+	if flagReuseLast {
+		// 复用历史 message 时直接进入 Review，无需 LLM Client
+		if initial, err = lastMessage(repo); err != nil {
+			return err
 		}
-		commits, err := col.RecentCommits(ctx, 10)
-		if err != nil {
-			if errors.Is(err, collector.ErrNotGitRepository) {
-				cmd.SilenceUsage = true
-				cmd.SilenceErrors = true
-				_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(flagLang))
-				os.Exit(1)
+	} else if !flagOffline && !fixupSeed {
+		if cli, err = newClient(); err != nil {
+			return err
+		}
+	}
+	col := collectorProvider()
+	if fixupSeed && !flagReuseLast {
+		// fixup! message 直接进入 Review，无需 LLM Client
+		if initial, err = fixupMessage(ctx, col, seedText); err != nil {
+			return err
+		}
+	} else if flagOffline {
+		// 规则化 message 直接进入 Review，无需 LLM Client
+		if initial, err = offlineGenerate(ctx, col); err != nil {
+			if errors.Is(err, collector.ErrNoDiff) {
+				_, _ = fmt.Fprintln(statusWriter(cmd), "Nothing to commit.")
+				return nil
 			}
 			return err
 		}
-		builder := promptProvider(flagLang)
-		systemPrompt := builder.BuildSystemPrompt()
-		
-		// Try to use the new BuildUserPromptWithBudget method
-		userPrompt, err := builder.BuildUserPromptWithBudget(ctx, col, seedText)
-		if err != nil {
-			if flagDebug {
-				appLogger.Debug("Smart prompt building failed, falling back to traditional method", zap.Error(err))
-			}
-			// Fallback to traditional method
-			branch, _ := col.BranchName(ctx)
-			files, _ := col.ChangedFiles(ctx)
-			userPrompt = builder.BuildUserPrompt(seedText, diffText, commits, branch, files)
-		}
-		
-		cli := clientProvider()
-		// Create timeout context only for API call
-		apiCtx, apiCancel := context.WithTimeout(ctx, time.Duration(flagTimeout)*time.Second)
-		defer apiCancel()
-		message, err := cli.GetCommitMessage(apiCtx, systemPrompt, userPrompt)
-		if err != nil {
-			return err
-		}
-
-		if flagDryRun {
-			_, _ = fmt.Fprintln(cmd.OutOrStdout(), message)
-			return nil
-		}
-
-		// yes = commit
-		_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Committing...", false))
-		// Only stage all if there are no staged changes and flagStageAll is true
-		if flagStageAll && !hasStagedChanges(ctx) {
-			if err := stageAll(ctx); err != nil {
-				return err
-			}
-		}
-		if err := committer.Commit(ctx, message); err != nil {
+		recordMessage(repo, initial, false)
+	}
+	var original string
+	if flagAmend {
+		if original, err = headMessage(ctx, col); err != nil {
 			return err
 		}
-		_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Committed successfully", true))
-		if flagPush {
-			_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Pushing...", false))
-			if err := committer.Push(ctx); err != nil {
-				return fmt.Errorf("push failed: %w", err)
-			}
-			_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Pushed successfully", true))
-			
-			// Create pull request if requested
-			if flagCreatePR {
-				_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Creating pull request...", false))
-				prURL, err := committer.CreatePullRequest(ctx)
-				if err != nil {
-					var prExists *ErrPRAlreadyExists
-					if errors.As(err, &prExists) {
-						_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Pull request already exists", true))
-						_, _ = fmt.Fprintf(cmd.OutOrStdout(), "PR URL: %s\n", prExists.URL)
-						return nil
-					}
-					return fmt.Errorf("failed to create pull request: %w", err)
-				}
-				_, _ = fmt.Fprintln(cmd.OutOrStdout(), renderStatusBar("Pull request created successfully", true))
-				if prURL != "" {
-					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "PR URL: %s\n", prURL)
-				}
-			}
+	}
+	createPR := flagCreatePR
+	if (pushEnabled || createPR) && isDetachedHead(ctx, col) {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s; the commit will not be pushed\n", detachedHeadHint)
+		pushEnabled, createPR = false, false
+	}
+	// --pr-dry-run 时 TUI 只提交，退出后输出将要执行的推送与 PR 命令
+	prDryRun := createPR && flagPRDryRun
+	dryRunPushEnabled := prDryRun && pushEnabled
+	if prDryRun {
+		pushEnabled = false
+	}
+	if !stageAllEnabled && !flagInteractiveStage && len(flagOnly) == 0 {
+		if warning := partiallyStagedWarning(ctx, col); warning != "" {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), warning)
 		}
-		return nil
 	}
-
-	// 交互模式：使用统一的MainModel
 	mainModel := ui.NewMainModel(
 		ctx, 
-		collectorProvider(), 
-		promptProvider(flagLang), 
-		clientProvider(), 
-		committer,
+		col, 
+		promptProvider(runConfig.Lang), 
+		cli, 
+		activeCommitter(),
 		seedText, 
-		flagLang, 
+		runConfig.Lang, 
 		time.Duration(flagTimeout)*time.Second,
-		flagPush,
-		flagStageAll,
-		flagCreatePR,
+		pushEnabled,
+		stageAllEnabled,
+		// --pr-preview 需要在终端上确认，TUI 退出后再创建 PR
+		createPR && !flagPRPreview && !flagPRDryRun,
 	)
+	mainModel.SetMessageRecorder(func(message string, edited bool) {
+		recordMessage(repo, message, edited)
+	})
+	if initial != "" {
+		mainModel.SetInitialMessage(initial)
+	}
+	if flagAmend {
+		mainModel.SetAmendOriginal(original)
+	}
+	mainModel.SetKeepOpen(*runConfig.KeepOpen)
+	if flagInteractiveStage {
+		mainModel.SetInteractiveStage(stageSelection)
+	}
+	if !flagCopyPRURL {
+		// 已使用 --copy-pr-url 时退出后自动复制，摘要界面无需再提供按键
+		mainModel.SetCopyPRURL(func(url string) error { return copyToClipboard(ctx, url) })
+	}
+	if pushEnabled {
+		mainModel.SetConfirmPush(protectedPushBranch(ctx, col))
+	}
 	
-	finalModel, err := tea.NewProgram(mainModel).Run()
+	finalModel, err := tea.NewProgram(mainModel, tea.WithContext(ctx)).Run()
 	if err != nil {
 		return err
 	}
@@ -583,31 +1147,55 @@ func run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("internal error: unexpected model type, got %T", finalModel)
 	}
 	
-	done, decision, _, err := m.IsDone()
-	if err != nil {
+	result := m.Result()
+	if err := result.Err; err != nil {
 		// Check if it's the "nothing to commit" error
 		if err == collector.ErrNoDiff {
-			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Nothing to commit.")
+			_, _ = fmt.Fprintln(statusWriter(cmd), "Nothing to commit.")
 			return nil
 		}
 		// Check if it's a git repository error
 		if errors.Is(err, collector.ErrNotGitRepository) {
 			cmd.SilenceUsage = true
 			cmd.SilenceErrors = true
-			_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(flagLang))
+			_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(runConfig.Lang))
 			os.Exit(1)
 		}
 		// 如果用户在加载时按 Ctrl+C 取消，则静默退出
 		if err == context.Canceled {
 			return nil
 		}
-		return err
+		return promptSizeError(err)
 	}
 	
+	done, _, _, _ := m.IsDone()
 	if done {
-		switch decision {
+		switch result.Decision {
 		case ui.DecisionAccept:
 			// MainModel has already handled the staging, commit, and push operations
+			if result.Committed && prDryRun {
+				if dryRunPushEnabled {
+					dryRunPush(cmd)
+				}
+				if _, err := createPullRequest(ctx, cmd); err != nil {
+					notifyCommit(ctx, col, repo, result.Message, result.CommitSHA, "")
+					return err
+				}
+			} else if result.Committed && createPR && flagPRPreview && result.Push != ui.PushSkipped {
+				// createPullRequest 预览、确认并按需复制 PR URL
+				prURL, err := createPullRequest(ctx, cmd)
+				if err != nil {
+					notifyCommit(ctx, col, repo, result.Message, result.CommitSHA, "")
+					return err
+				}
+				result.PRURL = prURL
+			} else {
+				copyPRURL(ctx, cmd, result.PRURL)
+			}
+			if result.Committed {
+				_, _ = fmt.Fprintln(statusWriter(cmd), resultSummary(result))
+			}
+			notifyCommit(ctx, col, repo, result.Message, result.CommitSHA, result.PRURL)
 			return nil
 		case ui.DecisionCancel:
 			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Canceled.")
@@ -617,6 +1205,749 @@ func run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// logBuffer 是可并发写入的日志缓冲区
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// flushTo 将缓冲的日志写入 w 并清空
+func (b *logBuffer) flushTo(w io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, _ = b.buf.WriteTo(w)
+}
+
+// interactiveLogger 创建 TUI 模式下的 logger：日志写入返回的缓冲区而不是终端。
+// 设置了 CATMIT_LOG_FILE 时 current 已写入该文件，直接沿用而不是再打开一次文件
+func interactiveLogger(current *zap.Logger, debug bool) (*zap.Logger, *logBuffer, error) {
+	logs := &logBuffer{}
+	if logger.FileConfigured() && current != nil {
+		return current, logs, nil
+	}
+	log, err := logger.NewTo(debug, logs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return log, logs, nil
+}
+
+// committedStatus 返回提交完成的状态文本，已知 SHA 时显示短 SHA
+func committedStatus(sha string) string {
+	if sha == "" {
+		return "Committed successfully"
+	}
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+	return "Committed " + sha
+}
+
+// resultSummary 将 TUI 的结果汇总为一行，例如 "Committed abc1234, pushed, PR: <url>"
+func resultSummary(r ui.Result) string {
+	parts := []string{committedStatus(r.CommitSHA)}
+	switch r.Push {
+	case ui.PushSucceeded:
+		parts = append(parts, "pushed")
+	case ui.PushSkipped:
+		parts = append(parts, "push skipped")
+	case ui.PushFailed:
+		parts = append(parts, "push failed")
+	}
+	if r.PRURL != "" {
+		parts = append(parts, "PR: "+r.PRURL)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// pushCommit 推送刚完成的提交并按需创建 PR（非交互路径），返回创建的 PR URL。
+// 分离 HEAD 或受保护分支时跳过推送，已提交的变更保留在本地，由用户决定是否手动推送；
+// 受保护分支跳过推送时返回退出码为 ExitCodePushSkipped 的错误，使脚本能察觉推送未执行。
+func pushCommit(ctx context.Context, cmd *cobra.Command, col collectorInterface) (string, error) {
+	if isDetachedHead(ctx, col) {
+		if !flagQuiet {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Skipped push: %s\n", detachedHeadHint)
+		}
+		return "", nil
+	}
+	if branch := protectedPushBranch(ctx, col); branch != "" {
+		if !flagQuiet {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Skipped push: %s is a protected branch (use --force-push to push anyway)\n", branch)
+		}
+		// 提示已输出，避免 cobra 再打印用法与错误
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return "", &ExitError{Code: ExitCodePushSkipped, Err: fmt.Errorf("committed locally but skipped push to protected branch %s", branch), Silent: true}
+	}
+	if flagCreatePR && flagPRDryRun {
+		dryRunPush(cmd)
+		return createPullRequest(ctx, cmd)
+	}
+	_, _ = fmt.Fprintln(statusWriter(cmd), renderStatusBar("Pushing...", false))
+	if err := committer.Push(ctx); err != nil {
+		return "", fmt.Errorf("push failed: %w", err)
+	}
+	_, _ = fmt.Fprintln(statusWriter(cmd), renderStatusBar("Pushed successfully", true))
+
+	// Create pull request if requested
+	if flagCreatePR {
+		return createPullRequest(ctx, cmd)
+	}
+	return "", nil
+}
+
+// createPRFromBranch 不创建新提交，直接从已提交的分支创建 PR：分支尚未推送时先推送。
+// 用于没有变更时的 --create-pr，以及 --dry-run --create-pr（此时 PR 创建为草稿）。
+func createPRFromBranch(ctx context.Context, cmd *cobra.Command, col collectorInterface) error {
+	if isDetachedHead(ctx, col) {
+		return fmt.Errorf("cannot create a pull request: %s", detachedHeadHint)
+	}
+	// Check if we need to push first
+	needsPush, err := committer.NeedsPush(ctx)
+	if err != nil {
+		if flagDebug {
+			appLogger.Debug("Failed to check if push is needed", zap.Error(err))
+		}
+		// Continue anyway, let the PR creation fail if needed
+		needsPush = false
+	}
+	
+	if needsPush && flagPRDryRun {
+		dryRunPush(cmd)
+	} else if needsPush {
+		if branch := protectedPushBranch(ctx, col); branch != "" {
+			return fmt.Errorf("refusing to push protected branch %s (use --force-push to push anyway)", branch)
+		}
+		_, _ = fmt.Fprintln(statusWriter(cmd), renderStatusBar("Pushing branch...", false))
+		if err := committer.Push(ctx); err != nil {
+			return fmt.Errorf("failed to push branch: %w", err)
+		}
+		_, _ = fmt.Fprintln(statusWriter(cmd), renderStatusBar("Branch pushed successfully", true))
+	}
+	
+	_, err = createPullRequest(ctx, cmd)
+	return err
+}
+
+// headMessage 返回 --amend 将要改写的 HEAD message
+func headMessage(ctx context.Context, col collectorInterface) (string, error) {
+	reader, ok := col.(interface {
+		HeadMessage(ctx context.Context) (string, error)
+	})
+	if !ok {
+		return "", fmt.Errorf("--amend is not supported by this collector")
+	}
+	message, err := reader.HeadMessage(ctx)
+	if err != nil {
+		return "", fmt.Errorf("nothing to amend: %w", err)
+	}
+	return message, nil
+}
+
+// printAmendComparison 以 diff 风格逐行输出 HEAD 原有 message 与新 message 的差异
+func printAmendComparison(w io.Writer, before, after string) {
+	if !msgdiff.Changed(before, after) {
+		_, _ = fmt.Fprintf(w, "The new message is identical to the current HEAD message:\n%s\n", after)
+		return
+	}
+	_, _ = fmt.Fprintln(w, "Amending HEAD message:")
+	for _, line := range msgdiff.Lines(before, after) {
+		_, _ = fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+}
+
+// runNonInteractive 执行 --dry-run 与 -y 的同步流程：收集 diff、生成 message，并按需提交、推送与创建 PR。
+// --max-retries 重试整个生成与提交的尝试：每次重新收集 diff，generateMessage 也会为每次请求创建新的超时 context。
+// 提交完成后不再重试，推送与创建 PR 在重试之外执行，避免重复提交。
+func runNonInteractive(ctx context.Context, cmd *cobra.Command, seedText string, stageAllEnabled, pushEnabled bool) error {
+	var err error
+	// 执行同步流程
+	col := collectorProvider()
+	var original string
+	if flagAmend {
+		if original, err = headMessage(ctx, col); err != nil {
+			return err
+		}
+	}
+	repo := currentRepo(ctx)
+	
+	var (
+		message, sha string
+		committed    bool
+		// next 是不需要提交时在重试之外执行的后续步骤，如从已提交的分支创建 PR
+		next func() error
+	)
+	err = withRetries(ctx, cmd, flagMaxRetries, func() error {
+		next = nil
+		// Use ComprehensiveDiff to include untracked files
+		diffText, err := col.ComprehensiveDiff(ctx)
+		if err != nil {
+			if err == collector.ErrNoDiff {
+				if flagCreatePR {
+					// Even with no changes, allow creating a PR if explicitly requested
+					next = func() error { return createPRFromBranch(ctx, cmd, col) }
+					return nil
+				}
+				_, _ = fmt.Fprintln(statusWriter(cmd), "Nothing to commit.")
+				if flagDebug {
+					appLogger.Debug("No staged, unstaged, or untracked changes detected")
+				}
+				return nil
+			}
+			if errors.Is(err, collector.ErrNotGitRepository) {
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(runConfig.Lang))
+				os.Exit(1)
+			}
+			if flagDebug {
+				appLogger.Debug("Comprehensive diff collection failed, trying fallback", zap.Error(err))
+			}
+			// Fallback to legacy diff for backward compatibility
+			diffText, err = col.Diff(ctx)
+			if err != nil {
+				if err == collector.ErrNoDiff {
+					_, _ = fmt.Fprintln(statusWriter(cmd), "Nothing to commit.")
+					return nil
+				}
+				if errors.Is(err, collector.ErrNotGitRepository) {
+					cmd.SilenceUsage = true
+					cmd.SilenceErrors = true
+					_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(runConfig.Lang))
+					return fmt.Errorf("git repository required")
+				}
+				return fmt.Errorf("failed to collect git diff: %w", err)
+			}
+		}
+		verbosef("Collected diff: %d bytes", len(diffText))
+		if verboseOut != nil {
+			if files, err := col.ChangedFiles(ctx); err == nil {
+				verbosef("Changed files: %d", len(files))
+			}
+		}
+		if flagReuseLast {
+			message, err = lastMessage(repo)
+			if err != nil {
+				return err
+			}
+			verbosef("Reusing last commit message for %s", repo)
+		} else if fixupSeed {
+			if message, err = fixupMessage(ctx, col, seedText); err != nil {
+				return err
+			}
+		} else if flagOffline {
+			if message, err = offlineGenerate(ctx, col); err != nil {
+				if errors.Is(err, collector.ErrNoDiff) {
+					_, _ = fmt.Fprintln(statusWriter(cmd), "Nothing to commit.")
+					return nil
+				}
+				return err
+			}
+			recordMessage(repo, message, false)
+		} else {
+			if message, err = generateMessage(ctx, cmd, col, diffText, seedText); err != nil {
+				return err
+			}
+			recordMessage(repo, message, false)
+		}
+		
+		if signatureEnabled() {
+			message = appendSignature(message)
+		}
+		
+		if flagDryRun {
+			if flagAmend {
+				printAmendComparison(cmd.OutOrStdout(), original, message)
+				return nil
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), message)
+			// 不提交新变更，但仍可从已提交的分支创建草稿 PR 供评审
+			if flagCreatePR {
+				next = func() error { return createPRFromBranch(ctx, cmd, col) }
+			}
+			return nil
+		}
+		if flagAmend {
+			printAmendComparison(statusWriter(cmd), original, message)
+		}
+		
+		// yes = commit
+		_, _ = fmt.Fprintln(statusWriter(cmd), renderStatusBar("Committing...", false))
+		// Only stage all if there are no staged changes and stage-all is enabled
+		if stageAllEnabled && !hasStagedChanges(ctx) {
+			verbosef("No staged changes, staging all changes")
+			if err := stageAll(ctx); err != nil {
+				return err
+			}
+		}
+		active := activeCommitter()
+		if err := active.Commit(ctx, message); err != nil {
+			return err
+		}
+		committed = true
+		// 查询失败时 sha 为空串，状态行不显示 SHA
+		sha, err = active.LastCommitSHA(ctx)
+		if err != nil && appLogger != nil {
+			appLogger.Debug("Failed to read commit SHA", zap.Error(err))
+		}
+		_, _ = fmt.Fprintln(statusWriter(cmd), renderStatusBar(committedStatus(sha), true))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !committed {
+		if next != nil {
+			return next()
+		}
+		return nil
+	}
+	
+	var prURL string
+	if pushEnabled {
+		prURL, err = pushCommit(ctx, cmd, col)
+	}
+	// 提交已完成，无论推送是否成功都发送通知
+	notifyCommit(ctx, col, repo, message, sha, prURL)
+	return err
+}
+
+// generateMessage 构建 prompt 并调用 LLM 生成 commit message（非交互路径）
+func generateMessage(ctx context.Context, cmd *cobra.Command, col collectorInterface, diffText, seedText string) (string, error) {
+	commits, err := col.RecentCommits(ctx, 10)
+	if err != nil {
+		if errors.Is(err, collector.ErrNotGitRepository) {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			_, _ = fmt.Fprintln(cmd.OutOrStderr(), getGitRepositoryErrorMessage(runConfig.Lang))
+			os.Exit(1)
+		}
+		return "", err
+	}
+	builder := promptProvider(runConfig.Lang)
+	systemPrompt := builder.BuildSystemPrompt()
+	
+	// Try to use the new BuildUserPromptWithBudget method
+	userPrompt, err := builder.BuildUserPromptWithBudget(ctx, col, seedText)
+	if err != nil {
+		// 超出 --max-prompt-tokens 时直接失败，不回退到传统方法
+		if sizeErr := promptSizeError(err); sizeErr != err {
+			return "", sizeErr
+		}
+		if flagDebug {
+			appLogger.Debug("Smart prompt building failed, falling back to traditional method", zap.Error(err))
+		}
+		// Fallback to traditional method
+		branch, _ := col.BranchName(ctx)
+		files, _ := col.ChangedFiles(ctx)
+		userPrompt = builder.BuildUserPrompt(seedText, diffText, commits, branch, files)
+	}
+	
+	verbosef("Estimated prompt tokens: %d", prompt.EstimateTokens(systemPrompt+userPrompt))
+
+	cli, err := newClient()
+	if err != nil {
+		return "", err
+	}
+	verbosef("Requesting commit message (timeout %ds)", flagTimeout)
+	// Create timeout context only for API call
+	apiCtx, apiCancel := context.WithTimeout(ctx, time.Duration(flagTimeout)*time.Second)
+	defer apiCancel()
+	return cli.GetCommitMessage(apiCtx, systemPrompt, userPrompt)
+}
+
+// maxStdinSeedSize 限制从标准输入读取的 seed 大小
+const maxStdinSeedSize = 64 * 1024
+
+// stdinIsTerminal 与 stdoutIsTerminal 报告标准输入/输出是否连接到终端；
+// stdinIsPiped 报告标准输入是否为管道或重定向的文件。测试时可替换
+var (
+	stdinIsTerminal  = func() bool { return isTerminal(os.Stdin) }
+	stdoutIsTerminal = func() bool { return isTerminal(os.Stdout) }
+	stdinIsPiped     = func() bool { return isPiped(os.Stdin) }
+)
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return true
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// isPiped 仅对命名管道和普通文件返回 true；socket 等其他非终端输入
+// 可能永远不会关闭，隐式读取会阻塞
+func isPiped(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	return mode&os.ModeNamedPipe != 0 || mode.IsRegular()
+}
+
+// resolveTUI 决定是否启动 TUI：--tui/--no-tui 优先，否则仅在标准输出为终端时启用
+func resolveTUI() (bool, error) {
+	switch {
+	case flagTUI && flagNoTUI:
+		return false, fmt.Errorf("--tui and --no-tui cannot be used together")
+	case flagTUI:
+		return true, nil
+	case flagNoTUI:
+		return false, nil
+	}
+	return stdoutIsTerminal(), nil
+}
+
+// resolveSeed 按 --seed、位置参数、标准输入的顺序确定 seed。
+// 指定 --seed - 时读取标准输入；未提供 seed 时仅在标准输入为管道或文件时读取。
+// fromStdin 表示 seed 来自标准输入（将以非交互方式运行），隐式读取到空输入时为 false。
+func resolveSeed(cmd *cobra.Command, args []string) (seed string, fromStdin bool, err error) {
+	switch {
+	case flagSeed == "-":
+	case flagSeed != "":
+		return flagSeed, false, nil
+	case len(args) > 0:
+		return args[0], false, nil
+	case stdinIsTerminal() || !stdinIsPiped():
+		return "", false, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(cmd.InOrStdin(), maxStdinSeedSize))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read seed from stdin: %w", err)
+	}
+	seed = strings.TrimSpace(string(data))
+	if flagSeed != "-" && seed == "" {
+		return "", false, nil
+	}
+	return seed, true, nil
+}
+
+// resolveConfig 合并各来源的配置，优先级：
+// 命令行参数 > 环境变量 > 仓库 .catmit.yaml > 全局配置 > 参数默认值。
+// 返回的配置中 Lang 非空，Preset 为已知预设，布尔指针字段均不为 nil。
+// 预设只提供 gitmoji 的默认值，任何来源显式设置的 gitmoji 都优先。
+func resolveConfig(ctx context.Context, cmd *cobra.Command) (*config.Config, error) {
+	cfg, err := loadConfigFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if env := os.Getenv("CATMIT_GITMOJI"); env != "" {
+		enabled, err := strconv.ParseBool(env)
+		if err != nil {
+			return nil, fmt.Errorf("CATMIT_GITMOJI: invalid boolean %q", env)
+		}
+		cfg.Gitmoji = &enabled
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("lang") || cfg.Lang == "" {
+		cfg.Lang = flagLang
+	}
+	if flags.Changed("preset") {
+		cfg.Preset = flagPreset
+	}
+	preset, err := prompt.LookupPreset(cfg.Preset)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Preset = preset.Name
+	for kind := range cfg.PrefixMap {
+		if _, ok := collector.DefaultPrefixMap[kind]; !ok {
+			return nil, fmt.Errorf("prefix_map: unknown change kind %q (valid: added, deleted, renamed, modified, mode, default)", kind)
+		}
+	}
+	if cfg.Gitmoji == nil && !flags.Changed("gitmoji") {
+		cfg.Gitmoji = &preset.Gitmoji
+	}
+	cfg.Gitmoji = boolSetting(flags.Changed("gitmoji"), flagGitmoji, cfg.Gitmoji)
+	cfg.Push = boolSetting(flags.Changed("push"), flagPush, cfg.Push)
+	cfg.StageAll = boolSetting(flags.Changed("stage-all"), flagStageAll, cfg.StageAll)
+	cfg.KeepOpen = boolSetting(flags.Changed("keep-open"), flagKeepOpen, cfg.KeepOpen)
+	return cfg, nil
+}
+
+// loadConfigFiles 读取全局配置与当前仓库的 .catmit.yaml，仓库配置优先。
+// 仓库根目录由 locateRepoRoot 从 git 获取，linked worktree 会读取自身检出中的配置；
+// 不在 Git 仓库中时只使用全局配置
+func loadConfigFiles(ctx context.Context) (*config.Config, error) {
+	global, err := config.LoadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+	repo := &config.Config{}
+	if root, err := locateRepoRoot(ctx); err == nil {
+		if repo, err = config.LoadRepoConfig(root); err != nil {
+			return nil, err
+		}
+	}
+	return global.Merge(repo), nil
+}
+
+// defaultProtectedBranches 是未配置 protected_branches 时推送前需要确认的分支模式
+var defaultProtectedBranches = []string{"main", "master", "release/*"}
+
+// isProtectedBranch 判断分支是否匹配任一模式（path.Match 语法，* 不跨越 /）
+func isProtectedBranch(branch string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// detachedHeadHint 说明分离 HEAD 状态下为何不能推送或创建 PR
+const detachedHeadHint = "HEAD is detached (not on a branch); check out a branch to push or create a pull request"
+
+// isDetachedHead 报告当前是否处于分离 HEAD 状态
+func isDetachedHead(ctx context.Context, col collectorInterface) bool {
+	_, err := col.BranchName(ctx)
+	return errors.Is(err, collector.ErrDetachedHead)
+}
+
+// partiallyStagedWarning 在有文件只暂存了部分改动（如 git add -p）时返回提示，否则返回空串
+func partiallyStagedWarning(ctx context.Context, col collectorInterface) string {
+	changes, err := col.AnalyzeChanges(ctx)
+	if err != nil || len(changes.PartiallyStaged) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Warning: only the staged hunks of %s will be committed (partially staged)", strings.Join(changes.PartiallyStaged, ", "))
+}
+
+// protectedPushBranch 返回需要确认才能推送的当前分支；
+// 指定 --force-push、分支未受保护或无法获取分支时返回空串
+func protectedPushBranch(ctx context.Context, col collectorInterface) string {
+	if flagForcePush {
+		return ""
+	}
+	branch, err := col.BranchName(ctx)
+	if err != nil || branch == "" {
+		return ""
+	}
+	patterns := defaultProtectedBranches
+	if runConfig != nil && runConfig.ProtectedBranches != nil {
+		patterns = runConfig.ProtectedBranches
+	}
+	if isProtectedBranch(branch, patterns) {
+		return branch
+	}
+	return ""
+}
+
+// currentLang 返回本次运行解析出的语言，尚未解析配置时使用 --lang
+func currentLang() string {
+	if runConfig != nil {
+		return runConfig.Lang
+	}
+	return flagLang
+}
+
+// boolSetting 显式指定的参数或未配置时取参数值，否则保留配置值
+func boolSetting(changed, flagValue bool, configured *bool) *bool {
+	if changed || configured == nil {
+		return &flagValue
+	}
+	return configured
+}
+
+// newClient 创建生成 commit message 的 LLM Client：在 newRawClient 之上叠加
+// gitmoji、BREAKING CHANGE、WIP 等只适用于 commit message 的后处理
+func newClient() (clientInterface, error) {
+	cli, err := newRawClient()
+	if err != nil {
+		return nil, err
+	}
+	if flagConsistency > 1 {
+		lint := commitlint.Config{}
+		if runConfig != nil {
+			lint = commitlint.Config{MaxSubject: runConfig.MaxSubject, Disabled: runConfig.LintDisable, Enabled: runConfig.LintEnable}
+		}
+		cli = consistencyClient{inner: cli, n: flagConsistency, lint: lint}
+	}
+	if bodyMode == prompt.BodyRequired {
+		cli = bodyRequiredClient{inner: cli}
+	}
+	cli = breakingChangeClient{inner: cli}
+	if useGitmoji {
+		cli = gitmojiClient{inner: cli}
+	}
+	if wipMessages {
+		cli = wipClient{inner: cli}
+	}
+	return cli, nil
+}
+
+// newRawClient 创建 LLM Client，校验其配置并报告使用的模型，不做任何后处理；
+// 用于 PR 描述等不是 commit message 的文本
+func newRawClient() (clientInterface, error) {
+	cli := clientProvider()
+	if v, ok := cli.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	reportClientInfo(cli)
+	return cli, nil
+}
+
+// gitmojiClient 为生成的 message 补齐 gitmoji 前缀，模型未遵循指令时也能得到一致结果
+type gitmojiClient struct {
+	inner clientInterface
+}
+
+func (c gitmojiClient) GetCommitMessage(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	message, err := c.inner.GetCommitMessage(ctx, systemPrompt, userPrompt)
+	if err != nil {
+		return "", err
+	}
+	return prompt.ApplyGitmoji(message), nil
+}
+
+// breakingChangeClient 使不兼容变更同时带有标题中的 ! 与 BREAKING CHANGE footer，模型只给出其一时补齐另一个
+type breakingChangeClient struct {
+	inner clientInterface
+}
+
+func (c breakingChangeClient) GetCommitMessage(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	message, err := c.inner.GetCommitMessage(ctx, systemPrompt, userPrompt)
+	if err != nil {
+		return "", err
+	}
+	return commitlint.FixBreakingChange(message), nil
+}
+
+// bodyRequiredClient 在要求正文而模型只返回标题行时重新请求一次
+type bodyRequiredClient struct {
+	inner clientInterface
+}
+
+func (c bodyRequiredClient) GetCommitMessage(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	message, err := c.inner.GetCommitMessage(ctx, systemPrompt, userPrompt)
+	if err != nil || prompt.HasBody(message) {
+		return message, err
+	}
+	verbosef("Response has no body, asking again")
+	retried, err := c.inner.GetCommitMessage(ctx, systemPrompt, userPrompt+"\n\n"+prompt.BodyReminder)
+	if err != nil {
+		// 重试失败时保留首次结果，而不是让整个流程失败
+		if appLogger != nil {
+			appLogger.Debug("Body re-prompt failed", zap.Error(err))
+		}
+		return message, nil
+	}
+	return retried, nil
+}
+
+// resolveBodyMode 按 --body/--no-body、CATMIT_BODY 的优先级确定正文要求，
+// 均未设置时使用 fallback（所选预设的默认值）
+func resolveBodyMode(fallback prompt.BodyMode) (prompt.BodyMode, error) {
+	switch {
+	case flagBody && flagNoBody:
+		return prompt.BodyAuto, fmt.Errorf("--body and --no-body cannot be used together")
+	case flagBody:
+		return prompt.BodyRequired, nil
+	case flagNoBody:
+		return prompt.BodyOmitted, nil
+	}
+	env := os.Getenv("CATMIT_BODY")
+	if env == "" {
+		return fallback, nil
+	}
+	mode, err := prompt.ParseBodyMode(env)
+	if err != nil {
+		return prompt.BodyAuto, fmt.Errorf("CATMIT_BODY: %w", err)
+	}
+	return mode, nil
+}
+
+// resolveCacheTTL 按 --cache-ttl、CATMIT_CACHE_TTL 的优先级确定 git 命令的缓存时长，0 表示不缓存
+func resolveCacheTTL(cmd *cobra.Command) (time.Duration, error) {
+	ttl := flagCacheTTL
+	if env := os.Getenv("CATMIT_CACHE_TTL"); env != "" && !cmd.Flags().Changed("cache-ttl") {
+		parsed, err := time.ParseDuration(env)
+		if err != nil {
+			return 0, fmt.Errorf("CATMIT_CACHE_TTL: invalid duration %q", env)
+		}
+		ttl = parsed
+	}
+	if ttl < 0 {
+		return 0, fmt.Errorf("cache TTL must not be negative, got %s", ttl)
+	}
+	return ttl, nil
+}
+
+// reportClientInfo 在 debug/verbose 模式下报告实际使用的模型与端点
+func reportClientInfo(cli clientInterface) {
+	reporter, ok := cli.(interface{ Info() client.Info })
+	if !ok {
+		return
+	}
+	info := reporter.Info()
+	if appLogger != nil {
+		appLogger.Debug("LLM client configuration",
+			zap.String("provider", info.Provider),
+			zap.String("model", info.Model),
+			zap.String("endpoint", info.Endpoint))
+	}
+	verbosef("Using model %s via %s", info.Model, info.Endpoint)
+}
+
+// dryRunPush 在 --create-pr --pr-dry-run 时代替推送，只输出将要执行的 git push 命令
+func dryRunPush(cmd *cobra.Command) {
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Would run: %s\n", quoteCommand("git", pushArgs()...))
+}
+
+// createPullRequest creates the PR and reports the result on the command output.
+// An already existing PR is treated as success.
+func createPullRequest(ctx context.Context, cmd *cobra.Command) (string, error) {
+	if flagPRDryRun {
+		prDryRunCommand = ""
+		if _, err := committer.CreatePullRequest(ctx); err != nil {
+			return "", fmt.Errorf("failed to build pull request command: %w", err)
+		}
+		if prDryRunCommand != "" {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Would run: %s\n", prDryRunCommand)
+		}
+		return "", nil
+	}
+
+	_, _ = fmt.Fprintln(statusWriter(cmd), renderStatusBar("Creating pull request...", false))
+	prURL, err := committer.CreatePullRequest(ctx)
+	if errors.Is(err, errPRDeclined) {
+		_, _ = fmt.Fprintln(statusWriter(cmd), "Pull request not created.")
+		return "", nil
+	}
+	if err != nil {
+		var prExists *ErrPRAlreadyExists
+		if errors.As(err, &prExists) {
+			status := "Pull request already exists"
+			if prExists.Updated {
+				status += "; filled its empty description sections"
+			}
+			_, _ = fmt.Fprintln(statusWriter(cmd), renderStatusBar(status, true))
+			_, _ = fmt.Fprintf(statusWriter(cmd), "PR URL: %s\n", prExists.URL)
+			copyPRURL(ctx, cmd, prExists.URL)
+			return prExists.URL, nil
+		}
+		return "", fmt.Errorf("failed to create pull request: %w", err)
+	}
+	_, _ = fmt.Fprintln(statusWriter(cmd), renderStatusBar("Pull request created successfully", true))
+	if prURL != "" {
+		_, _ = fmt.Fprintf(statusWriter(cmd), "PR URL: %s\n", prURL)
+	}
+	copyPRURL(ctx, cmd, prURL)
+	return prURL, nil
+}
+
 // stage all changes (tracked and untracked)
 func stageAll(ctx context.Context) error {
 	cmd := exec.CommandContext(ctx, "git", "add", "-A")
@@ -625,6 +1956,22 @@ func stageAll(ctx context.Context) error {
 	return cmd.Run()
 }
 
+// stageSelection 暂存 add 中的文件，并将 reset 中的文件移出暂存区（--interactive-stage）
+func stageSelection(ctx context.Context, add, reset []string) error {
+	runner := runnerProvider()
+	if len(reset) > 0 {
+		if out, err := runner.Run(ctx, "git", append([]string{"reset", "-q", "--"}, reset...)...); err != nil {
+			return fmt.Errorf("git reset failed: %w\n%s", err, strings.TrimSpace(string(out)))
+		}
+	}
+	if len(add) > 0 {
+		if out, err := runner.Run(ctx, "git", append([]string{"add", "--"}, add...)...); err != nil {
+			return fmt.Errorf("git add failed: %w\n%s", err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
 // hasStagedChanges checks if there are any staged changes
 func hasStagedChanges(ctx context.Context) bool {
 	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--quiet")
@@ -3,9 +3,30 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/penwyp/catmit/client"
 	"github.com/penwyp/catmit/collector"
+	"github.com/penwyp/catmit/collector/collectortest"
+	"github.com/penwyp/catmit/config"
+	"github.com/penwyp/catmit/internal/commitlint"
+	"github.com/penwyp/catmit/internal/logger"
+	"github.com/penwyp/catmit/internal/clipboard"
+	"github.com/penwyp/catmit/internal/history"
+	"github.com/penwyp/catmit/internal/notify"
+	"github.com/penwyp/catmit/prompt"
+	"github.com/penwyp/catmit/ui"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
 )
 
@@ -80,6 +101,7 @@ func (m mockClient) GetCommitMessage(ctx context.Context, systemPrompt, userProm
 type recordCommitter struct {
 	called bool
 	msg    string
+	pushed bool
 }
 
 func (r *recordCommitter) Commit(ctx context.Context, message string) error {
@@ -87,14 +109,35 @@ func (r *recordCommitter) Commit(ctx context.Context, message string) error {
 	r.msg = message
 	return nil
 }
-func (r *recordCommitter) Push(ctx context.Context) error { return nil }
+func (r *recordCommitter) Push(ctx context.Context) error {
+	r.pushed = true
+	return nil
+}
 func (r *recordCommitter) StageAll(ctx context.Context) error { return nil }
 func (r *recordCommitter) HasStagedChanges(ctx context.Context) bool { return true }
 func (r *recordCommitter) CreatePullRequest(ctx context.Context) (string, error) { return "", nil }
 func (r *recordCommitter) NeedsPush(ctx context.Context) (bool, error) { return false, nil }
+func (r *recordCommitter) LastCommitSHA(ctx context.Context) (string, error) { return "", nil }
 
 // ------------------------------------------------
 
+// TestMain 将历史文件重定向到临时目录，避免测试写入真实的 ~/.config，
+// 并固定标准输入的终端检测结果
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "catmit-cmd-test")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("XDG_CONFIG_HOME", dir)
+	// go test 的标准输入/输出可能是管道，固定为终端以免测试意外读取 seed 或关闭 TUI
+	stdinIsTerminal = func() bool { return true }
+	stdoutIsTerminal = func() bool { return true }
+	stdinIsPiped = func() bool { return false }
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestRoot_DryRun(t *testing.T) {
 	// 注入 mock 依赖
 	flagDryRun = false
@@ -251,6 +294,45 @@ func TestDefaultCommitter(t *testing.T) {
 	require.Implements(t, (*commitInterface)(nil), committer)
 }
 
+// shaCommitter 在 recordCommitter 基础上报告提交后的 SHA
+type shaCommitter struct {
+	recordCommitter
+	sha string
+}
+
+func (s *shaCommitter) LastCommitSHA(ctx context.Context) (string, error) { return s.sha, nil }
+
+func TestRoot_PrintsCommitSHA(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalFlagDryRun, originalFlagYes := flagDryRun, flagYes
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		flagDryRun, flagYes = originalFlagDryRun, originalFlagYes
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	flagDryRun = false
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "fix: sha"} }
+	committer = &shaCommitter{sha: "0123456789abcdef0123456789abcdef01234567"}
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"-y", "--push=false"})
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "Committed 0123456")
+	require.NotContains(t, buf.String(), "Committed successfully")
+}
+
 func TestRun_ErrorPaths(t *testing.T) {
 	// Save original values
 	originalCollectorProvider := collectorProvider
@@ -388,3 +470,2470 @@ func TestRoot_NoDiff_YesMode(t *testing.T) {
 	require.NoError(t, err)
 	require.Contains(t, buf.String(), "Nothing to commit")
 }
+
+func TestQuoteCommand(t *testing.T) {
+	require.Equal(t, "gh pr create --fill --base main", quoteCommand("gh", "pr", "create", "--fill", "--base", "main"))
+	require.Equal(t, "gh pr create --title 'fix: it'\\''s broken' ''", quoteCommand("gh", "pr", "create", "--title", "fix: it's broken", ""))
+}
+
+func TestCreatePullRequest_DryRun(t *testing.T) {
+	originalFlagPRDryRun := flagPRDryRun
+	originalFlagPRBase := flagPRBase
+	originalFlagBaseRef := flagBaseRef
+	originalClientProvider := clientProvider
+	defer func() {
+		flagPRDryRun = originalFlagPRDryRun
+		flagPRBase = originalFlagPRBase
+		flagBaseRef = originalFlagBaseRef
+		clientProvider = originalClientProvider
+	}()
+
+	flagPRDryRun = true
+	flagPRBase = "main"
+	prURL, err := defaultCommitter{}.CreatePullRequest(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, prURL)
+	require.Equal(t, "gh pr create --fill --base main --draft=false", prDryRunCommand)
+
+	// dry-run 不调用 LLM 总结分支
+	clientProvider = func() clientInterface { return mockClient{err: errors.New("LLM must not be called")} }
+	flagBaseRef = "origin/main"
+	_, err = defaultCommitter{}.CreatePullRequest(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "gh pr create --fill --base main --draft=false", prDryRunCommand)
+
+	flagBaseRef, flagPRGenerateBody = "", true
+	defer func() { flagPRGenerateBody = false }()
+	_, err = defaultCommitter{}.CreatePullRequest(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "gh pr create --fill --base main --draft=false", prDryRunCommand)
+}
+
+func TestRoot_PRDryRunSkipsPush(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalFlagDryRun, originalFlagYes := flagDryRun, flagYes
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		flagDryRun, flagYes, flagCreatePR, flagPRDryRun = originalFlagDryRun, originalFlagYes, false, false
+		for _, name := range []string{"yes", "create-pr", "pr-dry-run"} {
+			rootCmd.Flags().Lookup(name).Changed = false
+		}
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	flagDryRun = false
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "feat: preview"} }
+	comm := &recordCommitter{}
+	committer = comm
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"-y", "--push", "--create-pr", "--pr-dry-run"})
+	require.NoError(t, rootCmd.Execute())
+	require.True(t, comm.called)
+	require.False(t, comm.pushed)
+	require.Contains(t, buf.String(), "Would run: git push")
+}
+
+func TestPRCreateArgs(t *testing.T) {
+	require.Equal(t, []string{"pr", "create", "--fill", "--base", "main", "--draft=false"}, prCreateArgs("main", "", "", "", false))
+	require.Equal(t,
+		[]string{"pr", "create", "--title", "feat: add x", "--body", "- detail", "--base", "develop", "--draft=false"},
+		prCreateArgs("develop", "", "feat: add x", "- detail", false))
+	require.Equal(t, []string{"pr", "create", "--fill", "--base", "main", "--draft=true"}, prCreateArgs("main", "", "", "", true))
+	require.Equal(t, []string{"pr", "create", "--fill", "--base", "main", "--head", "alice:feature/x", "--draft=false"},
+		prCreateArgs("main", "alice:feature/x", "", "", false))
+}
+
+// stubHeadResolver 返回固定的 remote owner 与当前分支
+type stubHeadResolver struct {
+	owner string
+	err   error
+}
+
+func (s stubHeadResolver) RemoteOwner(_ context.Context, remote string) (string, error) {
+	return s.owner, s.err
+}
+func (s stubHeadResolver) BranchName(_ context.Context) (string, error) { return "feature/x", nil }
+
+func TestPRHead(t *testing.T) {
+	defer func() { flagRemote = "" }()
+	ctx := context.Background()
+
+	flagRemote = ""
+	require.Empty(t, prHead(ctx, stubHeadResolver{owner: "alice"}))
+
+	flagRemote = "fork"
+	require.Equal(t, "alice:feature/x", prHead(ctx, stubHeadResolver{owner: "alice"}))
+	// owner 无法确定时交给 gh 自行推断
+	require.Empty(t, prHead(ctx, stubHeadResolver{err: errors.New("no such remote")}))
+}
+
+// stubBranchDiffer 返回固定的分支 diff，并记录使用的 base ref
+type stubBranchDiffer struct {
+	diff    string
+	err     error
+	baseRef string
+}
+
+func (s *stubBranchDiffer) DiffAgainstRef(_ context.Context, baseRef string) (string, error) {
+	s.baseRef = baseRef
+	return s.diff, s.err
+}
+
+func (s *stubBranchDiffer) AnalyzeChangesAgainstRef(_ context.Context, _ string) (*collector.ChangesSummary, error) {
+	return &collector.ChangesSummary{FilesByPriority: []collector.FileStatus{{Path: "a.go"}}}, nil
+}
+
+func TestSummarizeBranch(t *testing.T) {
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalFlagBaseRef := flagBaseRef
+	defer func() {
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		flagBaseRef = originalFlagBaseRef
+	}()
+
+	flagBaseRef = "origin/main"
+	promptProvider = func(lang string) promptInterface { return prompt.NewBuilder(lang, 0) }
+
+	t.Run("title_and_body", func(t *testing.T) {
+		cli := &promptCapturingClient{message: "- first\n- second"}
+		clientProvider = func() clientInterface { return cli }
+		src := &stubPRBodySource{stubBranchDiffer: stubBranchDiffer{diff: "diff --git a/a.go b/a.go"}, head: "feat: add x"}
+		title, body, err := summarizeBranch(context.Background(), src)
+		require.NoError(t, err)
+		require.Equal(t, "origin/main", src.baseRef)
+		require.Equal(t, "feat: add x", title)
+		require.Equal(t, "- first\n- second", body)
+		// 使用 PR 描述的提示词，而不是 commit message 的提示词
+		require.Contains(t, cli.systemPrompt, "pull request")
+	})
+
+	t.Run("diff_fails", func(t *testing.T) {
+		clientProvider = func() clientInterface { return mockClient{message: "unused"} }
+		_, _, err := summarizeBranch(context.Background(), &stubPRBodySource{stubBranchDiffer: stubBranchDiffer{err: errors.New("invalid ref")}})
+		require.ErrorContains(t, err, "origin/main")
+	})
+}
+
+// stubPRBodySource 在 stubBranchDiffer 基础上提供 HEAD 提交信息
+type stubPRBodySource struct {
+	stubBranchDiffer
+	head    string
+	commits []string
+}
+
+func (s *stubPRBodySource) HeadMessage(_ context.Context) (string, error) {
+	return s.head, nil
+}
+
+func (s *stubPRBodySource) CommitsOnBranch(_ context.Context, _ string) ([]string, error) {
+	return s.commits, nil
+}
+
+// promptCapturingClient 记录收到的提示词并返回固定结果
+type promptCapturingClient struct {
+	message      string
+	systemPrompt string
+	userPrompt   string
+}
+
+func (c *promptCapturingClient) GetCommitMessage(_ context.Context, systemPrompt, userPrompt string) (string, error) {
+	c.systemPrompt, c.userPrompt = systemPrompt, userPrompt
+	return c.message, nil
+}
+
+func TestGeneratePRBody(t *testing.T) {
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	defer func() {
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+	}()
+
+	promptProvider = func(lang string) promptInterface { return prompt.NewBuilder(lang, 0) }
+	cli := &promptCapturingClient{message: "Adds x.\n\n- new x command\n"}
+	clientProvider = func() clientInterface { return cli }
+
+	src := &stubPRBodySource{stubBranchDiffer: stubBranchDiffer{diff: "diff --git a/x.go b/x.go\n+x"}, head: "feat: add x\n\nDetails.", commits: []string{"feat: add x", "fix: x typo"}}
+	title, body, err := generatePRBody(context.Background(), src, "origin/main")
+	require.NoError(t, err)
+	require.Equal(t, "origin/main", src.baseRef)
+	require.Equal(t, "feat: add x", title)
+	require.Equal(t, "Adds x.\n\n- new x command", body)
+	require.Contains(t, cli.systemPrompt, "pull request")
+	require.Contains(t, cli.userPrompt, "Pull request title: feat: add x")
+	require.Contains(t, cli.userPrompt, "- feat: add x\n- fix: x typo")
+	require.Contains(t, cli.userPrompt, "+x")
+
+	// commit message 的后处理（gitmoji、WIP、--consistency）不作用于 PR 描述
+	useGitmoji, wipMessages, flagConsistency = true, true, 3
+	_, body, err = generatePRBody(context.Background(), src, "origin/main")
+	useGitmoji, wipMessages, flagConsistency = false, false, 0
+	require.NoError(t, err)
+	require.Equal(t, "Adds x.\n\n- new x command", body)
+
+	_, _, err = generatePRBody(context.Background(), &stubPRBodySource{head: "feat: add x"}, "origin/main")
+	require.ErrorContains(t, err, "no changes since origin/main")
+
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	_, _, err = generatePRBody(context.Background(), src, "origin/main")
+	require.ErrorContains(t, err, "not supported")
+
+	// --max-prompt-tokens 未配合 --truncate 时，超出上限直接失败而不是截断 diff
+	promptProvider = func(lang string) promptInterface { return prompt.NewBuilder(lang, 0) }
+	defer func() { flagMaxPromptTokens, flagTruncate = 0, false }()
+	flagMaxPromptTokens = 50
+	large := &stubPRBodySource{stubBranchDiffer: stubBranchDiffer{diff: "diff --git a/x.go b/x.go\n+" + strings.Repeat("x", 4000)}, head: "feat: add x"}
+	_, _, err = generatePRBody(context.Background(), large, "origin/main")
+	require.True(t, client.IsType(err, client.ErrTypeValidation))
+	require.ErrorContains(t, err, "prompt too large")
+	flagTruncate = true
+	_, _, err = generatePRBody(context.Background(), large, "origin/main")
+	require.NoError(t, err)
+}
+
+func TestReadPRTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, exec.Command("git", "init", "-q", dir).Run())
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	originalLocator := repoLocator
+	repoLocator = nil
+	defer func() {
+		_ = os.Chdir(wd)
+		repoLocator = originalLocator
+	}()
+
+	require.Empty(t, readPRTemplate(context.Background()))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".github"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".github", "pull_request_template.md"), []byte("## Summary\n"), 0o644))
+	require.Equal(t, "## Summary\n", readPRTemplate(context.Background()))
+}
+
+func TestReadCommitTemplate(t *testing.T) {
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	dir := t.TempDir()
+	require.NoError(t, exec.Command("git", "init", "-q", dir).Run())
+	// 在子目录中运行：相对路径与 git 一致，相对于工作区根目录解析
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(sub))
+	defer func() { _ = os.Chdir(wd) }()
+
+	require.Empty(t, readCommitTemplate(context.Background()))
+
+	template := "# Explain why\n<subject>\n\nReviewed-by: <name>\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitmessage"), []byte(template), 0o644))
+	require.NoError(t, exec.Command("git", "-C", dir, "config", "commit.template", ".gitmessage").Run())
+	require.Equal(t, template, readCommitTemplate(context.Background()))
+
+	// 模板文件不存在时忽略
+	require.NoError(t, exec.Command("git", "-C", dir, "config", "commit.template", "missing").Run())
+	require.Empty(t, readCommitTemplate(context.Background()))
+}
+
+// stubRunner 记录调用参数并返回固定结果
+type stubRunner struct {
+	output []byte
+	err    error
+	calls  [][]string
+}
+
+func (s *stubRunner) Run(_ context.Context, name string, args ...string) ([]byte, error) {
+	s.calls = append(s.calls, append([]string{name}, args...))
+	return s.output, s.err
+}
+
+func TestLookupExistingPRURL(t *testing.T) {
+	t.Run("url_returned", func(t *testing.T) {
+		runner := &stubRunner{output: []byte("https://github.com/owner/repo/pull/42\n")}
+		require.Equal(t, "https://github.com/owner/repo/pull/42", lookupExistingPRURL(context.Background(), runner))
+		require.Equal(t, []string{"gh", "pr", "view", "--json", "url", "--jq", ".url"}, runner.calls[0])
+	})
+
+	t.Run("view_fails", func(t *testing.T) {
+		runner := &stubRunner{err: errors.New("no pull requests found")}
+		require.Empty(t, lookupExistingPRURL(context.Background(), runner))
+	})
+
+	t.Run("unexpected_output", func(t *testing.T) {
+		runner := &stubRunner{output: []byte("not a url")}
+		require.Empty(t, lookupExistingPRURL(context.Background(), runner))
+	})
+}
+
+func TestUpdateExistingPRBody(t *testing.T) {
+	url := "https://github.com/owner/repo/pull/42"
+	generated := "## Summary\nGenerated summary.\n\n## Testing\nUnit tests pass."
+
+	// 手动编辑过的 Summary 保留，空的 Testing 小节被填充
+	runner := collectortest.NewRecordingRunner().
+		On("gh pr view", "## Summary\nHand-written.\n\n## Testing\n<!-- how? -->\n", nil).
+		On("gh pr edit", "", nil)
+	require.True(t, updateExistingPRBody(context.Background(), runner, url, generated))
+	require.True(t, runner.Ran("gh pr view "+url+" --json body --jq .body"))
+	require.True(t, runner.Ran("gh pr edit "+url+" --body ## Summary\nHand-written.\n\n## Testing\nUnit tests pass."))
+
+	// 没有可填充的小节时不更新
+	runner = collectortest.NewRecordingRunner().On("gh pr view", "## Summary\nHand-written.\n\n## Testing\nManual.\n", nil)
+	require.False(t, updateExistingPRBody(context.Background(), runner, url, generated))
+	require.False(t, runner.Ran("gh pr edit"))
+
+	runner = collectortest.NewRecordingRunner().On("gh pr view", "", errors.New("no pull requests found"))
+	require.False(t, updateExistingPRBody(context.Background(), runner, url, generated))
+}
+
+func TestPushArgs(t *testing.T) {
+	defer func() { flagRemote, flagNoVerify = "", false }()
+
+	flagRemote = ""
+	require.Equal(t, []string{"push"}, pushArgs())
+	flagRemote = "fork"
+	require.Equal(t, []string{"push", "fork", "HEAD"}, pushArgs())
+	flagNoVerify = true
+	require.Equal(t, []string{"push", "--no-verify", "fork", "HEAD"}, pushArgs())
+}
+
+func TestCommitArgs(t *testing.T) {
+	defer func() { flagAmend, flagAuthor, flagDate, flagNoVerify = false, "", "", false }()
+
+	require.Equal(t, []string{"commit", "-m", "fix: a"}, commitArgs("fix: a"))
+	flagAmend, flagAuthor, flagDate, flagNoVerify = true, "Jane Doe <jane@example.com>", "2 days ago", true
+	require.Equal(t, []string{"commit", "-m", "fix: a", "--amend", "--author=Jane Doe <jane@example.com>", "--date=2 days ago", "--no-verify"}, commitArgs("fix: a"))
+}
+
+func TestResultSummary(t *testing.T) {
+	require.Equal(t, "Committed successfully", resultSummary(ui.Result{Committed: true}))
+	require.Equal(t, "Committed 0123456, pushed, PR: https://example.com/pr/1", resultSummary(ui.Result{
+		Committed: true,
+		CommitSHA: "0123456789abcdef",
+		Push:      ui.PushSucceeded,
+		PRURL:     "https://example.com/pr/1",
+	}))
+	require.Equal(t, "Committed abc1234, push skipped", resultSummary(ui.Result{Committed: true, CommitSHA: "abc1234", Push: ui.PushSkipped}))
+}
+
+func TestCopyPRURL(t *testing.T) {
+	original := copyToClipboard
+	defer func() { copyToClipboard, flagCopyPRURL = original, false }()
+	var copied []string
+	copyToClipboard = func(_ context.Context, text string) error {
+		copied = append(copied, text)
+		return nil
+	}
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	copyPRURL(context.Background(), cmd, "https://github.com/o/r/pull/1")
+	require.Empty(t, copied, "copies only with --copy-pr-url")
+
+	flagCopyPRURL = true
+	copyPRURL(context.Background(), cmd, "")
+	copyPRURL(context.Background(), cmd, "https://github.com/o/r/pull/1")
+	require.Equal(t, []string{"https://github.com/o/r/pull/1"}, copied)
+	require.Contains(t, out.String(), "Copied PR URL to clipboard")
+
+	copyToClipboard = func(context.Context, string) error { return clipboard.ErrUnavailable }
+	var errOut bytes.Buffer
+	cmd.SetErr(&errOut)
+	copyPRURL(context.Background(), cmd, "https://github.com/o/r/pull/1")
+	require.Contains(t, errOut.String(), "Warning: failed to copy PR URL")
+}
+
+func TestResolveCacheTTL(t *testing.T) {
+	defer func() {
+		flagCacheTTL = collector.DefaultCacheTTL
+		rootCmd.Flags().Lookup("cache-ttl").Changed = false
+	}()
+	t.Setenv("CATMIT_CACHE_TTL", "")
+
+	ttl, err := resolveCacheTTL(rootCmd)
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, ttl)
+
+	t.Setenv("CATMIT_CACHE_TTL", "0")
+	ttl, err = resolveCacheTTL(rootCmd)
+	require.NoError(t, err)
+	require.Zero(t, ttl)
+
+	// 命令行参数优先于环境变量
+	require.NoError(t, rootCmd.Flags().Set("cache-ttl", "5s"))
+	ttl, err = resolveCacheTTL(rootCmd)
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, ttl)
+
+	rootCmd.Flags().Lookup("cache-ttl").Changed = false
+	t.Setenv("CATMIT_CACHE_TTL", "soon")
+	_, err = resolveCacheTTL(rootCmd)
+	require.ErrorContains(t, err, `CATMIT_CACHE_TTL: invalid duration "soon"`)
+
+	t.Setenv("CATMIT_CACHE_TTL", "-1s")
+	_, err = resolveCacheTTL(rootCmd)
+	require.ErrorContains(t, err, "must not be negative")
+}
+
+func TestPickCandidate(t *testing.T) {
+	cfg := commitlint.Config{}
+	candidates := []string{
+		"Fixed the parser",                  // 无 type 前缀且非祈使语气
+		"fix(parser): handle tabs",          // 与第 4 条 header 一致
+		"feat(parser): accept tabs",         // 只有一票
+		"fix(parser)!: handle tabs in keys", // "!" 不影响投票
+	}
+	require.Equal(t, -2*(len(candidates)+1), scoreCandidate(candidates[0], candidates, cfg), "two violations, no header vote")
+	require.Equal(t, 2, scoreCandidate(candidates[1], candidates, cfg))
+	require.Equal(t, 1, scoreCandidate(candidates[2], candidates, cfg))
+	require.Equal(t, 1, pickCandidate(candidates, cfg), "ties go to the earlier candidate")
+
+	// 规则符合度优先于投票：多数派违反 subject-length 时选择唯一合规的候选
+	long := "fix: " + strings.Repeat("x", 80)
+	require.Equal(t, 2, pickCandidate([]string{long, long, "feat: add tabs"}, cfg))
+}
+
+// lockedSequenceClient 依次返回预设的 message 与错误，最后一条之后重复返回最后一条；可并发调用
+type lockedSequenceClient struct {
+	mu       sync.Mutex
+	messages []string
+	errs     []error
+	calls    int
+}
+
+func (c *lockedSequenceClient) GetCommitMessage(context.Context, string, string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.calls
+	c.calls++
+	if i >= len(c.messages) {
+		i = len(c.messages) - 1
+	}
+	return c.messages[i], c.errs[i]
+}
+
+func TestConsistencyClient(t *testing.T) {
+	inner := &lockedSequenceClient{
+		messages: []string{"fix: a", "fix: a", "fix: a"},
+		errs:     []error{nil, nil, nil},
+	}
+	message, err := consistencyClient{inner: inner, n: 3}.GetCommitMessage(context.Background(), "s", "u")
+	require.NoError(t, err)
+	require.Equal(t, "fix: a", message)
+	require.Equal(t, 3, inner.calls)
+
+	failure := errors.New("rate limited")
+	inner = &lockedSequenceClient{messages: []string{"", ""}, errs: []error{failure, failure}}
+	_, err = consistencyClient{inner: inner, n: 2}.GetCommitMessage(context.Background(), "s", "u")
+	require.ErrorIs(t, err, failure)
+
+	// 超过上限的 --consistency 在调用 LLM 之前被拒绝
+	defer func() {
+		flagConsistency = 0
+		rootCmd.Flags().Lookup("consistency").Changed = false
+	}()
+	rootCmd.SetArgs([]string{"--consistency", "6", "--dry-run"})
+	require.ErrorContains(t, rootCmd.Execute(), "--consistency must be at most 5, got 6")
+}
+
+func TestPreviewPR(t *testing.T) {
+	defer func() { prPreviewIn, prPreviewOut, prPreviewConfirm = os.Stdin, os.Stdout, false }()
+	var out bytes.Buffer
+	prPreviewOut = &out
+
+	require.NoError(t, previewPR("feat: add tabs", "## Summary\nAdds tabs.\n\n## Issue\n{{.Issue}} {{ .Issue }} {{.Ticket}}"))
+	require.Contains(t, out.String(), "Title: feat: add tabs\n\n## Summary\nAdds tabs.")
+	require.Contains(t, out.String(), "Warning: unfilled template placeholders: {{.Issue}}, {{ .Issue }}, {{.Ticket}}")
+	require.NotContains(t, out.String(), "[y/N]", "no confirmation in non-interactive mode")
+
+	prPreviewConfirm = true
+	for answer, want := range map[string]error{"y\n": nil, "Yes\n": nil, "\n": errPRDeclined, "no\n": errPRDeclined, "": errPRDeclined} {
+		out.Reset()
+		prPreviewIn = strings.NewReader(answer)
+		require.ErrorIs(t, previewPR("", ""), want, "answer %q", answer)
+		require.Contains(t, out.String(), "filled in by gh")
+		require.Contains(t, out.String(), "Create this pull request? [y/N]")
+	}
+}
+
+// declinedPRCommitter 模拟用户在 --pr-preview 的预览后拒绝创建
+type declinedPRCommitter struct{ recordCommitter }
+
+func (declinedPRCommitter) CreatePullRequest(context.Context) (string, error) {
+	return "", errPRDeclined
+}
+
+func TestCreatePullRequest_Declined(t *testing.T) {
+	original := committer
+	defer func() { committer = original }()
+	committer = &declinedPRCommitter{}
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	prURL, err := createPullRequest(context.Background(), cmd)
+	require.NoError(t, err)
+	require.Empty(t, prURL)
+	require.Contains(t, out.String(), "Pull request not created.")
+}
+
+func TestValidateIdentity(t *testing.T) {
+	require.NoError(t, validateIdentity("Jane Doe <jane@example.com>"))
+	for _, identity := range []string{"Jane Doe", "<jane@example.com>", "Jane <jane>", "Jane <jane@example.com", "Jane <a b@example.com>"} {
+		require.Error(t, validateIdentity(identity), identity)
+	}
+}
+
+func TestRoot_InvalidAuthor(t *testing.T) {
+	defer func() {
+		flagAuthor, flagDryRun = "", false
+		rootCmd.Flags().Lookup("author").Changed = false
+		rootCmd.Flags().Lookup("dry-run").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	rootCmd.SetArgs([]string{"--dry-run", "--author", "jane@example.com"})
+	require.ErrorContains(t, rootCmd.Execute(), `--author: invalid identity "jane@example.com"`)
+}
+
+func TestPushedRef_Remote(t *testing.T) {
+	dir := t.TempDir()
+	runGit := func(args ...string) {
+		t.Helper()
+		c := exec.Command("git", args...)
+		c.Dir = dir
+		out, err := c.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	runGit("init", "-q", "-b", "topic")
+	runGit("-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "--allow-empty", "-m", "init")
+	runGit("update-ref", "refs/remotes/fork/topic", "HEAD")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() {
+		_ = os.Chdir(wd)
+		flagRemote = ""
+	}()
+
+	flagRemote = "fork"
+	ref, err := pushedRef(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "fork/topic", ref)
+
+	// 所选 remote 上没有该分支时需要推送
+	flagRemote = "upstream"
+	_, err = pushedRef(context.Background())
+	require.Error(t, err)
+}
+
+func TestResolvePRBase(t *testing.T) {
+	originalFlagPRBase := flagPRBase
+	defer func() { flagPRBase = originalFlagPRBase }()
+
+	t.Run("detects_default_branch", func(t *testing.T) {
+		flagPRBase = ""
+		runner := &stubRunner{output: []byte("origin/develop\n")}
+		require.Equal(t, "develop", resolvePRBase(context.Background(), collector.New(runner)))
+		require.Equal(t, []string{"git", "symbolic-ref", "--short", "refs/remotes/origin/HEAD"}, runner.calls[0])
+	})
+
+	t.Run("falls_back_to_main", func(t *testing.T) {
+		flagPRBase = ""
+		runner := &stubRunner{err: errors.New("fatal: ref refs/remotes/origin/HEAD is not a symbolic ref")}
+		require.Equal(t, "main", resolvePRBase(context.Background(), collector.New(runner)))
+	})
+
+	t.Run("uses_selected_remote", func(t *testing.T) {
+		flagPRBase, flagRemote = "", "upstream"
+		defer func() { flagRemote = "" }()
+		runner := &stubRunner{output: []byte("upstream/trunk\n")}
+		require.Equal(t, "trunk", resolvePRBase(context.Background(), collector.New(runner)))
+		require.Equal(t, []string{"git", "symbolic-ref", "--short", "refs/remotes/upstream/HEAD"}, runner.calls[0])
+	})
+
+	t.Run("explicit_base_wins", func(t *testing.T) {
+		flagPRBase = "release"
+		runner := &stubRunner{output: []byte("origin/develop\n")}
+		require.Equal(t, "release", resolvePRBase(context.Background(), collector.New(runner)))
+		require.Empty(t, runner.calls)
+	})
+}
+
+func TestRoot_Verbose(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalFlagDryRun := flagDryRun
+	originalFlagVerbose := flagVerbose
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		flagDryRun = originalFlagDryRun
+		flagVerbose = originalFlagVerbose
+	}()
+
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff", commits: []string{"feat: a"}} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "feat: verbose"} }
+
+	rootCmd.SetArgs([]string{"--dry-run", "--verbose"})
+	var out, errOut bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+	defer rootCmd.SetErr(nil)
+
+	err := rootCmd.Execute()
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "feat: verbose")
+	require.Contains(t, errOut.String(), "Changed files: 1")
+	require.Contains(t, errOut.String(), "Estimated prompt tokens:")
+	require.NotContains(t, out.String(), "Estimated prompt tokens:")
+}
+
+func TestRoot_RecordsAndReusesHistory(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalHistoryProvider := historyProvider
+	originalFlagDryRun := flagDryRun
+	originalFlagReuseLast := flagReuseLast
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		historyProvider = originalHistoryProvider
+		flagDryRun = originalFlagDryRun
+		flagReuseLast = originalFlagReuseLast
+	}()
+
+	store := history.New(filepath.Join(t.TempDir(), "history.jsonl"), 0)
+	historyProvider = func() *history.Store { return store }
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "feat: remembered"} }
+
+	rootCmd.SetArgs([]string{"--dry-run"})
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	require.NoError(t, rootCmd.Execute())
+
+	entries, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "feat: remembered", entries[0].Message)
+	require.Equal(t, currentRepo(context.Background()), entries[0].Repo)
+
+	// --reuse-last 不应再调用 LLM
+	clientProvider = func() clientInterface { return mockClient{err: errors.New("should not be called")} }
+	buf.Reset()
+	rootCmd.SetArgs([]string{"--dry-run", "--reuse-last"})
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "feat: remembered")
+
+	buf.Reset()
+	flagHistoryLimit = 20
+	rootCmd.SetArgs([]string{"history"})
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "feat: remembered")
+}
+
+func TestRoot_ReuseLastWithoutHistory(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalHistoryProvider := historyProvider
+	originalFlagReuseLast := flagReuseLast
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		historyProvider = originalHistoryProvider
+		flagReuseLast = originalFlagReuseLast
+	}()
+
+	historyProvider = func() *history.Store { return history.New(filepath.Join(t.TempDir(), "history.jsonl"), 0) }
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+
+	rootCmd.SetArgs([]string{"--dry-run", "--reuse-last"})
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	err := rootCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no recorded commit message")
+}
+
+// seedRecordingPrompt 记录传入的 seed，用于校验 seed 来源
+type seedRecordingPrompt struct {
+	mockPrompt
+	seed *string
+}
+
+func (p seedRecordingPrompt) BuildUserPromptWithBudget(ctx context.Context, collector interface{}, seed string) (string, error) {
+	*p.seed = seed
+	return "user prompt with budget", nil
+}
+
+func TestRoot_SeedFromStdin(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalStdinIsTerminal := stdinIsTerminal
+	originalStdinIsPiped := stdinIsPiped
+	originalFlagSeed := flagSeed
+	originalFlagPush := flagPush
+	originalFlagDryRun := flagDryRun
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		stdinIsTerminal = originalStdinIsTerminal
+		stdinIsPiped = originalStdinIsPiped
+		flagSeed = originalFlagSeed
+		flagPush = originalFlagPush
+		flagDryRun = originalFlagDryRun
+		rootCmd.SetIn(nil)
+	}()
+
+	var seed string
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return seedRecordingPrompt{seed: &seed} }
+	clientProvider = func() clientInterface { return mockClient{message: "fix: null ptr"} }
+
+	t.Run("explicit dash reads stdin and commits without TUI", func(t *testing.T) {
+		seed = ""
+		flagDryRun = false
+		comm := &recordCommitter{}
+		committer = comm
+		rootCmd.SetIn(bytes.NewBufferString("fix null ptr\n"))
+		rootCmd.SetArgs([]string{"--seed", "-", "--push=false"})
+		var buf bytes.Buffer
+		rootCmd.SetOut(&buf)
+		rootCmd.SetErr(&buf)
+
+		require.NoError(t, rootCmd.Execute())
+		require.Equal(t, "fix null ptr", seed)
+		require.True(t, comm.called)
+	})
+
+	t.Run("piped stdin without seed", func(t *testing.T) {
+		seed = ""
+		flagSeed = ""
+		stdinIsTerminal = func() bool { return false }
+		stdinIsPiped = func() bool { return true }
+		rootCmd.SetIn(bytes.NewBufferString("- bullet one\n- bullet two\n"))
+		rootCmd.SetArgs([]string{"--dry-run"})
+		var buf bytes.Buffer
+		rootCmd.SetOut(&buf)
+		rootCmd.SetErr(&buf)
+
+		require.NoError(t, rootCmd.Execute())
+		require.Equal(t, "- bullet one\n- bullet two", seed)
+	})
+
+	t.Run("positional seed wins over piped stdin", func(t *testing.T) {
+		seed = ""
+		flagSeed = ""
+		stdinIsTerminal = func() bool { return false }
+		stdinIsPiped = func() bool { return true }
+		rootCmd.SetIn(bytes.NewBufferString("ignored"))
+		rootCmd.SetArgs([]string{"--dry-run", "from arg"})
+		var buf bytes.Buffer
+		rootCmd.SetOut(&buf)
+		rootCmd.SetErr(&buf)
+
+		require.NoError(t, rootCmd.Execute())
+		require.Equal(t, "from arg", seed)
+	})
+
+	t.Run("empty piped stdin stays interactive", func(t *testing.T) {
+		flagSeed = ""
+		stdinIsTerminal = func() bool { return false }
+		stdinIsPiped = func() bool { return true }
+		cmd := &cobra.Command{}
+		cmd.SetIn(bytes.NewBufferString("  \n"))
+
+		got, fromStdin, err := resolveSeed(cmd, nil)
+		require.NoError(t, err)
+		require.Empty(t, got)
+		require.False(t, fromStdin)
+	})
+
+	t.Run("non-pipe stdin is not read", func(t *testing.T) {
+		flagSeed = ""
+		stdinIsTerminal = func() bool { return false }
+		stdinIsPiped = func() bool { return false }
+		cmd := &cobra.Command{}
+		cmd.SetIn(bytes.NewBufferString("should not be read"))
+
+		got, fromStdin, err := resolveSeed(cmd, nil)
+		require.NoError(t, err)
+		require.Empty(t, got)
+		require.False(t, fromStdin)
+	})
+}
+
+// sequenceClient 依次返回预设的 message，并记录收到的用户提示词
+type sequenceClient struct {
+	messages    []string
+	userPrompts []string
+}
+
+func (c *sequenceClient) GetCommitMessage(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	c.userPrompts = append(c.userPrompts, userPrompt)
+	msg := c.messages[0]
+	if len(c.messages) > 1 {
+		c.messages = c.messages[1:]
+	}
+	return msg, nil
+}
+
+func TestBodyRequiredClient(t *testing.T) {
+	t.Run("re-prompts once when body is missing", func(t *testing.T) {
+		inner := &sequenceClient{messages: []string{"feat: add x", "feat: add x\n\nExplain why."}}
+		msg, err := bodyRequiredClient{inner: inner}.GetCommitMessage(context.Background(), "sys", "user")
+		require.NoError(t, err)
+		require.Equal(t, "feat: add x\n\nExplain why.", msg)
+		require.Len(t, inner.userPrompts, 2)
+		require.Contains(t, inner.userPrompts[1], prompt.BodyReminder)
+	})
+
+	t.Run("keeps a message that already has a body", func(t *testing.T) {
+		inner := &sequenceClient{messages: []string{"fix: y\n\nBecause."}}
+		msg, err := bodyRequiredClient{inner: inner}.GetCommitMessage(context.Background(), "sys", "user")
+		require.NoError(t, err)
+		require.Equal(t, "fix: y\n\nBecause.", msg)
+		require.Len(t, inner.userPrompts, 1)
+	})
+}
+
+func TestResolveBodyMode(t *testing.T) {
+	originalFlagBody, originalFlagNoBody := flagBody, flagNoBody
+	defer func() { flagBody, flagNoBody = originalFlagBody, originalFlagNoBody }()
+
+	flagBody, flagNoBody = false, false
+	t.Setenv("CATMIT_BODY", "always")
+	mode, err := resolveBodyMode(prompt.BodyAuto)
+	require.NoError(t, err)
+	require.Equal(t, prompt.BodyRequired, mode)
+
+	// 命令行参数优先于环境变量
+	flagNoBody = true
+	mode, err = resolveBodyMode(prompt.BodyAuto)
+	require.NoError(t, err)
+	require.Equal(t, prompt.BodyOmitted, mode)
+
+	flagBody = true
+	_, err = resolveBodyMode(prompt.BodyAuto)
+	require.Error(t, err)
+
+	// 未设置参数与环境变量时使用预设的默认值
+	flagBody, flagNoBody = false, false
+	t.Setenv("CATMIT_BODY", "")
+	mode, err = resolveBodyMode(prompt.BodyOmitted)
+	require.NoError(t, err)
+	require.Equal(t, prompt.BodyOmitted, mode)
+
+	t.Setenv("CATMIT_BODY", "bogus")
+	_, err = resolveBodyMode(prompt.BodyAuto)
+	require.Error(t, err)
+}
+
+func TestRoot_Gitmoji(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalFlagDryRun := flagDryRun
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		flagDryRun = originalFlagDryRun
+		flagGitmoji = false
+		rootCmd.Flags().Lookup("gitmoji").Changed = false
+	}()
+
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "fix: handle nil"} }
+
+	rootCmd.SetArgs([]string{"--dry-run", "--gitmoji"})
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "🐛 fix: handle nil")
+}
+
+// resetConfigFlags 恢复与配置合并相关的参数；pflag 的 Changed 状态会在多次 Execute 间保留
+func resetConfigFlags() {
+	flagLang, flagStageAll, flagPush, flagGitmoji, flagPreset, flagKeepOpen = "en", true, true, false, "", false
+	for _, name := range []string{"lang", "stage-all", "push", "gitmoji", "preset", "keep-open"} {
+		rootCmd.Flags().Lookup(name).Changed = false
+	}
+}
+
+// useRepoRoot 让 locateRepoRoot 返回 root，root 为空时模拟不在 Git 仓库中；测试结束后恢复 repoLocator
+func useRepoRoot(t *testing.T, root string) {
+	original := repoLocator
+	t.Cleanup(func() { repoLocator = original })
+	r := collectortest.NewRecordingRunner()
+	if root == "" {
+		r.On("git rev-parse --show-toplevel", "fatal: not a git repository", errors.New("exit status 128"))
+	} else {
+		r.On("git rev-parse --show-toplevel", root+"\n", nil)
+	}
+	repoLocator = collector.New(r)
+}
+
+func TestResolveConfig(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("CATMIT_GITMOJI", "")
+	require.NoError(t, os.MkdirAll(filepath.Join(xdg, "catmit"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(xdg, "catmit", "config.yaml"), []byte("lang: zh\npush: false\nmax_subject: 60\nkeep_open: true\n"), 0o644))
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".catmit.yaml"), []byte("stage_all: false\nmax_subject: 72\nexclude: [\"*.lock\"]\ngitmoji: true\n"), 0o644))
+	useRepoRoot(t, root)
+
+	resetConfigFlags()
+	defer resetConfigFlags()
+
+	cfg, err := resolveConfig(context.Background(), rootCmd)
+	require.NoError(t, err)
+	require.Equal(t, "zh", cfg.Lang)     // 全局配置
+	require.False(t, *cfg.Push)          // 全局配置
+	require.False(t, *cfg.StageAll)      // 仓库配置
+	require.Equal(t, 72, cfg.MaxSubject) // 仓库配置覆盖全局配置
+	require.Equal(t, []string{"*.lock"}, cfg.Exclude)
+	require.True(t, *cfg.Gitmoji)
+	require.True(t, *cfg.KeepOpen) // 全局配置
+
+	// 环境变量优先于配置文件
+	t.Setenv("CATMIT_GITMOJI", "false")
+	cfg, err = resolveConfig(context.Background(), rootCmd)
+	require.NoError(t, err)
+	require.False(t, *cfg.Gitmoji)
+
+	// 命令行参数优先于环境变量与配置文件
+	require.NoError(t, rootCmd.Flags().Set("lang", "ja"))
+	require.NoError(t, rootCmd.Flags().Set("stage-all", "true"))
+	require.NoError(t, rootCmd.Flags().Set("gitmoji", "true"))
+	require.NoError(t, rootCmd.Flags().Set("keep-open", "false"))
+	cfg, err = resolveConfig(context.Background(), rootCmd)
+	require.NoError(t, err)
+	require.Equal(t, "ja", cfg.Lang)
+	require.False(t, *cfg.KeepOpen)
+	require.True(t, *cfg.StageAll)
+	require.True(t, *cfg.Gitmoji)
+
+	t.Setenv("CATMIT_GITMOJI", "maybe")
+	_, err = resolveConfig(context.Background(), rootCmd)
+	require.Error(t, err)
+}
+
+func TestResolveConfig_Defaults(t *testing.T) {
+	resetConfigFlags()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("CATMIT_GITMOJI", "")
+
+	useRepoRoot(t, "")
+
+	cfg, err := resolveConfig(context.Background(), rootCmd)
+	require.NoError(t, err)
+	require.Equal(t, flagLang, cfg.Lang)
+	require.Equal(t, flagPush, *cfg.Push)
+	require.Equal(t, flagStageAll, *cfg.StageAll)
+	require.False(t, *cfg.Gitmoji)
+	require.False(t, *cfg.KeepOpen)
+	require.Zero(t, cfg.MaxSubject)
+	require.Equal(t, prompt.DefaultPreset.Name, cfg.Preset)
+}
+
+func TestResolveConfig_Preset(t *testing.T) {
+	resetConfigFlags()
+	defer resetConfigFlags()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("CATMIT_GITMOJI", "")
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".catmit.yaml"), []byte("preset: gitmoji\n"), 0o644))
+	useRepoRoot(t, root)
+
+	// 预设提供 gitmoji 的默认值
+	cfg, err := resolveConfig(context.Background(), rootCmd)
+	require.NoError(t, err)
+	require.Equal(t, "gitmoji", cfg.Preset)
+	require.True(t, *cfg.Gitmoji)
+
+	// 显式设置的 gitmoji 优先于预设
+	t.Setenv("CATMIT_GITMOJI", "false")
+	cfg, err = resolveConfig(context.Background(), rootCmd)
+	require.NoError(t, err)
+	require.False(t, *cfg.Gitmoji)
+
+	// 命令行参数优先于配置文件
+	t.Setenv("CATMIT_GITMOJI", "")
+	require.NoError(t, rootCmd.Flags().Set("preset", "Simple"))
+	cfg, err = resolveConfig(context.Background(), rootCmd)
+	require.NoError(t, err)
+	require.Equal(t, "simple", cfg.Preset)
+	require.False(t, *cfg.Gitmoji)
+
+	require.NoError(t, rootCmd.Flags().Set("preset", "bogus"))
+	_, err = resolveConfig(context.Background(), rootCmd)
+	require.ErrorContains(t, err, "unknown preset")
+}
+
+func TestResolveTUI(t *testing.T) {
+	originalStdoutIsTerminal := stdoutIsTerminal
+	defer func() {
+		stdoutIsTerminal = originalStdoutIsTerminal
+		flagTUI, flagNoTUI = false, false
+	}()
+
+	flagTUI, flagNoTUI = false, false
+	stdoutIsTerminal = func() bool { return false }
+	useTUI, err := resolveTUI()
+	require.NoError(t, err)
+	require.False(t, useTUI)
+
+	flagTUI = true
+	useTUI, err = resolveTUI()
+	require.NoError(t, err)
+	require.True(t, useTUI)
+
+	flagTUI, flagNoTUI = false, true
+	stdoutIsTerminal = func() bool { return true }
+	useTUI, err = resolveTUI()
+	require.NoError(t, err)
+	require.False(t, useTUI)
+
+	flagTUI = true
+	_, err = resolveTUI()
+	require.Error(t, err)
+}
+
+func TestRoot_NoTTYFallsBackToPlainCommit(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalStdoutIsTerminal := stdoutIsTerminal
+	originalFlagDryRun, originalFlagYes := flagDryRun, flagYes
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		stdoutIsTerminal = originalStdoutIsTerminal
+		flagDryRun, flagYes = originalFlagDryRun, originalFlagYes
+		plainOutput = false
+		resetConfigFlags()
+	}()
+
+	flagDryRun, flagYes = false, false
+	stdoutIsTerminal = func() bool { return false }
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "feat: plain"} }
+	comm := &recordCommitter{}
+	committer = comm
+
+	// 未指定 -y 或 --no-tui 时同样以纯文本逐步输出并提交，不修改 --dry-run
+	rootCmd.SetArgs([]string{"--push=false"})
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	require.NoError(t, rootCmd.Execute())
+	require.True(t, comm.called)
+	require.Equal(t, "feat: plain", comm.msg)
+	require.False(t, flagDryRun)
+	require.Contains(t, buf.String(), "✓ Committed successfully\n")
+	require.NotContains(t, buf.String(), "\x1b[")
+}
+
+func TestIsProtectedBranch(t *testing.T) {
+	for branch, want := range map[string]bool{
+		"main":            true,
+		"master":          true,
+		"release/1.2":     true,
+		"release/1.2/fix": false,
+		"feature/main":    false,
+		"develop":         false,
+	} {
+		require.Equal(t, want, isProtectedBranch(branch, defaultProtectedBranches), branch)
+	}
+}
+
+// branchCollector 返回指定分支名的 mockCollector
+type branchCollector struct {
+	mockCollector
+	branch string
+}
+
+func (b branchCollector) BranchName(_ context.Context) (string, error) { return b.branch, nil }
+
+func TestRoot_YesSkipsPushToProtectedBranch(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalFlagDryRun, originalFlagYes := flagDryRun, flagYes
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		flagDryRun, flagYes = originalFlagDryRun, originalFlagYes
+		flagForcePush = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	flagDryRun = false
+	collectorProvider = func() collectorInterface { return branchCollector{mockCollector: mockCollector{diff: "diff"}, branch: "main"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "fix: guarded"} }
+
+	comm := &recordCommitter{}
+	committer = comm
+	rootCmd.SetArgs([]string{"-y"})
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	// 提交保留在本地，但以非零退出码报告推送被跳过
+	var exitErr *ExitError
+	require.ErrorAs(t, rootCmd.Execute(), &exitErr)
+	require.Equal(t, ExitCodePushSkipped, exitErr.Code)
+	require.True(t, exitErr.Silent)
+	require.True(t, comm.called)
+	require.False(t, comm.pushed)
+	require.Contains(t, buf.String(), "Skipped push: main is a protected branch")
+
+	comm = &recordCommitter{}
+	committer = comm
+	rootCmd.SetArgs([]string{"-y", "--force-push"})
+	require.NoError(t, rootCmd.Execute())
+	require.True(t, comm.pushed)
+}
+
+type detachedCollector struct {
+	mockCollector
+}
+
+func (detachedCollector) BranchName(_ context.Context) (string, error) {
+	return "", collector.ErrDetachedHead
+}
+
+func TestRoot_DetachedHeadSkipsPushAndPR(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalFlagDryRun, originalFlagYes := flagDryRun, flagYes
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		flagDryRun, flagYes, flagCreatePR = originalFlagDryRun, originalFlagYes, false
+		rootCmd.Flags().Lookup("create-pr").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	flagDryRun = false
+	collectorProvider = func() collectorInterface { return detachedCollector{mockCollector{diff: "diff"}} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "fix: detached"} }
+	comm := &recordCommitter{}
+	committer = comm
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"-y"})
+	require.NoError(t, rootCmd.Execute())
+	require.True(t, comm.called)
+	require.False(t, comm.pushed)
+	require.Contains(t, buf.String(), "Skipped push: HEAD is detached")
+
+	// 没有变更时无法从分离 HEAD 创建 PR
+	collectorProvider = func() collectorInterface { return detachedCollector{mockCollector{err: collector.ErrNoDiff}} }
+	rootCmd.SetArgs([]string{"-y", "--create-pr"})
+	err := rootCmd.Execute()
+	require.ErrorContains(t, err, "check out a branch")
+}
+
+func TestCurrentRepo_LinkedWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	gitRun := func(dir string, args ...string) {
+		c := exec.Command("git", args...)
+		c.Dir = dir
+		c.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		out, err := c.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	mainDir := filepath.Join(base, "main")
+	wt := filepath.Join(base, "wt")
+	require.NoError(t, os.Mkdir(mainDir, 0o755))
+	gitRun(mainDir, "init", "-q")
+	gitRun(mainDir, "commit", "-q", "--allow-empty", "-m", "init")
+	gitRun(mainDir, "worktree", "add", "-q", wt)
+
+	// worktree 中的 .catmit.yaml 应优先于主仓库中的
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, ".catmit.yaml"), []byte("max_subject: 50\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wt, ".catmit.yaml"), []byte("max_subject: 60\n"), 0o644))
+	sub := filepath.Join(wt, "pkg")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+
+	orig, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(sub))
+	defer func() { _ = os.Chdir(orig) }()
+
+	// repoLocator 会记住首次查到的根目录，切换目录后需要新的实例
+	originalLocator := repoLocator
+	repoLocator = nil
+	defer func() { repoLocator = originalLocator }()
+
+	root := currentRepo(context.Background())
+	require.Equal(t, wt, root)
+
+	resetConfigFlags()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cfg, err := resolveConfig(context.Background(), rootCmd)
+	require.NoError(t, err)
+	require.Equal(t, 60, cfg.MaxSubject)
+}
+
+func TestLint(t *testing.T) {
+	originalProvider := lintMessageProvider
+	originalRunnerProvider := runnerProvider
+	defer func() {
+		lintMessageProvider = originalProvider
+		runnerProvider = originalRunnerProvider
+	}()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// 默认实现通过 runnerProvider 读取提交的 message
+	runner := collectortest.NewRecordingRunner().On("git log", "fix: read via runner\n", nil)
+	runnerProvider = func() collector.Runner { return runner }
+	message, err := lintMessageProvider(context.Background(), "HEAD~1")
+	require.NoError(t, err)
+	require.Equal(t, "fix: read via runner\n", message)
+	require.True(t, runner.Ran("git log -1 --pretty=%B HEAD~1 --"))
+	runnerProvider = originalRunnerProvider
+
+	var gotRef string
+	lintMessageProvider = func(_ context.Context, ref string) (string, error) {
+		gotRef = ref
+		return "feat(cli): add lint command\n\nValidate existing messages.\n", nil
+	}
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"lint", "HEAD~1"})
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "HEAD~1", gotRef)
+	require.Contains(t, buf.String(), "✔")
+
+	// 从标准输入读取，违规时返回退出码 5
+	buf.Reset()
+	rootCmd.SetIn(bytes.NewBufferString("Added lint command\nno blank line\n"))
+	defer rootCmd.SetIn(nil)
+	rootCmd.SetArgs([]string{"lint", "-"})
+	err = rootCmd.Execute()
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, ExitCodeLintFailed, exitErr.Code)
+	require.Contains(t, buf.String(), "type-prefix")
+	require.Contains(t, buf.String(), "body-leading-blank")
+
+	// --disable 跳过指定规则
+	buf.Reset()
+	rootCmd.SetIn(bytes.NewBufferString("feat: added lint command\n"))
+	rootCmd.SetArgs([]string{"lint", "-", "--disable", "subject-imperative"})
+	require.NoError(t, rootCmd.Execute())
+	lintCmd.Flags().Lookup("disable").Changed = false
+	flagLintDisable = nil
+
+	rootCmd.SetArgs([]string{"lint", "-", "--disable", "no-such-rule"})
+	require.Error(t, rootCmd.Execute())
+	lintCmd.Flags().Lookup("disable").Changed = false
+	flagLintDisable = nil
+
+	// footer 规则默认关闭，--enable 后才检查
+	rootCmd.SetIn(bytes.NewBufferString("feat(api): drop v1\n\nBREAKING CHANGE: v1 is gone\n"))
+	rootCmd.SetArgs([]string{"lint", "-"})
+	require.NoError(t, rootCmd.Execute())
+	buf.Reset()
+	rootCmd.SetIn(bytes.NewBufferString("feat(api): drop v1\n\nBREAKING CHANGE: v1 is gone\n"))
+	rootCmd.SetArgs([]string{"lint", "-", "--enable", "breaking-change-marker"})
+	require.ErrorAs(t, rootCmd.Execute(), &exitErr)
+	require.Contains(t, buf.String(), "breaking-change-marker")
+	lintCmd.Flags().Lookup("enable").Changed = false
+	flagLintEnable = nil
+
+	// 未指定 ref 时读取管道输入；管道为空则回退到 HEAD
+	originalStdinIsTerminal, originalStdinIsPiped := stdinIsTerminal, stdinIsPiped
+	defer func() { stdinIsTerminal, stdinIsPiped = originalStdinIsTerminal, originalStdinIsPiped }()
+	stdinIsTerminal = func() bool { return false }
+	stdinIsPiped = func() bool { return true }
+	gotRef = ""
+	rootCmd.SetIn(bytes.NewBufferString("feat: piped message\n"))
+	rootCmd.SetArgs([]string{"lint"})
+	require.NoError(t, rootCmd.Execute())
+	require.Empty(t, gotRef)
+
+	rootCmd.SetIn(bytes.NewBufferString(""))
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "HEAD", gotRef)
+}
+
+func TestRoot_QuietSuppressesStatus(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalFlagDryRun, originalFlagYes, originalFlagQuiet := flagDryRun, flagYes, flagQuiet
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		flagDryRun, flagYes, flagQuiet = originalFlagDryRun, originalFlagYes, originalFlagQuiet
+		rootCmd.Flags().Lookup("quiet").Changed = false
+		resetConfigFlags()
+	}()
+
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "feat: quiet"} }
+	comm := &recordCommitter{}
+	committer = comm
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+
+	flagDryRun = false
+	rootCmd.SetArgs([]string{"-y", "-q", "--push=false", "--stage-all=false"})
+	require.NoError(t, rootCmd.Execute())
+	require.True(t, comm.called)
+	require.Empty(t, buf.String())
+
+	// --dry-run 仍输出 message
+	flagYes = false
+	rootCmd.SetArgs([]string{"--dry-run", "-q"})
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "feat: quiet\n", buf.String())
+
+	// 交互式 TUI 不能与 --quiet 同时使用
+	flagDryRun = false
+	rootCmd.SetArgs([]string{"-q"})
+	err := rootCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "add -y")
+}
+
+type headCollector struct {
+	mockCollector
+	head string
+}
+
+func (h headCollector) HeadMessage(_ context.Context) (string, error) { return h.head, nil }
+
+func TestRoot_AmendComparison(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalFlagDryRun, originalFlagYes := flagDryRun, flagYes
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		flagDryRun, flagYes, flagAmend = originalFlagDryRun, originalFlagYes, false
+		rootCmd.Flags().Lookup("amend").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	collectorProvider = func() collectorInterface {
+		return headCollector{mockCollector: mockCollector{diff: "diff"}, head: "fix: update stuff"}
+	}
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "fix(parser): handle empty input"} }
+	comm := &recordCommitter{}
+	committer = comm
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+
+	// --dry-run 输出对比但不提交
+	flagDryRun, flagYes = false, false
+	rootCmd.SetArgs([]string{"--amend", "--dry-run"})
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "Amending HEAD message:\n- fix: update stuff\n+ fix(parser): handle empty input\n", buf.String())
+	require.False(t, comm.called)
+
+	// -y 先输出对比再 amend，且不会推送
+	buf.Reset()
+	flagDryRun = false
+	rootCmd.SetArgs([]string{"--amend", "-y"})
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "- fix: update stuff")
+	require.True(t, comm.called)
+	require.Equal(t, "fix(parser): handle empty input", comm.msg)
+	require.False(t, comm.pushed)
+
+	// collector 无法读取 HEAD message 时报错
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	err := rootCmd.Execute()
+	require.ErrorContains(t, err, "--amend is not supported")
+}
+
+type recordNotifier struct {
+	events []notify.Event
+	err    error
+}
+
+func (r *recordNotifier) Notify(_ context.Context, e notify.Event) error {
+	r.events = append(r.events, e)
+	return r.err
+}
+
+func TestRoot_NotifiesAfterCommit(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalNotifierProvider := notifierProvider
+	originalFlagDryRun, originalFlagYes := flagDryRun, flagYes
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		notifierProvider = originalNotifierProvider
+		flagDryRun, flagYes = originalFlagDryRun, originalFlagYes
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	flagDryRun = false
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "feat: notify\n\nbody"} }
+	committer = &recordCommitter{}
+	// 通知失败不影响提交结果
+	n := &recordNotifier{err: errors.New("webhook returned 500")}
+	notifierProvider = func() notify.Notifier { return n }
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"-y"})
+	require.NoError(t, rootCmd.Execute())
+	require.Len(t, n.events, 1)
+	require.Equal(t, "feat: notify", n.events[0].Subject)
+	require.Equal(t, "test", n.events[0].Branch)
+
+	// --dry-run 不提交，也不通知
+	n.events = nil
+	flagYes = false
+	rootCmd.SetArgs([]string{"--dry-run"})
+	require.NoError(t, rootCmd.Execute())
+	require.Empty(t, n.events)
+}
+
+func TestOfflineMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary *collector.ChangesSummary
+		want    string
+	}{
+		{
+			name: "single file",
+			summary: &collector.ChangesSummary{SuggestedPrefix: "chore", FilesByPriority: []collector.FileStatus{
+				{Path: "config/providers.yaml", IndexStatus: 'M', AffectedArea: "config"},
+			}},
+			want: "chore(config): update providers.yaml",
+		},
+		{
+			name: "root files added",
+			summary: &collector.ChangesSummary{SuggestedPrefix: "feat", FilesByPriority: []collector.FileStatus{
+				{Path: "a.go", IndexStatus: 'A'},
+				{Path: "b.go", IsUntracked: true, IndexStatus: '?'},
+			}},
+			want: "feat: add a.go and b.go",
+		},
+		{
+			name: "many files",
+			summary: &collector.ChangesSummary{SuggestedPrefix: "fix", FilesByPriority: []collector.FileStatus{
+				{Path: "cmd/a.go", IndexStatus: 'M'},
+				{Path: "cmd/b.go", IndexStatus: 'D'},
+				{Path: "ui/c.go", IndexStatus: 'M'},
+				{Path: "cmd/d.go", IndexStatus: 'M'},
+			}},
+			want: "fix(cmd): update 4 files\n\n- cmd/a.go\n- cmd/b.go\n- ui/c.go\n- cmd/d.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, offlineMessage(tt.summary))
+		})
+	}
+}
+
+type offlineCollector struct {
+	mockCollector
+}
+
+func (offlineCollector) AnalyzeChanges(_ context.Context) (*collector.ChangesSummary, error) {
+	return &collector.ChangesSummary{SuggestedPrefix: "docs", FilesByPriority: []collector.FileStatus{
+		{Path: "docs/guide.md", IndexStatus: 'M', AffectedArea: "docs"},
+	}}, nil
+}
+
+type splitCollector struct {
+	mockCollector
+}
+
+func (splitCollector) AnalyzeChanges(_ context.Context) (*collector.ChangesSummary, error) {
+	return &collector.ChangesSummary{HasStagedChanges: true, FilesByPriority: []collector.FileStatus{
+		{Path: "cmd/split.go", IsUntracked: true, IndexStatus: '?', ContentType: "code", AffectedArea: "cmd"},
+		{Path: "cmd/root.go", IndexStatus: 'M', ContentType: "code", AffectedArea: "cmd"},
+		{Path: "cmd/root_test.go", IndexStatus: 'M', ContentType: "test", AffectedArea: "cmd"},
+		{Path: "docs/new name.md", OldPath: "docs/old.md", IsRenamed: true, IndexStatus: 'R', ContentType: "docs", AffectedArea: "docs"},
+		{Path: "go.mod", IndexStatus: 'M', ContentType: "other", AffectedArea: "root"},
+	}}, nil
+}
+
+func TestSplit(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalConfig := runConfig
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		runConfig = originalConfig
+	}()
+
+	collectorProvider = func() collectorInterface { return splitCollector{} }
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"split"})
+	require.NoError(t, rootCmd.Execute())
+
+	out := buf.String()
+	require.Contains(t, out, "Suggested split into 4 commits:")
+	require.Contains(t, out, "already staged; run git reset first")
+	require.Contains(t, out, "1. feat(cmd): update split.go and root.go\n   git add -- cmd/split.go cmd/root.go\n")
+	require.Contains(t, out, "2. test(cmd): update root_test.go\n   git add -- cmd/root_test.go\n")
+	require.Contains(t, out, "3. docs(docs): rename new name.md\n   git add -- docs/old.md 'docs/new name.md'\n")
+	require.Contains(t, out, "4. fix: update go.mod\n   git add -- go.mod\n")
+
+	collectorProvider = func() collectorInterface { return mockCollector{} }
+	buf.Reset()
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "Nothing to commit.")
+}
+
+func TestRoot_OfflineSkipsLLM(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalFlagDryRun, originalFlagYes := flagDryRun, flagYes
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		flagDryRun, flagYes, flagOffline = originalFlagDryRun, originalFlagYes, false
+		rootCmd.Flags().Lookup("offline").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	flagDryRun = false
+	collectorProvider = func() collectorInterface { return offlineCollector{mockCollector{diff: "diff"}} }
+	clientProvider = func() clientInterface {
+		t.Fatal("--offline must not create an LLM client")
+		return nil
+	}
+	comm := &recordCommitter{}
+	committer = comm
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"--offline", "-y", "--push=false"})
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "docs(docs): update guide.md", comm.msg)
+}
+
+// sinceCollector 在 mockCollector 基础上支持 --since
+type sinceCollector struct {
+	mockCollector
+	stubBranchDiffer
+	commits []string
+}
+
+func (c *sinceCollector) ResolveSince(_ context.Context, since string) (string, error) {
+	if since == "1w" {
+		return "", collector.ErrNoDiff
+	}
+	return since, nil
+}
+
+func (c *sinceCollector) CommitsOnBranch(_ context.Context, _ string) ([]string, error) {
+	return c.commits, nil
+}
+
+func TestRoot_Since(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		flagSince = ""
+		rootCmd.Flags().Lookup("since").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	col := &sinceCollector{stubBranchDiffer: stubBranchDiffer{diff: "diff --git a/x.go b/x.go\n+x"}, commits: []string{"feat: add x", "fix: x typo"}}
+	collectorProvider = func() collectorInterface { return col }
+	promptProvider = func(lang string) promptInterface { return prompt.NewBuilder(lang, 0) }
+	cli := &promptCapturingClient{message: "feat: add x\n\n- add x\n- fix a typo in x\n"}
+	clientProvider = func() clientInterface { return cli }
+	comm := &recordCommitter{}
+	committer = comm
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs([]string{"--since", "v1.2.0"})
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "feat: add x\n\n- add x\n- fix a typo in x\n", out.String())
+	require.Equal(t, "v1.2.0", col.baseRef)
+	require.Contains(t, cli.userPrompt, "Summarize every change since v1.2.0")
+	require.Contains(t, cli.userPrompt, "- fix: x typo")
+	require.False(t, comm.called, "--since must not commit")
+
+	out.Reset()
+	rootCmd.SetArgs([]string{"--since", "1w"})
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, out.String(), "No changes since 1w.")
+
+	rootCmd.SetArgs([]string{"--since", "v1.2.0", "--offline"})
+	require.ErrorContains(t, rootCmd.Execute(), "--since and --offline cannot be used together")
+	flagOffline = false
+	rootCmd.Flags().Lookup("offline").Changed = false
+}
+
+func TestIsRetryable(t *testing.T) {
+	ctx := context.Background()
+	require.True(t, isRetryable(ctx, context.DeadlineExceeded))
+	require.True(t, isRetryable(ctx, &client.Error{Type: client.ErrTypeNetwork, Message: "failed to connect"}))
+	require.True(t, isRetryable(ctx, fmt.Errorf("generate: %w", &client.StatusError{StatusCode: 502})))
+	require.True(t, isRetryable(ctx, &client.StatusError{StatusCode: 429}))
+	require.False(t, isRetryable(ctx, &client.StatusError{StatusCode: 401}))
+	require.False(t, isRetryable(ctx, &client.Error{Type: client.ErrTypeConfig, Message: "missing key"}))
+	require.False(t, isRetryable(ctx, collector.ErrNotGitRepository))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.False(t, isRetryable(canceled, context.DeadlineExceeded))
+}
+
+// flakyClient 前 failures 次返回 err，之后返回 message
+type flakyClient struct {
+	failures int
+	err      error
+	message  string
+	calls    int
+}
+
+func (c *flakyClient) GetCommitMessage(_ context.Context, _, _ string) (string, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		return "", c.err
+	}
+	return c.message, nil
+}
+
+func TestRoot_MaxRetries(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalDelay := retryBaseDelay
+	originalFlagDryRun := flagDryRun
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		retryBaseDelay = originalDelay
+		flagDryRun, flagMaxRetries = originalFlagDryRun, 0
+		rootCmd.Flags().Lookup("dry-run").Changed = false
+		rootCmd.Flags().Lookup("max-retries").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	retryBaseDelay = 0
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff --git a/a.go b/a.go"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+
+	t.Run("transient_error_is_retried", func(t *testing.T) {
+		cli := &flakyClient{failures: 2, err: &client.StatusError{StatusCode: 503}, message: "feat: retried"}
+		clientProvider = func() clientInterface { return cli }
+		var out, errOut bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&errOut)
+		rootCmd.SetArgs([]string{"--dry-run", "--max-retries", "2"})
+		require.NoError(t, rootCmd.Execute())
+		require.Equal(t, 3, cli.calls)
+		require.Equal(t, "feat: retried\n", out.String())
+		require.Contains(t, errOut.String(), "Attempt 1 of 3 failed")
+	})
+
+	t.Run("gives_up_after_max_retries", func(t *testing.T) {
+		cli := &flakyClient{failures: 5, err: &client.StatusError{StatusCode: 503}, message: "unused"}
+		clientProvider = func() clientInterface { return cli }
+		rootCmd.SetArgs([]string{"--dry-run", "--max-retries", "1"})
+		require.ErrorContains(t, rootCmd.Execute(), "status 503")
+		require.Equal(t, 2, cli.calls)
+	})
+
+	t.Run("auth_error_is_not_retried", func(t *testing.T) {
+		cli := &flakyClient{failures: 5, err: &client.StatusError{StatusCode: 401}, message: "unused"}
+		clientProvider = func() clientInterface { return cli }
+		rootCmd.SetArgs([]string{"--dry-run", "--max-retries", "3"})
+		require.ErrorContains(t, rootCmd.Execute(), "status 401")
+		require.Equal(t, 1, cli.calls)
+	})
+
+	t.Run("errors_after_commit_are_not_retried", func(t *testing.T) {
+		originalCommitter := committer
+		defer func() {
+			committer = originalCommitter
+			flagYes = false
+			rootCmd.Flags().Lookup("yes").Changed = false
+		}()
+		comm := &failingPushCommitter{err: &client.StatusError{StatusCode: 503}}
+		committer = comm
+		flagDryRun = false
+		cli := &flakyClient{message: "feat: once"}
+		clientProvider = func() clientInterface { return cli }
+		rootCmd.SetArgs([]string{"-y", "--push", "--max-retries", "3"})
+		require.ErrorContains(t, rootCmd.Execute(), "status 503")
+		require.Equal(t, 1, comm.commits)
+		require.Equal(t, 1, cli.calls)
+	})
+
+	t.Run("diff_collection_is_retried", func(t *testing.T) {
+		originalCommitter := committer
+		defer func() {
+			committer = originalCommitter
+			flagYes = false
+			rootCmd.Flags().Lookup("yes").Changed = false
+		}()
+		comm := &recordCommitter{}
+		committer = comm
+		flagDryRun = false
+		col := &flakyDiffCollector{mockCollector: mockCollector{diff: "diff --git a/a.go b/a.go"}, failures: 1}
+		collectorProvider = func() collectorInterface { return col }
+		cli := &flakyClient{message: "feat: fresh"}
+		clientProvider = func() clientInterface { return cli }
+		rootCmd.SetArgs([]string{"-y", "--push=false", "--max-retries", "2"})
+		require.NoError(t, rootCmd.Execute())
+		// 整个尝试重新执行：diff 重新收集后才生成 message 并提交
+		require.Equal(t, 2, col.calls)
+		require.Equal(t, 1, cli.calls)
+		require.Equal(t, "feat: fresh", comm.msg)
+	})
+}
+
+// flakyDiffCollector 前 failures 次收集 diff（含回退的 Diff）时返回网络错误
+type flakyDiffCollector struct {
+	mockCollector
+	failures int
+	calls    int
+}
+
+func (f *flakyDiffCollector) ComprehensiveDiff(ctx context.Context) (string, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return "", &client.Error{Type: client.ErrTypeNetwork, Message: "connection reset"}
+	}
+	return f.mockCollector.ComprehensiveDiff(ctx)
+}
+
+func (f *flakyDiffCollector) Diff(ctx context.Context) (string, error) {
+	if f.calls <= f.failures {
+		return "", &client.Error{Type: client.ErrTypeNetwork, Message: "connection reset"}
+	}
+	return f.mockCollector.Diff(ctx)
+}
+
+// failingPushCommitter 提交成功但推送失败，并记录提交次数
+type failingPushCommitter struct {
+	recordCommitter
+	err     error
+	commits int
+}
+
+func (f *failingPushCommitter) Commit(ctx context.Context, message string) error {
+	f.commits++
+	return nil
+}
+func (f *failingPushCommitter) Push(ctx context.Context) error { return f.err }
+
+type partialCollector struct {
+	mockCollector
+	partial []string
+}
+
+func (p partialCollector) AnalyzeChanges(_ context.Context) (*collector.ChangesSummary, error) {
+	return &collector.ChangesSummary{PartiallyStaged: p.partial}, nil
+}
+
+func TestPartiallyStagedWarning(t *testing.T) {
+	ctx := context.Background()
+	require.Empty(t, partiallyStagedWarning(ctx, partialCollector{}))
+	require.Equal(t, "Warning: only the staged hunks of cmd/root.go, main.go will be committed (partially staged)",
+		partiallyStagedWarning(ctx, partialCollector{partial: []string{"cmd/root.go", "main.go"}}))
+}
+
+func TestAppendSignature(t *testing.T) {
+	originalVersion := version
+	defer func() { version = originalVersion }()
+	version = "1.4.0"
+
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"subject only", "fix: handle nil config", "fix: handle nil config\n\nGenerated-by: catmit v1.4.0"},
+		{"body", "feat: add cache\n\nCache lookups for an hour.", "feat: add cache\n\nCache lookups for an hour.\n\nGenerated-by: catmit v1.4.0"},
+		{"after trailers", "fix: retry uploads\n\nCo-authored-by: A <a@example.com>\nCloses #12", "fix: retry uploads\n\nCo-authored-by: A <a@example.com>\nCloses #12\nGenerated-by: catmit v1.4.0"},
+		{"idempotent", "fix: retry uploads\n\nCloses #12\nGenerated-by: catmit v1.3.0\n", "fix: retry uploads\n\nCloses #12\nGenerated-by: catmit v1.4.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, appendSignature(tt.message))
+			require.Equal(t, tt.want, appendSignature(appendSignature(tt.message)))
+		})
+	}
+}
+
+func TestSignatureEnabled(t *testing.T) {
+	originalConfig, originalFlag, originalCommitter := runConfig, flagSignature, committer
+	defer func() { runConfig, flagSignature, committer = originalConfig, originalFlag, originalCommitter }()
+
+	runConfig, flagSignature = &config.Config{}, false
+	require.False(t, signatureEnabled())
+	flagSignature = true
+	require.True(t, signatureEnabled())
+	committer = &shaCommitter{sha: "0123456789abcdef"}
+	active := activeCommitter()
+	_, ok := active.(signingCommitter)
+	require.True(t, ok)
+	// 签名包装不影响提交后显示 SHA
+	sha, err := active.LastCommitSHA(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "0123456789abcdef", sha)
+	// 配置禁用时忽略 --signature
+	runConfig = &config.Config{DisableSignature: true}
+	require.False(t, signatureEnabled())
+}
+
+func TestRoot_SignatureDryRun(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		flagDryRun, flagSignature = false, false
+		rootCmd.Flags().Lookup("dry-run").Changed = false
+		rootCmd.Flags().Lookup("signature").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "fix: signed"} }
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"--dry-run", "--signature"})
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "fix: signed\n\nGenerated-by: catmit ")
+}
+
+func TestRoot_MaxPromptTokens(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		flagDryRun, flagMaxPromptTokens, flagTruncate = false, 0, false
+		for _, name := range []string{"dry-run", "max-prompt-tokens", "truncate"} {
+			rootCmd.Flags().Lookup(name).Changed = false
+		}
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	diff := strings.Repeat("+a very large generated change\n", 2000)
+	collectorProvider = func() collectorInterface { return mockCollector{diff: diff} }
+	promptProvider = defaultPromptProvider
+	clientProvider = func() clientInterface { return mockClient{message: "chore: big change"} }
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"--dry-run", "--max-prompt-tokens", "2000"})
+	err := rootCmd.Execute()
+	require.True(t, client.IsType(err, client.ErrTypeValidation), "got %v", err)
+	require.ErrorContains(t, err, "--max-prompt-tokens is 2000")
+	require.ErrorContains(t, err, "stage fewer changes")
+
+	// --truncate 退回按预算截断 diff
+	buf.Reset()
+	rootCmd.SetArgs([]string{"--dry-run", "--max-prompt-tokens", "2000", "--truncate"})
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "chore: big change")
+
+	flagMaxPromptTokens = 0
+	rootCmd.Flags().Lookup("max-prompt-tokens").Changed = false
+	rootCmd.SetArgs([]string{"--dry-run", "--truncate"})
+	require.ErrorContains(t, rootCmd.Execute(), "--truncate requires --max-prompt-tokens")
+}
+
+func TestParseSeedIntent(t *testing.T) {
+	tests := []struct {
+		seed   string
+		intent seedIntent
+		rest   string
+	}{
+		{"wip", seedIntentWIP, ""},
+		{" WIP ", seedIntentWIP, ""},
+		{"wip: login form", seedIntentWIP, "login form"},
+		{"fixup", seedIntentFixup, ""},
+		{"Fixup! parser", seedIntentFixup, "parser"},
+		{"fixup: parser", seedIntentFixup, "parser"},
+		{"wipe cache", seedIntentNone, "wipe cache"},
+		{"fixup the parser", seedIntentNone, "fixup the parser"},
+		{"", seedIntentNone, ""},
+	}
+	for _, tt := range tests {
+		intent, rest := parseSeedIntent(tt.seed)
+		require.Equal(t, tt.intent, intent, tt.seed)
+		require.Equal(t, tt.rest, rest, tt.seed)
+	}
+}
+
+func TestFixupTarget(t *testing.T) {
+	commits := []string{"fixup! feat: add parser", "docs: update README", "feat: add parser", "fix: parser crash"}
+
+	target, err := fixupTarget(commits, "")
+	require.NoError(t, err)
+	require.Equal(t, "docs: update README", target)
+
+	target, err = fixupTarget(commits, "Parser")
+	require.NoError(t, err)
+	require.Equal(t, "feat: add parser", target)
+
+	_, err = fixupTarget(commits, "login")
+	require.ErrorContains(t, err, `no recent commit matches "login"`)
+	_, err = fixupTarget(nil, "")
+	require.Error(t, err)
+}
+
+func TestWipMessage(t *testing.T) {
+	require.Equal(t, "WIP: feat: add parser", wipMessage("feat: add parser\n"))
+	require.Equal(t, "wip: already marked", wipMessage("wip: already marked"))
+}
+
+func TestRoot_SeedIntent(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		flagDryRun = false
+		rootCmd.Flags().Lookup("dry-run").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	collectorProvider = func() collectorInterface {
+		return mockCollector{diff: "diff", commits: []string{"docs: update README", "feat: add parser"}}
+	}
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "feat: add login form"} }
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"--dry-run", "wip: login form"})
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "WIP: feat: add login form\n", buf.String())
+
+	// fixup 不调用 LLM，指向匹配的最近提交
+	clientProvider = func() clientInterface {
+		t.Fatal("fixup must not create an LLM client")
+		return nil
+	}
+	buf.Reset()
+	rootCmd.SetArgs([]string{"--dry-run", "fixup! parser"})
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "fixup! feat: add parser\n", buf.String())
+
+	// 普通 seed 不受影响
+	clientProvider = func() clientInterface { return mockClient{message: "fix: wipe cache"} }
+	buf.Reset()
+	rootCmd.SetArgs([]string{"--dry-run", "wipe cache"})
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "fix: wipe cache\n", buf.String())
+}
+
+func TestRoot_RunnerProvider(t *testing.T) {
+	originalRunnerProvider := runnerProvider
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalLocator := repoLocator
+	defer func() {
+		runnerProvider = originalRunnerProvider
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		repoLocator = originalLocator
+		flagDryRun = false
+		rootCmd.Flags().Lookup("dry-run").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	// 真实的 Collector 与仓库检查都通过注入的 Runner 执行，不启动 git
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	repoLocator = nil
+	runner := collectortest.NewRecordingRunner().
+		On("git rev-parse --show-toplevel", wd+"\n", nil).
+		On("git diff --no-ext-diff --no-color --cached", "diff --git a/parser.go b/parser.go\n+func Parse() {}", nil).
+		On("git diff --no-ext-diff --no-color", "", nil).
+		On("git ls-files --others --exclude-standard", "", nil).
+		On("git log", "feat: add lexer", nil).
+		On("git", "", nil)
+	runnerProvider = func() collector.Runner { return runner }
+	collectorProvider = defaultCollectorProvider
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "feat: add parser"} }
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"--dry-run"})
+	require.NoError(t, rootCmd.Execute())
+	require.Equal(t, "feat: add parser\n", buf.String())
+	require.True(t, runner.Ran("git rev-parse --show-toplevel"))
+	require.True(t, runner.Ran("git diff --no-ext-diff --no-color --cached"))
+	require.True(t, runner.Ran("git log"))
+}
+
+func TestInteractiveLogger(t *testing.T) {
+	// 替换 os.Stdout/os.Stderr，确认 TUI 模式的日志不会写入终端
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	log, logs, err := interactiveLogger(nil, true)
+	if err == nil {
+		log.Debug("collecting diff")
+		log.Warn("slow git status")
+	}
+	os.Stdout, os.Stderr = stdout, stderr
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	terminal, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Empty(t, string(terminal))
+
+	var flushed bytes.Buffer
+	logs.flushTo(&flushed)
+	require.Contains(t, flushed.String(), "collecting diff")
+	require.Contains(t, flushed.String(), "slow git status")
+	require.NotContains(t, flushed.String(), "\x1b[", "buffered logs must not contain colors")
+
+	flushed.Reset()
+	logs.flushTo(&flushed)
+	require.Empty(t, flushed.String())
+
+	// 设置 CATMIT_LOG_FILE 时沿用已写入该文件的 logger，不重复打开文件
+	t.Setenv(logger.EnvLogFile, filepath.Join(t.TempDir(), "catmit.log"))
+	current, err := logger.New(true)
+	require.NoError(t, err)
+	log, _, err = interactiveLogger(current, true)
+	require.NoError(t, err)
+	require.Same(t, current, log)
+}
+
+func TestResolveTestCommand(t *testing.T) {
+	originalConfig := runConfig
+	defer func() { runConfig = originalConfig }()
+
+	goDir, nodeDir, emptyDir := t.TempDir(), t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(goDir, "go.mod"), []byte("module x\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(nodeDir, "package.json"), []byte("{}\n"), 0o644))
+
+	runConfig = &config.Config{}
+	command, err := resolveTestCommand(goDir)
+	require.NoError(t, err)
+	require.Equal(t, "go test ./...", command)
+	command, err = resolveTestCommand(nodeDir)
+	require.NoError(t, err)
+	require.Equal(t, "npm test", command)
+	_, err = resolveTestCommand(emptyDir)
+	require.ErrorContains(t, err, "test_command")
+
+	// test_commands 覆盖项目类型的默认命令，test_command 优先于一切
+	runConfig = &config.Config{TestCommands: map[string]string{"node": "pnpm test"}}
+	command, err = resolveTestCommand(nodeDir)
+	require.NoError(t, err)
+	require.Equal(t, "pnpm test", command)
+	runConfig = &config.Config{TestCommand: "make check"}
+	command, err = resolveTestCommand(emptyDir)
+	require.NoError(t, err)
+	require.Equal(t, "make check", command)
+}
+
+func TestRoot_RequireTests(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	originalTestRunner := testRunner
+	originalFlagDryRun, originalFlagYes := flagDryRun, flagYes
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		testRunner = originalTestRunner
+		flagDryRun, flagYes, flagRequireTests = originalFlagDryRun, originalFlagYes, false
+		rootCmd.Flags().Lookup("require-tests").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	flagDryRun = false
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "fix: gated"} }
+	var ran string
+	testRunner = func(_ context.Context, _, command string) ([]byte, error) {
+		ran = command
+		return []byte("--- FAIL: TestSomething\nFAIL\n"), errors.New("exit status 1")
+	}
+	comm := &shaCommitter{sha: "0123456789abcdef"}
+	committer = comm
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"-y", "--push=false", "--require-tests"})
+	err := rootCmd.Execute()
+	require.ErrorContains(t, err, "--- FAIL: TestSomething")
+	require.Equal(t, "go test ./...", ran)
+	require.False(t, comm.called)
+
+	// 测试通过后正常提交，包装后的 committer 仍报告 SHA
+	testRunner = func(_ context.Context, _, _ string) ([]byte, error) { return []byte("ok"), nil }
+	require.NoError(t, rootCmd.Execute())
+	require.True(t, comm.called)
+	require.Equal(t, "fix: gated", comm.msg)
+	require.Contains(t, buf.String(), "Committed 0123456")
+}
+
+type doctorClient struct {
+	mockClient
+	validateErr error
+}
+
+func (d doctorClient) Validate() error { return d.validateErr }
+
+func (doctorClient) Info() client.Info {
+	return client.Info{Provider: "openai-compatible", Model: "test-model", Endpoint: "https://llm.example.com/v1/chat/completions"}
+}
+
+func TestDoctor(t *testing.T) {
+	originalClientProvider := clientProvider
+	originalLookPath, originalRun := doctorLookPath, doctorRun
+	defer func() {
+		clientProvider = originalClientProvider
+		doctorLookPath, doctorRun = originalLookPath, originalRun
+	}()
+
+	doctorLookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	doctorRun = func(_ context.Context, _ string, _ ...string) ([]byte, error) {
+		return nil, errors.New("not logged in")
+	}
+	clientProvider = func() clientInterface { return doctorClient{mockClient: mockClient{message: "OK"}} }
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"doctor"})
+	// gh 未登录不是致命错误
+	require.NoError(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "✓ git installed: /usr/bin/git")
+	require.Contains(t, buf.String(), "✓ LLM configuration valid: test-model via https://llm.example.com/v1/chat/completions")
+	require.Contains(t, buf.String(), "✓ LLM endpoint accepts requests: https://llm.example.com/v1/chat/completions")
+	require.Contains(t, buf.String(), "✗ GitHub CLI logged in\n    → run 'gh auth login'")
+
+	// 401 在真正运行前被诊断为 Key 被拒绝，并以非零退出码退出
+	buf.Reset()
+	clientProvider = func() clientInterface {
+		return doctorClient{mockClient: mockClient{err: fmt.Errorf("wrapped: %w", &client.StatusError{StatusCode: 401})}}
+	}
+	err := rootCmd.Execute()
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 1, exitErr.Code)
+	require.Contains(t, buf.String(), "✗ LLM endpoint accepts requests: status 401: the API key was rejected")
+
+	// LLM 配置无效时跳过请求
+	buf.Reset()
+	clientProvider = func() clientInterface { return doctorClient{validateErr: errors.New("CATMIT_LLM_API_URL is invalid")} }
+	require.Error(t, rootCmd.Execute())
+	require.Contains(t, buf.String(), "✗ LLM configuration valid: CATMIT_LLM_API_URL is invalid")
+	require.Contains(t, buf.String(), "✗ LLM endpoint accepts requests: skipped: invalid LLM configuration")
+}
+
+func TestDiagnoseLLMError(t *testing.T) {
+	refused := &client.Error{Type: client.ErrTypeNetwork, Message: "failed to connect to LLM API",
+		Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"timeout", context.DeadlineExceeded, "timed out after 15s"},
+		{"refused", refused, "connection refused"},
+		{"forbidden", &client.StatusError{StatusCode: 403}, "status 403: the API key was rejected"},
+		{"not found", &client.StatusError{StatusCode: 404}, "status 404: no API at this URL"},
+		{"other", &client.StatusError{StatusCode: 500}, "API error: status 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, suggestion := diagnoseLLMError(tt.err)
+			require.Equal(t, tt.want, detail)
+			require.NotEmpty(t, suggestion)
+		})
+	}
+}
+
+// prCommitter 记录 PR 创建，并返回固定的 PR URL
+type prCommitter struct {
+	recordCommitter
+	prCreated bool
+}
+
+func (p *prCommitter) CreatePullRequest(_ context.Context) (string, error) {
+	p.prCreated = true
+	return "https://github.com/owner/repo/pull/7", nil
+}
+
+func TestRoot_DryRunCreatePR(t *testing.T) {
+	originalCollectorProvider := collectorProvider
+	originalPromptProvider := promptProvider
+	originalClientProvider := clientProvider
+	originalCommitter := committer
+	defer func() {
+		collectorProvider = originalCollectorProvider
+		promptProvider = originalPromptProvider
+		clientProvider = originalClientProvider
+		committer = originalCommitter
+		flagDryRun, flagCreatePR = false, false
+		rootCmd.Flags().Lookup("dry-run").Changed = false
+		rootCmd.Flags().Lookup("create-pr").Changed = false
+		resetConfigFlags()
+	}()
+
+	resetConfigFlags()
+	collectorProvider = func() collectorInterface { return mockCollector{diff: "diff"} }
+	promptProvider = func(lang string) promptInterface { return mockPrompt{} }
+	clientProvider = func() clientInterface { return mockClient{message: "feat: draft"} }
+	comm := &prCommitter{}
+	committer = comm
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	rootCmd.SetArgs([]string{"--dry-run", "--create-pr"})
+	require.NoError(t, rootCmd.Execute())
+	require.False(t, comm.called, "--dry-run must not commit")
+	require.True(t, comm.prCreated)
+	require.Contains(t, buf.String(), "feat: draft")
+	require.Contains(t, buf.String(), "PR URL: https://github.com/owner/repo/pull/7")
+}
+
+func TestStageSelection(t *testing.T) {
+	originalRunnerProvider := runnerProvider
+	defer func() { runnerProvider = originalRunnerProvider }()
+
+	runner := collectortest.NewRecordingRunner().On("git", "", nil)
+	runnerProvider = func() collector.Runner { return runner }
+	require.NoError(t, stageSelection(context.Background(), []string{"b.go", "new.txt"}, []string{"a.go"}))
+	require.Equal(t, []string{"git reset -q -- a.go", "git add -- b.go new.txt"}, runner.Commands())
+
+	runner = collectortest.NewRecordingRunner().On("git add", "fatal: index.lock exists", errors.New("exit status 128"))
+	err := stageSelection(context.Background(), []string{"b.go"}, nil)
+	require.ErrorContains(t, err, "git add failed: exit status 128\nfatal: index.lock exists")
+}
+
+func TestInteractiveStage_Validation(t *testing.T) {
+	defer func() {
+		flagDryRun, flagAmend, flagInteractiveStage = false, false, false
+		for _, name := range []string{"dry-run", "amend", "interactive-stage"} {
+			rootCmd.Flags().Lookup(name).Changed = false
+		}
+		resetConfigFlags()
+	}()
+	resetConfigFlags()
+
+	rootCmd.SetArgs([]string{"--dry-run", "--interactive-stage"})
+	require.ErrorContains(t, rootCmd.Execute(), "--interactive-stage requires the interactive TUI")
+
+	rootCmd.SetArgs([]string{"--dry-run", "--interactive-stage", "--amend"})
+	require.ErrorContains(t, rootCmd.Execute(), "--interactive-stage and --amend cannot be used together")
+}
+
+func TestApplyOnly(t *testing.T) {
+	originalRunnerProvider := runnerProvider
+	defer func() {
+		runnerProvider = originalRunnerProvider
+		onlyPaths = nil
+	}()
+
+	newRunner := func() *collectortest.RecordingRunner {
+		return collectortest.NewRecordingRunner().
+			On("git rev-parse --show-prefix", "sub/\n", nil).
+			On("git diff --no-ext-diff --no-color --cached --name-only", "a.go\nsub/b.go\n", nil).
+			On("git diff --no-ext-diff --no-color --name-only", "sub/c.go\n", nil).
+			On("git ls-files --others", "sub/new/d.txt\n", nil).
+			On("git add", "", nil)
+	}
+	runner := newRunner()
+	runnerProvider = func() collector.Runner { return runner }
+
+	err := applyOnly(context.Background(), []string{"c.go", "missing.go"})
+	require.ErrorContains(t, err, "--only: no changes in missing.go; changed files:\n  a.go\n  sub/b.go\n  sub/c.go\n  sub/new/d.txt")
+
+	// 路径相对当前目录；目录匹配其下的变更。校验时不改动暂存区
+	require.NoError(t, applyOnly(context.Background(), []string{"c.go", "new", "b.go"}))
+	require.Equal(t, []string{"sub/c.go", "sub/new", "sub/b.go"}, onlyPaths)
+	require.False(t, runner.Ran("git reset"))
+	require.False(t, runner.Ran("git add"))
+
+	// 提交时以 git commit --only 只提交这些路径，未跟踪文件先记为 intent-to-add
+	require.Equal(t, []string{"commit", "-m", "fix: a", "--only", "--", ":(top,literal)sub/c.go", ":(top,literal)sub/new", ":(top,literal)sub/b.go"}, commitArgs("fix: a"))
+	require.NoError(t, intentToAddOnly(context.Background()))
+	require.True(t, runner.Ran("git add --intent-to-add -- :(top,literal)sub/c.go :(top,literal)sub/new :(top,literal)sub/b.go"))
+
+	require.ErrorContains(t, applyOnly(context.Background(), []string{"../../x"}), "outside the repository")
+}
+
+func TestOnly_Validation(t *testing.T) {
+	defer func() {
+		flagDryRun, flagAmend, flagOnly = false, false, nil
+		for _, name := range []string{"dry-run", "amend", "only"} {
+			rootCmd.Flags().Lookup(name).Changed = false
+		}
+		resetConfigFlags()
+	}()
+	resetConfigFlags()
+
+	rootCmd.SetArgs([]string{"--dry-run", "--only", "a.go", "--amend"})
+	require.ErrorContains(t, rootCmd.Execute(), "--only and --amend cannot be used together")
+}
+
+func TestBreakingChangeClient(t *testing.T) {
+	cli := breakingChangeClient{inner: mockClient{message: "feat(api)!: drop v1 endpoints"}}
+	message, err := cli.GetCommitMessage(context.Background(), "", "")
+	require.NoError(t, err)
+	require.Equal(t, "feat(api)!: drop v1 endpoints\n\nBREAKING CHANGE: drop v1 endpoints", message)
+
+	cli = breakingChangeClient{inner: mockClient{err: errors.New("timeout")}}
+	_, err = cli.GetCommitMessage(context.Background(), "", "")
+	require.EqualError(t, err, "timeout")
+}
+
+// statPRBodySource 在 stubPRBodySource 基础上提供相对 base 的变更统计
+type statPRBodySource struct {
+	stubPRBodySource
+	stat string
+	err  error
+}
+
+func (s *statPRBodySource) DiffStatAgainstRef(_ context.Context, _ string) (string, error) {
+	return s.stat, s.err
+}
+
+func TestPRTemplateData(t *testing.T) {
+	commits := []string{"feat: add x"}
+	data := prTemplateData(context.Background(), &statPRBodySource{stat: "2 files changed, +10/-3"}, "origin/main", commits)
+	require.Equal(t, prompt.PRTemplateData{Commits: commits, DiffStat: "2 files changed, +10/-3"}, data)
+
+	data = prTemplateData(context.Background(), &statPRBodySource{err: collector.ErrNoDiff}, "origin/main", commits)
+	require.Empty(t, data.DiffStat)
+	data = prTemplateData(context.Background(), &stubPRBodySource{}, "origin/main", commits)
+	require.Empty(t, data.DiffStat)
+}
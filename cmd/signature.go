@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// signatureToken 是 --signature 添加的 trailer 名称
+const signatureToken = "Generated-by"
+
+// trailerLinePattern 匹配 trailer 行，如 "Co-authored-by: ..."、"Closes #123"
+var trailerLinePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*(: | #)`)
+
+// signatureEnabled 报告本次运行是否添加签名 trailer：需显式指定 --signature，
+// 且配置文件未设置 disable_signature（团队禁止此类 trailer 时优先于命令行）
+func signatureEnabled() bool {
+	if !flagSignature {
+		return false
+	}
+	if runConfig != nil && runConfig.DisableSignature {
+		verbosef("Skipping --signature: disabled by disable_signature in the config")
+		return false
+	}
+	return true
+}
+
+// signatureTrailer 返回 "Generated-by: catmit vX.Y" 形式的 trailer
+func signatureTrailer() string {
+	v := strings.TrimPrefix(GetVersionString(), "catmit version ")
+	if v != "" && v[0] >= '0' && v[0] <= '9' {
+		v = "v" + v
+	}
+	return signatureToken + ": catmit " + v
+}
+
+// appendSignature 在 message 末尾追加签名 trailer，位于已有的 Co-authored-by、
+// Closes #123 等 trailer 之后。已有的签名 trailer 会先被移除，
+// 因此对同一 message 重复调用（如 --amend）只保留一行。
+func appendSignature(message string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(message, "\n"), "\n") {
+		if !strings.HasPrefix(line, signatureToken+": catmit") {
+			lines = append(lines, line)
+		}
+	}
+	body := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if body == "" {
+		return signatureTrailer()
+	}
+
+	// 最后一段全部是 trailer 时并入该段，否则另起一段
+	paragraphs := strings.Split(body, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	separator := "\n\n"
+	if len(paragraphs) > 1 && isTrailerBlock(last) {
+		separator = "\n"
+	}
+	return body + separator + signatureTrailer()
+}
+
+// isTrailerBlock 报告段落的每一行是否都是 trailer
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerLinePattern.MatchString(strings.TrimSpace(line)) {
+			return false
+		}
+	}
+	return true
+}
+
+// signingCommitter 在提交前为 message 追加签名 trailer，供交互模式在用户确认后使用
+type signingCommitter struct {
+	commitInterface
+}
+
+func (s signingCommitter) Commit(ctx context.Context, message string) error {
+	return s.commitInterface.Commit(ctx, appendSignature(message))
+}
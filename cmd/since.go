@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/penwyp/catmit/collector"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// sincePromptBuilder 是可选能力：支持汇总一段时间内变更的 prompt builder
+type sincePromptBuilder interface {
+	BuildSummaryUserPrompt(since string, commits []string, diff string, files []string) string
+}
+
+// sinceSource 提供 --since 所需的比较基准解析、分支 diff 与提交列表
+type sinceSource interface {
+	branchDiffer
+	ResolveSince(ctx context.Context, since string) (string, error)
+	CommitsOnBranch(ctx context.Context, baseRef string) ([]string, error)
+}
+
+// runSince 输出汇总 --since 以来全部已提交变更的 message（--since），不执行提交
+func runSince(ctx context.Context, cmd *cobra.Command, col collectorInterface) error {
+	src, ok := col.(sinceSource)
+	if !ok {
+		return fmt.Errorf("--since is not supported by this collector")
+	}
+	message, err := summarizeSince(ctx, src, flagSince)
+	if errors.Is(err, collector.ErrNoDiff) {
+		_, _ = fmt.Fprintf(statusWriter(cmd), "No changes since %s.\n", flagSince)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	recordMessage(currentRepo(ctx), message, false)
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), message)
+	return nil
+}
+
+// summarizeSince 让 LLM 将 since（ref 或 2d 之类的时长）以来的变更与提交汇总为一条 commit message
+func summarizeSince(ctx context.Context, col sinceSource, since string) (string, error) {
+	base, err := col.ResolveSince(ctx, since)
+	if err != nil {
+		return "", err
+	}
+	diff, err := col.DiffAgainstRef(ctx, base)
+	if err != nil {
+		if errors.Is(err, collector.ErrNoDiff) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to diff against %s: %w", since, err)
+	}
+
+	var files []string
+	if changes, err := col.AnalyzeChangesAgainstRef(ctx, base); err == nil {
+		for _, file := range changes.FilesByPriority {
+			files = append(files, file.Path)
+		}
+	}
+	commits, err := col.CommitsOnBranch(ctx, base)
+	if err != nil && appLogger != nil {
+		appLogger.Debug("Failed to list commits", zap.Error(err))
+	}
+	verbosef("Summarizing %d commits and %d files changed since %s", len(commits), len(files), since)
+
+	builder := promptProvider(currentLang())
+	summaryBuilder, ok := builder.(sincePromptBuilder)
+	if !ok {
+		return "", fmt.Errorf("--since is not supported by this prompt builder")
+	}
+	cli, err := newClient()
+	if err != nil {
+		return "", err
+	}
+	apiCtx, apiCancel := context.WithTimeout(ctx, time.Duration(flagTimeout)*time.Second)
+	defer apiCancel()
+	message, err := cli.GetCommitMessage(apiCtx, builder.BuildSystemPrompt(), summaryBuilder.BuildSummaryUserPrompt(since, commits, diff, files))
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize changes since %s: %w", since, err)
+	}
+	return strings.TrimSpace(message), nil
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/penwyp/catmit/collector"
+	"github.com/spf13/cobra"
+)
+
+var splitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Suggest how to split the uncommitted changes into several commits",
+	Long: `Suggest how to split the uncommitted changes into several logical commits.
+
+Changed files are grouped by the top-level directory they live in and by
+content type, so docs, tests and configuration get their own commits. For
+each group catmit prints a rule-based message and the git add command that
+stages it. Nothing is staged or committed; the LLM is not called.`,
+	Args: cobra.NoArgs,
+	RunE: runSplit,
+}
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+}
+
+// splitGroup 是建议拆分出的一个提交
+type splitGroup struct {
+	area  string // 顶层目录，仓库根目录为 root
+	kind  string // docs、test、config，其余为空
+	files []collector.FileStatus
+}
+
+func runSplit(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	cfg, err := loadConfigFiles(ctx)
+	if err != nil {
+		return err
+	}
+	// collectorProvider 依据 runConfig 应用 exclude 与 prefix_map
+	runConfig = cfg
+
+	summary, err := collectorProvider().AnalyzeChanges(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to analyze changes: %w", err)
+	}
+	if len(summary.FilesByPriority) == 0 {
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Nothing to commit.")
+		return nil
+	}
+
+	groups := groupForSplit(summary.FilesByPriority)
+	prefixes := collector.ResolvePrefixMap(cfg.PrefixMap)
+	out := cmd.OutOrStdout()
+	if len(groups) == 1 {
+		_, _ = fmt.Fprintln(out, "The changes already form one logical commit:")
+	} else {
+		_, _ = fmt.Fprintf(out, "Suggested split into %d commits:\n", len(groups))
+	}
+	if summary.HasStagedChanges {
+		_, _ = fmt.Fprintln(out, "(some changes are already staged; run git reset first to start from an empty index)")
+	}
+	for i, g := range groups {
+		message := offlineMessage(&collector.ChangesSummary{
+			SuggestedPrefix: splitPrefix(g, prefixes),
+			FilesByPriority: g.files,
+		})
+		subject, _, _ := strings.Cut(message, "\n")
+		_, _ = fmt.Fprintf(out, "\n%d. %s\n   %s\n", i+1, subject, quoteCommand("git", splitAddArgs(g.files)...))
+	}
+	return nil
+}
+
+// groupForSplit 按顶层目录与内容类型分组，组的顺序沿用文件优先级
+func groupForSplit(files []collector.FileStatus) []*splitGroup {
+	var groups []*splitGroup
+	index := make(map[string]*splitGroup)
+	for _, f := range files {
+		area := f.AffectedArea
+		if area == "" {
+			area = "root"
+		}
+		kind := splitKind(f.ContentType)
+		key := area + "\x00" + kind
+		g, ok := index[key]
+		if !ok {
+			g = &splitGroup{area: area, kind: kind}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.files = append(g.files, f)
+	}
+	return groups
+}
+
+// splitKind 将文档、测试与配置从代码中分离出来，其余内容类型归为一组
+func splitKind(contentType string) string {
+	switch contentType {
+	case "docs", "test", "config":
+		return contentType
+	}
+	return ""
+}
+
+// splitPrefix 选择一组的提交类型：文档与测试使用 docs、test，配置使用 chore，
+// 其余按 prefix_map 取新增、删除、重命名或修改对应的类型
+func splitPrefix(g *splitGroup, prefixes map[string]string) string {
+	switch g.kind {
+	case "docs", "test":
+		return g.kind
+	case "config":
+		return "chore"
+	}
+	counts := make(map[string]int)
+	for _, f := range g.files {
+		switch offlineVerb(f) {
+		case "add":
+			counts["added"]++
+		case "remove":
+			counts["deleted"]++
+		case "rename":
+			counts["renamed"]++
+		default:
+			counts["modified"]++
+		}
+	}
+	for _, kind := range []string{"added", "deleted", "renamed", "modified"} {
+		if counts[kind] > 0 {
+			return prefixes[kind]
+		}
+	}
+	return prefixes["default"]
+}
+
+// splitAddArgs 返回暂存一组文件的 git add 参数；重命名同时暂存旧路径以记录删除
+func splitAddArgs(files []collector.FileStatus) []string {
+	args := []string{"add", "--"}
+	for _, f := range files {
+		if f.IsRenamed && f.OldPath != "" {
+			args = append(args, f.OldPath)
+		}
+		args = append(args, f.Path)
+	}
+	return args
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// projectMarkers 按优先级列出识别项目类型的标志文件（相对仓库根目录）
+var projectMarkers = []struct {
+	File string
+	Kind string
+}{
+	{"go.mod", "go"},
+	{"package.json", "node"},
+}
+
+// defaultTestCommands 是各项目类型的默认测试命令，可由配置文件的 test_commands 覆盖
+var defaultTestCommands = map[string]string{
+	"go":   "go test ./...",
+	"node": "npm test",
+}
+
+// testRunner 在 dir 中通过 shell 执行测试命令并返回合并的输出；测试时可替换
+var testRunner = runTestCommand
+
+func runTestCommand(ctx context.Context, dir, command string) ([]byte, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	c := exec.CommandContext(ctx, shell, flag, command)
+	c.Dir = dir
+	return c.CombinedOutput()
+}
+
+// resolveTestCommand 返回 --require-tests 要执行的命令：配置的 test_command 优先，
+// 否则按 projectMarkers 识别项目类型，再查 test_commands 与 defaultTestCommands
+func resolveTestCommand(root string) (string, error) {
+	if runConfig != nil && runConfig.TestCommand != "" {
+		return runConfig.TestCommand, nil
+	}
+	for _, marker := range projectMarkers {
+		if _, err := os.Stat(filepath.Join(root, marker.File)); err != nil {
+			continue
+		}
+		if runConfig != nil {
+			if command := runConfig.TestCommands[marker.Kind]; command != "" {
+				return command, nil
+			}
+		}
+		return defaultTestCommands[marker.Kind], nil
+	}
+	return "", fmt.Errorf("--require-tests: no go.mod or package.json found; set test_command in .catmit.yaml")
+}
+
+// activeCommitter 返回本次运行使用的 committer：启用 --signature 时追加签名 trailer，
+// 启用 --require-tests 时先运行测试
+func activeCommitter() commitInterface {
+	c := committer
+	if signatureEnabled() {
+		c = signingCommitter{c}
+	}
+	if flagRequireTests {
+		c = testGateCommitter{c}
+	}
+	return c
+}
+
+// testGateCommitter 在提交前运行测试（--require-tests），测试失败时不提交并返回测试输出
+type testGateCommitter struct {
+	commitInterface
+}
+
+func (t testGateCommitter) Commit(ctx context.Context, message string) error {
+	root, err := locateRepoRoot(ctx)
+	if err != nil {
+		return err
+	}
+	command, err := resolveTestCommand(root)
+	if err != nil {
+		return err
+	}
+	verbosef("Running tests: %s", command)
+	if out, err := testRunner(ctx, root, command); err != nil {
+		return fmt.Errorf("tests failed (%s), not committing: %w\n%s", command, err, strings.TrimSpace(string(out)))
+	}
+	return t.commitInterface.Commit(ctx, message)
+}
@@ -35,15 +35,21 @@
 package collector
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 // Runner 抽象出命令执行器，方便在单元测试中注入 Mock。
@@ -73,6 +79,19 @@ type FileStatus struct {
 	AffectedArea    string // Primary affected area (frontend, backend, etc.)
 	IsUntracked     bool   // Whether this is an untracked file
 	FileSize        int64  // File size in bytes (for untracked files)
+	IsGenerated     bool   // Whether the file starts with a code generation banner
+}
+
+// ModeChange 表示文件权限位的变化，例如 chmod +x 产生的 100644 => 100755
+type ModeChange struct {
+	Path    string // 文件路径
+	OldMode string // 变更前的 mode，如 100644
+	NewMode string // 变更后的 mode，如 100755
+}
+
+// MadeExecutable 报告文件是否被加上了可执行位
+func (m ModeChange) MadeExecutable() bool {
+	return m.OldMode == "100644" && m.NewMode == "100755"
 }
 
 // FileStatusSummary 文件状态摘要，包含分支信息和文件状态列表
@@ -111,7 +130,11 @@ type PerformanceCache struct {
 	ttl   time.Duration          // Time-to-live for cache entries
 }
 
-// NewPerformanceCache creates a new performance cache with specified TTL
+// DefaultCacheTTL is the cache TTL used by New
+const DefaultCacheTTL = 30 * time.Second
+
+// NewPerformanceCache creates a new performance cache with specified TTL.
+// A TTL of zero or less disables caching
 func NewPerformanceCache(ttl time.Duration) *PerformanceCache {
 	return &PerformanceCache{
 		cache: make(map[string]*CacheEntry),
@@ -125,7 +148,7 @@ func (pc *PerformanceCache) Get(key string) ([]byte, error, bool) {
 	defer pc.mutex.RUnlock()
 	
 	entry, exists := pc.cache[key]
-	if !exists {
+	if !exists || pc.ttl <= 0 {
 		return nil, nil, false
 	}
 	
@@ -139,6 +162,9 @@ func (pc *PerformanceCache) Get(key string) ([]byte, error, bool) {
 
 // Set stores a result in the cache
 func (pc *PerformanceCache) Set(key string, result []byte, err error) {
+	if pc.ttl <= 0 {
+		return
+	}
 	pc.mutex.Lock()
 	defer pc.mutex.Unlock()
 	
@@ -173,23 +199,60 @@ type Collector struct {
 	runner      Runner
 	cache       *PerformanceCache
 	retryConfig *RetryConfig
+	fs          FileSystem
+	maxFileSize int64                         // 超过该大小的文件不纳入 diff，0 表示不限制
+	onSkip      func(path string, size int64) // 文件因大小被跳过时的回调，可为 nil
+	exclude     []string                      // 不纳入 diff 的文件 glob 模式
+	noUntracked bool                          // 跳过未跟踪文件的扫描
+	amend       bool                          // 收集 HEAD 提交与已暂存变更，用于重写 HEAD 的 message
+	statHeader  bool                          // 在 ComprehensiveDiff 开头附加 DiffStat 概览
+	prefixMap   map[string]string             // 变更类别到建议前缀的映射，nil 时使用 DefaultPrefixMap
+	maxLineLen  int                           // 超过该字符数的 diff 行被折叠，0 表示不折叠
+	collapseDel bool                          // 将已删除文件的内容折叠为一行摘要
+	ignoreSpace bool                          // diff 命令附加 --ignore-all-space，只保留语义变更
+	stagedOnly  bool                          // 只收集已暂存的变更，忽略未暂存与未跟踪的文件
+	only        []string                      // 只纳入这些路径（相对仓库根目录的文件或目录），为空时不限制
+
+	slowThreshold time.Duration       // git 命令耗时达到该值时记录为慢命令，0 表示不记录
+	onSlow        func(CommandTiming) // 记录慢命令时的回调，可为 nil
+	slowMu        sync.Mutex
+	slow          []CommandTiming
+
+	rootMu   sync.Mutex
+	repoRoot string // RepoRoot 的结果，运行期间不会变化
+}
+
+// FileSystem 抽象文件元数据访问，方便在单元测试中注入 Mock。
+type FileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+}
+
+// osFileSystem 使用 os.Stat 实现 FileSystem。
+type osFileSystem struct{}
+
+func (osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
 }
 
 // New 创建 Collector 实例。
 func New(r Runner) *Collector {
 	return &Collector{
 		runner:      r,
-		cache:       NewPerformanceCache(30 * time.Second), // 30-second cache TTL
+		cache:       NewPerformanceCache(DefaultCacheTTL),
 		retryConfig: DefaultRetryConfig(),
+		fs:          osFileSystem{},
+		maxLineLen:  DefaultMaxLineLength,
 	}
 }
 
-// NewWithCache 创建带有自定义缓存配置的 Collector 实例。
+// NewWithCache 创建带有自定义缓存配置的 Collector 实例，cacheTTL 不大于 0 时不缓存。
 func NewWithCache(r Runner, cacheTTL time.Duration) *Collector {
 	return &Collector{
 		runner:      r,
 		cache:       NewPerformanceCache(cacheTTL),
 		retryConfig: DefaultRetryConfig(),
+		fs:          osFileSystem{},
+		maxLineLen:  DefaultMaxLineLength,
 	}
 }
 
@@ -199,11 +262,263 @@ func NewWithConfig(r Runner, cacheTTL time.Duration, retryConfig *RetryConfig) *
 		runner:      r,
 		cache:       NewPerformanceCache(cacheTTL),
 		retryConfig: retryConfig,
+		fs:          osFileSystem{},
+		maxLineLen:  DefaultMaxLineLength,
+	}
+}
+
+// SetFileSystem 替换用于读取文件大小的 FileSystem，主要用于测试。
+func (c *Collector) SetFileSystem(fs FileSystem) {
+	c.fs = fs
+}
+
+// SetMaxFileSize 设置纳入 diff 的最大文件大小（字节），0 表示不限制。
+// 超限文件会从 ComprehensiveDiff 中排除，并通过 onSkip（可为 nil）通知调用方。
+func (c *Collector) SetMaxFileSize(limit int64, onSkip func(path string, size int64)) {
+	c.maxFileSize = limit
+	c.onSkip = onSkip
+}
+
+// worktreePath 将 git 输出的仓库根相对路径转换为可直接访问的路径。
+// 从子目录运行时 cwd 不是仓库根，必须先拼接 RepoRoot；获取失败时原样返回。
+func (c *Collector) worktreePath(ctx context.Context, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	root, err := c.RepoRoot(ctx)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(root, filepath.FromSlash(path))
+}
+
+// fileSize 返回工作区中文件的大小；文件不存在（如已删除）时返回 false。
+// path 相对于仓库根目录。
+func (c *Collector) fileSize(ctx context.Context, path string) (int64, bool) {
+	if c.fs == nil {
+		return 0, false
+	}
+	info, err := c.fs.Stat(c.worktreePath(ctx, path))
+	if err != nil || info.IsDir() {
+		return 0, false
+	}
+	return info.Size(), true
+}
+
+// SetExcludePatterns 设置不纳入 diff 的文件模式。
+// 模式与完整路径或文件名做 glob 匹配；以 "/" 结尾的模式匹配该目录下的所有文件。
+func (c *Collector) SetExcludePatterns(patterns []string) {
+	c.exclude = patterns
+}
+
+// SetSkipUntracked 设置是否跳过未跟踪文件。
+// 大型仓库中扫描未跟踪文件（git ls-files --others）开销最大，跳过后只统计已跟踪文件的变更。
+func (c *Collector) SetSkipUntracked(skip bool) {
+	c.noUntracked = skip
+}
+
+// SetAmend 设置是否以 amend 模式收集变更。
+// amend 模式下 diff、文件列表与变更分析描述的是 amend 后的 HEAD 提交，
+// 即 HEAD 的父提交与暂存区之间的差异；未暂存与未跟踪的变更不会被 amend，因此不纳入。
+func (c *Collector) SetAmend(amend bool) {
+	c.amend = amend
+}
+
+// SetStagedOnly 设置是否只收集已暂存的变更：diff、文件列表与变更分析不包含
+// 未暂存的修改与未跟踪文件，例如用户已手动挑选了要提交的文件时
+func (c *Collector) SetStagedOnly(stagedOnly bool) {
+	c.stagedOnly = stagedOnly
+}
+
+// SetOnlyPaths 设置只纳入 diff、文件列表与变更分析的路径（相对仓库根目录），
+// 目录包含其下的所有文件；与排除模式相反，是一个白名单。nil 表示不限制
+func (c *Collector) SetOnlyPaths(paths []string) {
+	c.only = paths
+}
+
+// InPaths 判断 path 是否为 paths 中的某个文件或位于其中某个目录下；"." 匹配所有路径
+func InPaths(path string, paths []string) bool {
+	path = filepath.ToSlash(path)
+	for _, p := range paths {
+		p = strings.TrimSuffix(filepath.ToSlash(p), "/")
+		if p == "." || path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPrefixMap 设置变更类别（见 DefaultPrefixMap）到建议前缀的映射，
+// 未覆盖的类别仍使用默认前缀，例如 {"added": "feature", "modified": "bugfix"}。
+func (c *Collector) SetPrefixMap(overrides map[string]string) {
+	c.prefixMap = ResolvePrefixMap(overrides)
+}
+
+// CommandTiming 记录一次 git 命令的耗时
+type CommandTiming struct {
+	Command  string        // 完整命令行，例如 "git status --porcelain"
+	Duration time.Duration
+}
+
+// SetSlowCommandThreshold 设置慢 git 命令的阈值，0 表示不记录。
+// 经由缓存执行的命令耗时达到 threshold 时会被记录（见 SlowCommands），并调用 onSlow（可为 nil），
+// 便于诊断网络文件系统等环境下的慢仓库问题。
+func (c *Collector) SetSlowCommandThreshold(threshold time.Duration, onSlow func(CommandTiming)) {
+	c.slowThreshold = threshold
+	c.onSlow = onSlow
+}
+
+// SlowCommands 返回至今记录的慢 git 命令，按完成顺序排列
+func (c *Collector) SlowCommands() []CommandTiming {
+	c.slowMu.Lock()
+	defer c.slowMu.Unlock()
+	return append([]CommandTiming(nil), c.slow...)
+}
+
+// recordDuration 在命令耗时达到 slowThreshold 时记录慢命令
+func (c *Collector) recordDuration(name string, args []string, d time.Duration) {
+	if c.slowThreshold <= 0 || d < c.slowThreshold {
+		return
+	}
+	timing := CommandTiming{Command: strings.Join(append([]string{name}, args...), " "), Duration: d}
+	c.slowMu.Lock()
+	c.slow = append(c.slow, timing)
+	c.slowMu.Unlock()
+	if c.onSlow != nil {
+		c.onSlow(timing)
+	}
+}
+
+// DefaultMaxLineLength 是 diff 行折叠前的默认最大字符数
+const DefaultMaxLineLength = 2000
+
+// SetMaxLineLength 设置 diff 行的最大字符数，0 表示不折叠。
+// 漏过排除规则的压缩文件（如 min.js）一行可达数 MB，既破坏按行截断也无助于生成 message，
+// 超长的行会折叠为 "<long line elided, N chars>"。
+func (c *Collector) SetMaxLineLength(limit int) {
+	c.maxLineLen = limit
+}
+
+// SetCollapseDeletions 设置是否将已删除文件的 diff 内容折叠为 "deleted file <path> (N lines)"。
+// 删除大文件时 diff 会以 "-" 行列出全部内容，而模型只需要知道文件被删除；新增与修改的文件不受影响。
+func (c *Collector) SetCollapseDeletions(enabled bool) {
+	c.collapseDel = enabled
+}
+
+// SetIgnoreWhitespace 设置 diff 是否忽略空白变更（git diff --ignore-all-space）。
+// 纯格式化的改动会从 diff 中消失；若全部变更都只涉及空白，status 回退会标注为仅空白变更。
+func (c *Collector) SetIgnoreWhitespace(enabled bool) {
+	c.ignoreSpace = enabled
+}
+
+// SetDiffStatHeader 设置是否在 ComprehensiveDiff 开头附加 DiffStat 的统计行，
+// 让模型在逐个 hunk 之前先了解变更规模。
+func (c *Collector) SetDiffStatHeader(enabled bool) {
+	c.statHeader = enabled
+}
+
+// amendBase 返回 amend 模式下的 diff 基准：HEAD 的父提交，根提交时为空树
+func (c *Collector) amendBase(ctx context.Context) (string, error) {
+	if out, err := c.runner.Run(ctx, "git", "rev-parse", "--verify", "--quiet", "HEAD^"); err == nil {
+		return strings.TrimSpace(string(out)), nil
+	}
+	// 根提交没有父提交，与空树比较即为其全部内容
+	out, err := c.runner.Run(ctx, "git", "hash-object", "-t", "tree", os.DevNull)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the parent of HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// amendDiff 返回 amend 后 HEAD 提交将包含的 diff（git diff --cached HEAD^），按文件优先级排序
+func (c *Collector) amendDiff(ctx context.Context) (string, error) {
+	base, err := c.amendBase(ctx)
+	if err != nil {
+		return "", err
+	}
+	diff, err := c.executeDiffCommand(ctx, "git diff --cached HEAD^ failed", ErrNoDiff, "git", DiffArgs("--cached", base)...)
+	if err != nil {
+		return "", err
+	}
+	sections := c.dropSkippedSections(ctx, splitDiffByFile(diff))
+	return strings.TrimSpace(strings.Join(c.orderDiffSections(sections), "\n\n")), nil
+}
+
+// amendFiles 返回 amend 后 HEAD 提交将包含的文件状态（git diff --cached --name-status HEAD^）
+func (c *Collector) amendFiles(ctx context.Context) ([]FileStatus, error) {
+	base, err := c.amendBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.runWithCache(ctx, "git", DiffArgs("--cached", "--name-status", base)...)
+	if err != nil {
+		return nil, fmt.Errorf("git diff --cached --name-status HEAD^ failed: %w", err)
+	}
+	all := parseNameStatus(stripANSI(string(out)))
+	files := make([]FileStatus, 0, len(all))
+	for _, f := range all {
+		if !c.skipFile(ctx, f.Path) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// statusArgs 返回 git status 参数，跳过未跟踪文件时追加 -uno
+func (c *Collector) statusArgs(args ...string) []string {
+	args = append([]string{"status"}, args...)
+	if c.noUntracked {
+		args = append(args, "-uno")
+	}
+	return args
+}
+
+// isExcluded 判断文件是否匹配任一排除模式，或不在 SetOnlyPaths 的白名单中
+func (c *Collector) isExcluded(path string) bool {
+	path = filepath.ToSlash(path)
+	if len(c.only) > 0 && !InPaths(path, c.only) {
+		return true
+	}
+	for _, pattern := range c.exclude {
+		if dir := strings.TrimSuffix(pattern, "/"); dir != pattern {
+			if strings.HasPrefix(path, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// skipFile 判断文件是否应从 diff 中排除：匹配排除模式或超过大小限制
+func (c *Collector) skipFile(ctx context.Context, path string) bool {
+	return c.isExcluded(path) || c.exceedsMaxFileSize(ctx, path)
+}
+
+// exceedsMaxFileSize 判断文件是否超过 maxFileSize，超过时触发 onSkip 回调。
+func (c *Collector) exceedsMaxFileSize(ctx context.Context, path string) bool {
+	if c.maxFileSize <= 0 {
+		return false
+	}
+	size, ok := c.fileSize(ctx, path)
+	if !ok || size <= c.maxFileSize {
+		return false
 	}
+	if c.onSkip != nil {
+		c.onSkip(path, size)
+	}
+	return true
 }
 
 // runWithCache executes a git command with caching support
-// Cache key is generated from command name and arguments
+// Cache key is generated from command name and arguments.
+// Results with a retryable error (see isRetryableError) are not cached
 func (c *Collector) runWithCache(ctx context.Context, name string, args ...string) ([]byte, error) {
 	// Generate cache key from command and args
 	cacheKey := name + ":" + strings.Join(args, ":")
@@ -214,14 +529,19 @@ func (c *Collector) runWithCache(ctx context.Context, name string, args ...strin
 	}
 	
 	// Execute command and cache result
+	start := time.Now()
 	result, err := c.runner.Run(ctx, name, args...)
+	c.recordDuration(name, args, time.Since(start))
 	
 	// Check if error indicates not being in a git repository
 	if err != nil && isNotGitRepositoryError(err) {
 		err = ErrNotGitRepository
 	}
 	
-	c.cache.Set(cacheKey, result, err)
+	// 暂时性错误不缓存，否则 runWithRetry 的重试会在整个 TTL 内命中同一个错误
+	if !isRetryableError(err) {
+		c.cache.Set(cacheKey, result, err)
+	}
 	
 	return result, err
 }
@@ -256,9 +576,10 @@ func (c *Collector) ClearCache() {
 //   batch.ExecuteBatch(ctx)
 //   results, errors := batch.GetResults()
 type BatchGitOperations struct {
-	operations []func(context.Context) (interface{}, error) // Functions to execute
-	results    []interface{}                                // Results from operations
-	errors     []error                                      // Errors from operations
+	operations     []func(context.Context) (interface{}, error) // Functions to execute
+	results        []interface{}                                // Results from operations
+	errors         []error                                      // Errors from operations
+	maxConcurrency int                                          // Max operations in flight, 0 means unlimited
 }
 
 // NewBatchGitOperations creates a new batch operations instance
@@ -273,17 +594,26 @@ func (b *BatchGitOperations) AddOperation(op func(context.Context) (interface{},
 	b.operations = append(b.operations, op)
 }
 
-// ExecuteBatch executes all operations in the batch concurrently
+// SetMaxConcurrency limits how many operations run at once, e.g. to avoid
+// exhausting file descriptors when reading many files. n <= 0 means unlimited.
+func (b *BatchGitOperations) SetMaxConcurrency(n int) {
+	b.maxConcurrency = n
+}
+
+// ExecuteBatch executes all operations in the batch concurrently and waits for
+// the ones already running. Operations that have not started when ctx is done
+// are not run; their error is ctx.Err().
 func (b *BatchGitOperations) ExecuteBatch(ctx context.Context) {
 	b.results = make([]interface{}, len(b.operations))
 	b.errors = make([]error, len(b.operations))
+	sem := b.newSemaphore()
 	
 	var wg sync.WaitGroup
 	for i, op := range b.operations {
 		wg.Add(1)
 		go func(index int, operation func(context.Context) (interface{}, error)) {
 			defer wg.Done()
-			result, err := operation(ctx)
+			result, err := runOperation(ctx, sem, operation)
 			b.results[index] = result
 			b.errors[index] = err
 		}(i, op)
@@ -291,6 +621,76 @@ func (b *BatchGitOperations) ExecuteBatch(ctx context.Context) {
 	wg.Wait()
 }
 
+// ExecuteBatchContext is like ExecuteBatch but returns as soon as ctx is done
+// instead of waiting for in-flight operations. Operations that have not
+// finished by then are marked with ctx.Err(), which is also returned.
+// In-flight git commands are killed by the runner's CommandContext; their
+// late results are discarded.
+func (b *BatchGitOperations) ExecuteBatchContext(ctx context.Context) error {
+	n := len(b.operations)
+	results := make([]interface{}, n)
+	errs := make([]error, n)
+	sem := b.newSemaphore()
+	
+	type outcome struct {
+		index  int
+		result interface{}
+		err    error
+	}
+	// Buffered so goroutines finishing after an early return never block
+	done := make(chan outcome, n)
+	for i, op := range b.operations {
+		go func(index int, operation func(context.Context) (interface{}, error)) {
+			result, err := runOperation(ctx, sem, operation)
+			done <- outcome{index: index, result: result, err: err}
+		}(i, op)
+	}
+	
+	finished := make([]bool, n)
+	var ctxErr error
+	for remaining := n; remaining > 0 && ctxErr == nil; remaining-- {
+		select {
+		case o := <-done:
+			results[o.index], errs[o.index] = o.result, o.err
+			finished[o.index] = true
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+			for i := range errs {
+				if !finished[i] {
+					errs[i] = ctxErr
+				}
+			}
+		}
+	}
+	b.results, b.errors = results, errs
+	return ctxErr
+}
+
+// newSemaphore returns a channel bounding concurrency, or nil when unlimited
+func (b *BatchGitOperations) newSemaphore() chan struct{} {
+	if b.maxConcurrency <= 0 {
+		return nil
+	}
+	return make(chan struct{}, b.maxConcurrency)
+}
+
+// runOperation runs operation once a semaphore slot is free, skipping it if
+// ctx is done before it could start
+func runOperation(ctx context.Context, sem chan struct{}, operation func(context.Context) (interface{}, error)) (interface{}, error) {
+	if sem != nil {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return operation(ctx)
+}
+
 // GetResults returns the results of all operations
 func (b *BatchGitOperations) GetResults() ([]interface{}, []error) {
 	return b.results, b.errors
@@ -303,13 +703,16 @@ func optimizeStringSlice(slice []string) []string {
 	}
 	
 	// Remove empty strings and duplicates
-	seen := make(map[string]bool)
+	seen := make(map[string]struct{}, len(slice))
 	result := make([]string, 0, len(slice))
 	
 	for _, s := range slice {
 		trimmed := strings.TrimSpace(s)
-		if trimmed != "" && !seen[trimmed] {
-			seen[trimmed] = true
+		if trimmed == "" {
+			continue
+		}
+		if _, dup := seen[trimmed]; !dup {
+			seen[trimmed] = struct{}{}
 			result = append(result, trimmed)
 		}
 	}
@@ -317,9 +720,41 @@ func optimizeStringSlice(slice []string) []string {
 	return result
 }
 
+// countLines 返回 out 按换行拆分后的最大行数，用于预分配切片
+func countLines(out []byte) int {
+	if len(out) == 0 {
+		return 0
+	}
+	return bytes.Count(out, []byte{'\n'}) + 1
+}
+
+// splitLines 将 git 输出按行追加到 dst，跳过空行并去掉终端转义序列。
+// 输出只转换为一次 string，各行为其子串，避免逐行分配。
+func splitLines(dst []string, out []byte) []string {
+	if dst == nil {
+		dst = make([]string, 0, countLines(out))
+	}
+	text := stripANSI(string(out))
+	for text != "" {
+		line := text
+		if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+			line, text = text[:idx], text[idx+1:]
+		} else {
+			text = ""
+		}
+		if line != "" {
+			dst = append(dst, line)
+		}
+	}
+	return dst
+}
+
 // ErrNoDiff 表示当前仓库没有待提交的 diff。
 var ErrNoDiff = fmt.Errorf("nothing to commit")
 
+// ErrDetachedHead 表示 HEAD 未指向任何分支，例如 rebase 过程中或 CI 直接检出某个提交。
+var ErrDetachedHead = fmt.Errorf("HEAD is detached")
+
 // Enhanced error types for better error handling and categorization.
 // These provide semantic meaning to different types of failures.
 var (
@@ -360,10 +795,17 @@ type GitError struct {
 	Timestamp time.Time // When the error occurred
 }
 
+// gitErrorStderrLines limits how many trailing stderr lines are shown in error messages
+const gitErrorStderrLines = 3
+
 // Error implements the error interface
 func (e *GitError) Error() string {
-	return fmt.Sprintf("git command failed: %s %s (exit code: %d) - %s",
+	msg := fmt.Sprintf("git command failed: %s %s (exit code: %d) - %s",
 		e.Command, strings.Join(e.Args, " "), e.ExitCode, e.Context)
+	if tail := lastLines(e.Stderr, gitErrorStderrLines); tail != "" {
+		msg += "\n" + tail
+	}
+	return msg
 }
 
 // Unwrap returns the underlying error
@@ -371,6 +813,50 @@ func (e *GitError) Unwrap() error {
 	return e.Cause
 }
 
+// CommandError is returned by a Runner whose command failed, keeping stdout
+// and stderr apart so GitError can report them separately. Runners that only
+// return combined output may return the raw error instead; GitError then
+// treats that output as stderr.
+type CommandError struct {
+	Err    error  // Underlying error, e.g. *exec.ExitError
+	Stdout []byte // Standard output
+	Stderr []byte // Standard error output
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// AsGitError reports whether err wraps a *GitError and returns it.
+// It is a convenience wrapper around errors.As.
+func AsGitError(err error) (*GitError, bool) {
+	var gitErr *GitError
+	if errors.As(err, &gitErr) {
+		return gitErr, true
+	}
+	return nil, false
+}
+
+// lastLines returns the last n non-empty lines of s
+func lastLines(s string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
 // RetryConfig defines retry behavior for git operations
 type RetryConfig struct {
 	MaxRetries int
@@ -392,6 +878,7 @@ func DefaultRetryConfig() *RetryConfig {
 // runWithRetry executes a git command with retry logic
 func (c *Collector) runWithRetry(ctx context.Context, config *RetryConfig, name string, args ...string) ([]byte, error) {
 	var lastErr error
+	var lastOutput []byte
 	delay := config.InitialDelay
 	
 	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
@@ -410,6 +897,7 @@ func (c *Collector) runWithRetry(ctx context.Context, config *RetryConfig, name
 		}
 		
 		lastErr = err
+		lastOutput = result
 		
 		// Check if error indicates not being in a git repository
 		if isNotGitRepositoryError(err) {
@@ -429,13 +917,26 @@ func (c *Collector) runWithRetry(ctx context.Context, config *RetryConfig, name
 	}
 	
 	// Create enhanced error with context
-	return nil, &GitError{
+	stdout, stderr := "", string(lastOutput)
+	var cmdErr *CommandError
+	if errors.As(lastErr, &cmdErr) {
+		stdout, stderr = string(cmdErr.Stdout), string(cmdErr.Stderr)
+	}
+	gitErr := &GitError{
 		Command:   name,
 		Args:      args,
+		Stderr:    strings.TrimSpace(stderr),
+		Stdout:    strings.TrimSpace(stdout),
 		Cause:     lastErr,
 		Context:   fmt.Sprintf("failed after %d attempts", config.MaxRetries+1),
 		Timestamp: time.Now(),
 	}
+	// exec.ExitError 等错误类型会提供退出码
+	var exitErr interface{ ExitCode() int }
+	if errors.As(lastErr, &exitErr) {
+		gitErr.ExitCode = exitErr.ExitCode()
+	}
+	return nil, gitErr
 }
 
 // isRetryableError determines if an error is worth retrying
@@ -500,6 +1001,13 @@ func isNotGitRepositoryError(err error) bool {
 	return false
 }
 
+// isNoCommitsError 判断 git log 失败是否因为当前分支还没有任何提交，
+// 例如 "fatal: your current branch 'main' does not have any commits yet"
+func isNoCommitsError(err error, output []byte) bool {
+	text := err.Error() + "\n" + string(output)
+	return strings.Contains(text, "does not have any commits") || strings.Contains(text, "no commits yet")
+}
+
 // 安全验证：确保分支名称和文件路径不包含危险字符
 var (
 	// 允许的分支名称格式：字母、数字、短划线、斜杠、点、下划线
@@ -508,12 +1016,37 @@ var (
 	dangerousChars = regexp.MustCompile(`[;&|$\x00-\x1f\x7f-\x9f]`)
 )
 
+// ansiEscape 匹配 CSI 序列（如颜色码 \x1b[31m）与以 BEL 或 ST 结尾的 OSC 序列
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// stripANSI 去掉终端转义序列。即使用户配置了 color.ui = always，LLM 也不会收到颜色码；
+// 不含 ESC 的输入原样返回
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
 // sanitizeOutput 清理输出中的危险字符
 func sanitizeOutput(s string) string {
+	// 快速路径：绝大多数输出不含危险字符，大量文件名时可省去逐个正则替换
+	if strings.IndexFunc(s, isDangerousRune) < 0 {
+		return s
+	}
 	// 移除控制字符和潜在的危险字符
 	return dangerousChars.ReplaceAllString(s, "")
 }
 
+// isDangerousRune 与 dangerousChars 匹配同一字符集
+func isDangerousRune(r rune) bool {
+	switch r {
+	case ';', '&', '|', '$':
+		return true
+	}
+	return r <= 0x1f || (r >= 0x7f && r <= 0x9f)
+}
+
 // shouldIgnoreFile 判断是否应该忽略某个文件
 // 根据文档建议，过滤锁文件、构建产物、二进制文件等噪音
 func shouldIgnoreFile(filePath string) bool {
@@ -521,7 +1054,7 @@ func shouldIgnoreFile(filePath string) bool {
 	filePath = filepath.ToSlash(filePath)
 	fileName := filepath.Base(filePath)
 	
-	// 1. 锁文件和依赖文件
+	// 1. 锁文件和依赖文件。与 lockfileDiffers 分开维护：能解析版本变化的锁文件不一定要从摘要中过滤
 	lockFiles := []string{
 		"package-lock.json", "yarn.lock", "pnpm-lock.yaml", 
 		"go.sum", "go.mod", "composer.lock", "Pipfile.lock",
@@ -589,7 +1122,8 @@ func filterFiles(files []string) []string {
 // parseGitStatusPorcelain 解析 git status --porcelain -b 的输出
 // 返回文件状态摘要信息，包含分支名和文件状态列表
 func parseGitStatusPorcelain(output string) (*FileStatusSummary, error) {
-	lines := strings.Split(strings.TrimSpace(output), "\n")
+	// 行首的空格是状态列的一部分（" M" 表示仅在工作区修改），不能去除
+	lines := strings.Split(strings.TrimRight(output, "\r\n"), "\n")
 	if len(lines) == 0 {
 		return &FileStatusSummary{}, nil
 	}
@@ -599,8 +1133,8 @@ func parseGitStatusPorcelain(output string) (*FileStatusSummary, error) {
 	}
 	
 	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
 			continue
 		}
 		
@@ -613,6 +1147,10 @@ func parseGitStatusPorcelain(output string) (*FileStatusSummary, error) {
 			} else {
 				summary.BranchName = branchInfo
 			}
+			// 分离 HEAD 时输出 "## HEAD (no branch)"，不作为分支名
+			if summary.BranchName == "HEAD (no branch)" {
+				summary.BranchName = ""
+			}
 			continue
 		}
 		
@@ -663,9 +1201,18 @@ func (c *Collector) RecentCommits(ctx context.Context, n int) ([]string, error)
 		return nil, fmt.Errorf("n too large, maximum is 1000")
 	}
 
+	args := []string{"log", "--pretty=format:%s", fmt.Sprintf("-n%d", n)}
+	if c.amend {
+		// 被 amend 的 HEAD 不作为风格参考，避免模型照抄原 message
+		args = append(args, "--skip=1")
+	}
 	// Use cached execution for better performance
-	out, err := c.runWithCache(ctx, "git", "log", "--pretty=format:%s", fmt.Sprintf("-n%d", n))
+	out, err := c.runWithCache(ctx, "git", args...)
 	if err != nil {
+		// 尚无提交的新仓库没有历史可参考，仍应能生成第一条 commit message
+		if isNoCommitsError(err, out) {
+			return []string{}, nil
+		}
 		return nil, fmt.Errorf("git log failed: %w", err)
 	}
 
@@ -699,6 +1246,45 @@ func (c *Collector) Diff(ctx context.Context) (string, error) {
 	return c.CombinedDiff(ctx)
 }
 
+// HeadMessage 返回 HEAD 提交的完整 message（git log -1 --pretty=%B），去掉首尾空白。
+// 结果不缓存，amend 之后再次调用会得到新的 message。
+func (c *Collector) HeadMessage(ctx context.Context) (string, error) {
+	out, err := c.runner.Run(ctx, "git", "log", "-1", "--pretty=%B")
+	if err != nil {
+		if isNotGitRepositoryError(err) {
+			err = ErrNotGitRepository
+		}
+		return "", fmt.Errorf("git log -1 failed: %w", err)
+	}
+	return strings.TrimSpace(stripANSI(string(out))), nil
+}
+
+// RepoRoot 返回当前工作树的根目录。
+// 使用 git rev-parse --show-toplevel，在 linked worktree 或设置了 GIT_DIR/GIT_WORK_TREE 时
+// 也能得到实际检出的目录，而不是主仓库目录。
+// 成功的结果在 Collector 生命周期内缓存（不受缓存 TTL 影响），git 只会被调用一次。
+func (c *Collector) RepoRoot(ctx context.Context) (string, error) {
+	c.rootMu.Lock()
+	defer c.rootMu.Unlock()
+	if c.repoRoot != "" {
+		return c.repoRoot, nil
+	}
+
+	out, err := c.runner.Run(ctx, "git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		if isNotGitRepositoryError(err) {
+			err = ErrNotGitRepository
+		}
+		return "", fmt.Errorf("git rev-parse --show-toplevel failed: %w", err)
+	}
+	root := strings.TrimSpace(string(out))
+	if root == "" {
+		return "", fmt.Errorf("git rev-parse --show-toplevel returned an empty path")
+	}
+	c.repoRoot = filepath.Clean(root)
+	return c.repoRoot, nil
+}
+
 // BranchName 返回当前 Git 分支名称。
 // Phase 3 Enhancement: Added caching for better performance
 func (c *Collector) BranchName(ctx context.Context) (string, error) {
@@ -708,6 +1294,10 @@ func (c *Collector) BranchName(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("git rev-parse failed: %w", err)
 	}
 	branchName := strings.TrimSpace(string(out))
+	// 分离 HEAD 时 git 输出 "HEAD"，它不是可以推送或创建 PR 的分支
+	if branchName == "HEAD" {
+		return "", ErrDetachedHead
+	}
 	// 安全验证：确保分支名称格式合法
 	if !validBranchName.MatchString(branchName) {
 		return "", fmt.Errorf("invalid branch name format: %s", sanitizeOutput(branchName))
@@ -715,24 +1305,140 @@ func (c *Collector) BranchName(ctx context.Context) (string, error) {
 	return branchName, nil
 }
 
+// BranchDescription 返回当前分支的描述（`git config branch.<name>.description`，
+// 可用 `git branch --edit-description` 设置），未设置或处于分离 HEAD 时返回空串
+func (c *Collector) BranchDescription(ctx context.Context) (string, error) {
+	branch, err := c.BranchName(ctx)
+	if err != nil {
+		if errors.Is(err, ErrDetachedHead) {
+			return "", nil
+		}
+		return "", err
+	}
+	out, err := c.runWithCache(ctx, "git", "config", "--get", "branch."+branch+".description")
+	if err != nil {
+		// 未设置时 git config --get 以退出码 1 结束且没有输出
+		if len(strings.TrimSpace(string(out))) == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("git config failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CommitTemplatePath 返回 `git config commit.template` 指定的 commit message 模板路径
+// （已展开 ~），未设置时返回空串
+func (c *Collector) CommitTemplatePath(ctx context.Context) (string, error) {
+	out, err := c.runWithCache(ctx, "git", "config", "--type=path", "--get", "commit.template")
+	if err != nil {
+		// 未设置时 git config --get 以退出码 1 结束且没有输出
+		if len(strings.TrimSpace(string(out))) == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("git config failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// DefaultBranch 返回远程仓库的默认分支名称（例如 main、master、develop）。
+// 通过 `git symbolic-ref refs/remotes/<remote>/HEAD` 获取，结果会被缓存。
+func (c *Collector) DefaultBranch(ctx context.Context, remote string) (string, error) {
+	if !validBranchName.MatchString(remote) {
+		return "", fmt.Errorf("invalid remote name: %s", sanitizeOutput(remote))
+	}
+	out, err := c.runWithCache(ctx, "git", "symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git symbolic-ref failed: %w", err)
+	}
+	ref := strings.TrimSpace(string(out))
+	branch := strings.TrimPrefix(ref, remote+"/")
+	if branch == "" || !validBranchName.MatchString(branch) {
+		return "", fmt.Errorf("invalid default branch: %s", sanitizeOutput(ref))
+	}
+	return branch, nil
+}
+
+// RemoteOwner 返回远程仓库 URL 中的 owner（例如 git@github.com:alice/catmit.git 中的 alice），
+// 用于向 fork 推送后以 <owner>:<branch> 指定 PR 的 head。
+func (c *Collector) RemoteOwner(ctx context.Context, remote string) (string, error) {
+	if !validBranchName.MatchString(remote) {
+		return "", fmt.Errorf("invalid remote name: %s", sanitizeOutput(remote))
+	}
+	out, err := c.runWithCache(ctx, "git", "remote", "get-url", remote)
+	if err != nil {
+		return "", fmt.Errorf("git remote get-url failed: %w", err)
+	}
+	url := strings.TrimSpace(string(out))
+	owner := parseRemoteOwner(url)
+	if owner == "" {
+		return "", fmt.Errorf("cannot determine owner from remote URL: %s", sanitizeOutput(url))
+	}
+	return owner, nil
+}
+
+// parseRemoteOwner 从 https://host/owner/repo(.git)、ssh://git@host/owner/repo 与
+// git@host:owner/repo 形式的 URL 中取出 owner，无法识别时返回空串
+func parseRemoteOwner(url string) string {
+	path := url
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+		slash := strings.Index(path, "/")
+		if slash < 0 {
+			return ""
+		}
+		path = path[slash+1:]
+	} else if i := strings.Index(path, ":"); i >= 0 {
+		path = path[i+1:]
+	} else {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || !validBranchName.MatchString(parts[len(parts)-2]) {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
 // ChangedFiles 返回当前 staged 文件列表，已过滤掉不需要的文件类型。
 // Phase 3 Enhancement: Added batched operations and memory optimization
 func (c *Collector) ChangedFiles(ctx context.Context) ([]string, error) {
+	if c.amend {
+		files, err := c.amendFiles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		return paths, nil
+	}
+	if c.noUntracked || c.stagedOnly {
+		// 快速路径：只需一次 git 调用，不扫描未跟踪文件
+		stagedOut, err := c.runWithCache(ctx, "git", DiffArgs("--cached", "--name-only")...)
+		if err != nil {
+			return nil, fmt.Errorf("git diff --name-only failed: %w", err)
+		}
+		return c.processAndFilterFiles(splitLines(nil, stagedOut)), nil
+	}
+
 	// Use batched operations to get both staged and untracked files concurrently
 	batch := NewBatchGitOperations()
 	
 	// Add staged files operation
 	batch.AddOperation(func(ctx context.Context) (interface{}, error) {
-		return c.runWithCache(ctx, "git", "diff", "--cached", "--name-only")
+		return c.runWithCache(ctx, "git", DiffArgs("--cached", "--name-only")...)
 	})
 	
 	// Add untracked files operation
 	batch.AddOperation(func(ctx context.Context) (interface{}, error) {
-		return c.runWithCache(ctx, "git", "ls-files", "--others", "--exclude-standard")
+		return c.runWithCache(ctx, "git", "ls-files", "--others", "--exclude-standard", "--full-name")
 	})
 	
 	// Execute batch operations
-	batch.ExecuteBatch(ctx)
+	if err := batch.ExecuteBatchContext(ctx); err != nil {
+		return nil, err
+	}
 	results, errors := batch.GetResults()
 	
 	// Check for errors in staged files
@@ -740,16 +1446,15 @@ func (c *Collector) ChangedFiles(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("git diff --name-only failed: %w", errors[0])
 	}
 	
-	// Process staged files
+	// 按两份输出的总行数一次性分配，避免 append 反复扩容
 	stagedOut := results[0].([]byte)
-	files := strings.Split(strings.TrimSpace(string(stagedOut)), "\n")
-	
-	// Process untracked files (ignore errors for untracked files)
-	if errors[1] == nil && len(results[1].([]byte)) > 0 {
-		untrackedOut := results[1].([]byte)
-		untrackedFiles := strings.Split(strings.TrimSpace(string(untrackedOut)), "\n")
-		files = append(files, untrackedFiles...)
+	var untrackedOut []byte
+	if errors[1] == nil { // Ignore errors for untracked files
+		untrackedOut = results[1].([]byte)
 	}
+	files := make([]string, 0, countLines(stagedOut)+countLines(untrackedOut))
+	files = splitLines(files, stagedOut)
+	files = splitLines(files, untrackedOut)
 	
 	// Use the new helper function to process and filter files
 	res := c.processAndFilterFiles(files)
@@ -764,15 +1469,41 @@ func (c *Collector) ChangedFiles(ctx context.Context) ([]string, error) {
 // 使用 git status --porcelain -b 获取完整的状态信息
 // Phase 3 Enhancement: Uses cached execution for better performance
 func (c *Collector) FileStatusSummary(ctx context.Context) (*FileStatusSummary, error) {
-	out, err := c.runWithCache(ctx, "git", "status", "--porcelain", "-b")
+	if c.amend {
+		files, err := c.amendFiles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		branch, _ := c.BranchName(ctx)
+		return &FileStatusSummary{BranchName: branch, Files: files}, nil
+	}
+	out, err := c.runWithCache(ctx, "git", c.statusArgs("--porcelain", "-b")...)
 	if err != nil {
 		return nil, fmt.Errorf("git status --porcelain -b failed: %w", err)
 	}
 	
-	summary, err := parseGitStatusPorcelain(string(out))
+	summary, err := parseGitStatusPorcelain(stripANSI(string(out)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse git status output: %w", err)
 	}
+	if c.stagedOnly {
+		staged := summary.Files[:0]
+		for _, f := range summary.Files {
+			if f.IndexStatus != ' ' && f.IndexStatus != '?' && f.IndexStatus != 0 {
+				staged = append(staged, f)
+			}
+		}
+		summary.Files = staged
+	}
+	if len(c.only) > 0 {
+		kept := summary.Files[:0]
+		for _, f := range summary.Files {
+			if InPaths(f.Path, c.only) {
+				kept = append(kept, f)
+			}
+		}
+		summary.Files = kept
+	}
 	
 	return summary, nil
 }
@@ -820,6 +1551,11 @@ func getFilePriority(status FileStatus) int {
 		basePriority += 10
 	}
 	
+	// 4. 生成文件 - 优先级最低，通常可以从 prompt 中排除
+	if status.IsGenerated {
+		basePriority += 30
+	}
+	
 	return basePriority
 }
 
@@ -849,28 +1585,91 @@ func sortFilesByPriority(files []FileStatus) []FileStatus {
 // GitReader Interface Implementation
 // ============================================================================
 
+// DiffArgs 构造 git diff 参数，所有 diff 命令（包括 collector 包外的调用）都必须经由此函数：
+// --no-ext-diff 避免 diff.external 配置的外部工具替换输出，
+// --no-color 避免 color.ui=always 时 ANSI 颜色码混入 LLM 输入。
+func DiffArgs(args ...string) []string {
+	return append([]string{"diff", "--no-ext-diff", "--no-color"}, args...)
+}
+
 // StagedDiff returns staged changes (equivalent to `git diff --cached`)
 // Returns ErrNoDiff if no staged changes are found
 // Phase 3 Enhancement: Uses cached execution for better performance
 func (c *Collector) StagedDiff(ctx context.Context) (string, error) {
-	return c.executeDiffCommand(ctx, "git diff --cached failed", ErrNoDiff, "git", "diff", "--cached", "--no-ext-diff")
+	diff, err := c.executeDiffCommand(ctx, "git diff --cached failed", ErrNoDiff, "git", DiffArgs("--cached")...)
+	if err == nil || errors.Is(err, ErrNoDiff) {
+		return diff, err
+	}
+	// 仓库还没有 HEAD 时，与空树比较得到第一次提交的全部暂存内容
+	if initial, headErr := c.IsInitialCommit(ctx); headErr == nil && initial {
+		return c.executeDiffCommand(ctx, "git diff --cached failed", ErrNoDiff, "git", DiffArgs("--cached", emptyTreeHash)...)
+	}
+	return "", err
 }
 
-// UnstagedDiff returns unstaged changes (equivalent to `git diff`)
-// Returns empty string if no unstaged changes are found
-// Phase 3 Enhancement: Uses cached execution for better performance
+// emptyTreeHash 是空树对象的哈希（SHA-1 仓库），没有 HEAD 时作为 diff 的比较基准
+const emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// IsInitialCommit 报告仓库是否还没有任何提交（`git rev-parse --verify HEAD` 失败），
+// 即下一次提交是第一次提交
+func (c *Collector) IsInitialCommit(ctx context.Context) (bool, error) {
+	out, err := c.runWithCache(ctx, "git", "rev-parse", "--verify", "--quiet", "HEAD")
+	if err == nil {
+		return false, nil
+	}
+	if errors.Is(err, ErrNotGitRepository) || strings.Contains(string(out), "not a git repository") {
+		return false, ErrNotGitRepository
+	}
+	// --quiet 时 HEAD 不存在只以非零退出码表示，不输出内容
+	if strings.TrimSpace(string(out)) == "" {
+		return true, nil
+	}
+	return false, fmt.Errorf("git rev-parse HEAD failed: %w", err)
+}
+
+// UnstagedDiff returns unstaged changes (equivalent to `git diff`)
+// Returns empty string if no unstaged changes are found
+// Phase 3 Enhancement: Uses cached execution for better performance
 func (c *Collector) UnstagedDiff(ctx context.Context) (string, error) {
-	return c.executeDiffCommand(ctx, "git diff failed", nil, "git", "diff", "--no-ext-diff")
+	return c.executeDiffCommand(ctx, "git diff failed", nil, "git", DiffArgs()...)
+}
+
+// DiffStat returns the summary lines of `git diff --cached --stat` and `git diff --stat`,
+// labeled "staged:" and "unstaged:"; either line is omitted when that side has no changes
+func (c *Collector) DiffStat(ctx context.Context) (string, error) {
+	var lines []string
+	for _, side := range []struct {
+		label string
+		args  []string
+	}{
+		{"staged", DiffArgs("--cached", "--stat")},
+		{"unstaged", DiffArgs("--stat")},
+	} {
+		stat, err := c.executeDiffCommand(ctx, "git "+strings.Join(side.args, " ")+" failed", nil, "git", side.args...)
+		if err != nil {
+			return "", err
+		}
+		if stat == "" {
+			continue
+		}
+		// 最后一行是 "N files changed, X insertions(+), Y deletions(-)"
+		summary := stat[strings.LastIndex(stat, "\n")+1:]
+		lines = append(lines, side.label+": "+strings.TrimSpace(summary))
+	}
+	return strings.Join(lines, "\n"), nil
 }
 
 // executeDiffCommand is a helper function to reduce code duplication in diff operations
 func (c *Collector) executeDiffCommand(ctx context.Context, errMsg string, emptyErr error, name string, args ...string) (string, error) {
+	if c.ignoreSpace && len(args) > 0 && args[0] == "diff" {
+		args = append([]string{"diff", "--ignore-all-space"}, args[1:]...)
+	}
 	result, err := c.runWithCache(ctx, name, args...)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", errMsg, err)
 	}
 	
-	resultStr := strings.TrimSpace(string(result))
+	resultStr := strings.TrimSpace(stripANSI(string(result)))
 	if resultStr == "" && emptyErr != nil {
 		return "", emptyErr
 	}
@@ -882,7 +1681,174 @@ func (c *Collector) executeDiffCommand(ctx context.Context, errMsg string, empty
 // Format: XY filename, where X is index status, Y is worktree status
 // Phase 3 Enhancement: Uses cached execution for better performance
 func (c *Collector) GitStatus(ctx context.Context) (string, error) {
-	return c.executeDiffCommand(ctx, "git status --porcelain failed", nil, "git", "status", "--porcelain")
+	return c.executeDiffCommand(ctx, "git status --porcelain failed", nil, "git", c.statusArgs("--porcelain")...)
+}
+
+// DiffAgainstRef returns committed changes since the merge base with baseRef
+// (equivalent to `git diff <baseRef>...HEAD`), ordered by file priority.
+// Returns ErrNoDiff if the branch has no changes against baseRef
+func (c *Collector) DiffAgainstRef(ctx context.Context, baseRef string) (string, error) {
+	if err := validateRef(baseRef); err != nil {
+		return "", err
+	}
+	
+	diff, err := c.executeDiffCommand(ctx, "git diff "+baseRef+"...HEAD failed", ErrNoDiff, "git", DiffArgs(baseRef+"...HEAD")...)
+	if err != nil {
+		return "", err
+	}
+	
+	sections := c.dropSkippedSections(ctx, splitDiffByFile(diff))
+	return strings.TrimSpace(strings.Join(c.orderDiffSections(sections), "\n\n")), nil
+}
+
+// DiffStatAgainstRef summarizes `git diff --numstat <baseRef>...HEAD` as
+// "5 files changed, +120/-30" for pull request descriptions. Excluded files are not
+// counted, and binary files count as changed without added or deleted lines.
+// Returns ErrNoDiff if the branch has no changes against baseRef
+func (c *Collector) DiffStatAgainstRef(ctx context.Context, baseRef string) (string, error) {
+	if err := validateRef(baseRef); err != nil {
+		return "", err
+	}
+	
+	out, err := c.executeDiffCommand(ctx, "git diff --numstat "+baseRef+"...HEAD failed", ErrNoDiff, "git", DiffArgs("--numstat", baseRef+"...HEAD")...)
+	if err != nil {
+		return "", err
+	}
+	
+	files, added, deleted := 0, 0, 0
+	for _, line := range strings.Split(out, "\n") {
+		// <added>\t<deleted>\t<path>，二进制文件的行数为 "-"
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || c.isExcluded(fields[2]) {
+			continue
+		}
+		files++
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			added += n
+		}
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			deleted += n
+		}
+	}
+	if files == 0 {
+		return "", ErrNoDiff
+	}
+	noun := "files"
+	if files == 1 {
+		noun = "file"
+	}
+	return fmt.Sprintf("%d %s changed, +%d/-%d", files, noun, added, deleted), nil
+}
+
+// CommitsOnBranch returns the subjects of the commits in baseRef..HEAD, oldest first,
+// so a PR description can summarize the whole branch
+func (c *Collector) CommitsOnBranch(ctx context.Context, baseRef string) ([]string, error) {
+	if err := validateRef(baseRef); err != nil {
+		return nil, err
+	}
+	
+	out, err := c.runner.Run(ctx, "git", "log", "--reverse", "--format=%s", baseRef+"..HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("git log %s..HEAD failed: %w", baseRef, err)
+	}
+	
+	var subjects []string
+	for _, line := range strings.Split(stripANSI(string(out)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects, nil
+}
+
+// sinceDurationPattern 匹配 --since 的时长写法，例如 12h、2d、1w
+var sinceDurationPattern = regexp.MustCompile(`^([1-9][0-9]*)([hdw])$`)
+
+// parseSinceDuration 解析 12h、2d、1w 形式的时长，不是时长时返回 false
+func parseSinceDuration(since string) (time.Duration, bool) {
+	m := sinceDurationPattern.FindStringSubmatch(since)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	unit := time.Hour
+	switch m[2] {
+	case "d":
+		unit = 24 * time.Hour
+	case "w":
+		unit = 7 * 24 * time.Hour
+	}
+	return time.Duration(n) * unit, true
+}
+
+// ResolveSince resolves a --since value to a ref usable with DiffAgainstRef and
+// CommitsOnBranch. A duration such as 12h, 2d or 1w selects the commits from
+// `git log --since` and returns the parent of the oldest one; anything else is
+// treated as a ref (for example a tag) and returned as is.
+// Returns ErrNoDiff if no commit falls within the duration
+func (c *Collector) ResolveSince(ctx context.Context, since string) (string, error) {
+	d, ok := parseSinceDuration(since)
+	if !ok {
+		if err := validateRef(since); err != nil {
+			return "", err
+		}
+		return since, nil
+	}
+	
+	cutoff := time.Now().Add(-d).Format(time.RFC3339)
+	out, err := c.runner.Run(ctx, "git", "log", "--since="+cutoff, "--reverse", "--format=%H", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git log --since failed: %w", err)
+	}
+	fields := strings.Fields(stripANSI(string(out)))
+	if len(fields) == 0 {
+		return "", ErrNoDiff
+	}
+	
+	parent, err := c.runner.Run(ctx, "git", "rev-parse", "--verify", "--quiet", fields[0]+"^")
+	if err != nil {
+		// 最早的提交是根提交，无法取得父提交作为比较基准
+		return "", fmt.Errorf("every commit is newer than %s; pass a ref such as a tag instead", since)
+	}
+	return strings.TrimSpace(string(parent)), nil
+}
+
+// validateRef 校验用户提供的 ref，拒绝可能被 git 解析为选项的值
+func validateRef(ref string) error {
+	if !validBranchName.MatchString(ref) || strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid ref: %s", sanitizeOutput(ref))
+	}
+	return nil
+}
+
+// parseNameStatus 解析 git diff --name-status 的输出，例如
+// "M\tpath"、"A\tpath" 与 "R100\told\tnew"
+func parseNameStatus(output string) []FileStatus {
+	var files []FileStatus
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		
+		status := FileStatus{
+			IndexStatus: rune(fields[0][0]),
+			WorkStatus:  ' ',
+			Path:        sanitizeOutput(fields[len(fields)-1]),
+		}
+		if (status.IndexStatus == 'R' || status.IndexStatus == 'C') && len(fields) >= 3 {
+			status.IsRenamed = status.IndexStatus == 'R'
+			status.OldPath = sanitizeOutput(fields[1])
+		}
+		
+		if !shouldIgnoreFile(status.Path) {
+			files = append(files, status)
+		}
+	}
+	return files
 }
 
 // ============================================================================
@@ -897,12 +1863,6 @@ func (c *Collector) AnalyzeChanges(ctx context.Context) (*ChangesSummary, error)
 		return nil, fmt.Errorf("failed to get file status summary: %w", err)
 	}
 	
-	changes := &ChangesSummary{
-		ChangeTypes: make(map[string]int),
-		AffectedAreas: []string{},
-		UntrackedFiles: []FileStatus{},
-	}
-	
 	// Get untracked files
 	untrackedFiles, err := c.UntrackedFiles(ctx)
 	if err != nil {
@@ -910,6 +1870,7 @@ func (c *Collector) AnalyzeChanges(ctx context.Context) (*ChangesSummary, error)
 	}
 	
 	// Process untracked files
+	untracked := make([]FileStatus, 0, len(untrackedFiles))
 	for _, untrackedPath := range untrackedFiles {
 		untrackedFileStatus := FileStatus{
 			Path:        untrackedPath,
@@ -917,14 +1878,121 @@ func (c *Collector) AnalyzeChanges(ctx context.Context) (*ChangesSummary, error)
 			WorkStatus:  '?',
 			IsUntracked: true,
 		}
+		// Untracked files have no diff stat, so record their on-disk size
+		if size, ok := c.fileSize(ctx, untrackedPath); ok {
+			untrackedFileStatus.FileSize = size
+		}
 		
 		// Enhance with metadata
 		untrackedFileStatus = c.enhanceFileStatus(untrackedFileStatus)
-		changes.UntrackedFiles = append(changes.UntrackedFiles, untrackedFileStatus)
+		untracked = append(untracked, untrackedFileStatus)
+	}
+	
+	changes := c.summarizeChanges(ctx, summary.Files, untracked)
+	// 暂存区与工作区的权限变更都可能进入本次提交
+	modes := c.modeChanges(ctx, "--cached")
+	if !c.stagedOnly {
+		modes = append(modes, c.modeChanges(ctx)...)
+	}
+	c.applyModeChanges(changes, modes)
+	return changes, nil
+}
+
+// AnalyzeChangesAgainstRef summarizes committed changes since the merge base with baseRef
+// File statuses come from `git diff --name-status <baseRef>...HEAD`
+func (c *Collector) AnalyzeChangesAgainstRef(ctx context.Context, baseRef string) (*ChangesSummary, error) {
+	if err := validateRef(baseRef); err != nil {
+		return nil, err
+	}
+	
+	out, err := c.runWithCache(ctx, "git", DiffArgs("--name-status", baseRef+"...HEAD")...)
+	if err != nil {
+		return nil, fmt.Errorf("git diff --name-status %s...HEAD failed: %w", baseRef, err)
+	}
+	
+	changes := c.summarizeChanges(ctx, parseNameStatus(stripANSI(string(out))), []FileStatus{})
+	c.applyModeChanges(changes, c.modeChanges(ctx, baseRef+"...HEAD"))
+	return changes, nil
+}
+
+// isChangeStatus 报告 porcelain 状态字符是否表示一处改动（非空格、非未跟踪）
+func isChangeStatus(status rune) bool {
+	return status != ' ' && status != '?' && status != 0
+}
+
+// modeChanges 通过 `git diff --summary` 列出权限变更；命令失败时返回 nil，
+// 权限变更只用于辅助判断提交类型，不影响变更分析本身
+func (c *Collector) modeChanges(ctx context.Context, args ...string) []ModeChange {
+	out, err := c.runWithCache(ctx, "git", DiffArgs(append([]string{"--summary"}, args...)...)...)
+	if err != nil {
+		return nil
+	}
+	return ParseModeChanges(stripANSI(string(out)))
+}
+
+// ParseModeChanges 解析 `git diff --summary` 输出中的 "mode change 100644 => 100755 path" 行，
+// 以及 diff 扩展头中成对的 "old mode"/"new mode" 行
+func ParseModeChanges(output string) []ModeChange {
+	var changes []ModeChange
+	var pending ModeChange
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "mode change "):
+			// mode change 100644 => 100755 script.sh
+			fields := strings.SplitN(strings.TrimPrefix(line, "mode change "), " ", 4)
+			if len(fields) == 4 && fields[1] == "=>" {
+				changes = append(changes, ModeChange{Path: fields[3], OldMode: fields[0], NewMode: fields[2]})
+			}
+		case strings.HasPrefix(line, "diff --git "):
+			pending = ModeChange{}
+			if _, b, ok := strings.Cut(line, " b/"); ok {
+				pending.Path = b
+			}
+		case strings.HasPrefix(line, "old mode "):
+			pending.OldMode = strings.TrimPrefix(line, "old mode ")
+		case strings.HasPrefix(line, "new mode "):
+			pending.NewMode = strings.TrimPrefix(line, "new mode ")
+			if pending.Path != "" && pending.OldMode != "" {
+				changes = append(changes, pending)
+			}
+		}
+	}
+	return changes
+}
+
+// applyModeChanges 记录权限变更（按路径去重）；权限变更占变更文件多数时建议 mode 对应的前缀（默认 chore）
+func (c *Collector) applyModeChanges(changes *ChangesSummary, modes []ModeChange) {
+	seen := make(map[string]bool)
+	for _, mode := range modes {
+		if seen[mode.Path] {
+			continue
+		}
+		seen[mode.Path] = true
+		changes.ModeChanges = append(changes.ModeChanges, mode)
+	}
+	if len(changes.ModeChanges) > 0 && len(changes.ModeChanges)*2 > changes.TotalChangedFiles {
+		changes.SuggestedPrefix = c.prefixFor("mode")
+	}
+}
+
+// summarizeChanges builds a ChangesSummary from tracked and untracked file statuses
+func (c *Collector) summarizeChanges(ctx context.Context, tracked, untracked []FileStatus) *ChangesSummary {
+	changes := &ChangesSummary{
+		ChangeTypes: make(map[string]int),
+		AffectedAreas: []string{},
+		UntrackedFiles: untracked,
 	}
 	
 	// Analyze all files (tracked + untracked)
-	allFiles := append(summary.Files, changes.UntrackedFiles...)
+	allFiles := append(tracked, untracked...)
+	
+	// Flag generated files by their content header so they sort last
+	for i := range allFiles {
+		if allFiles[i].IndexStatus != 'D' && allFiles[i].WorkStatus != 'D' {
+			allFiles[i].IsGenerated = c.IsGeneratedFile(ctx, allFiles[i].Path)
+		}
+	}
 	
 	for _, file := range allFiles {
 		changes.TotalFiles++
@@ -944,6 +2012,11 @@ func (c *Collector) AnalyzeChanges(ctx context.Context) (*ChangesSummary, error)
 			changes.HasUntrackedFiles = true
 		}
 		
+		// 暂存区与工作区都有改动：只有已暂存的 hunk 会被提交
+		if !file.IsUntracked && isChangeStatus(file.IndexStatus) && isChangeStatus(file.WorkStatus) {
+			changes.PartiallyStaged = append(changes.PartiallyStaged, file.Path)
+		}
+		
 		// Categorize change types
 		switch file.IndexStatus {
 		case 'A':
@@ -982,7 +2055,7 @@ func (c *Collector) AnalyzeChanges(ctx context.Context) (*ChangesSummary, error)
 	// Sort files by priority
 	changes.FilesByPriority = c.sortFilesByPriorityEnhanced(allFiles)
 	
-	return changes, nil
+	return changes
 }
 
 // GetPriorityFiles returns files ordered by importance for commit message generation
@@ -1005,13 +2078,16 @@ func (c *Collector) GetPriorityFiles(ctx context.Context) ([]FileStatus, error)
 // Files are filtered to exclude build artifacts, dependencies, and binary files
 // Phase 3 Enhancement: Uses cached execution and optimized filtering
 func (c *Collector) UntrackedFiles(ctx context.Context) ([]string, error) {
-	untracked, err := c.runWithCache(ctx, "git", "ls-files", "--others", "--exclude-standard")
+	if c.noUntracked || c.amend || c.stagedOnly {
+		return []string{}, nil
+	}
+	// --full-name 使路径与 diff 一致，均相对于仓库根目录
+	untracked, err := c.runWithCache(ctx, "git", "ls-files", "--others", "--exclude-standard", "--full-name")
 	if err != nil {
 		return nil, fmt.Errorf("git ls-files --others --exclude-standard failed: %w", err)
 	}
 	
-	files := strings.Split(strings.TrimSpace(string(untracked)), "\n")
-	result := c.processAndFilterFiles(files)
+	result := c.processAndFilterFiles(splitLines(nil, untracked))
 	
 	return result, nil
 }
@@ -1019,10 +2095,10 @@ func (c *Collector) UntrackedFiles(ctx context.Context) ([]string, error) {
 // processAndFilterFiles is a helper function to process and filter file lists
 // Reduces code duplication across multiple methods
 func (c *Collector) processAndFilterFiles(files []string) []string {
-	var result []string
+	result := make([]string, 0, len(files))
 	
 	for _, file := range files {
-		if file != "" && !shouldIgnoreFile(file) {
+		if file != "" && !shouldIgnoreFile(file) && (len(c.only) == 0 || InPaths(file, c.only)) {
 			result = append(result, sanitizeOutput(file))
 		}
 	}
@@ -1046,7 +2122,7 @@ func (c *Collector) UntrackedFileContent(ctx context.Context, path string) (stri
 	}
 	
 	// Use head to limit file size (10KB = 10240 bytes, approximately 640 lines of 16 chars each)
-	content, err := c.runner.Run(ctx, "head", "-c", "10240", path)
+	content, err := c.runner.Run(ctx, "head", "-c", "10240", c.worktreePath(ctx, path))
 	if err != nil {
 		return "", fmt.Errorf("failed to read file %s: %w", path, err)
 	}
@@ -1054,6 +2130,64 @@ func (c *Collector) UntrackedFileContent(ctx context.Context, path string) (stri
 	return string(content), nil
 }
 
+// generatedHeaderBytes limits how much of a file is read to detect a generation banner
+const generatedHeaderBytes = 1024
+
+// generatedHeaderLines is the number of leading non-empty lines checked for a banner
+const generatedHeaderLines = 5
+
+var (
+	// goGeneratedMarker matches the standard Go marker, see https://go.dev/s/generatedcode
+	goGeneratedMarker = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+	// generatedBanners matches known codegen banners from other toolchains. The banner must open a
+	// comment line, so a hand-written "do not edit" note in an ordinary file is not a match:
+	//   /* @generated by relay-compiler */
+	//   # Generated by the protocol buffer compiler.  DO NOT EDIT!
+	//   # This file was automatically generated by SWIG
+	//    * Autogenerated by Thrift Compiler (0.9.3)
+	//   // <auto-generated />
+	generatedBanners = regexp.MustCompile(`(?i)^(?://+|#+|/\*+|\*|--|<!--)\s*(?:@generated\b|<auto-generated\b|generated by .+\bdo not edit\b|this file (?:is|was) (?:automatically |auto-?)generated\b|autogenerated by \S)`)
+)
+
+// isGeneratedContent reports whether the leading lines of content carry a
+// code generation banner such as "// Code generated ... DO NOT EDIT."
+func isGeneratedContent(content string) bool {
+	checked := 0
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if goGeneratedMarker.MatchString(line) || generatedBanners.MatchString(line) {
+			return true
+		}
+		checked++
+		if checked >= generatedHeaderLines {
+			break
+		}
+	}
+	return false
+}
+
+// IsGeneratedFile reads the head of path and reports whether it is a generated file.
+// The header is read in-process so that analyzing many files does not spawn a process per file.
+// path is relative to the repository root. Unreadable or ignored files are reported as not generated.
+func (c *Collector) IsGeneratedFile(ctx context.Context, path string) bool {
+	if sanitizeOutput(path) != path || shouldIgnoreFile(path) {
+		return false
+	}
+	f, err := os.Open(c.worktreePath(ctx, path))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, generatedHeaderBytes))
+	if err != nil {
+		return false
+	}
+	return isGeneratedContent(string(content))
+}
+
 // UntrackedFileAsDiff formats an untracked file's content as a diff-like output
 // This is essential for including untracked files in commit message generation
 func (c *Collector) UntrackedFileAsDiff(ctx context.Context, path string) (string, error) {
@@ -1085,28 +2219,62 @@ func (c *Collector) UntrackedFileAsDiff(ctx context.Context, path string) (strin
 // EnhancedDiffProvider Interface Implementation
 // ============================================================================
 
+// maxUntrackedReaders caps concurrent untracked file reads in ComprehensiveDiff
+const maxUntrackedReaders = 8
+
 // ComprehensiveDiff returns a complete diff including staged, unstaged, and untracked files
-// This is the primary method for getting all changes for commit message generation
+// This is the primary method for getting all changes for commit message generation.
+// File sections are ordered by sortFilesByPriorityEnhanced so that, when the prompt
+// budget truncates the diff, high-priority code changes are kept over trailing noise.
 func (c *Collector) ComprehensiveDiff(ctx context.Context) (string, error) {
-	var diffParts []string
+	return c.ComprehensiveDiffWithProgress(ctx, nil)
+}
+
+// ComprehensiveDiffWithProgress is ComprehensiveDiff reporting how many untracked
+// files have been read so far, e.g. to update a spinner label in large repositories.
+// progress may be nil; it is called from multiple goroutines and must not block.
+// progress is never called after ComprehensiveDiffWithProgress returns, even when a
+// cancelled ctx makes it return while untracked files are still being read.
+func (c *Collector) ComprehensiveDiffWithProgress(ctx context.Context, progress func(done, total int)) (string, error) {
+	if c.amend {
+		return c.amendDiff(ctx)
+	}
+	if progress != nil {
+		var mu sync.Mutex
+		stopped := false
+		report := progress
+		progress = func(done, total int) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !stopped {
+				report(done, total)
+			}
+		}
+		// 取消后仍在运行的读取协程不再回调；进行中的回调结束后才返回
+		defer func() {
+			mu.Lock()
+			stopped = true
+			mu.Unlock()
+		}()
+	}
+	var sections []diffSection
 	
 	// 1. Get staged diff
 	stagedDiff, err := c.StagedDiff(ctx)
 	if err != nil && !errors.Is(err, ErrNoDiff) {
 		return "", fmt.Errorf("failed to get staged diff: %w", err)
 	}
-	if stagedDiff != "" {
-		diffParts = append(diffParts, stagedDiff)
-	}
+	sections = append(sections, splitDiffByFile(stagedDiff)...)
 	
 	// 2. Get unstaged diff
-	unstagedDiff, err := c.UnstagedDiff(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get unstaged diff: %w", err)
-	}
-	if unstagedDiff != "" {
-		diffParts = append(diffParts, unstagedDiff)
+	if !c.stagedOnly {
+		unstagedDiff, err := c.UnstagedDiff(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get unstaged diff: %w", err)
+		}
+		sections = append(sections, splitDiffByFile(unstagedDiff)...)
 	}
+	sections = c.dropSkippedSections(ctx, sections)
 	
 	// 3. Get untracked files as diff - THIS IS THE CORE FIX
 	untrackedFiles, err := c.UntrackedFiles(ctx)
@@ -1114,21 +2282,60 @@ func (c *Collector) ComprehensiveDiff(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("failed to get untracked files: %w", err)
 	}
 	
-	// Convert untracked files to diff format
+	// Convert untracked files to diff format. Files are read concurrently with
+	// a bounded number of readers; results keep the sorted path order.
+	var candidates []string
 	for _, file := range untrackedFiles {
-		fileDiff, err := c.UntrackedFileAsDiff(ctx, file)
-		if err != nil {
-			// Skip files that can't be read, but log the issue
-			continue
+		if !c.skipFile(ctx, file) {
+			candidates = append(candidates, file)
 		}
-		diffParts = append(diffParts, fileDiff)
 	}
+	sort.Strings(candidates)
+	
+	batch := NewBatchGitOperations()
+	batch.SetMaxConcurrency(maxUntrackedReaders)
+	var read atomic.Int64
+	for _, file := range candidates {
+		batch.AddOperation(func(ctx context.Context) (interface{}, error) {
+			fileDiff, err := c.UntrackedFileAsDiff(ctx, file)
+			if progress != nil {
+				progress(int(read.Add(1)), len(candidates))
+			}
+			return fileDiff, err
+		})
+	}
+	if err := batch.ExecuteBatchContext(ctx); err != nil {
+		return "", err
+	}
+	results, errs := batch.GetResults()
 	
-	// 4. Combine all diffs
-	combined := strings.Join(diffParts, "\n\n")
+	for i, file := range candidates {
+		if errs[i] != nil {
+			// Skip files that can't be read, but log the issue
+			continue
+		}
+		fileDiff := results[i].(string)
+		sections = append(sections, diffSection{
+			status: FileStatus{
+				Path:        file,
+				IndexStatus: '?',
+				WorkStatus:  '?',
+				IsUntracked: true,
+				IsGenerated: isGeneratedDiff(strings.Split(fileDiff, "\n")),
+			},
+			text: fileDiff,
+		})
+	}
+	
+	// 4. Combine all diffs, most important files first
+	combined := strings.Join(c.orderDiffSections(sections), "\n\n")
 	combined = strings.TrimSpace(combined)
 	
 	if combined == "" {
+		if c.stagedOnly {
+			// 未暂存的变更不会被提交
+			return "", ErrNoDiff
+		}
 		// Check if there are any changes at all
 		status, err := c.GitStatus(ctx)
 		if err != nil {
@@ -1138,27 +2345,293 @@ func (c *Collector) ComprehensiveDiff(ctx context.Context) (string, error) {
 			return "", ErrNoDiff
 		}
 		// Return status if no diff but there are changes
-		return status, nil
+		if c.ignoreSpace {
+			return annotateWhitespaceOnly(status), nil
+		}
+		return annotateNoTextualDiff(status), nil
+	}
+	
+	// 5. Optionally prepend the stat overview; untracked files are not counted by git --stat
+	if c.statHeader {
+		if stat, err := c.DiffStat(ctx); err == nil && stat != "" {
+			combined = diffStatHeader + "\n" + stat + "\n\n" + combined
+		}
 	}
 	
 	return combined, nil
 }
 
+// diffStatHeader 标注 ComprehensiveDiff 开头的统计行
+const diffStatHeader = "Diff stat:"
+
+// noTextualDiffHeader 说明 status 回退中的文件没有文本差异
+const noTextualDiffHeader = "Changed files without a textual diff:"
+
+// annotateNoTextualDiff 为 status 回退中的已修改文件标注没有文本差异。
+// 例如只改动了被 git 规范化掉的行尾空白、换行符或文件权限，文件出现在 git status 中却没有 diff；
+// 明确标注后模型可以写出 "chore: fix whitespace" 一类准确的 message，而不是猜测改动内容。
+// 新增、删除、未跟踪等条目缺少 diff 另有原因，只要存在这类条目就原样返回 status。
+func annotateNoTextualDiff(status string) string {
+	for _, line := range strings.Split(strings.Trim(status, "\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) != "" && !isModifiedStatusLine(line) {
+			return status
+		}
+	}
+	return annotateStatus(status, noTextualDiffHeader, "whitespace/permission-only change")
+}
+
+// isModifiedStatusLine 报告 porcelain 行是否只表示内容或类型修改（M/T），不含新增、删除、重命名等
+func isModifiedStatusLine(line string) bool {
+	if len(line) < 3 {
+		return false
+	}
+	modified := false
+	for _, code := range line[:2] {
+		switch code {
+		case 'M', 'T':
+			modified = true
+		case ' ':
+		default:
+			return false
+		}
+	}
+	return modified
+}
+
+// whitespaceOnlyHeader 说明忽略空白后 status 回退中的文件只有空白变更
+const whitespaceOnlyHeader = "Changed files with whitespace-only changes (the diff ignores whitespace):"
+
+// annotateWhitespaceOnly 为 --ignore-all-space 下的 status 回退标注仅空白变更，
+// 让模型写出 "style: fix indentation" 一类的 message
+func annotateWhitespaceOnly(status string) string {
+	return annotateStatus(status, whitespaceOnlyHeader, "whitespace-only change")
+}
+
+// annotateStatus 以 header 开头，为 status 的每一行附加 "(note)"
+func annotateStatus(status, header, note string) string {
+	lines := []string{header}
+	// 只去掉换行：porcelain 行首的空格表示未暂存，是状态的一部分
+	for _, line := range strings.Split(strings.Trim(status, "\n"), "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line+" ("+note+")")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffSection is the part of a diff that belongs to a single file
+type diffSection struct {
+	status FileStatus // Status derived from the diff header; Path is empty for text without a header
+	text   string     // Raw diff text for this file
+}
+
+// splitDiffByFile splits unified diff output into per-file sections at each
+// "diff --git" header. The change type is inferred from the extended header
+// lines so that sections can be prioritized without an extra git status call.
+func splitDiffByFile(diff string) []diffSection {
+	diff = strings.TrimSpace(diff)
+	if diff == "" {
+		return nil
+	}
+	
+	var sections []diffSection
+	var current []string
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		sections = append(sections, diffSection{
+			status: diffSectionStatus(current),
+			text:   strings.Join(current, "\n"),
+		})
+		current = nil
+	}
+	
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+		}
+		current = append(current, line)
+	}
+	flush()
+	
+	return sections
+}
+
+// diffSectionStatus derives a FileStatus from a section's "diff --git" header
+func diffSectionStatus(lines []string) FileStatus {
+	header := lines[0]
+	if !strings.HasPrefix(header, "diff --git ") {
+		return FileStatus{}
+	}
+	
+	status := FileStatus{IndexStatus: 'M', IsGenerated: isGeneratedDiff(lines)}
+	if idx := strings.LastIndex(header, " b/"); idx != -1 {
+		status.Path = header[idx+3:]
+	}
+	for _, line := range lines[1:] {
+		switch {
+		case strings.HasPrefix(line, "new file mode"):
+			status.IndexStatus = 'A'
+		case strings.HasPrefix(line, "deleted file mode"):
+			status.IndexStatus = 'D'
+		case strings.HasPrefix(line, "rename from "):
+			status.IndexStatus = 'R'
+			status.IsRenamed = true
+			status.OldPath = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "@@"):
+			// Extended headers end at the first hunk
+			return status
+		}
+	}
+	return status
+}
+
+// isGeneratedDiff checks the top of the new file version, as far as the diff
+// shows it, for a code generation banner. Only content from a hunk starting at
+// line 1 (or from an untracked file's synthetic diff) is considered.
+func isGeneratedDiff(lines []string) bool {
+	var head []string
+	inTop := false
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			inTop = true
+			continue
+		case strings.HasPrefix(line, "@@"):
+			if len(head) > 0 {
+				return isGeneratedContent(strings.Join(head, "\n"))
+			}
+			inTop = strings.Contains(line, " +1,") || strings.Contains(line, " +1 ")
+			continue
+		}
+		if !inTop || line == "" || line[0] == '-' {
+			continue
+		}
+		head = append(head, line[1:])
+		if len(head) >= generatedHeaderLines {
+			break
+		}
+	}
+	return isGeneratedContent(strings.Join(head, "\n"))
+}
+
+// dropSkippedSections removes sections of excluded files and of files larger
+// than maxFileSize. Each oversized file is reported once even if it has several sections.
+func (c *Collector) dropSkippedSections(ctx context.Context, sections []diffSection) []diffSection {
+	if c.maxFileSize <= 0 && len(c.exclude) == 0 && len(c.only) == 0 {
+		return sections
+	}
+	
+	skipped := make(map[string]bool)
+	kept := sections[:0]
+	for _, section := range sections {
+		path := section.status.Path
+		if path != "" {
+			if _, checked := skipped[path]; !checked {
+				skipped[path] = c.skipFile(ctx, path)
+			}
+			if skipped[path] {
+				continue
+			}
+		}
+		kept = append(kept, section)
+	}
+	return kept
+}
+
+// orderDiffSections orders sections by file priority while keeping all sections
+// of the same file (e.g. staged and unstaged) together in their original order.
+// Text without a recognizable header is kept first, as-is.
+func (c *Collector) orderDiffSections(sections []diffSection) []string {
+	var ordered []string
+	var files []FileStatus
+	byPath := make(map[string][]string)
+	
+	for _, section := range sections {
+		text := section.text
+		if c.collapseDel && section.status.IndexStatus == 'D' {
+			text = collapseDeletion(text, section.status.Path)
+		}
+		text = elideLongLines(text, c.maxLineLen)
+		if section.status.Path == "" {
+			ordered = append(ordered, text)
+			continue
+		}
+		if _, seen := byPath[section.status.Path]; !seen {
+			files = append(files, section.status)
+		}
+		byPath[section.status.Path] = append(byPath[section.status.Path], text)
+	}
+	
+	for _, file := range c.sortFilesByPriorityEnhanced(files) {
+		ordered = append(ordered, byPath[file.Path]...)
+	}
+	
+	return ordered
+}
+
+// collapseDeletion 保留已删除文件 diff 的头部，将其后的内容替换为一行摘要；
+// 没有文本内容的删除（如二进制文件）原样返回
+func collapseDeletion(text, path string) string {
+	lines := strings.Split(text, "\n")
+	header := len(lines)
+	removed := 0
+	for i, line := range lines {
+		if header == len(lines) && (strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "@@")) {
+			header = i
+		}
+		if i > header && strings.HasPrefix(line, "-") {
+			removed++
+		}
+	}
+	if removed == 0 {
+		return text
+	}
+	return strings.Join(lines[:header], "\n") + fmt.Sprintf("\ndeleted file %s (%d lines)", path, removed)
+}
+
+// elideLongLines 将超过 limit 个字符的行替换为 "<long line elided, N chars>"，
+// 保留 diff 行首的 "+"、"-" 或空格标记；limit 不大于 0 时原样返回
+func elideLongLines(text string, limit int) string {
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		// 字节数不超过 limit 时字符数必然不超过，避免逐行计数
+		if len(line) <= limit {
+			continue
+		}
+		n := utf8.RuneCountInString(line)
+		if n <= limit {
+			continue
+		}
+		marker := ""
+		if line[0] == '+' || line[0] == '-' || line[0] == ' ' {
+			marker = line[:1]
+		}
+		lines[i] = fmt.Sprintf("%s<long line elided, %d chars>", marker, n)
+	}
+	return strings.Join(lines, "\n")
+}
+
 // CombinedDiff returns staged and unstaged diffs combined (legacy behavior)
 func (c *Collector) CombinedDiff(ctx context.Context) (string, error) {
-	// --no-ext-diff 避免外部 diff 工具干扰，--cached 获取 staged diff。
-	staged, err := c.runner.Run(ctx, "git", "diff", "--cached", "--no-ext-diff")
+	// --cached 获取 staged diff。
+	staged, err := c.runner.Run(ctx, "git", DiffArgs("--cached")...)
 	if err != nil {
 		return "", fmt.Errorf("git diff --cached failed: %w", err)
 	}
 
 	// 未暂存的改动。
-	unstaged, err := c.runner.Run(ctx, "git", "diff", "--no-ext-diff")
+	unstaged, err := c.runner.Run(ctx, "git", DiffArgs()...)
 	if err != nil {
 		return "", fmt.Errorf("git diff failed: %w", err)
 	}
 
-	combined := string(staged) + string(unstaged)
+	combined := stripANSI(string(staged) + string(unstaged))
 	combined = strings.TrimSpace(combined)
 	if combined == "" {
 		// 可能是新文件删除等导致 diff 为空，检查 git status
@@ -1166,7 +2639,7 @@ func (c *Collector) CombinedDiff(ctx context.Context) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("git status --porcelain failed: %w", err)
 		}
-		statusStr := strings.TrimSpace(string(status))
+		statusStr := strings.TrimSpace(stripANSI(string(status)))
 		if statusStr == "" {
 			return "", ErrNoDiff
 		}
@@ -1206,13 +2679,13 @@ func (c *Collector) enhanceFileStatus(status FileStatus) FileStatus {
 	
 	// Determine content type
 	status.ContentType = c.determineContentType(status.Path)
+	if status.IsGenerated {
+		status.ContentType = "generated"
+	}
 	
 	// Determine affected area
 	status.AffectedArea = c.determineAffectedArea(status.Path)
 	
-	// For untracked files, we could get file size, but it's optional
-	// status.FileSize = c.getFileSize(status.Path)
-	
 	return status
 }
 
@@ -1294,22 +2767,56 @@ func (c *Collector) calculatePriority(changes *ChangesSummary) int {
 	return priority
 }
 
+// DefaultPrefixMap maps the kind of change that dominates a changeset to the
+// suggested commit prefix. Keys: added (including untracked files), deleted,
+// renamed, modified, mode (permission-only changes) and default
+var DefaultPrefixMap = map[string]string{
+	"added":    "feat",
+	"deleted":  "chore",
+	"renamed":  "refactor",
+	"modified": "fix",
+	"mode":     "chore",
+	"default":  "chore",
+}
+
+// ResolvePrefixMap 返回以 overrides 覆盖 DefaultPrefixMap 后的映射，忽略空值
+func ResolvePrefixMap(overrides map[string]string) map[string]string {
+	resolved := make(map[string]string, len(DefaultPrefixMap))
+	for kind, prefix := range DefaultPrefixMap {
+		resolved[kind] = prefix
+	}
+	for kind, prefix := range overrides {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			resolved[kind] = prefix
+		}
+	}
+	return resolved
+}
+
+// prefixFor 返回某类变更对应的提交前缀，未配置时使用 DefaultPrefixMap
+func (c *Collector) prefixFor(kind string) string {
+	if prefix, ok := c.prefixMap[kind]; ok {
+		return prefix
+	}
+	return DefaultPrefixMap[kind]
+}
+
 // determineSuggestedPrefix suggests a commit prefix based on changes
 func (c *Collector) determineSuggestedPrefix(changes *ChangesSummary) string {
 	if changes.ChangeTypes["added"] > 0 || changes.ChangeTypes["untracked"] > 0 {
-		return "feat"
+		return c.prefixFor("added")
 	}
 	if changes.ChangeTypes["deleted"] > 0 {
-		return "chore"
+		return c.prefixFor("deleted")
 	}
 	if changes.ChangeTypes["renamed"] > 0 {
-		return "refactor"
+		return c.prefixFor("renamed")
 	}
 	if changes.ChangeTypes["modified"] > 0 {
 		// Check if it's likely a bug fix or improvement
-		return "fix"
+		return c.prefixFor("modified")
 	}
-	return "chore"
+	return c.prefixFor("default")
 }
 
 // sortFilesByPriorityEnhanced sorts files with enhanced priority logic
@@ -1329,7 +2836,7 @@ func (c *Collector) sortFilesByPriorityEnhanced(files []FileStatus) []FileStatus
 		
 		// Then by content type importance
 		typeOrder := map[string]int{
-			"code": 1, "config": 2, "frontend": 3, "docs": 4, "test": 5, "other": 6,
+			"code": 1, "config": 2, "frontend": 3, "docs": 4, "test": 5, "other": 6, "generated": 7,
 		}
 		orderI := typeOrder[enhanced[i].ContentType]
 		orderJ := typeOrder[enhanced[j].ContentType]
@@ -3,19 +3,30 @@ package collector
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/penwyp/catmit/collector/collectortest"
 	"github.com/stretchr/testify/require"
 )
 
 // mockRunner 用于单元测试，按调用顺序返回预设结果。
 type mockRunner struct {
+	mu      sync.Mutex
 	outputs [][]byte
 	errs    []error
 	idx     int
 }
 
 func (m *mockRunner) Run(_ context.Context, _ string, _ ...string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.idx >= len(m.outputs) {
 		return nil, errors.New("unexpected call")
 	}
@@ -97,6 +108,25 @@ func TestCollector_Diff(t *testing.T) {
 		require.Equal(t, "M  file.txt\nA  newfile.txt", diff)
 	})
 
+	t.Run("modified_without_textual_diff", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{
+				[]byte(""),                          // StagedDiff (returns ErrNoDiff)
+				[]byte(""),                          // UnstagedDiff
+				[]byte(""),                          // UntrackedFiles
+				[]byte("M  run.sh\n M file.txt\n"), // GitStatus (fallback)
+			},
+			errs: []error{ErrNoDiff, nil, nil, nil},
+		}
+		c := New(mr)
+		diff, err := c.Diff(context.Background())
+		require.NoError(t, err)
+		// 只有修改但没有文本差异的文件被标注，模型不必猜测改动内容
+		require.Equal(t, "Changed files without a textual diff:\n"+
+			"M  run.sh (whitespace/permission-only change)\n"+
+			" M file.txt (whitespace/permission-only change)", diff)
+	})
+
 	t.Run("git_status_command_fails", func(t *testing.T) {
 		mr := &mockRunner{
 			// ComprehensiveDiff calls: StagedDiff, UnstagedDiff, UntrackedFiles, GitStatus (fails)
@@ -132,6 +162,215 @@ func TestCollector_ChangedFiles(t *testing.T) {
 		require.NoError(t, err)
 		require.ElementsMatch(t, []string{"a.go", "b.go"}, files)
 	})
+
+	t.Run("skip_untracked", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{[]byte("a.go\nb.go\n")},
+			errs:    []error{nil},
+		}
+		c := New(mr)
+		c.SetSkipUntracked(true)
+		files, err := c.ChangedFiles(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"a.go", "b.go"}, files)
+		require.Equal(t, 1, mr.idx) // 不调用 git ls-files
+
+		untracked, err := c.UntrackedFiles(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, untracked)
+		require.Equal(t, 1, mr.idx)
+	})
+}
+
+func TestStripANSI(t *testing.T) {
+	clean := "diff --git a/a.go b/a.go\n+package a"
+	require.Equal(t, clean, stripANSI(clean))
+
+	colored := "\x1b[1mdiff --git a/a.go b/a.go\x1b[m\n\x1b[32m+package a\x1b[m\n\x1b[31m-package b\x1b[0;1m\x1b]8;;http://x\x07"
+	require.Equal(t, "diff --git a/a.go b/a.go\n+package a\n-package b", stripANSI(colored))
+}
+
+func TestCollector_StagedDiff_StripsColor(t *testing.T) {
+	t.Parallel()
+
+	mr := &mockRunner{
+		outputs: [][]byte{[]byte("\x1b[1mdiff --git a/a.go b/a.go\x1b[m\n\x1b[31m-old\x1b[m\n\x1b[32m+new\x1b[m\n")},
+		errs:    []error{nil},
+	}
+	diff, err := New(mr).StagedDiff(context.Background())
+	require.NoError(t, err)
+	require.NotContains(t, diff, "\x1b")
+	require.Equal(t, "diff --git a/a.go b/a.go\n-old\n+new", diff)
+}
+
+func TestSplitLines(t *testing.T) {
+	require.Empty(t, splitLines(nil, nil))
+	require.Equal(t, []string{"a.go", "b.go"}, splitLines(nil, []byte("a.go\n\nb.go")))
+	require.Equal(t, []string{"x", "a.go"}, splitLines([]string{"x"}, []byte("a.go\n")))
+	require.Equal(t, 3, countLines([]byte("a\nb\n")))
+}
+
+// fileListRunner 按 git 子命令返回固定输出，可重复调用，用于基准测试
+type fileListRunner struct {
+	staged, untracked []byte
+}
+
+func (r fileListRunner) Run(_ context.Context, _ string, args ...string) ([]byte, error) {
+	if len(args) > 0 && args[0] == "ls-files" {
+		return r.untracked, nil
+	}
+	return r.staged, nil
+}
+
+// BenchmarkChangedFiles 模拟 10k 文件的大型仓库：5k 个暂存文件与 5k 个未跟踪文件
+func BenchmarkChangedFiles(b *testing.B) {
+	var staged, untracked strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&staged, "pkg/module%03d/file%d.go\n", i%100, i)
+		fmt.Fprintf(&untracked, "tmp/gen%03d/new%d.go\n", i%100, i)
+	}
+	runner := fileListRunner{staged: []byte(staged.String()), untracked: []byte(untracked.String())}
+	ctx := context.Background()
+
+	for _, skip := range []bool{false, true} {
+		name := "with_untracked"
+		if skip {
+			name = "no_untracked"
+		}
+		b.Run(name, func(b *testing.B) {
+			c := New(runner)
+			c.SetSkipUntracked(skip)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.ClearCache()
+				if _, err := c.ChangedFiles(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestCollector_RepoRoot(t *testing.T) {
+	t.Parallel()
+
+	t.Run("worktree_root", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{[]byte("/src/catmit-wt/\n")},
+			errs:    []error{nil},
+		}
+		c := New(mr)
+		root, err := c.RepoRoot(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "/src/catmit-wt", root)
+	})
+
+	t.Run("memoized", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{[]byte("/src/catmit\n")},
+			errs:    []error{nil},
+		}
+		c := NewWithCache(mr, 0)
+		for i := 0; i < 3; i++ {
+			root, err := c.RepoRoot(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, "/src/catmit", root)
+		}
+		require.Equal(t, 1, mr.idx)
+	})
+
+	t.Run("not_a_repository", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{nil},
+			errs:    []error{errors.New("fatal: not a git repository")},
+		}
+		c := New(mr)
+		_, err := c.RepoRoot(context.Background())
+		require.ErrorIs(t, err, ErrNotGitRepository)
+	})
+}
+
+func TestCollector_CacheTTL(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name string
+		ttl  time.Duration
+		runs int
+	}{
+		{"cached", time.Minute, 1},
+		{"disabled", 0, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := collectortest.NewRecordingRunner().On("git status --porcelain", " M a.go", nil)
+			c := NewWithCache(runner, tt.ttl)
+			for i := 0; i < 3; i++ {
+				out, err := c.runWithCache(context.Background(), "git", "status", "--porcelain")
+				require.NoError(t, err)
+				require.Equal(t, " M a.go", string(out))
+			}
+			require.Len(t, runner.Calls(), tt.runs)
+		})
+	}
+}
+
+func TestCollector_RecentCommits_NoCommitsYet(t *testing.T) {
+	t.Parallel()
+
+	mr := &mockRunner{
+		outputs: [][]byte{[]byte("fatal: your current branch 'main' does not have any commits yet\n")},
+		errs:    []error{errors.New("exit status 128")},
+	}
+	commits, err := New(mr).RecentCommits(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{}, commits)
+
+	// 其他 git log 错误仍然返回
+	mr = &mockRunner{
+		outputs: [][]byte{[]byte("fatal: bad revision\n")},
+		errs:    []error{errors.New("exit status 128")},
+	}
+	_, err = New(mr).RecentCommits(context.Background(), 10)
+	require.ErrorContains(t, err, "git log failed")
+}
+
+func TestCollector_IsInitialCommit(t *testing.T) {
+	t.Parallel()
+
+	mr := &mockRunner{outputs: [][]byte{[]byte("0123456789abcdef\n")}, errs: []error{nil}}
+	initial, err := New(mr).IsInitialCommit(context.Background())
+	require.NoError(t, err)
+	require.False(t, initial)
+
+	mr = &mockRunner{outputs: [][]byte{nil}, errs: []error{errors.New("exit status 1")}}
+	initial, err = New(mr).IsInitialCommit(context.Background())
+	require.NoError(t, err)
+	require.True(t, initial)
+
+	mr = &mockRunner{
+		outputs: [][]byte{[]byte("fatal: not a git repository (or any of the parent directories): .git\n")},
+		errs:    []error{errors.New("exit status 128")},
+	}
+	_, err = New(mr).IsInitialCommit(context.Background())
+	require.ErrorIs(t, err, ErrNotGitRepository)
+}
+
+func TestCollector_StagedDiff_InitialCommit(t *testing.T) {
+	t.Parallel()
+
+	mr := &recordingRunner{mockRunner: mockRunner{
+		outputs: [][]byte{
+			[]byte("fatal: bad revision 'HEAD'\n"),
+			nil,
+			[]byte("diff --git a/main.go b/main.go\n+package main"),
+		},
+		errs: []error{errors.New("exit status 128"), errors.New("exit status 1"), nil},
+	}}
+	diff, err := New(mr).StagedDiff(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "diff --git a/main.go b/main.go\n+package main", diff)
+	require.Equal(t, []string{"git", "diff", "--no-ext-diff", "--no-color", "--cached", emptyTreeHash}, mr.calls[2])
 }
 
 func TestCollector_BranchName(t *testing.T) {
@@ -159,6 +398,17 @@ func TestCollector_BranchName(t *testing.T) {
 		require.Equal(t, "feature/awesome-feature", branch)
 	})
 
+	t.Run("detached_head", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{[]byte("HEAD\n")},
+			errs:    []error{nil},
+		}
+		c := New(mr)
+		branch, err := c.BranchName(context.Background())
+		require.ErrorIs(t, err, ErrDetachedHead)
+		require.Empty(t, branch)
+	})
+
 	t.Run("invalid_branch_name", func(t *testing.T) {
 		mr := &mockRunner{
 			outputs: [][]byte{[]byte("main;rm -rf /\n")},
@@ -182,6 +432,99 @@ func TestCollector_BranchName(t *testing.T) {
 	})
 }
 
+func TestCollector_BranchDescription(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set", func(t *testing.T) {
+		mr := &recordingRunner{mockRunner: mockRunner{
+			outputs: [][]byte{[]byte("feature/login\n"), []byte("Add SSO login for enterprise tenants\n")},
+			errs:    []error{nil, nil},
+		}}
+		description, err := New(mr).BranchDescription(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "Add SSO login for enterprise tenants", description)
+		require.Equal(t, []string{"git", "config", "--get", "branch.feature/login.description"}, mr.calls[1])
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{[]byte("main\n"), nil},
+			errs:    []error{nil, errors.New("exit status 1")},
+		}
+		description, err := New(mr).BranchDescription(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, description)
+	})
+
+	t.Run("detached_head", func(t *testing.T) {
+		mr := &mockRunner{outputs: [][]byte{[]byte("HEAD\n")}, errs: []error{nil}}
+		description, err := New(mr).BranchDescription(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, description)
+	})
+}
+
+func TestParseRemoteOwner(t *testing.T) {
+	t.Parallel()
+
+	for url, want := range map[string]string{
+		"git@github.com:alice/catmit.git":        "alice",
+		"https://github.com/alice/catmit.git":    "alice",
+		"https://github.com/alice/catmit":        "alice",
+		"ssh://git@github.com/alice/catmit.git":  "alice",
+		"https://gitlab.com/group/alice/catmit/": "alice",
+		"/srv/git/catmit.git":                    "",
+		"https://github.com/catmit":              "",
+	} {
+		require.Equal(t, want, parseRemoteOwner(url), url)
+	}
+}
+
+func TestCollector_RemoteOwner(t *testing.T) {
+	t.Parallel()
+
+	r := collectortest.NewRecordingRunner().On("git remote get-url fork", "git@github.com:alice/catmit.git\n", nil)
+	owner, err := New(r).RemoteOwner(context.Background(), "fork")
+	require.NoError(t, err)
+	require.Equal(t, "alice", owner)
+
+	_, err = New(r).RemoteOwner(context.Background(), "-x;rm")
+	require.Error(t, err)
+}
+
+func TestCollector_DefaultBranch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("develop_default", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{[]byte("origin/develop\n")},
+			errs:    []error{nil},
+		}
+		c := New(mr)
+		branch, err := c.DefaultBranch(context.Background(), "origin")
+		require.NoError(t, err)
+		require.Equal(t, "develop", branch)
+	})
+
+	t.Run("no_remote_head", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{nil},
+			errs:    []error{errors.New("fatal: ref refs/remotes/origin/HEAD is not a symbolic ref")},
+		}
+		c := New(mr)
+		_, err := c.DefaultBranch(context.Background(), "origin")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "git symbolic-ref failed")
+	})
+
+	t.Run("invalid_remote", func(t *testing.T) {
+		c := New(&mockRunner{})
+		_, err := c.DefaultBranch(context.Background(), "origin;rm")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid remote name")
+	})
+}
+
 func TestCollector_RecentCommits_Security(t *testing.T) {
 	t.Parallel()
 
@@ -235,6 +578,11 @@ func TestSanitizeOutput(t *testing.T) {
 			input:    "file|dangerous",
 			expected: "filedangerous",
 		},
+		{
+			name:     "unicode_and_c1_control",
+			input:    "文档/说明\u0085.md",
+			expected: "文档/说明.md",
+		},
 	}
 
 	for _, tt := range tests {
@@ -325,6 +673,13 @@ func TestParseGitStatusPorcelain(t *testing.T) {
 		require.Empty(t, summary.Files)
 	})
 
+	t.Run("detached_head", func(t *testing.T) {
+		summary, err := parseGitStatusPorcelain("## HEAD (no branch)\nM  file1.go")
+		require.NoError(t, err)
+		require.Empty(t, summary.BranchName)
+		require.Len(t, summary.Files, 1)
+	})
+
 	t.Run("modified_files", func(t *testing.T) {
 		output := `## main
 M  file1.go
@@ -345,6 +700,15 @@ D  file3.txt`
 		require.Equal(t, "file3.txt", summary.Files[2].Path)
 	})
 
+	t.Run("unstaged_first_line", func(t *testing.T) {
+		// 行首空格属于状态列，不能被裁掉
+		summary, err := parseGitStatusPorcelain(" M first.go\nM  second.go\n")
+		require.NoError(t, err)
+		require.Len(t, summary.Files, 2)
+		require.Equal(t, FileStatus{Path: "first.go", IndexStatus: ' ', WorkStatus: 'M'}, summary.Files[0])
+		require.Equal(t, "second.go", summary.Files[1].Path)
+	})
+
 	t.Run("renamed_file", func(t *testing.T) {
 		output := `## main
 R  old.txt -> new.txt`
@@ -446,18 +810,13 @@ func TestCollector_ComprehensiveDiff(t *testing.T) {
 	t.Parallel()
 
 	t.Run("with_untracked_files", func(t *testing.T) {
-		// Mock responses for: staged diff, unstaged diff, ls-files, head
-		mr := &mockRunner{
-			outputs: [][]byte{
-				[]byte("diff --git a/main.go b/main.go\n+func main() {\n+  fmt.Println(\"hello\")\n+}"), // staged diff
-				[]byte(""), // unstaged diff (empty)
-				[]byte("newfile.txt"), // untracked files
-				[]byte("This is a new file content"), // file content
-			},
-			errs: []error{nil, nil, nil, nil},
-		}
+		r := collectortest.NewRecordingRunner().
+			On("git diff --no-ext-diff --no-color --cached", "diff --git a/main.go b/main.go\n+func main() {\n+  fmt.Println(\"hello\")\n+}", nil).
+			On("git diff --no-ext-diff --no-color", "", nil).
+			On("git ls-files --others --exclude-standard", "newfile.txt", nil).
+			On("head -c 10240 newfile.txt", "This is a new file content", nil)
 
-		c := New(mr)
+		c := New(r)
 		diff, err := c.ComprehensiveDiff(context.Background())
 		require.NoError(t, err)
 		
@@ -470,36 +829,76 @@ func TestCollector_ComprehensiveDiff(t *testing.T) {
 	})
 
 	t.Run("no_changes", func(t *testing.T) {
-		// Mock responses for: staged diff (empty), unstaged diff (empty), ls-files (empty), git status (empty)
-		mr := &mockRunner{
-			outputs: [][]byte{
-				[]byte(""), // staged diff
-				[]byte(""), // unstaged diff
-				[]byte(""), // untracked files
-				[]byte(""), // git status
-			},
-			errs: []error{ErrNoDiff, nil, nil, nil},
-		}
+		r := collectortest.NewRecordingRunner().
+			On("git diff", "", nil).
+			On("git ls-files", "", nil).
+			On("git status --porcelain", "", nil)
 
-		c := New(mr)
+		c := New(r)
 		_, err := c.ComprehensiveDiff(context.Background())
 		require.ErrorIs(t, err, ErrNoDiff)
+		require.False(t, r.Ran("head"), "no untracked file should be read")
+	})
+
+	t.Run("staged_only", func(t *testing.T) {
+		r := collectortest.NewRecordingRunner().
+			On("git diff --no-ext-diff --no-color --cached", "diff --git a/main.go b/main.go\n+staged", nil).
+			On("git diff --no-ext-diff --no-color", "diff --git a/other.go b/other.go\n+unstaged", nil).
+			On("git ls-files --others --exclude-standard", "newfile.txt", nil)
+
+		c := New(r)
+		c.SetStagedOnly(true)
+		diff, err := c.ComprehensiveDiff(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "diff --git a/main.go b/main.go\n+staged", diff)
+		require.False(t, r.Ran("git ls-files"), "untracked files are not scanned")
+
+		r.On("git diff --no-ext-diff --no-color --cached", "", nil).On("git status --porcelain", " M other.go", nil)
+		_, err = New(r).ComprehensiveDiff(context.Background())
+		require.NoError(t, err, "unstaged changes are included by default")
+		c = New(r)
+		c.SetStagedOnly(true)
+		_, err = c.ComprehensiveDiff(context.Background())
+		require.ErrorIs(t, err, ErrNoDiff)
+
+		r.On("git status --porcelain -b", "## main\n M a.go\nMM b.go\n?? c.go", nil)
+		summary, err := c.FileStatusSummary(context.Background())
+		require.NoError(t, err)
+		require.Len(t, summary.Files, 1)
+		require.Equal(t, "b.go", summary.Files[0].Path)
+	})
+
+	t.Run("only_paths", func(t *testing.T) {
+		r := collectortest.NewRecordingRunner().
+			On("git diff --no-ext-diff --no-color --cached", "diff --git a/main.go b/main.go\n+staged", nil).
+			On("git diff --no-ext-diff --no-color", "diff --git a/cmd/root.go b/cmd/root.go\n+unstaged", nil).
+			On("git ls-files --others --exclude-standard", "cmd/new.go\nnotes.txt", nil).
+			On("git status --porcelain", " M cmd/root.go", nil).
+			On("head -c 10240 cmd/new.go", "package cmd", nil)
+
+		c := New(r)
+		c.SetOnlyPaths([]string{"cmd"})
+		diff, err := c.ComprehensiveDiff(context.Background())
+		require.NoError(t, err)
+		require.Contains(t, diff, "diff --git a/cmd/root.go b/cmd/root.go")
+		require.Contains(t, diff, "diff --git a/cmd/new.go b/cmd/new.go")
+		require.NotContains(t, diff, "main.go")
+		require.NotContains(t, diff, "notes.txt")
+		require.False(t, r.Ran("head -c 10240 notes.txt"))
+
+		require.True(t, InPaths("cmd/root.go", []string{"cmd/"}))
+		require.True(t, InPaths("a.go", []string{"."}))
+		require.False(t, InPaths("cmdline.go", []string{"cmd"}))
 	})
 
 	t.Run("only_untracked_files", func(t *testing.T) {
-		// Mock responses for: staged diff (empty), unstaged diff (empty), ls-files, head
-		mr := &mockRunner{
-			outputs: [][]byte{
-				[]byte(""), // staged diff
-				[]byte(""), // unstaged diff
-				[]byte("README.md\ndocs/api.md"), // untracked files
-				[]byte("# New Project\nThis is a new project"), // README.md content
-				[]byte("# API Documentation\nAPI endpoints"), // docs/api.md content
-			},
-			errs: []error{ErrNoDiff, nil, nil, nil, nil},
-		}
+		r := collectortest.NewRecordingRunner().
+			On("git diff", "", nil).
+			On("git ls-files --others --exclude-standard", "README.md\ndocs/api.md", nil).
+			On("head -c 10240 README.md", "# New Project\nThis is a new project", nil).
+			On("head -c 10240 docs/api.md", "# API Documentation\nAPI endpoints", nil)
 
-		c := New(mr)
+		c := New(r)
 		diff, err := c.ComprehensiveDiff(context.Background())
 		require.NoError(t, err)
 		
@@ -511,69 +910,1064 @@ func TestCollector_ComprehensiveDiff(t *testing.T) {
 	})
 }
 
-// Test UntrackedFileAsDiff method
-func TestCollector_UntrackedFileAsDiff(t *testing.T) {
-	t.Parallel()
+// untrackedRunner 按命令返回结果：ls-files 返回 files，head 返回文件对应内容，
+// 并记录同时执行的 head 调用数量的峰值
+type untrackedRunner struct {
+	files    []string
+	contents map[string]string
 
-	t.Run("success", func(t *testing.T) {
-		mr := &mockRunner{
-			outputs: [][]byte{
-				[]byte("package main\n\nfunc main() {\n\tfmt.Println(\"Hello World\")\n}"),
-			},
-			errs: []error{nil},
-		}
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
 
-		c := New(mr)
-		diff, err := c.UntrackedFileAsDiff(context.Background(), "main.go")
-		require.NoError(t, err)
+func (r *untrackedRunner) Run(_ context.Context, name string, args ...string) ([]byte, error) {
+	switch {
+	case name == "head":
+		r.mu.Lock()
+		r.inFlight++
+		if r.inFlight > r.peak {
+			r.peak = r.inFlight
+		}
+		r.mu.Unlock()
+		time.Sleep(time.Millisecond)
+		r.mu.Lock()
+		r.inFlight--
+		r.mu.Unlock()
+
+		path := args[len(args)-1]
+		content, ok := r.contents[path]
+		if !ok {
+			return nil, errors.New("permission denied")
+		}
+		return []byte(content), nil
+	case len(args) > 0 && args[0] == "ls-files":
+		return []byte(strings.Join(r.files, "\n")), nil
+	default:
+		return []byte(""), nil
+	}
+}
 
-		// Check diff format
-		require.Contains(t, diff, "diff --git a/main.go b/main.go")
-		require.Contains(t, diff, "new file mode 100644")
-		require.Contains(t, diff, "+package main")
-		require.Contains(t, diff, "+func main() {")
-	})
+func TestCollector_ComprehensiveDiff_ConcurrentUntracked(t *testing.T) {
+	t.Parallel()
 
-	t.Run("file_read_error", func(t *testing.T) {
-		mr := &mockRunner{
-			outputs: [][]byte{[]byte("")},
-			errs:    []error{errors.New("file not found")},
+	r := &untrackedRunner{contents: map[string]string{}}
+	for i := 19; i >= 0; i-- {
+		path := fmt.Sprintf("docs/note%02d.md", i)
+		r.files = append(r.files, path)
+		if i != 7 { // docs/note07.md 不可读，应被跳过
+			r.contents[path] = "note " + path
 		}
+	}
 
-		c := New(mr)
-		_, err := c.UntrackedFileAsDiff(context.Background(), "nonexistent.go")
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "file not found")
-	})
+	c := New(r)
+	diff, err := c.ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.NotContains(t, diff, "docs/note07.md")
+	require.LessOrEqual(t, r.peak, maxUntrackedReaders)
+
+	// 每个文件的内容与其 header 对应，且按路径排序
+	last := -1
+	for i := 0; i < 20; i++ {
+		if i == 7 {
+			continue
+		}
+		path := fmt.Sprintf("docs/note%02d.md", i)
+		idx := strings.Index(diff, "+++ b/"+path+"\n+note "+path)
+		require.Greater(t, idx, last, path)
+		last = idx
+	}
 }
 
-// Test enhanced AnalyzeChanges method
-func TestCollector_AnalyzeChanges_Enhanced(t *testing.T) {
+func TestCollector_ComprehensiveDiffWithProgress(t *testing.T) {
 	t.Parallel()
 
-	t.Run("with_untracked_files", func(t *testing.T) {
-		// Mock git status output with some files
-		gitStatusOutput := `## main
-M  src/main.go
-A  docs/README.md
-D  old/deprecated.txt`
-		
-		// Mock untracked files
-		untrackedOutput := "newfile.py\nconfig.json"
-		
-		mr := &mockRunner{
-			outputs: [][]byte{
-				[]byte(gitStatusOutput), // FileStatusSummary
-				[]byte(untrackedOutput), // UntrackedFiles
-			},
-			errs: []error{nil, nil},
+	r := &untrackedRunner{contents: map[string]string{}}
+	for i := 0; i < 5; i++ {
+		path := fmt.Sprintf("docs/note%02d.md", i)
+		r.files = append(r.files, path)
+		if i != 2 { // 读取失败的文件同样计入进度
+			r.contents[path] = "note " + path
 		}
+	}
 
-		c := New(mr)
-		summary, err := c.AnalyzeChanges(context.Background())
-		require.NoError(t, err)
+	var mu sync.Mutex
+	var seen, totals []int
+	c := New(r)
+	_, err := c.ComprehensiveDiffWithProgress(context.Background(), func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, done)
+		totals = append(totals, total)
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int{1, 2, 3, 4, 5}, seen)
+	require.Equal(t, []int{5, 5, 5, 5, 5}, totals)
+}
 
-		// Check basic fields
+// blockingUntrackedRunner 的 head 调用忽略 ctx，直到 release 关闭才返回
+type blockingUntrackedRunner struct {
+	files   []string
+	release chan struct{}
+	started chan struct{}
+}
+
+func (r *blockingUntrackedRunner) Run(_ context.Context, name string, args ...string) ([]byte, error) {
+	switch {
+	case name == "head":
+		r.started <- struct{}{}
+		<-r.release
+		return []byte("note"), nil
+	case len(args) > 0 && args[0] == "ls-files":
+		return []byte(strings.Join(r.files, "\n")), nil
+	default:
+		return []byte(""), nil
+	}
+}
+
+func TestCollector_ComprehensiveDiffWithProgress_NoProgressAfterCancel(t *testing.T) {
+	t.Parallel()
+
+	r := &blockingUntrackedRunner{release: make(chan struct{}), started: make(chan struct{}, 16)}
+	for i := 0; i < 8; i++ {
+		r.files = append(r.files, fmt.Sprintf("docs/note%02d.md", i))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-r.started
+		cancel()
+	}()
+
+	var mu sync.Mutex
+	returned, late := false, 0
+	_, err := New(r).ComprehensiveDiffWithProgress(ctx, func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if returned {
+			late++
+		}
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	mu.Lock()
+	returned = true
+	mu.Unlock()
+
+	// 放行仍在运行的读取协程，它们不应再回调 progress
+	close(r.release)
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Zero(t, late)
+}
+
+func TestBatchGitOperations_MaxConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	batch := NewBatchGitOperations()
+	batch.SetMaxConcurrency(3)
+	for i := 0; i < 12; i++ {
+		batch.AddOperation(func(ctx context.Context) (interface{}, error) {
+			mu.Lock()
+			inFlight++
+			if inFlight > peak {
+				peak = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return i, nil
+		})
+	}
+	batch.ExecuteBatch(context.Background())
+	results, errs := batch.GetResults()
+	require.LessOrEqual(t, peak, 3)
+	for i := range results {
+		require.NoError(t, errs[i])
+		require.Equal(t, i, results[i])
+	}
+}
+
+func TestBatchGitOperations_ExecuteBatchContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns_early_on_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		release := make(chan struct{})
+		defer close(release)
+
+		batch := NewBatchGitOperations()
+		batch.AddOperation(func(ctx context.Context) (interface{}, error) {
+			return "fast", nil
+		})
+		batch.AddOperation(func(ctx context.Context) (interface{}, error) {
+			<-release // 模拟不响应取消的慢操作
+			return "slow", nil
+		})
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		start := time.Now()
+		err := batch.ExecuteBatchContext(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Less(t, time.Since(start), time.Second)
+
+		results, errs := batch.GetResults()
+		require.NoError(t, errs[0])
+		require.Equal(t, "fast", results[0])
+		require.ErrorIs(t, errs[1], context.Canceled)
+		require.Nil(t, results[1])
+	})
+
+	t.Run("completes_without_cancel", func(t *testing.T) {
+		batch := NewBatchGitOperations()
+		batch.AddOperation(func(ctx context.Context) (interface{}, error) { return 1, nil })
+		batch.AddOperation(func(ctx context.Context) (interface{}, error) { return nil, errors.New("boom") })
+		require.NoError(t, batch.ExecuteBatchContext(context.Background()))
+		results, errs := batch.GetResults()
+		require.Equal(t, 1, results[0])
+		require.EqualError(t, errs[1], "boom")
+	})
+}
+
+func TestBatchGitOperations_ExecuteBatch_SkipsCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var ran atomic.Int32
+	batch := NewBatchGitOperations()
+	batch.SetMaxConcurrency(1)
+	for i := 0; i < 5; i++ {
+		batch.AddOperation(func(ctx context.Context) (interface{}, error) {
+			ran.Add(1)
+			return nil, nil
+		})
+	}
+	batch.ExecuteBatch(ctx)
+	_, errs := batch.GetResults()
+	require.Zero(t, ran.Load())
+	for _, err := range errs {
+		require.ErrorIs(t, err, context.Canceled)
+	}
+}
+
+func TestCollector_ComprehensiveDiff_PriorityOrder(t *testing.T) {
+	t.Parallel()
+
+	staged := strings.Join([]string{
+		"diff --git a/internal/handler_test.go b/internal/handler_test.go",
+		"index 1111111..2222222 100644",
+		"--- a/internal/handler_test.go",
+		"+++ b/internal/handler_test.go",
+		"@@ -1,3 +1,4 @@",
+		"+// new test case",
+		"diff --git a/internal/handler.go b/internal/handler.go",
+		"new file mode 100644",
+		"index 0000000..3333333",
+		"--- /dev/null",
+		"+++ b/internal/handler.go",
+		"@@ -0,0 +1,3 @@",
+		"+package internal",
+	}, "\n")
+	unstaged := strings.Join([]string{
+		"diff --git a/internal/handler.go b/internal/handler.go",
+		"index 3333333..4444444 100644",
+		"--- a/internal/handler.go",
+		"+++ b/internal/handler.go",
+		"@@ -1,3 +1,4 @@",
+		"+func Handle() {}",
+	}, "\n")
+
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte(staged),   // staged diff
+			[]byte(unstaged), // unstaged diff
+			[]byte(""),       // untracked files
+		},
+		errs: []error{nil, nil, nil},
+	}
+
+	c := New(mr)
+	diff, err := c.ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+
+	codeIdx := strings.Index(diff, "diff --git a/internal/handler.go")
+	testIdx := strings.Index(diff, "diff --git a/internal/handler_test.go")
+	require.NotEqual(t, -1, codeIdx)
+	require.NotEqual(t, -1, testIdx)
+	require.Less(t, codeIdx, testIdx, "high-priority .go hunk should precede the test file hunk")
+
+	// Staged and unstaged sections of the same file stay together, staged first
+	require.Less(t, strings.Index(diff, "+package internal"), strings.Index(diff, "+func Handle() {}"))
+	require.Less(t, strings.Index(diff, "+func Handle() {}"), testIdx)
+}
+
+func TestIsGeneratedContent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"go_marker", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb", true},
+		{"go_marker_after_license", "// Copyright 2024\n\n// Code generated by mockgen. DO NOT EDIT.\npackage mocks", true},
+		{"at_generated", "/* @generated by relay-compiler */\nexport default {}", true},
+		{"autogenerated_python", "# This file was automatically generated by SWIG\nimport os", true},
+		{"plain_go", "package main\n\nfunc main() {}", false},
+		{"marker_too_deep", "a\nb\nc\nd\ne\n// Code generated by x. DO NOT EDIT.", false},
+		{"protoc_python", "# Generated by the protocol buffer compiler.  DO NOT EDIT!\nimport sys", true},
+		{"thrift", "/**\n * Autogenerated by Thrift Compiler (0.9.3)\n */", true},
+		{"csharp_auto_generated", "// <auto-generated />\nnamespace App", true},
+		{"hand_written_do_not_edit", "# Do not edit this section manually\nkey: value", false},
+		{"generated_in_code", "package main\n\nvar autogenerated = true", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, isGeneratedContent(tt.content))
+		})
+	}
+}
+
+func TestCollector_AnalyzeChanges_GeneratedFiles(t *testing.T) {
+	t.Parallel()
+
+	// git 输出的路径相对于仓库根目录，文件头需要拼接 RepoRoot 后读取
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "api"), 0o755))
+	generated := "api/service.pb.go"
+	handwritten := "api/service.go"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, generated), []byte("// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, handwritten), []byte("package api\n\ntype Service struct{}"), 0o644))
+
+	// 文件头在进程内读取，runner 只需要返回 git 命令的输出
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte("## main\nM  " + generated + "\nM  " + handwritten), // FileStatusSummary
+			[]byte(""),  // UntrackedFiles
+			[]byte(dir), // RepoRoot
+		},
+		errs: []error{nil, nil, nil},
+	}
+
+	c := New(mr)
+	summary, err := c.AnalyzeChanges(context.Background())
+	require.NoError(t, err)
+	require.Len(t, summary.FilesByPriority, 2)
+	require.Equal(t, handwritten, summary.FilesByPriority[0].Path)
+	require.False(t, summary.FilesByPriority[0].IsGenerated)
+	require.Equal(t, generated, summary.FilesByPriority[1].Path)
+	require.True(t, summary.FilesByPriority[1].IsGenerated)
+	require.Equal(t, "generated", summary.FilesByPriority[1].ContentType)
+}
+
+func TestParseModeChanges(t *testing.T) {
+	t.Parallel()
+
+	summary := " mode change 100644 => 100755 scripts/build.sh\n rename x.txt => y.txt (100%)\n mode change 100755 => 100644 my tool.py"
+	require.Equal(t, []ModeChange{
+		{Path: "scripts/build.sh", OldMode: "100644", NewMode: "100755"},
+		{Path: "my tool.py", OldMode: "100755", NewMode: "100644"},
+	}, ParseModeChanges(summary))
+
+	diff := "diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\ndiff --git a/main.go b/main.go\nindex 1111111..2222222 100644"
+	modes := ParseModeChanges(diff)
+	require.Equal(t, []ModeChange{{Path: "run.sh", OldMode: "100644", NewMode: "100755"}}, modes)
+	require.True(t, modes[0].MadeExecutable())
+
+	require.Empty(t, ParseModeChanges(""))
+}
+
+func TestCollector_AnalyzeChanges_ModeChanges(t *testing.T) {
+	t.Parallel()
+
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte("## main\nM  scripts/build.sh\nM  scripts/deploy.sh\nM  main.go"), // FileStatusSummary
+			[]byte(""),                       // UntrackedFiles
+			[]byte("/repo"),                  // RepoRoot
+			[]byte(" mode change 100644 => 100755 scripts/build.sh\n mode change 100644 => 100755 scripts/deploy.sh"), // diff --cached --summary
+			[]byte(" mode change 100644 => 100755 scripts/build.sh"),                                                 // diff --summary
+		},
+		errs: []error{nil, nil, nil, nil, nil},
+	}
+
+	c := New(mr)
+	summary, err := c.AnalyzeChanges(context.Background())
+	require.NoError(t, err)
+	require.Len(t, summary.ModeChanges, 2)
+	require.Equal(t, "scripts/build.sh", summary.ModeChanges[0].Path)
+	require.Equal(t, "scripts/deploy.sh", summary.ModeChanges[1].Path)
+	// 三个文件中两个是权限变更，建议 chore 而不是 fix
+	require.Equal(t, "chore", summary.SuggestedPrefix)
+}
+
+func TestCollector_AnalyzeChanges_PartiallyStaged(t *testing.T) {
+	t.Parallel()
+
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte("## main\nMM cmd/root.go\nM  main.go\nAM new.go\n M README.md"), // FileStatusSummary
+			[]byte("scratch.txt"), // UntrackedFiles
+		},
+		errs: []error{nil, nil},
+	}
+
+	c := New(mr)
+	summary, err := c.AnalyzeChanges(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"cmd/root.go", "new.go"}, summary.PartiallyStaged)
+}
+
+func TestCollector_AnalyzeChanges_PrefixMap(t *testing.T) {
+	t.Parallel()
+
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte("## main\nM  main.go"), // FileStatusSummary
+			[]byte(""),                    // UntrackedFiles
+		},
+		errs: []error{nil, nil},
+	}
+
+	c := New(mr)
+	c.SetPrefixMap(map[string]string{"added": "feature", "modified": "bugfix"})
+	summary, err := c.AnalyzeChanges(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "bugfix", summary.SuggestedPrefix)
+
+	// 未覆盖的类别沿用默认前缀
+	resolved := ResolvePrefixMap(map[string]string{"added": "feature", "renamed": " "})
+	require.Equal(t, "feature", resolved["added"])
+	require.Equal(t, "refactor", resolved["renamed"])
+	require.Equal(t, "chore", resolved["default"])
+}
+
+func TestCollector_ComprehensiveDiff_GeneratedLast(t *testing.T) {
+	t.Parallel()
+
+	staged := strings.Join([]string{
+		"diff --git a/mocks/store.go b/mocks/store.go",
+		"index 1111111..2222222 100644",
+		"--- a/mocks/store.go",
+		"+++ b/mocks/store.go",
+		"@@ -1,4 +1,4 @@",
+		" // Code generated by MockGen. DO NOT EDIT.",
+		"-// Source: store.go",
+		"+// Source: internal/store.go",
+		"diff --git a/store.go b/store.go",
+		"index 3333333..4444444 100644",
+		"--- a/store.go",
+		"+++ b/store.go",
+		"@@ -10,3 +10,4 @@",
+		"+func (s *Store) Close() error { return nil }",
+	}, "\n")
+
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte(staged), // staged diff
+			[]byte(""),     // unstaged diff
+			[]byte(""),     // untracked files
+		},
+		errs: []error{nil, nil, nil},
+	}
+
+	c := New(mr)
+	diff, err := c.ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.Less(t, strings.Index(diff, "diff --git a/store.go"), strings.Index(diff, "diff --git a/mocks/store.go"))
+}
+
+// mockFileSystem 按路径返回预设的文件大小，未登记的路径视为不存在。
+type mockFileSystem map[string]int64
+
+func (m mockFileSystem) Stat(name string) (os.FileInfo, error) {
+	size, ok := m[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return mockFileInfo{name: name, size: size}, nil
+}
+
+type mockFileInfo struct {
+	name string
+	size int64
+}
+
+func (m mockFileInfo) Name() string       { return m.name }
+func (m mockFileInfo) Size() int64        { return m.size }
+func (m mockFileInfo) Mode() os.FileMode  { return 0o644 }
+func (m mockFileInfo) ModTime() time.Time { return time.Time{} }
+func (m mockFileInfo) IsDir() bool        { return false }
+func (m mockFileInfo) Sys() interface{}   { return nil }
+
+func TestCollector_AnalyzeChanges_UntrackedFileSize(t *testing.T) {
+	t.Parallel()
+
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte("## main\nM  main.go"), // FileStatusSummary
+			[]byte("data/data.csv"),        // UntrackedFiles（--full-name，相对于仓库根目录）
+			[]byte("/repo"),                // RepoRoot
+		},
+		errs: []error{nil, nil, nil},
+	}
+
+	c := New(mr)
+	c.SetFileSystem(mockFileSystem{"/repo/data/data.csv": 4096, "data.csv": 1, "/repo/main.go": 100})
+	summary, err := c.AnalyzeChanges(context.Background())
+	require.NoError(t, err)
+	require.Len(t, summary.UntrackedFiles, 1)
+	require.Equal(t, int64(4096), summary.UntrackedFiles[0].FileSize)
+}
+
+func TestCollector_ComprehensiveDiff_MaxFileSize(t *testing.T) {
+	t.Parallel()
+
+	staged := strings.Join([]string{
+		"diff --git a/dump.sql b/dump.sql",
+		"new file mode 100644",
+		"--- /dev/null",
+		"+++ b/dump.sql",
+		"@@ -0,0 +1,2 @@",
+		"+INSERT INTO t VALUES (1);",
+		"diff --git a/main.go b/main.go",
+		"--- a/main.go",
+		"+++ b/main.go",
+		"@@ -1,1 +1,2 @@",
+		"+// main",
+	}, "\n")
+
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte(staged),           // staged diff
+			[]byte(""),               // unstaged diff
+			[]byte("/repo"),          // RepoRoot
+			[]byte("assets.bin.txt"), // untracked files
+		},
+		errs: []error{nil, nil, nil, nil},
+	}
+
+	var skipped []string
+	c := New(mr)
+	c.SetFileSystem(mockFileSystem{"/repo/dump.sql": 50 << 20, "/repo/main.go": 200, "/repo/assets.bin.txt": 20 << 20})
+	c.SetMaxFileSize(1<<20, func(path string, size int64) {
+		skipped = append(skipped, path)
+	})
+
+	diff, err := c.ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, diff, "diff --git a/main.go")
+	require.NotContains(t, diff, "dump.sql")
+	require.NotContains(t, diff, "assets.bin.txt")
+	require.ElementsMatch(t, []string{"dump.sql", "assets.bin.txt"}, skipped)
+}
+
+func TestCollector_ComprehensiveDiff_ExcludePatterns(t *testing.T) {
+	t.Parallel()
+
+	staged := strings.Join([]string{
+		"diff --git a/go.sum b/go.sum",
+		"--- a/go.sum",
+		"+++ b/go.sum",
+		"@@ -1,1 +1,2 @@",
+		"+example.com/x v1.0.0 h1:abc",
+		"diff --git a/vendor/x/x.go b/vendor/x/x.go",
+		"--- a/vendor/x/x.go",
+		"+++ b/vendor/x/x.go",
+		"@@ -1,1 +1,2 @@",
+		"+package x",
+		"diff --git a/main.go b/main.go",
+		"--- a/main.go",
+		"+++ b/main.go",
+		"@@ -1,1 +1,2 @@",
+		"+// main",
+	}, "\n")
+
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte(staged),           // staged diff
+			[]byte(""),               // unstaged diff
+			[]byte("web/app.min.js"), // untracked files
+		},
+		errs: []error{nil, nil, nil},
+	}
+
+	c := New(mr)
+	c.SetExcludePatterns([]string{"go.sum", "vendor/", "*.min.js"})
+
+	diff, err := c.ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, diff, "diff --git a/main.go")
+	require.NotContains(t, diff, "go.sum")
+	require.NotContains(t, diff, "vendor/x")
+	require.NotContains(t, diff, "app.min.js")
+}
+
+// recordingRunner 记录每次调用的参数，并按顺序返回预设结果。
+type recordingRunner struct {
+	mockRunner
+	calls [][]string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	r.calls = append(r.calls, append([]string{name}, args...))
+	return r.mockRunner.Run(ctx, name, args...)
+}
+
+// diffCallRunner 记录所有 git diff 调用的参数；其他命令返回空输出，可并发调用
+type diffCallRunner struct {
+	mu    sync.Mutex
+	diffs [][]string
+}
+
+func (r *diffCallRunner) Run(_ context.Context, name string, args ...string) ([]byte, error) {
+	if name == "git" && len(args) > 0 && args[0] == "diff" {
+		r.mu.Lock()
+		r.diffs = append(r.diffs, args)
+		r.mu.Unlock()
+		return []byte("diff --git a/a.go b/a.go\n+package a"), nil
+	}
+	return []byte(""), nil
+}
+
+// 所有产生 diff 的方法都必须经由 DiffArgs，外部 diff 工具与颜色码不能混入 LLM 输入
+func TestCollector_DiffCommandsUseDiffArgs(t *testing.T) {
+	t.Parallel()
+
+	methods := map[string]func(c *Collector) error{
+		"StagedDiff":   func(c *Collector) error { _, err := c.StagedDiff(context.Background()); return err },
+		"UnstagedDiff": func(c *Collector) error { _, err := c.UnstagedDiff(context.Background()); return err },
+		"DiffStat":     func(c *Collector) error { _, err := c.DiffStat(context.Background()); return err },
+		"CombinedDiff": func(c *Collector) error { _, err := c.CombinedDiff(context.Background()); return err },
+		"ComprehensiveDiff": func(c *Collector) error {
+			_, err := c.ComprehensiveDiff(context.Background())
+			return err
+		},
+		"Diff":         func(c *Collector) error { _, err := c.Diff(context.Background()); return err },
+		"ChangedFiles": func(c *Collector) error { _, err := c.ChangedFiles(context.Background()); return err },
+		"DiffAgainstRef": func(c *Collector) error {
+			_, err := c.DiffAgainstRef(context.Background(), "origin/main")
+			return err
+		},
+		"AnalyzeChangesAgainstRef": func(c *Collector) error {
+			_, err := c.AnalyzeChangesAgainstRef(context.Background(), "origin/main")
+			return err
+		},
+		"DiffStatAgainstRef": func(c *Collector) error {
+			_, err := c.DiffStatAgainstRef(context.Background(), "origin/main")
+			return err
+		},
+	}
+	for name, call := range methods {
+		t.Run(name, func(t *testing.T) {
+			r := &diffCallRunner{}
+			c := New(r)
+			_ = call(c)
+			require.NotEmpty(t, r.diffs, "%s should run git diff", name)
+			for _, args := range r.diffs {
+				require.Equal(t, DiffArgs(args[3:]...), args, "%s: git %s", name, strings.Join(args, " "))
+			}
+		})
+	}
+}
+
+func TestCollector_DiffStat(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{mockRunner: mockRunner{
+		outputs: [][]byte{
+			[]byte(" a.go | 10 +++++++---\n b.go |  2 +-\n 2 files changed, 8 insertions(+), 4 deletions(-)\n"),
+			[]byte(""),
+		},
+		errs: []error{nil, nil},
+	}}
+	c := New(r)
+	stat, err := c.DiffStat(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "staged: 2 files changed, 8 insertions(+), 4 deletions(-)", stat)
+	require.Equal(t, append([]string{"git"}, DiffArgs("--cached", "--stat")...), r.calls[0])
+	require.Equal(t, append([]string{"git"}, DiffArgs("--stat")...), r.calls[1])
+}
+
+func TestCollector_ComprehensiveDiff_StatHeader(t *testing.T) {
+	t.Parallel()
+
+	mr := &mockRunner{
+		outputs: [][]byte{
+			[]byte("diff --git a/a.go b/a.go\n+package a"), // staged diff
+			[]byte(""), // unstaged diff
+			[]byte(""), // untracked files
+			[]byte(" a.go | 1 +\n 1 file changed, 1 insertion(+)"), // staged stat
+			[]byte(""), // unstaged stat
+		},
+		errs: []error{nil, nil, nil, nil, nil},
+	}
+	c := New(mr)
+	c.SetDiffStatHeader(true)
+	diff, err := c.ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Diff stat:\nstaged: 1 file changed, 1 insertion(+)\n\ndiff --git a/a.go b/a.go\n+package a", diff)
+}
+
+func TestElideLongLines(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("x", 50)
+	text := "diff --git a/app.min.js b/app.min.js\n+" + long + "\n-" + long + "\n+short\n+" + strings.Repeat("é", 30)
+	require.Equal(t,
+		"diff --git a/app.min.js b/app.min.js\n+<long line elided, 51 chars>\n-<long line elided, 51 chars>\n+short\n+"+strings.Repeat("é", 30),
+		elideLongLines(text, 40), "limit counts characters, not bytes")
+	require.Equal(t, text, elideLongLines(text, 0))
+}
+
+func TestCollector_ComprehensiveDiff_CollapseDeletions(t *testing.T) {
+	t.Parallel()
+
+	deleted := "diff --git a/legacy.go b/legacy.go\ndeleted file mode 100644\nindex 1234567..0000000\n--- a/legacy.go\n+++ /dev/null\n@@ -1,3 +0,0 @@\n-package legacy\n-\n-func Old() {}"
+	modified := "diff --git a/main.go b/main.go\nindex 1111111..2222222 100644\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package old\n+package main"
+	binary := "diff --git a/logo.png b/logo.png\ndeleted file mode 100644\nindex 1234567..0000000\nBinary files a/logo.png and /dev/null differ"
+	runner := collectortest.NewRecordingRunner().
+		On("git diff --no-ext-diff --no-color --cached", deleted+"\n"+modified+"\n"+binary, nil).
+		On("git diff --no-ext-diff --no-color", "", nil).
+		On("git ls-files", "", nil)
+
+	c := New(runner)
+	c.SetCollapseDeletions(true)
+	diff, err := c.ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, diff, "diff --git a/legacy.go b/legacy.go\ndeleted file mode 100644\nindex 1234567..0000000\ndeleted file legacy.go (3 lines)")
+	require.NotContains(t, diff, "func Old")
+	require.Contains(t, diff, modified, "modifications are kept in full")
+	require.Contains(t, diff, binary, "deletions without text content are kept")
+
+	diff, err = New(runner).ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, diff, "-func Old() {}", "off by default")
+}
+
+func TestCollector_ComprehensiveDiff_IgnoreWhitespace(t *testing.T) {
+	t.Parallel()
+
+	runner := collectortest.NewRecordingRunner().
+		On("git diff --ignore-all-space --no-ext-diff --no-color --cached", "", nil).
+		On("git diff --ignore-all-space --no-ext-diff --no-color", "", nil).
+		On("git ls-files", "", nil).
+		On("git status --porcelain", "M  main.go", nil)
+
+	c := New(runner)
+	c.SetIgnoreWhitespace(true)
+	diff, err := c.ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Changed files with whitespace-only changes (the diff ignores whitespace):\n"+
+		"M  main.go (whitespace-only change)", diff)
+	require.False(t, runner.Ran("git diff --no-ext-diff"), "every diff ignores whitespace")
+}
+
+func TestCollector_ComprehensiveDiff_LongLines(t *testing.T) {
+	t.Parallel()
+
+	minified := "+" + strings.Repeat("a", DefaultMaxLineLength+10)
+	runner := collectortest.NewRecordingRunner().
+		On("git diff --no-ext-diff --no-color --cached", "diff --git a/app.min.js b/app.min.js\n"+minified, nil).
+		On("git diff --no-ext-diff --no-color", "", nil).
+		On("git ls-files", "", nil)
+
+	diff, err := New(runner).ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "diff --git a/app.min.js b/app.min.js\n+<long line elided, 2011 chars>", diff)
+
+	c := New(runner)
+	c.SetMaxLineLength(0)
+	diff, err = c.ComprehensiveDiff(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, diff, minified)
+}
+
+// sleepyRunner 让名称匹配 slow 的命令耗时 delay
+type sleepyRunner struct {
+	slow  string
+	delay time.Duration
+}
+
+func (r sleepyRunner) Run(_ context.Context, name string, args ...string) ([]byte, error) {
+	if len(args) > 0 && args[0] == r.slow {
+		time.Sleep(r.delay)
+	}
+	return nil, nil
+}
+
+func TestCollector_SlowCommands(t *testing.T) {
+	t.Parallel()
+
+	c := New(sleepyRunner{slow: "status", delay: 30 * time.Millisecond})
+	var warned []CommandTiming
+	c.SetSlowCommandThreshold(20*time.Millisecond, func(timing CommandTiming) {
+		warned = append(warned, timing)
+	})
+
+	_, err := c.runWithCache(context.Background(), "git", "status", "--porcelain")
+	require.NoError(t, err)
+	_, err = c.runWithCache(context.Background(), "git", "rev-parse", "HEAD")
+	require.NoError(t, err)
+	// 命中缓存的调用不再计时
+	_, err = c.runWithCache(context.Background(), "git", "status", "--porcelain")
+	require.NoError(t, err)
+
+	require.Len(t, warned, 1)
+	require.Equal(t, "git status --porcelain", warned[0].Command)
+	require.GreaterOrEqual(t, warned[0].Duration, 20*time.Millisecond)
+	require.Equal(t, warned, c.SlowCommands())
+
+	// 未设置阈值时不记录
+	c = New(sleepyRunner{slow: "status", delay: 30 * time.Millisecond})
+	_, err = c.runWithCache(context.Background(), "git", "status")
+	require.NoError(t, err)
+	require.Empty(t, c.SlowCommands())
+}
+
+func TestCollector_DiffAgainstRef(t *testing.T) {
+	t.Parallel()
+
+	t.Run("three_dot_diff", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{
+			outputs: [][]byte{[]byte("diff --git a/a.go b/a.go\n+package a")},
+			errs:    []error{nil},
+		}}
+		diff, err := New(r).DiffAgainstRef(context.Background(), "origin/main")
+		require.NoError(t, err)
+		require.Contains(t, diff, "diff --git a/a.go b/a.go")
+		require.Equal(t, []string{"git", "diff", "--no-ext-diff", "--no-color", "origin/main...HEAD"}, r.calls[0])
+	})
+
+	t.Run("no_changes", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{outputs: [][]byte{[]byte("")}, errs: []error{nil}}}
+		_, err := New(r).DiffAgainstRef(context.Background(), "main")
+		require.ErrorIs(t, err, ErrNoDiff)
+	})
+
+	t.Run("invalid_ref", func(t *testing.T) {
+		for _, ref := range []string{"", "main;rm -rf /", "--output=/tmp/x", "main..HEAD~1"} {
+			r := &recordingRunner{}
+			_, err := New(r).DiffAgainstRef(context.Background(), ref)
+			require.Error(t, err, ref)
+			require.Empty(t, r.calls, ref)
+		}
+	})
+}
+
+func TestCollector_DiffStatAgainstRef(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{mockRunner: mockRunner{
+		outputs: [][]byte{[]byte("100\t20\tcmd/root.go\n15\t10\tREADME.md\n-\t-\tlogo.png\n5\t0\tdist/app.min.js\n")},
+		errs:    []error{nil},
+	}}
+	c := New(r)
+	c.SetExcludePatterns([]string{"dist/"})
+	stat, err := c.DiffStatAgainstRef(context.Background(), "origin/main")
+	require.NoError(t, err)
+	require.Equal(t, "3 files changed, +115/-30", stat)
+	require.Equal(t, []string{"git", "diff", "--no-ext-diff", "--no-color", "--numstat", "origin/main...HEAD"}, r.calls[0])
+
+	r = &recordingRunner{mockRunner: mockRunner{outputs: [][]byte{[]byte("1\t0\tgo.mod\n")}, errs: []error{nil}}}
+	stat, err = New(r).DiffStatAgainstRef(context.Background(), "main")
+	require.NoError(t, err)
+	require.Equal(t, "1 file changed, +1/-0", stat)
+
+	r = &recordingRunner{mockRunner: mockRunner{outputs: [][]byte{[]byte("")}, errs: []error{nil}}}
+	_, err = New(r).DiffStatAgainstRef(context.Background(), "main")
+	require.ErrorIs(t, err, ErrNoDiff)
+}
+
+func TestCollector_CommitsOnBranch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("subjects", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{
+			outputs: [][]byte{[]byte("feat: add x\nfix: handle empty x\n\n")},
+			errs:    []error{nil},
+		}}
+		commits, err := New(r).CommitsOnBranch(context.Background(), "origin/main")
+		require.NoError(t, err)
+		require.Equal(t, []string{"feat: add x", "fix: handle empty x"}, commits)
+		require.Equal(t, []string{"git", "log", "--reverse", "--format=%s", "origin/main..HEAD"}, r.calls[0])
+	})
+
+	t.Run("no_commits", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{outputs: [][]byte{[]byte("")}, errs: []error{nil}}}
+		commits, err := New(r).CommitsOnBranch(context.Background(), "main")
+		require.NoError(t, err)
+		require.Empty(t, commits)
+	})
+
+	t.Run("invalid_ref", func(t *testing.T) {
+		r := &recordingRunner{}
+		_, err := New(r).CommitsOnBranch(context.Background(), "--all")
+		require.Error(t, err)
+		require.Empty(t, r.calls)
+	})
+}
+
+func TestParseSinceDuration(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]time.Duration{
+		"12h": 12 * time.Hour,
+		"2d":  48 * time.Hour,
+		"1w":  7 * 24 * time.Hour,
+	} {
+		got, ok := parseSinceDuration(input)
+		require.True(t, ok, input)
+		require.Equal(t, want, got, input)
+	}
+	for _, input := range []string{"v1.2.0", "0d", "2", "d", "2days", "origin/main"} {
+		_, ok := parseSinceDuration(input)
+		require.False(t, ok, input)
+	}
+}
+
+func TestCollector_ResolveSince(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ref", func(t *testing.T) {
+		r := &recordingRunner{}
+		base, err := New(r).ResolveSince(context.Background(), "v1.2.0")
+		require.NoError(t, err)
+		require.Equal(t, "v1.2.0", base)
+		require.Empty(t, r.calls)
+
+		_, err = New(r).ResolveSince(context.Background(), "--all")
+		require.Error(t, err)
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{
+			outputs: [][]byte{[]byte("aaa111\nbbb222\n"), []byte("000999\n")},
+			errs:    []error{nil, nil},
+		}}
+		base, err := New(r).ResolveSince(context.Background(), "2d")
+		require.NoError(t, err)
+		require.Equal(t, "000999", base)
+		require.Equal(t, "log", r.calls[0][1])
+		require.True(t, strings.HasPrefix(r.calls[0][2], "--since="), r.calls[0][2])
+		require.Equal(t, []string{"git", "rev-parse", "--verify", "--quiet", "aaa111^"}, r.calls[1])
+	})
+
+	t.Run("no_commits", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{outputs: [][]byte{[]byte("")}, errs: []error{nil}}}
+		_, err := New(r).ResolveSince(context.Background(), "1w")
+		require.ErrorIs(t, err, ErrNoDiff)
+	})
+
+	t.Run("root_commit", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{
+			outputs: [][]byte{[]byte("aaa111\n"), nil},
+			errs:    []error{nil, errors.New("exit status 1")},
+		}}
+		_, err := New(r).ResolveSince(context.Background(), "1w")
+		require.ErrorContains(t, err, "pass a ref")
+	})
+}
+
+func TestCollector_AnalyzeChangesAgainstRef(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{mockRunner: mockRunner{
+		outputs: [][]byte{[]byte("A\tcmd/new.go\nM\tREADME.md\nR087\told/name.go\tnew/name.go\nM\tgo.sum")},
+		errs:    []error{nil},
+	}}
+
+	summary, err := New(r).AnalyzeChangesAgainstRef(context.Background(), "origin/main")
+	require.NoError(t, err)
+	require.Equal(t, []string{"git", "diff", "--no-ext-diff", "--no-color", "--name-status", "origin/main...HEAD"}, r.calls[0])
+	require.Equal(t, 3, summary.TotalChangedFiles) // go.sum is filtered out
+	require.Equal(t, 1, summary.ChangeTypes["added"])
+	require.Equal(t, 1, summary.ChangeTypes["modified"])
+	require.Equal(t, 1, summary.ChangeTypes["renamed"])
+	require.False(t, summary.HasUntrackedFiles)
+	require.Equal(t, "cmd/new.go", summary.FilesByPriority[0].Path)
+
+	for _, f := range summary.FilesByPriority {
+		if f.Path == "new/name.go" {
+			require.True(t, f.IsRenamed)
+			require.Equal(t, "old/name.go", f.OldPath)
+		}
+	}
+}
+
+// Test UntrackedFileAsDiff method
+func TestCollector_UntrackedFileAsDiff(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{
+				[]byte("/repo"), // RepoRoot
+				[]byte("package main\n\nfunc main() {\n\tfmt.Println(\"Hello World\")\n}"),
+			},
+			errs: []error{nil, nil},
+		}
+
+		c := New(mr)
+		diff, err := c.UntrackedFileAsDiff(context.Background(), "main.go")
+		require.NoError(t, err)
+
+		// Check diff format
+		require.Contains(t, diff, "diff --git a/main.go b/main.go")
+		require.Contains(t, diff, "new file mode 100644")
+		require.Contains(t, diff, "+package main")
+		require.Contains(t, diff, "+func main() {")
+	})
+
+	t.Run("file_read_error", func(t *testing.T) {
+		mr := &mockRunner{
+			outputs: [][]byte{[]byte("/repo"), []byte("")},
+			errs:    []error{nil, errors.New("file not found")},
+		}
+
+		c := New(mr)
+		_, err := c.UntrackedFileAsDiff(context.Background(), "nonexistent.go")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "file not found")
+	})
+}
+
+// Test enhanced AnalyzeChanges method
+func TestCollector_AnalyzeChanges_Enhanced(t *testing.T) {
+	t.Parallel()
+
+	t.Run("with_untracked_files", func(t *testing.T) {
+		// Mock git status output with some files
+		gitStatusOutput := `## main
+M  src/main.go
+A  docs/README.md
+D  old/deprecated.txt`
+		
+		// Mock untracked files
+		untrackedOutput := "newfile.py\nconfig.json"
+		
+		mr := &mockRunner{
+			outputs: [][]byte{
+				[]byte(gitStatusOutput), // FileStatusSummary
+				[]byte(untrackedOutput), // UntrackedFiles
+			},
+			errs: []error{nil, nil},
+		}
+
+		c := New(mr)
+		summary, err := c.AnalyzeChanges(context.Background())
+		require.NoError(t, err)
+
+		// Check basic fields
 		require.True(t, summary.HasStagedChanges)
 		require.True(t, summary.HasUntrackedFiles)
 		require.Equal(t, 5, summary.TotalChangedFiles) // 3 tracked + 2 untracked
@@ -802,3 +2196,57 @@ func TestCollector_BackwardCompatibility(t *testing.T) {
 		var _ LegacyCollectorInterface = c
 	})
 }
+
+func TestCollector_HeadMessage(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{mockRunner: mockRunner{
+		outputs: [][]byte{[]byte("fix: old subject\n\nbody\n\n")},
+		errs:    []error{nil},
+	}}
+	msg, err := New(r).HeadMessage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "fix: old subject\n\nbody", msg)
+	require.Equal(t, []string{"git", "log", "-1", "--pretty=%B"}, r.calls[0])
+}
+
+func TestCollector_Amend(t *testing.T) {
+	t.Parallel()
+
+	t.Run("diff_against_parent", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{
+			outputs: [][]byte{[]byte("abc123\n"), []byte("diff --git a/a.go b/a.go\n+package a")},
+			errs:    []error{nil, nil},
+		}}
+		c := New(r)
+		c.SetAmend(true)
+		diff, err := c.ComprehensiveDiff(context.Background())
+		require.NoError(t, err)
+		require.Contains(t, diff, "+package a")
+		require.Equal(t, []string{"git", "rev-parse", "--verify", "--quiet", "HEAD^"}, r.calls[0])
+		require.Equal(t, []string{"git", "diff", "--no-ext-diff", "--no-color", "--cached", "abc123"}, r.calls[1])
+	})
+
+	t.Run("root_commit_uses_empty_tree", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{
+			outputs: [][]byte{nil, []byte("4b825dc\n"), []byte("A\tmain.go\nA\tgo.sum")},
+			errs:    []error{errors.New("exit status 1"), nil, nil},
+		}}
+		c := New(r)
+		c.SetAmend(true)
+		files, err := c.ChangedFiles(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"main.go"}, files)
+		require.Equal(t, []string{"git", "hash-object", "-t", "tree", os.DevNull}, r.calls[1])
+		require.Equal(t, []string{"git", "diff", "--no-ext-diff", "--no-color", "--cached", "--name-status", "4b825dc"}, r.calls[2])
+	})
+
+	t.Run("recent_commits_skip_head", func(t *testing.T) {
+		r := &recordingRunner{mockRunner: mockRunner{outputs: [][]byte{[]byte("feat: a")}, errs: []error{nil}}}
+		c := New(r)
+		c.SetAmend(true)
+		_, err := c.RecentCommits(context.Background(), 3)
+		require.NoError(t, err)
+		require.Equal(t, []string{"git", "log", "--pretty=format:%s", "-n3", "--skip=1"}, r.calls[0])
+	})
+}
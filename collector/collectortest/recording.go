@@ -0,0 +1,118 @@
+// Package collectortest provides test helpers for code built on the collector package.
+package collectortest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Call is one command executed through a RecordingRunner
+type Call struct {
+	Name string
+	Args []string
+}
+
+// String returns the command line, e.g. "git diff --cached"
+func (c Call) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+type response struct {
+	pattern string
+	output  []byte
+	err     error
+}
+
+// RecordingRunner implements collector.Runner. It records every command and answers
+// each one with the response registered for the longest pattern matching it, so tests
+// do not depend on the order in which the collector runs its commands.
+// A pattern matches a command line equal to it or starting with it followed by a space:
+// "git diff" matches "git diff --cached", while "git diff --cached" is preferred for it
+// when both are registered. Commands without a matching pattern fail.
+// It is safe for concurrent use.
+//
+// Example:
+//
+//	runner := collectortest.NewRecordingRunner().
+//		On("git diff --no-ext-diff --no-color --cached", "diff --git a/a.go b/a.go", nil).
+//		On("git", "", nil)
+//	diff, err := collector.New(runner).StagedDiff(ctx)
+type RecordingRunner struct {
+	mu        sync.Mutex
+	responses []response
+	calls     []Call
+}
+
+// NewRecordingRunner creates a RecordingRunner without any responses
+func NewRecordingRunner() *RecordingRunner {
+	return &RecordingRunner{}
+}
+
+// On registers output and err as the response to commands matching pattern; a pattern
+// registered again replaces its earlier response. It returns r for chaining
+func (r *RecordingRunner) On(pattern, output string, err error) *RecordingRunner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.responses {
+		if r.responses[i].pattern == pattern {
+			r.responses[i].output, r.responses[i].err = []byte(output), err
+			return r
+		}
+	}
+	r.responses = append(r.responses, response{pattern: pattern, output: []byte(output), err: err})
+	return r
+}
+
+// Run records the command and returns the response of the longest matching pattern
+func (r *RecordingRunner) Run(_ context.Context, name string, args ...string) ([]byte, error) {
+	call := Call{Name: name, Args: append([]string(nil), args...)}
+	line := call.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+	var best *response
+	for i := range r.responses {
+		resp := &r.responses[i]
+		if matches(resp.pattern, line) && (best == nil || len(resp.pattern) > len(best.pattern)) {
+			best = resp
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("collectortest: unexpected command %q", line)
+	}
+	return append([]byte(nil), best.output...), best.err
+}
+
+// Calls returns the commands run so far, in the order they started
+func (r *RecordingRunner) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Commands returns the command lines run so far, in the order they started
+func (r *RecordingRunner) Commands() []string {
+	calls := r.Calls()
+	lines := make([]string, len(calls))
+	for i, call := range calls {
+		lines[i] = call.String()
+	}
+	return lines
+}
+
+// Ran reports whether a command matching pattern has been run
+func (r *RecordingRunner) Ran(pattern string) bool {
+	for _, line := range r.Commands() {
+		if matches(pattern, line) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(pattern, line string) bool {
+	return line == pattern || strings.HasPrefix(line, pattern+" ")
+}
@@ -0,0 +1,70 @@
+package collectortest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingRunner_MatchesLongestPattern(t *testing.T) {
+	t.Parallel()
+
+	failure := errors.New("exit status 1")
+	r := NewRecordingRunner().
+		On("git diff", "unstaged", nil).
+		On("git diff --cached", "staged", nil).
+		On("git config", "", failure)
+
+	out, err := r.Run(context.Background(), "git", "diff", "--cached")
+	require.NoError(t, err)
+	require.Equal(t, "staged", string(out))
+
+	out, err = r.Run(context.Background(), "git", "diff")
+	require.NoError(t, err)
+	require.Equal(t, "unstaged", string(out))
+
+	_, err = r.Run(context.Background(), "git", "config", "--get", "branch.main.description")
+	require.ErrorIs(t, err, failure)
+
+	// 只匹配完整的参数，"git diff" 不匹配 "git difftool"
+	_, err = r.Run(context.Background(), "git", "difftool")
+	require.ErrorContains(t, err, `unexpected command "git difftool"`)
+
+	require.Equal(t, []string{
+		"git diff --cached",
+		"git diff",
+		"git config --get branch.main.description",
+		"git difftool",
+	}, r.Commands())
+	require.Equal(t, Call{Name: "git", Args: []string{"diff", "--cached"}}, r.Calls()[0])
+	require.True(t, r.Ran("git config"))
+	require.False(t, r.Ran("git log"))
+}
+
+func TestRecordingRunner_OnReplaces(t *testing.T) {
+	t.Parallel()
+
+	r := NewRecordingRunner().On("git log", "old", nil).On("git log", "new", nil)
+	out, err := r.Run(context.Background(), "git", "log")
+	require.NoError(t, err)
+	require.Equal(t, "new", string(out))
+}
+
+func TestRecordingRunner_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	r := NewRecordingRunner().On("head", "content", nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = r.Run(context.Background(), "head", "-c", "10", "file")
+		}()
+	}
+	wg.Wait()
+	require.Len(t, r.Calls(), 20)
+}
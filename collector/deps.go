@@ -0,0 +1,46 @@
+package collector
+
+import "fmt"
+
+// DependencyChange 描述锁文件 diff 中一个依赖的版本变化；From 为空表示新增，To 为空表示移除
+type DependencyChange struct {
+	Name string
+	From string
+	To   string
+}
+
+// String 以 "name: from → to" 的形式描述版本变化，新增与移除分别写作 "name: added to" 与 "name: removed from"
+func (d DependencyChange) String() string {
+	switch {
+	case d.From == "":
+		return d.Name + ": added " + d.To
+	case d.To == "":
+		return d.Name + ": removed " + d.From
+	default:
+		return d.Name + ": " + d.From + " → " + d.To
+	}
+}
+
+// DependencySubject 为依赖版本变化生成简短的提交标题（不含类型前缀），
+// 例如 "bump lodash to 4.17.21 and 2 others"；changes 为空时返回 "update dependencies"
+func DependencySubject(changes []DependencyChange) string {
+	if len(changes) == 0 {
+		return "update dependencies"
+	}
+	first := changes[0]
+	subject := "bump " + first.Name + " to " + first.To
+	switch {
+	case first.From == "":
+		subject = "add " + first.Name + " " + first.To
+	case first.To == "":
+		subject = "remove " + first.Name
+	}
+	switch others := len(changes) - 1; others {
+	case 0:
+	case 1:
+		subject += " and 1 other"
+	default:
+		subject += fmt.Sprintf(" and %d others", others)
+	}
+	return subject
+}
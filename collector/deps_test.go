@@ -0,0 +1,18 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencySubject(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "update dependencies", DependencySubject(nil))
+	bump := DependencyChange{Name: "lodash", From: "4.17.20", To: "4.17.21"}
+	require.Equal(t, "bump lodash to 4.17.21", DependencySubject([]DependencyChange{bump}))
+	require.Equal(t, "add zod 3.23.8 and 1 other", DependencySubject([]DependencyChange{{Name: "zod", To: "3.23.8"}, bump}))
+	require.Equal(t, "remove left-pad and 2 others", DependencySubject([]DependencyChange{{Name: "left-pad", From: "1.3.0"}, bump, bump}))
+	require.Equal(t, "lodash: 4.17.20 → 4.17.21", bump.String())
+}
@@ -10,9 +10,16 @@ import (
 // Methods in this interface directly correspond to git commands:
 // - StagedDiff: `git diff --cached`
 // - UnstagedDiff: `git diff`
+// - DiffStat: `git diff --cached --stat` and `git diff --stat`
 // - GitStatus: `git status --porcelain`
 // - RecentCommits: `git log --pretty=format:%s -n<count>`
 // - BranchName: `git rev-parse --abbrev-ref HEAD`
+// - BranchDescription: `git config --get branch.<name>.description`
+// - IsInitialCommit: `git rev-parse --verify --quiet HEAD`
+// - RepoRoot: `git rev-parse --show-toplevel`
+// - HeadMessage: `git log -1 --pretty=%B`
+// - CommitsOnBranch: `git log <base>..HEAD --format=%s`
+// - ResolveSince: `git log --since=<time> --format=%H`
 //
 // Example usage:
 //
@@ -30,10 +37,20 @@ type GitReader interface {
 	// Returns empty string if no unstaged changes are found
 	UnstagedDiff(ctx context.Context) (string, error)
 	
+	// DiffStat returns git's --stat summary lines for staged and unstaged changes,
+	// e.g. "staged: 5 files changed, 120 insertions(+), 30 deletions(-)"
+	// Returns empty string if there are no tracked changes
+	DiffStat(ctx context.Context) (string, error)
+	
 	// GitStatus returns git status in porcelain format
 	// Format: XY filename, where X is index status, Y is worktree status
 	GitStatus(ctx context.Context) (string, error)
 	
+	// DiffAgainstRef returns committed changes since the merge base with baseRef
+	// (equivalent to `git diff <baseRef>...HEAD`)
+	// Returns ErrNoDiff if the branch has no changes against baseRef
+	DiffAgainstRef(ctx context.Context, baseRef string) (string, error)
+	
 	// RecentCommits returns the last n commit messages (subject only)
 	// Returns error if n <= 0 or n > 1000 (performance protection)
 	RecentCommits(ctx context.Context, n int) ([]string, error)
@@ -41,6 +58,34 @@ type GitReader interface {
 	// BranchName returns the current branch name
 	// Returns error if not in a git repository or branch name is invalid
 	BranchName(ctx context.Context) (string, error)
+	
+	// BranchDescription returns the description of the current branch
+	// (set with `git branch --edit-description`)
+	// Returns empty string if none is set or HEAD is detached
+	BranchDescription(ctx context.Context) (string, error)
+	
+	// IsInitialCommit reports whether the repository has no commits yet,
+	// so the next commit is the initial one
+	IsInitialCommit(ctx context.Context) (bool, error)
+	
+	// RepoRoot returns the top-level directory of the current working tree
+	// (equivalent to `git rev-parse --show-toplevel`), which is the linked
+	// worktree's own root rather than the main repository's
+	RepoRoot(ctx context.Context) (string, error)
+	
+	// HeadMessage returns the full message of the HEAD commit
+	// (equivalent to `git log -1 --pretty=%B`), trimmed of surrounding whitespace
+	HeadMessage(ctx context.Context) (string, error)
+	
+	// CommitsOnBranch returns the subjects of the commits reachable from HEAD
+	// but not from baseRef (equivalent to `git log <baseRef>..HEAD --format=%s`),
+	// oldest first. Returns an empty slice if the branch has no own commits
+	CommitsOnBranch(ctx context.Context, baseRef string) ([]string, error)
+	
+	// ResolveSince resolves a ref (returned as is) or a duration such as 2d
+	// (via `git log --since`) to the base ref of the selected changes.
+	// Returns ErrNoDiff if no commit falls within the duration
+	ResolveSince(ctx context.Context, since string) (string, error)
 }
 
 // ChangeMagnitude represents the scale of changes in the commit
@@ -71,6 +116,8 @@ type ChangesSummary struct {
 	Priority          int             // Overall change priority (1-100)
 	SuggestedPrefix   string          // Suggested commit prefix (feat, fix, etc.)
 	FilesByPriority   []FileStatus    // Files sorted by priority
+	ModeChanges       []ModeChange    // Files whose permission bits changed, e.g. chmod +x
+	PartiallyStaged   []string        // Files with both staged and unstaged changes, e.g. after git add -p
 }
 
 // ChangeAnalyzer provides high-level analysis of repository changes.
@@ -95,6 +142,10 @@ type ChangeAnalyzer interface {
 	// Combines staged and unstaged changes into a comprehensive analysis
 	AnalyzeChanges(ctx context.Context) (*ChangesSummary, error)
 	
+	// AnalyzeChangesAgainstRef summarizes committed changes since the merge base
+	// with baseRef, e.g. to describe a whole branch for a pull request
+	AnalyzeChangesAgainstRef(ctx context.Context, baseRef string) (*ChangesSummary, error)
+	
 	// GetPriorityFiles returns files ordered by importance for commit message generation
 	// Files are sorted by: change type priority, file type importance, and path
 	GetPriorityFiles(ctx context.Context) ([]FileStatus, error)
@@ -115,9 +115,10 @@ func TestNewInterfaceImplementations(t *testing.T) {
 		mr := &mockRunner{
 			outputs: [][]byte{
 				[]byte("untracked_file.txt\nnew_script.go\n"),  // UntrackedFiles
+				[]byte("/repo"),                                         // RepoRoot
 				[]byte("package main\n\nfunc main() {\n\t// Hello world\n}"), // UntrackedFileContent
 			},
-			errs: []error{nil, nil},
+			errs: []error{nil, nil, nil},
 		}
 
 		collector := New(mr)
@@ -349,8 +350,78 @@ func TestPerformanceOptimizations(t *testing.T) {
 		assert.Equal(t, "git", gitErr.Command)
 		assert.Contains(t, gitErr.Context, "failed after")
 	})
+
+	t.Run("RetryableErrorNotCached", func(t *testing.T) {
+		mr := &enhancedMockRunner{
+			outputs: [][]byte{nil, []byte("## main")},
+			errs:    []error{fmt.Errorf("connection reset by peer"), nil},
+		}
+
+		collector := New(mr)
+		out, err := collector.runWithRetry(context.Background(), &RetryConfig{
+			MaxRetries: 1,
+			InitialDelay: 1 * time.Millisecond,
+			MaxDelay: 10 * time.Millisecond,
+			BackoffFactor: 2.0,
+		}, "git", "status")
+		
+		// 第一次的网络错误没有被缓存，重试时重新执行了命令
+		require.NoError(t, err)
+		assert.Equal(t, "## main", string(out))
+		assert.Equal(t, 2, mr.callCount)
+		
+		// 成功的结果仍然会被缓存
+		_, err = collector.runWithCache(context.Background(), "git", "status")
+		require.NoError(t, err)
+		assert.Equal(t, 2, mr.callCount)
+	})
+
+	t.Run("ErrorStderr", func(t *testing.T) {
+		mr := &enhancedMockRunner{
+			outputs: [][]byte{[]byte("To github.com:owner/repo.git\n ! [rejected] main -> main (fetch first)\nerror: failed to push some refs\n")},
+			errs:    []error{exitCodeError{code: 1}},
+		}
+
+		collector := New(mr)
+		_, err := collector.runWithRetry(context.Background(), &RetryConfig{MaxRetries: 0}, "git", "push")
+
+		gitErr, ok := AsGitError(fmt.Errorf("wrapped: %w", err))
+		require.True(t, ok)
+		assert.Equal(t, 1, gitErr.ExitCode)
+		assert.Contains(t, gitErr.Stderr, "failed to push some refs")
+		assert.Contains(t, err.Error(), "error: failed to push some refs")
+
+		_, ok = AsGitError(errors.New("plain error"))
+		assert.False(t, ok)
+	})
+
+	t.Run("ErrorSeparateStderr", func(t *testing.T) {
+		mr := &enhancedMockRunner{
+			outputs: [][]byte{[]byte("partial output\nfatal: bad revision\n")},
+			errs: []error{&CommandError{
+				Err:    exitCodeError{code: 128},
+				Stdout: []byte("partial output\n"),
+				Stderr: []byte("fatal: bad revision\n"),
+			}},
+		}
+
+		collector := New(mr)
+		_, err := collector.runWithRetry(context.Background(), &RetryConfig{MaxRetries: 0}, "git", "log")
+
+		gitErr, ok := AsGitError(err)
+		require.True(t, ok)
+		assert.Equal(t, 128, gitErr.ExitCode)
+		assert.Equal(t, "fatal: bad revision", gitErr.Stderr)
+		assert.Equal(t, "partial output", gitErr.Stdout)
+	})
 }
 
+// exitCodeError 模拟 exec.ExitError 的退出码接口
+type exitCodeError struct{ code int }
+
+func (e exitCodeError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+func (e exitCodeError) ExitCode() int  { return e.code }
+
 // Benchmarks for performance testing
 func BenchmarkCollectorOperations(b *testing.B) {
 	mr := &mockRunner{
@@ -0,0 +1,265 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupportedLockfile 表示没有可比较该锁文件完整内容的解析器
+var ErrUnsupportedLockfile = errors.New("unsupported lockfile")
+
+// LockfileDiffer 比较同一锁文件的新旧完整内容，返回依赖版本变化；每个生态各有一个实现。
+// 新增或删除的锁文件以空字符串作为缺失一侧的内容
+type LockfileDiffer interface {
+	LockfileDiff(path, oldContent, newContent string) ([]DependencyChange, error)
+}
+
+// lockfileDiffers 按文件名列出支持完整内容比较的锁文件
+var lockfileDiffers = map[string]LockfileDiffer{
+	"package-lock.json": packageLockDiffer{},
+	"go.mod":            goModDiffer{},
+	"go.sum":            goSumDiffer{},
+	"Cargo.lock":        cargoLockDiffer{},
+}
+
+// LockfileDiff 使用 path 对应生态的 LockfileDiffer 比较新旧内容，结果按依赖名排序；
+// 不支持的锁文件返回 ErrUnsupportedLockfile
+func LockfileDiff(p, oldContent, newContent string) ([]DependencyChange, error) {
+	differ, ok := lockfileDiffers[path.Base(p)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedLockfile, p)
+	}
+	return differ.LockfileDiff(p, oldContent, newContent)
+}
+
+// diffVersions 比较两份依赖名到版本的映射，返回按依赖名排序的变化
+func diffVersions(from, to map[string]string) []DependencyChange {
+	var changes []DependencyChange
+	for name, version := range from {
+		if to[name] != version {
+			changes = append(changes, DependencyChange{Name: name, From: version, To: to[name]})
+		}
+	}
+	for name, version := range to {
+		if _, ok := from[name]; !ok {
+			changes = append(changes, DependencyChange{Name: name, To: version})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// packageLockDiffer 解析 npm 的 package-lock.json：v2/v3 的 packages 与 v1 的 dependencies，
+// 只比较顶层依赖，node_modules 中嵌套的重复版本不计入
+type packageLockDiffer struct{}
+
+func (packageLockDiffer) LockfileDiff(p, oldContent, newContent string) ([]DependencyChange, error) {
+	from, err := parsePackageLock(oldContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse old %s: %w", p, err)
+	}
+	to, err := parsePackageLock(newContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse new %s: %w", p, err)
+	}
+	return diffVersions(from, to), nil
+}
+
+func parsePackageLock(content string) (map[string]string, error) {
+	versions := make(map[string]string)
+	if strings.TrimSpace(content) == "" {
+		return versions, nil
+	}
+	type entry struct {
+		Version string `json:"version"`
+	}
+	var lock struct {
+		Packages     map[string]entry `json:"packages"`
+		Dependencies map[string]entry `json:"dependencies"`
+	}
+	if err := json.Unmarshal([]byte(content), &lock); err != nil {
+		return nil, err
+	}
+	if len(lock.Packages) > 0 {
+		for key, e := range lock.Packages {
+			name, ok := strings.CutPrefix(key, "node_modules/")
+			if !ok || strings.Contains(name, "/node_modules/") || e.Version == "" {
+				continue
+			}
+			versions[name] = e.Version
+		}
+		return versions, nil
+	}
+	for name, e := range lock.Dependencies {
+		if e.Version != "" {
+			versions[name] = e.Version
+		}
+	}
+	return versions, nil
+}
+
+// goModDiffer 解析 go.mod 中单行与块形式的 require 指令
+type goModDiffer struct{}
+
+func (goModDiffer) LockfileDiff(_, oldContent, newContent string) ([]DependencyChange, error) {
+	return diffVersions(parseGoMod(oldContent), parseGoMod(newContent)), nil
+}
+
+func parseGoMod(content string) map[string]string {
+	versions := make(map[string]string)
+	inRequire := false
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inRequire:
+			if fields[0] == ")" {
+				inRequire = false
+			} else if len(fields) >= 2 {
+				versions[fields[0]] = fields[1]
+			}
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inRequire = true
+		case fields[0] == "require" && len(fields) >= 3:
+			versions[fields[1]] = fields[2]
+		}
+	}
+	return versions
+}
+
+// goSumDiffer 解析 go.sum；同一模块保留多个版本时取最后一条，go mod tidy 按版本升序排列
+type goSumDiffer struct{}
+
+func (goSumDiffer) LockfileDiff(_, oldContent, newContent string) ([]DependencyChange, error) {
+	return diffVersions(parseGoSum(oldContent), parseGoSum(newContent)), nil
+}
+
+func parseGoSum(content string) map[string]string {
+	versions := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		versions[fields[0]] = strings.TrimSuffix(fields[1], "/go.mod")
+	}
+	return versions
+}
+
+var (
+	tomlBlockName    = regexp.MustCompile(`^name = "([^"]+)"$`)
+	tomlBlockVersion = regexp.MustCompile(`^version = "([^"]+)"$`)
+)
+
+// cargoLockDiffer 解析 Cargo.lock 的 [[package]] 表；同名 crate 的多个版本以 ", " 连接
+type cargoLockDiffer struct{}
+
+func (cargoLockDiffer) LockfileDiff(_, oldContent, newContent string) ([]DependencyChange, error) {
+	return diffVersions(parseCargoLock(oldContent), parseCargoLock(newContent)), nil
+}
+
+func parseCargoLock(content string) map[string]string {
+	all := make(map[string][]string)
+	name := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			name = ""
+			continue
+		}
+		if m := tomlBlockName.FindStringSubmatch(line); m != nil {
+			name = m[1]
+		} else if m := tomlBlockVersion.FindStringSubmatch(line); m != nil && name != "" {
+			all[name] = append(all[name], m[1])
+		}
+	}
+	versions := make(map[string]string, len(all))
+	for name, list := range all {
+		sort.Strings(list)
+		versions[name] = strings.Join(list, ", ")
+	}
+	return versions
+}
+
+// DependencyChanges 比较本次变更中每个受支持锁文件在 HEAD（amend 时为 HEAD 的父提交）
+// 与待提交版本中的内容，返回依赖版本变化。待提交版本在只收集暂存区时取自暂存区，否则取自工作区。
+// go.mod 有变更时跳过 go.sum
+func (c *Collector) DependencyChanges(ctx context.Context) ([]DependencyChange, error) {
+	diff, err := c.ComprehensiveDiff(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNoDiff) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var paths []string
+	hasGoMod := false
+	for _, section := range splitDiffByFile(diff) {
+		p := section.status.Path
+		if _, ok := lockfileDiffers[path.Base(p)]; ok {
+			paths = append(paths, p)
+			hasGoMod = hasGoMod || path.Base(p) == "go.mod"
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	base := "HEAD"
+	if c.amend {
+		if base, err = c.amendBase(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var changes []DependencyChange
+	for _, p := range paths {
+		if hasGoMod && path.Base(p) == "go.sum" {
+			continue
+		}
+		// 新增的锁文件在基准中不存在，按空内容处理
+		oldContent, _ := c.runner.Run(ctx, "git", "show", base+":"+p)
+		newContent, err := c.pendingContent(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		fileChanges, err := LockfileDiff(p, string(oldContent), newContent)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, fileChanges...)
+	}
+	return changes, nil
+}
+
+// pendingContent 返回文件将被提交的内容；文件已删除时返回空字符串
+func (c *Collector) pendingContent(ctx context.Context, p string) (string, error) {
+	if c.amend || c.stagedOnly {
+		// 已删除的文件不在暂存区中，git show 失败
+		out, _ := c.runner.Run(ctx, "git", "show", ":"+p)
+		return string(out), nil
+	}
+	root, err := c.RepoRoot(ctx)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(p)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/penwyp/catmit/collector/collectortest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockfileDiff(t *testing.T) {
+	t.Parallel()
+
+	t.Run("package_lock_v3", func(t *testing.T) {
+		oldLock := `{"lockfileVersion": 3, "packages": {
+			"": {"name": "web"},
+			"node_modules/lodash": {"version": "4.17.20"},
+			"node_modules/left-pad": {"version": "1.3.0"},
+			"node_modules/@babel/core": {"version": "7.23.0"},
+			"node_modules/@babel/core/node_modules/semver": {"version": "6.3.0"}
+		}}`
+		newLock := `{"lockfileVersion": 3, "packages": {
+			"": {"name": "web"},
+			"node_modules/lodash": {"version": "4.17.21"},
+			"node_modules/@babel/core": {"version": "7.23.0"},
+			"node_modules/@babel/core/node_modules/semver": {"version": "6.3.1"},
+			"node_modules/zod": {"version": "3.23.8"}
+		}}`
+		changes, err := LockfileDiff("web/package-lock.json", oldLock, newLock)
+		require.NoError(t, err)
+		require.Equal(t, []DependencyChange{
+			{Name: "left-pad", From: "1.3.0"},
+			{Name: "lodash", From: "4.17.20", To: "4.17.21"},
+			{Name: "zod", To: "3.23.8"},
+		}, changes)
+	})
+
+	t.Run("package_lock_v1", func(t *testing.T) {
+		changes, err := LockfileDiff("package-lock.json",
+			`{"lockfileVersion": 1, "dependencies": {"lodash": {"version": "4.17.20"}}}`,
+			`{"lockfileVersion": 1, "dependencies": {"lodash": {"version": "4.17.21"}}}`)
+		require.NoError(t, err)
+		require.Equal(t, []DependencyChange{{Name: "lodash", From: "4.17.20", To: "4.17.21"}}, changes)
+
+		_, err = LockfileDiff("package-lock.json", "", "{")
+		require.ErrorContains(t, err, "failed to parse new package-lock.json")
+	})
+
+	t.Run("go_mod", func(t *testing.T) {
+		oldMod := "module example.com/app\n\ngo 1.22\n\nrequire github.com/spf13/cobra v1.8.0\n\nrequire (\n\tgo.uber.org/zap v1.26.0\n)\n"
+		newMod := "module example.com/app\n\ngo 1.22\n\nrequire github.com/spf13/cobra v1.8.0\n\nrequire (\n\tgo.uber.org/zap v1.27.0\n\tgolang.org/x/sync v0.7.0 // indirect\n)\n\nreplace example.com/old => ./old\n"
+		changes, err := LockfileDiff("go.mod", oldMod, newMod)
+		require.NoError(t, err)
+		require.Equal(t, []DependencyChange{
+			{Name: "go.uber.org/zap", From: "v1.26.0", To: "v1.27.0"},
+			{Name: "golang.org/x/sync", To: "v0.7.0"},
+		}, changes)
+	})
+
+	t.Run("go_sum", func(t *testing.T) {
+		oldSum := "go.uber.org/zap v1.26.0 h1:abc=\ngo.uber.org/zap v1.26.0/go.mod h1:def=\n"
+		newSum := oldSum + "go.uber.org/zap v1.27.0 h1:ghi=\ngo.uber.org/zap v1.27.0/go.mod h1:jkl=\n"
+		changes, err := LockfileDiff("go.sum", oldSum, newSum)
+		require.NoError(t, err)
+		require.Equal(t, []DependencyChange{{Name: "go.uber.org/zap", From: "v1.26.0", To: "v1.27.0"}}, changes)
+	})
+
+	t.Run("cargo_lock", func(t *testing.T) {
+		oldLock := "version = 3\n\n[[package]]\nname = \"serde\"\nversion = \"1.0.196\"\nsource = \"registry+https://github.com/rust-lang/crates.io-index\"\n\n" +
+			"[[package]]\nname = \"syn\"\nversion = \"1.0.109\"\n\n[[package]]\nname = \"syn\"\nversion = \"2.0.48\"\n"
+		newLock := "version = 3\n\n[[package]]\nname = \"serde\"\nversion = \"1.0.197\"\ndependencies = [\n \"serde_derive\",\n]\n\n" +
+			"[[package]]\nname = \"syn\"\nversion = \"2.0.48\"\n"
+		changes, err := LockfileDiff("Cargo.lock", oldLock, newLock)
+		require.NoError(t, err)
+		require.Equal(t, []DependencyChange{
+			{Name: "serde", From: "1.0.196", To: "1.0.197"},
+			{Name: "syn", From: "1.0.109, 2.0.48", To: "2.0.48"},
+		}, changes)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := LockfileDiff("yarn.lock", "", "")
+		require.True(t, errors.Is(err, ErrUnsupportedLockfile))
+	})
+}
+
+func TestCollector_DependencyChanges(t *testing.T) {
+	t.Parallel()
+
+	r := collectortest.NewRecordingRunner().
+		On("git diff --no-ext-diff --no-color --cached", "diff --git a/go.mod b/go.mod\n+\tgo.uber.org/zap v1.27.0\n"+
+			"diff --git a/go.sum b/go.sum\n+go.uber.org/zap v1.27.0 h1:ghi=\n"+
+			"diff --git a/main.go b/main.go\n+package main\n", nil).
+		On("git show HEAD:go.mod", "module app\n\nrequire go.uber.org/zap v1.26.0\n", nil).
+		On("git show :go.mod", "module app\n\nrequire go.uber.org/zap v1.27.0\n", nil)
+
+	c := New(r)
+	c.SetStagedOnly(true)
+	changes, err := c.DependencyChanges(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []DependencyChange{{Name: "go.uber.org/zap", From: "v1.26.0", To: "v1.27.0"}}, changes)
+	require.False(t, r.Ran("git show HEAD:go.sum"), "go.sum is skipped when go.mod changed")
+}
@@ -0,0 +1,142 @@
+// Package config loads catmit settings from the global config file and a
+// repository-local .catmit.yaml.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoConfigFile 是仓库级配置文件名，位于 Git 仓库根目录
+const RepoConfigFile = ".catmit.yaml"
+
+// Config 是配置文件中支持的设置项，全局配置与仓库配置共用同一结构。
+// 零值（空串、nil、0）表示未设置，由更低优先级的来源或默认值决定。
+type Config struct {
+	Lang           string   `yaml:"lang"`            // commit message 语言
+	Exclude        []string `yaml:"exclude"`         // 不纳入 diff 的文件 glob 模式
+	CommitTemplate string   `yaml:"commit_template"` // message 需要遵循的模板
+	Preset         string   `yaml:"preset"`          // 提示词预设名称
+	MaxSubject     int      `yaml:"max_subject"`     // 标题行最大长度
+	Gitmoji        *bool    `yaml:"gitmoji"`         // 是否在标题前添加 gitmoji
+	Push           *bool    `yaml:"push"`            // 提交后是否自动 push
+	StageAll       *bool    `yaml:"stage_all"`       // 无暂存变更时是否自动 stage 全部变更
+	KeepOpen       *bool    `yaml:"keep_open"`       // 完成后是否停留在 TUI 摘要界面等待按键
+
+	ProtectedBranches []string `yaml:"protected_branches"` // 推送前需要确认的分支模式
+	LintDisable       []string `yaml:"lint_disable"`       // catmit lint 中禁用的规则
+	LintEnable        []string `yaml:"lint_enable"`        // catmit lint 中启用的默认关闭的规则
+	DisableSignature  bool     `yaml:"disable_signature"`  // 忽略 --signature，不添加 Generated-by trailer
+
+	TestCommand  string            `yaml:"test_command"`  // --require-tests 执行的命令，优先于按项目类型选择
+	TestCommands map[string]string `yaml:"test_commands"` // 项目类型（go、node）到测试命令的映射
+	PrefixMap    map[string]string `yaml:"prefix_map"`    // 变更类别（added、modified 等）到提交类型的映射
+}
+
+// Merge 返回以 over 中已设置的字段覆盖 c 的新配置，两者均不会被修改
+func (c *Config) Merge(over *Config) *Config {
+	merged := *c
+	if over == nil {
+		return &merged
+	}
+	if over.Lang != "" {
+		merged.Lang = over.Lang
+	}
+	if over.Exclude != nil {
+		merged.Exclude = over.Exclude
+	}
+	if over.CommitTemplate != "" {
+		merged.CommitTemplate = over.CommitTemplate
+	}
+	if over.Preset != "" {
+		merged.Preset = over.Preset
+	}
+	if over.MaxSubject != 0 {
+		merged.MaxSubject = over.MaxSubject
+	}
+	if over.Gitmoji != nil {
+		merged.Gitmoji = over.Gitmoji
+	}
+	if over.Push != nil {
+		merged.Push = over.Push
+	}
+	if over.StageAll != nil {
+		merged.StageAll = over.StageAll
+	}
+	if over.KeepOpen != nil {
+		merged.KeepOpen = over.KeepOpen
+	}
+	if over.ProtectedBranches != nil {
+		merged.ProtectedBranches = over.ProtectedBranches
+	}
+	if over.LintDisable != nil {
+		merged.LintDisable = over.LintDisable
+	}
+	if over.LintEnable != nil {
+		merged.LintEnable = over.LintEnable
+	}
+	if over.TestCommand != "" {
+		merged.TestCommand = over.TestCommand
+	}
+	if over.TestCommands != nil {
+		merged.TestCommands = over.TestCommands
+	}
+	if over.PrefixMap != nil {
+		merged.PrefixMap = over.PrefixMap
+	}
+	// 任一配置禁用签名即生效，更高优先级的配置无法重新启用
+	if over.DisableSignature {
+		merged.DisableSignature = true
+	}
+	return &merged
+}
+
+// GlobalPath 返回全局配置文件路径 $XDG_CONFIG_HOME/catmit/config.yaml，
+// 未设置 XDG_CONFIG_HOME 时使用 ~/.config/catmit/config.yaml
+func GlobalPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "catmit", "config.yaml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "catmit", "config.yaml"), nil
+}
+
+// LoadGlobalConfig 读取全局配置文件；文件不存在时返回空配置
+func LoadGlobalConfig() (*Config, error) {
+	path, err := GlobalPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadFile(path)
+}
+
+// LoadRepoConfig 读取 repoRoot 下的 .catmit.yaml；文件不存在时返回空配置
+func LoadRepoConfig(repoRoot string) (*Config, error) {
+	return loadFile(filepath.Join(repoRoot, RepoConfigFile))
+}
+
+func loadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if cfg.MaxSubject < 0 {
+		return nil, fmt.Errorf("invalid max_subject in %s: must not be negative", path)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRepoConfig(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		cfg, err := LoadRepoConfig(t.TempDir())
+		require.NoError(t, err)
+		require.Equal(t, &Config{}, cfg)
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		root := t.TempDir()
+		content := "stage_all: false\nlang: zh\nexclude:\n  - \"*.lock\"\n  - vendor/\ncommit_template: \"<type>: <subject>\"\nmax_subject: 72\ngitmoji: true\n"
+		require.NoError(t, os.WriteFile(filepath.Join(root, RepoConfigFile), []byte(content), 0o644))
+
+		cfg, err := LoadRepoConfig(root)
+		require.NoError(t, err)
+		require.NotNil(t, cfg.StageAll)
+		require.False(t, *cfg.StageAll)
+		require.Nil(t, cfg.Push)
+		require.Equal(t, "zh", cfg.Lang)
+		require.Equal(t, []string{"*.lock", "vendor/"}, cfg.Exclude)
+		require.Equal(t, "<type>: <subject>", cfg.CommitTemplate)
+		require.Equal(t, 72, cfg.MaxSubject)
+		require.True(t, *cfg.Gitmoji)
+	})
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(root, RepoConfigFile), []byte("stage_all: [\n"), 0o644))
+
+		_, err := LoadRepoConfig(root)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), RepoConfigFile)
+	})
+}
+
+func TestLoadGlobalConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "catmit"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "catmit", "config.yaml"), []byte("push: false\n"), 0o644))
+
+	cfg, err := LoadGlobalConfig()
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Push)
+	require.False(t, *cfg.Push)
+}
+
+func TestConfig_Merge(t *testing.T) {
+	yes, no := true, false
+	global := &Config{Lang: "en", MaxSubject: 50, Push: &yes, Exclude: []string{"*.min.js"}}
+	repo := &Config{Lang: "zh", Push: &no, StageAll: &no, ProtectedBranches: []string{"prod"}}
+
+	merged := global.Merge(repo)
+	require.Equal(t, "zh", merged.Lang)
+	require.Equal(t, 50, merged.MaxSubject)
+	require.False(t, *merged.Push)
+	require.False(t, *merged.StageAll)
+	require.Equal(t, []string{"*.min.js"}, merged.Exclude)
+	require.Nil(t, merged.Gitmoji)
+	require.Equal(t, []string{"prod"}, merged.ProtectedBranches)
+
+	// 全局配置禁用签名后，仓库配置无法重新启用
+	global.DisableSignature = true
+	require.True(t, global.Merge(repo).DisableSignature)
+
+	// 原配置不应被修改
+	require.Equal(t, "en", global.Lang)
+	require.True(t, *global.Push)
+}
@@ -0,0 +1,56 @@
+// Package clipboard copies text to the system clipboard through the platform's
+// clipboard command (pbcopy, clip, wl-copy, xclip or xsel).
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable 表示当前系统没有可用的剪贴板命令
+var ErrUnavailable = errors.New("no clipboard command found (install wl-copy, xclip or xsel)")
+
+// Copy 将 text 写入系统剪贴板
+func Copy(ctx context.Context, text string) error {
+	args, err := command(runtime.GOOS, exec.LookPath, os.Getenv)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// command 返回 goos 上写入剪贴板的命令；Linux 等系统在 Wayland 会话中优先 wl-copy，
+// 否则依次尝试 xclip 与 xsel
+func command(goos string, lookPath func(string) (string, error), getenv func(string) string) ([]string, error) {
+	var candidates [][]string
+	switch goos {
+	case "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case "windows":
+		candidates = [][]string{{"clip"}}
+	default:
+		if getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, []string{"wl-copy"})
+		}
+		candidates = append(candidates,
+			[]string{"xclip", "-selection", "clipboard"},
+			[]string{"xsel", "--clipboard", "--input"},
+		)
+	}
+	for _, args := range candidates {
+		if _, err := lookPath(args[0]); err == nil {
+			return args, nil
+		}
+	}
+	return nil, ErrUnavailable
+}
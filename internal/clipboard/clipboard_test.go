@@ -0,0 +1,47 @@
+package clipboard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	installed := func(names ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, n := range names {
+				if n == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	tests := []struct {
+		name    string
+		goos    string
+		path    []string
+		env     map[string]string
+		want    []string
+		wantErr error
+	}{
+		{"macOS", "darwin", []string{"pbcopy"}, nil, []string{"pbcopy"}, nil},
+		{"windows", "windows", []string{"clip"}, nil, []string{"clip"}, nil},
+		{"wayland prefers wl-copy", "linux", []string{"wl-copy", "xclip"}, map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, []string{"wl-copy"}, nil},
+		{"x11 ignores wl-copy", "linux", []string{"wl-copy", "xclip"}, nil, []string{"xclip", "-selection", "clipboard"}, nil},
+		{"xsel fallback", "linux", []string{"xsel"}, nil, []string{"xsel", "--clipboard", "--input"}, nil},
+		{"nothing installed", "linux", nil, nil, nil, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := command(tt.goos, installed(tt.path...), env(tt.env))
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
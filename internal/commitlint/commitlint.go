@@ -0,0 +1,181 @@
+// Package commitlint validates commit messages against the Conventional
+// Commits format catmit generates.
+package commitlint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 规则名称，用于报告违规与在配置中禁用规则
+const (
+	RuleSubjectLength = "subject-length"     // 标题行不超过 MaxSubject 个字符
+	RuleTypePrefix    = "type-prefix"        // 标题行以 <type>(<scope>): 开头且 type 合法
+	RuleBlankLine     = "body-leading-blank" // 标题与正文之间有空行
+	RuleImperative    = "subject-imperative" // 标题描述使用祈使语气
+
+	RuleFooterLeadingBlank = "footer-leading-blank"   // footer 与正文之间有空行
+	RuleFooterFormat       = "footer-format"          // footer 的值非空，BREAKING CHANGE 全部大写
+	RuleBreakingMarker     = "breaking-change-marker" // 有 BREAKING CHANGE footer 时标题带 !
+)
+
+// Rules 按检查顺序列出全部规则
+var Rules = []string{RuleSubjectLength, RuleTypePrefix, RuleBlankLine, RuleImperative, RuleFooterLeadingBlank, RuleFooterFormat, RuleBreakingMarker}
+
+// OptInRules 是默认关闭的规则，需通过 Config.Enabled 显式启用，
+// 避免已有的 commit-msg hook 因新增的 footer 检查而开始失败
+var OptInRules = []string{RuleFooterLeadingBlank, RuleFooterFormat, RuleBreakingMarker}
+
+// DefaultTypes 是 type-prefix 规则默认接受的 Conventional Commits 类型
+var DefaultTypes = []string{"feat", "fix", "refactor", "docs", "style", "test", "perf", "chore", "build", "ci", "revert"}
+
+// DefaultMaxSubject 是未配置时标题行允许的最大字符数
+const DefaultMaxSubject = 72
+
+// headerPattern 匹配 <type>(<scope>)!: <description>
+var headerPattern = regexp.MustCompile(`^([a-zA-Z]+)(\([^()]+\))?!?: (\S.*)$`)
+
+// Config 控制启用的规则及其参数，零值使用默认设置
+type Config struct {
+	MaxSubject int      // 标题行最大字符数，<= 0 时使用 DefaultMaxSubject
+	Types      []string // 允许的类型，为空时使用 DefaultTypes
+	Disabled   []string // 禁用的规则名称
+	Enabled    []string // 启用的 OptInRules 中的规则名称；同时出现在 Disabled 中时仍禁用
+}
+
+// Violation 描述一条违规
+type Violation struct {
+	Rule    string
+	Line    int // 违规所在行号，从 1 开始
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("line %d: %s (%s)", v.Line, v.Message, v.Rule)
+}
+
+// ValidateRules 检查 names 均为已知规则，用于校验配置与命令行参数
+func ValidateRules(names []string) error {
+	for _, name := range names {
+		if !contains(Rules, name) {
+			return fmt.Errorf("unknown lint rule %q (available: %s)", name, strings.Join(Rules, ", "))
+		}
+	}
+	return nil
+}
+
+// Lint 按 cfg 检查 message，返回的违规按行号排列；无违规时返回 nil。
+// 以 # 开头的注释行（如 git commit 模板中的提示）会被忽略。
+func Lint(message string, cfg Config) []Violation {
+	lines := messageLines(message)
+	if len(lines) == 0 {
+		return []Violation{{Rule: RuleTypePrefix, Line: 1, Message: "commit message is empty"}}
+	}
+
+	maxSubject := cfg.MaxSubject
+	if maxSubject <= 0 {
+		maxSubject = DefaultMaxSubject
+	}
+	types := cfg.Types
+	if len(types) == 0 {
+		types = DefaultTypes
+	}
+	enabled := func(rule string) bool {
+		if contains(OptInRules, rule) && !contains(cfg.Enabled, rule) {
+			return false
+		}
+		return !contains(cfg.Disabled, rule)
+	}
+
+	var violations []Violation
+	subject := lines[0]
+	header := stripGitmoji(subject)
+
+	if enabled(RuleSubjectLength) {
+		if n := utf8.RuneCountInString(subject); n > maxSubject {
+			violations = append(violations, Violation{
+				Rule:    RuleSubjectLength,
+				Line:    1,
+				Message: fmt.Sprintf("subject is %d characters, exceeds %d", n, maxSubject),
+			})
+		}
+	}
+
+	m := headerPattern.FindStringSubmatch(header)
+	if enabled(RuleTypePrefix) {
+		switch {
+		case m == nil:
+			violations = append(violations, Violation{
+				Rule:    RuleTypePrefix,
+				Line:    1,
+				Message: "subject must match \"<type>(<scope>): <description>\"",
+			})
+		case !contains(types, strings.ToLower(m[1])):
+			violations = append(violations, Violation{
+				Rule:    RuleTypePrefix,
+				Line:    1,
+				Message: fmt.Sprintf("unknown type %q (allowed: %s)", m[1], strings.Join(types, ", ")),
+			})
+		}
+	}
+
+	if enabled(RuleBlankLine) && len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		violations = append(violations, Violation{
+			Rule:    RuleBlankLine,
+			Line:    2,
+			Message: "body must be separated from the subject by a blank line",
+		})
+	}
+
+	if enabled(RuleImperative) {
+		if word, imperative, ok := ImperativeSuggestion(subject); ok {
+			violations = append(violations, Violation{
+				Rule:    RuleImperative,
+				Line:    1,
+				Message: fmt.Sprintf("subject should use the imperative mood: %q instead of %q", imperative, word),
+			})
+		}
+	}
+	violations = append(violations, lintFooters(lines, header, enabled)...)
+	return violations
+}
+
+// messageLines 去掉注释行与首尾空行后按行拆分
+func messageLines(message string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// stripGitmoji 去掉 --gitmoji 添加在标题前的 emoji，使类型检查不受影响
+func stripGitmoji(subject string) string {
+	if r, _ := utf8.DecodeRuneInString(subject); r < utf8.RuneSelf {
+		return subject
+	}
+	if idx := strings.IndexByte(subject, ' '); idx >= 0 {
+		return strings.TrimLeft(subject[idx:], " ")
+	}
+	return subject
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package commitlint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func rulesOf(vs []Violation) []string {
+	var rules []string
+	for _, v := range vs {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestLint_Valid(t *testing.T) {
+	for _, msg := range []string{
+		"feat(api): add endpoint",
+		"fix!: drop legacy flag",
+		"refactor: split parser\n\nThe parser was too large.",
+		"✨ feat(ui): show spinner",
+		"# Please enter the commit message\nchore: bump deps\n",
+	} {
+		require.Empty(t, Lint(msg, Config{}), msg)
+	}
+}
+
+func TestLint_Violations(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []string
+	}{
+		{"missing type", "add endpoint", []string{RuleTypePrefix}},
+		{"unknown type", "feature: add endpoint", []string{RuleTypePrefix}},
+		{"long subject", "feat: " + strings.Repeat("x", 80), []string{RuleSubjectLength}},
+		{"no blank line", "feat: add endpoint\nbody text", []string{RuleBlankLine}},
+		{"past tense", "fix(auth): Fixed token refresh", []string{RuleImperative}},
+		{"empty", "\n# comment only\n", []string{RuleTypePrefix}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, rulesOf(Lint(tt.message, Config{})))
+		})
+	}
+}
+
+func TestLint_Config(t *testing.T) {
+	msg := "feature: added endpoint"
+	require.Equal(t, []string{RuleTypePrefix}, rulesOf(Lint(msg, Config{Disabled: []string{RuleImperative}})))
+	require.Empty(t, Lint(msg, Config{Types: []string{"feature"}, Disabled: []string{RuleImperative}}))
+	require.Equal(t, []string{RuleSubjectLength}, rulesOf(Lint("feat: add endpoint", Config{MaxSubject: 10})))
+}
+
+func TestValidateRules(t *testing.T) {
+	require.NoError(t, ValidateRules([]string{RuleImperative, RuleBlankLine}))
+	require.Error(t, ValidateRules([]string{"no-such-rule"}))
+}
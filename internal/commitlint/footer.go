@@ -0,0 +1,205 @@
+package commitlint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Footer 是 message 末段中的一条 footer（git trailer），例如
+// "BREAKING CHANGE: drop Go 1.20"、"Closes: #12"、"Refs #34" 或 "Signed-off-by: A <a@b.c>"
+type Footer struct {
+	Token string // 例如 BREAKING CHANGE、Closes、Signed-off-by
+	Value string // 多行的值以换行连接
+	Line  int    // 第一行所在行号，从 1 开始（不计注释行）
+}
+
+// IsBreaking 判断 footer 是否声明了不兼容变更（BREAKING CHANGE 或同义的 BREAKING-CHANGE）
+func (f Footer) IsBreaking() bool {
+	return f.Token == breakingToken || f.Token == "BREAKING-CHANGE"
+}
+
+const breakingToken = "BREAKING CHANGE"
+
+var (
+	// footerPattern 匹配 "<token>: <value>" 或 "<token> #<value>"；token 中只有 BREAKING CHANGE 可以包含空格。
+	// 分隔符必须是 ": " 或 " #"，"Note:see below" 或以冒号结尾的 "Changes:" 之类的正文不会被当作 footer
+	footerPattern = regexp.MustCompile(`^((?i:breaking change)|[A-Za-z][A-Za-z0-9-]*)(?:: | #)(.*)$`)
+	// breakingLine 匹配任意大小写的 BREAKING CHANGE 行，用于修正位置或写法不规范的 footer
+	breakingLine = regexp.MustCompile(`^(?i:breaking[ -]change):\s*(.*)$`)
+	// breakingHeader 匹配标题的 <type>(<scope>) 部分与可选的 !
+	breakingHeader = regexp.MustCompile(`^([a-zA-Z]+(?:\([^()]+\))?)(!?): (\S.*)$`)
+)
+
+// knownFooterTokens 是常见的 footer token，出现在正文段落中间时提示缺少空行
+var knownFooterTokens = []string{
+	"breaking change", "breaking-change", "closes", "fixes", "resolves", "refs",
+	"signed-off-by", "co-authored-by", "reviewed-by", "acked-by",
+}
+
+// ParseFooters 解析 message 末段中的 footer。只有当最后一段（与标题之间有空行）
+// 的第一行形如 footer 时，该段才被视为 footer 段；不匹配的行作为上一条 footer 值的续行
+func ParseFooters(message string) []Footer {
+	lines := messageLines(message)
+	start := footerStart(lines)
+	if start < 0 {
+		return nil
+	}
+	var footers []Footer
+	for i := start; i < len(lines); i++ {
+		if m := footerPattern.FindStringSubmatch(lines[i]); m != nil {
+			footers = append(footers, Footer{Token: m[1], Value: strings.TrimSpace(m[2]), Line: i + 1})
+			continue
+		}
+		last := &footers[len(footers)-1]
+		last.Value = strings.TrimSpace(last.Value + "\n" + strings.TrimSpace(lines[i]))
+	}
+	return footers
+}
+
+// footerStart 返回 footer 段第一行的下标，没有 footer 段时返回 -1
+func footerStart(lines []string) int {
+	start := lastParagraph(lines)
+	if start <= 0 || !footerPattern.MatchString(lines[start]) {
+		return -1
+	}
+	return start
+}
+
+// lastParagraph 返回最后一段第一行的下标
+func lastParagraph(lines []string) int {
+	for i := len(lines) - 1; i > 0; i-- {
+		if lines[i-1] == "" {
+			return i
+		}
+	}
+	return 0
+}
+
+// lintFooters 检查 footer 的位置、写法，以及不兼容变更在标题中的 ! 标记
+func lintFooters(lines []string, header string, enabled func(string) bool) []Violation {
+	var violations []Violation
+	if enabled(RuleFooterLeadingBlank) {
+		// 最后一段不是 footer 段，但段中出现了常见的 footer 行，说明缺少空行
+		if start := lastParagraph(lines); start > 0 && footerStart(lines) < 0 {
+			for i := start + 1; i < len(lines); i++ {
+				if m := footerPattern.FindStringSubmatch(lines[i]); m != nil && contains(knownFooterTokens, strings.ToLower(m[1])) {
+					violations = append(violations, Violation{
+						Rule:    RuleFooterLeadingBlank,
+						Line:    i + 1,
+						Message: "footer must be separated from the body by a blank line",
+					})
+					break
+				}
+			}
+		}
+	}
+
+	footers := ParseFooters(strings.Join(lines, "\n"))
+	if enabled(RuleFooterFormat) {
+		for _, f := range footers {
+			switch {
+			case strings.EqualFold(f.Token, breakingToken) && f.Token != breakingToken:
+				violations = append(violations, Violation{
+					Rule:    RuleFooterFormat,
+					Line:    f.Line,
+					Message: fmt.Sprintf("footer token %q must be written as %q", f.Token, breakingToken),
+				})
+			case f.Value == "":
+				violations = append(violations, Violation{
+					Rule:    RuleFooterFormat,
+					Line:    f.Line,
+					Message: fmt.Sprintf("footer %q has no value", f.Token),
+				})
+			}
+		}
+	}
+
+	if enabled(RuleBreakingMarker) {
+		for _, f := range footers {
+			if f.IsBreaking() {
+				if m := breakingHeader.FindStringSubmatch(header); m != nil && m[2] == "" {
+					violations = append(violations, Violation{
+						Rule:    RuleBreakingMarker,
+						Line:    1,
+						Message: fmt.Sprintf("breaking change must be marked in the subject, e.g. %q", m[1]+"!:"),
+					})
+				}
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// FixBreakingChange 使不兼容变更的两种标记保持一致：标题带 ! 而没有 BREAKING CHANGE footer 时，
+// 以标题描述补上 footer；有 footer 而标题缺少 ! 时补上 !。写法不规范或未与正文空行分隔的
+// BREAKING CHANGE 行会被移到末尾的 footer 段。标题不符合 Conventional Commits 或没有
+// 不兼容变更时原样返回
+func FixBreakingChange(message string) string {
+	message = strings.TrimSpace(message)
+	lines := strings.Split(message, "\n")
+	subject := lines[0]
+	header := stripGitmoji(subject)
+	m := breakingHeader.FindStringSubmatch(header)
+	if m == nil {
+		return message
+	}
+	marked := m[2] == "!"
+
+	wellFormed := false
+	for _, f := range ParseFooters(message) {
+		wellFormed = wellFormed || (f.Token == breakingToken && f.Value != "")
+	}
+	if marked && wellFormed {
+		return message
+	}
+
+	// 取出所有 BREAKING CHANGE 行及其续行（直到空行或下一条 footer），其余正文保持原样
+	var descriptions, rest []string
+	inBreaking := false
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		if bm := breakingLine.FindStringSubmatch(trimmed); bm != nil {
+			descriptions = append(descriptions, strings.TrimSpace(bm[1]))
+			inBreaking = true
+			continue
+		}
+		if inBreaking && trimmed != "" && !footerPattern.MatchString(trimmed) {
+			last := &descriptions[len(descriptions)-1]
+			*last = strings.TrimSpace(*last + "\n" + trimmed)
+			continue
+		}
+		inBreaking = false
+		rest = append(rest, line)
+	}
+	kept := descriptions[:0]
+	for _, d := range descriptions {
+		if d != "" {
+			kept = append(kept, d)
+		}
+	}
+	descriptions = kept
+	if !marked && len(descriptions) == 0 {
+		return message
+	}
+	if len(descriptions) == 0 {
+		descriptions = []string{m[3]}
+	}
+
+	prefix := subject[:len(subject)-len(header)]
+	fixed := []string{prefix + m[1] + "!: " + m[3]}
+	body := strings.Trim(strings.Join(rest, "\n"), "\n")
+	footer := breakingToken + ": " + strings.Join(descriptions, "\n")
+	if body == "" {
+		return strings.Join(append(fixed, "", footer), "\n")
+	}
+	bodyLines := strings.Split(body, "\n")
+	if start := footerStart(append([]string{subject, ""}, bodyLines...)); start >= 0 {
+		// 已有 footer 段（如 Closes: #12）时将 BREAKING CHANGE 放在该段开头
+		start -= 2
+		bodyLines = append(bodyLines[:start], append([]string{footer}, bodyLines[start:]...)...)
+		return strings.Join(append(append(fixed, ""), bodyLines...), "\n")
+	}
+	return strings.Join(append(append(fixed, ""), append(bodyLines, "", footer)...), "\n")
+}
@@ -0,0 +1,89 @@
+package commitlint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFooters(t *testing.T) {
+	message := "feat(api)!: drop v1 endpoints\n\nThe v1 API was deprecated a year ago.\n\n" +
+		"BREAKING CHANGE: the /v1 routes are removed;\n  clients must use /v2\nCloses: #12\nRefs #34\nSigned-off-by: Jane <jane@example.com>"
+	require.Equal(t, []Footer{
+		{Token: "BREAKING CHANGE", Value: "the /v1 routes are removed;\nclients must use /v2", Line: 5},
+		{Token: "Closes", Value: "#12", Line: 7},
+		{Token: "Refs", Value: "34", Line: 8},
+		{Token: "Signed-off-by", Value: "Jane <jane@example.com>", Line: 9},
+	}, ParseFooters(message))
+	require.True(t, ParseFooters(message)[0].IsBreaking())
+
+	require.Nil(t, ParseFooters("feat: add endpoint\n\nThe endpoint is new."))
+	require.Nil(t, ParseFooters("Closes: #12"), "the subject is never a footer")
+	require.Nil(t, ParseFooters("feat: add endpoint\n\nChanges:\n- a new route"), "a colon must be followed by a space")
+	require.Nil(t, ParseFooters("feat: add endpoint\n\nNote:see the docs"))
+}
+
+func TestLint_Footers(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []string
+	}{
+		{"valid", "feat!: drop v1\n\nBody.\n\nBREAKING CHANGE: v1 is gone\nCloses #12", nil},
+		{"missing blank line", "fix: handle nil\n\nThe parser crashed.\nCloses: #12", []string{RuleFooterLeadingBlank}},
+		{"empty value", "fix: handle nil\n\nRefs #", []string{RuleFooterFormat}},
+		{"lowercase breaking change", "feat!: drop v1\n\nBreaking change: v1 is gone", []string{RuleFooterFormat}},
+		{"missing marker", "feat(api): drop v1\n\nBREAKING CHANGE: v1 is gone", []string{RuleBreakingMarker}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, rulesOf(Lint(tt.message, Config{Enabled: OptInRules})))
+			// footer 规则需要显式启用
+			require.Empty(t, rulesOf(Lint(tt.message, Config{})))
+		})
+	}
+
+	disabled := Config{Enabled: OptInRules, Disabled: []string{RuleBreakingMarker}}
+	require.Empty(t, Lint("feat(api): drop v1\n\nBREAKING CHANGE: v1 is gone", disabled))
+}
+
+func TestFixBreakingChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "not breaking",
+			message: "feat: add endpoint\n\nCloses #12",
+			want:    "feat: add endpoint\n\nCloses #12",
+		},
+		{
+			name:    "already consistent",
+			message: "feat!: drop v1\n\nBREAKING CHANGE: v1 is gone",
+			want:    "feat!: drop v1\n\nBREAKING CHANGE: v1 is gone",
+		},
+		{
+			name:    "marker only",
+			message: "feat(api)!: drop v1 endpoints\n\nThey were deprecated.",
+			want:    "feat(api)!: drop v1 endpoints\n\nThey were deprecated.\n\nBREAKING CHANGE: drop v1 endpoints",
+		},
+		{
+			name:    "footer only",
+			message: "✨ feat(api): drop v1\n\nBREAKING CHANGE: v1 is gone",
+			want:    "✨ feat(api)!: drop v1\n\nBREAKING CHANGE: v1 is gone",
+		},
+		{
+			name:    "footer inside the body",
+			message: "refactor: rename config keys\n\nKeys now use snake_case.\nbreaking change: old keys are\nno longer read\n\nCloses: #7",
+			want:    "refactor!: rename config keys\n\nKeys now use snake_case.\n\nBREAKING CHANGE: old keys are\nno longer read\nCloses: #7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FixBreakingChange(tt.message)
+			require.Equal(t, tt.want, got)
+			require.Empty(t, Lint(got, Config{Enabled: OptInRules, Disabled: []string{RuleImperative}}))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package commitlint
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// nonImperativeVerbs 将标题中常见的过去式、第三人称单数与动名词形式映射到祈使形式。
+// 这是启发式检查：只看描述的第一个单词，扩展时在表中添加条目即可。
+var nonImperativeVerbs = map[string]string{
+	"added": "add", "adds": "add", "adding": "add",
+	"allowed": "allow", "allows": "allow", "allowing": "allow",
+	"bumped": "bump", "bumps": "bump", "bumping": "bump",
+	"changed": "change", "changes": "change", "changing": "change",
+	"cleaned": "clean", "cleans": "clean", "cleaning": "clean",
+	"created": "create", "creates": "create", "creating": "create",
+	"deleted": "delete", "deletes": "delete", "deleting": "delete",
+	"deprecated": "deprecate", "deprecates": "deprecate", "deprecating": "deprecate",
+	"disabled": "disable", "disables": "disable", "disabling": "disable",
+	"enabled": "enable", "enables": "enable", "enabling": "enable",
+	"extracted": "extract", "extracts": "extract", "extracting": "extract",
+	"fixed": "fix", "fixes": "fix", "fixing": "fix",
+	"handled": "handle", "handles": "handle", "handling": "handle",
+	"implemented": "implement", "implements": "implement", "implementing": "implement",
+	"improved": "improve", "improves": "improve", "improving": "improve",
+	"introduced": "introduce", "introduces": "introduce", "introducing": "introduce",
+	"made": "make", "makes": "make", "making": "make",
+	"merged": "merge", "merges": "merge", "merging": "merge",
+	"moved": "move", "moves": "move", "moving": "move",
+	"optimized": "optimize", "optimizes": "optimize", "optimizing": "optimize",
+	"refactored": "refactor", "refactors": "refactor", "refactoring": "refactor",
+	"removed": "remove", "removes": "remove", "removing": "remove",
+	"renamed": "rename", "renames": "rename", "renaming": "rename",
+	"replaced": "replace", "replaces": "replace", "replacing": "replace",
+	"reverted": "revert", "reverts": "revert", "reverting": "revert",
+	"simplified": "simplify", "simplifies": "simplify", "simplifying": "simplify",
+	"supported": "support", "supports": "support", "supporting": "support",
+	"updated": "update", "updates": "update", "updating": "update",
+	"upgraded": "upgrade", "upgrades": "upgrade", "upgrading": "upgrade",
+	"used": "use", "uses": "use", "using": "use",
+	"wrote": "write", "writes": "write", "writing": "write",
+}
+
+// ImperativeSuggestion 检查标题行描述的第一个单词，若为非祈使语气，
+// 返回该单词及保留原大小写的祈使形式
+func ImperativeSuggestion(subject string) (word, imperative string, ok bool) {
+	description := stripGitmoji(strings.TrimSpace(subject))
+	if m := headerPattern.FindStringSubmatch(description); m != nil {
+		description = m[3]
+	}
+	fields := strings.Fields(description)
+	if len(fields) == 0 {
+		return "", "", false
+	}
+	word = strings.Trim(fields[0], ".,:;!")
+	imperative, ok = nonImperativeVerbs[strings.ToLower(word)]
+	if !ok {
+		return "", "", false
+	}
+	if r, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(r) {
+		imperative = strings.ToUpper(imperative[:1]) + imperative[1:]
+	}
+	return word, imperative, true
+}
+
+// ImperativeHint 返回 message 标题行的祈使语气提示，如 `use "add" instead of "added"`；无问题时返回空串
+func ImperativeHint(message string) string {
+	subject := strings.TrimSpace(message)
+	if idx := strings.IndexByte(subject, '\n'); idx >= 0 {
+		subject = subject[:idx]
+	}
+	word, imperative, ok := ImperativeSuggestion(subject)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("use %q instead of %q (imperative mood)", imperative, word)
+}
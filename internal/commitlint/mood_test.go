@@ -0,0 +1,44 @@
+package commitlint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImperativeSuggestion(t *testing.T) {
+	tests := []struct {
+		subject    string
+		word       string
+		imperative string
+		ok         bool
+	}{
+		{"feat(api): Added endpoint", "Added", "Add", true},
+		{"fix: fixes crash on start", "fixes", "fix", true},
+		{"✨ feat: updating docs index", "updating", "update", true},
+		{"refactor: Simplified parser", "Simplified", "Simplify", true},
+		{"Removed dead code", "Removed", "Remove", true},
+		{"feat(api): add endpoint", "", "", false},
+		{"fix: embed assets", "", "", false},
+		{"docs:", "", "", false},
+	}
+	for _, tt := range tests {
+		word, imperative, ok := ImperativeSuggestion(tt.subject)
+		require.Equal(t, tt.ok, ok, tt.subject)
+		require.Equal(t, tt.word, word, tt.subject)
+		require.Equal(t, tt.imperative, imperative, tt.subject)
+	}
+}
+
+func TestNonImperativeVerbs_MapToBaseForm(t *testing.T) {
+	for form, base := range nonImperativeVerbs {
+		require.NotEqual(t, form, base)
+		_, isForm := nonImperativeVerbs[base]
+		require.False(t, isForm, "%s maps to %s, which is itself flagged", form, base)
+	}
+}
+
+func TestImperativeHint(t *testing.T) {
+	require.Equal(t, `use "Add" instead of "Added" (imperative mood)`, ImperativeHint("feat: Added endpoint\n\nBody."))
+	require.Empty(t, ImperativeHint("feat: add endpoint"))
+}
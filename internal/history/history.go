@@ -0,0 +1,141 @@
+// Package history keeps a bounded, append-only log of generated commit
+// messages so that a message lost to an accidental cancel can be recovered.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultMaxEntries bounds the history file; older entries are trimmed first.
+const DefaultMaxEntries = 500
+
+// Entry is one recorded commit message
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Repo    string    `json:"repo"`
+	Message string    `json:"message"`
+	Edited  bool      `json:"edited,omitempty"` // true if the user edited the generated message
+}
+
+// Store reads and writes history entries in a JSON Lines file
+type Store struct {
+	path       string
+	maxEntries int
+}
+
+// New creates a Store backed by path, keeping at most maxEntries entries.
+// maxEntries <= 0 uses DefaultMaxEntries.
+func New(path string, maxEntries int) *Store {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Store{path: path, maxEntries: maxEntries}
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/catmit/history.jsonl,
+// falling back to ~/.config/catmit/history.jsonl.
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "catmit", "history.jsonl"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "catmit", "history.jsonl"), nil
+}
+
+// Path returns the file backing the store
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append records e, trimming the oldest entries beyond the size bound.
+// The file is rewritten atomically so a crash never leaves it truncated.
+func (s *Store) Append(e Entry) error {
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+	entries = append(entries, e)
+	if len(entries) > s.maxEntries {
+		entries = entries[len(entries)-s.maxEntries:]
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to encode history entry: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".history-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// List returns all entries, oldest first. A missing file yields no entries;
+// malformed lines are skipped so one bad write cannot hide the rest.
+func (s *Store) List() ([]Entry, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// Last returns the most recent entry recorded for repo
+func (s *Store) Last(repo string) (Entry, bool, error) {
+	entries, err := s.List()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Repo == repo {
+			return entries[i], true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
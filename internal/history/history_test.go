@@ -0,0 +1,76 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_AppendAndList(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "catmit", "history.jsonl"), 0)
+
+	entries, err := s.List()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, s.Append(Entry{Time: now, Repo: "/repo/a", Message: "feat: a"}))
+	require.NoError(t, s.Append(Entry{Time: now, Repo: "/repo/b", Message: "fix: b\n\nbody", Edited: true}))
+
+	entries, err = s.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "feat: a", entries[0].Message)
+	require.Equal(t, "fix: b\n\nbody", entries[1].Message)
+	require.True(t, entries[1].Edited)
+	require.True(t, now.Equal(entries[0].Time))
+}
+
+func TestStore_TrimsOldEntries(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "history.jsonl"), 3)
+	for _, msg := range []string{"one", "two", "three", "four", "five"} {
+		require.NoError(t, s.Append(Entry{Repo: "/repo", Message: msg}))
+	}
+
+	entries, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "three", entries[0].Message)
+	require.Equal(t, "five", entries[2].Message)
+}
+
+func TestStore_Last(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "history.jsonl"), 0)
+	require.NoError(t, s.Append(Entry{Repo: "/repo/a", Message: "feat: first"}))
+	require.NoError(t, s.Append(Entry{Repo: "/repo/a", Message: "feat: second"}))
+	require.NoError(t, s.Append(Entry{Repo: "/repo/b", Message: "fix: other"}))
+
+	e, ok, err := s.Last("/repo/a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "feat: second", e.Message)
+
+	_, ok, err = s.Last("/repo/missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestStore_SkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"repo\":\"/r\",\"message\":\"ok\"}\nnot json\n"), 0o600))
+
+	entries, err := New(path, 0).List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "ok", entries[0].Message)
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	path, err := DefaultPath()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("/tmp/xdg", "catmit", "history.jsonl"), path)
+}
@@ -1,17 +1,48 @@
 package logger
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+const (
+	// EnvLogFile 指定日志文件路径；设置后日志写入该文件而不是标准输出，避免与 TUI 交错
+	EnvLogFile = "CATMIT_LOG_FILE"
+	// EnvLogLevel 指定日志级别：debug、info、warn、error，覆盖 --debug 对应的级别
+	EnvLogLevel = "CATMIT_LOG_LEVEL"
+)
+
+// FileConfigured reports whether CATMIT_LOG_FILE is set, in which case every
+// logger returned by New and NewTo writes to that file.
+func FileConfigured() bool {
+	return strings.TrimSpace(os.Getenv(EnvLogFile)) != ""
+}
+
 // New creates a new zap logger configured for console output
 // with line numbers and no timestamps.
+// CATMIT_LOG_LEVEL overrides the level chosen by debug, and CATMIT_LOG_FILE
+// sends the output to a size-rotated file instead of stdout.
 func New(debug bool) (*zap.Logger, error) {
+	return NewTo(debug, nil)
+}
+
+// NewTo is New writing to w instead of stdout, e.g. a buffer while a TUI owns the
+// terminal; CATMIT_LOG_FILE still takes precedence. A nil w behaves like New.
+func NewTo(debug bool, w io.Writer) (*zap.Logger, error) {
 	level := zapcore.InfoLevel
 	if debug {
 		level = zapcore.DebugLevel
 	}
+	if name := strings.TrimSpace(os.Getenv(EnvLogLevel)); name != "" {
+		if err := level.UnmarshalText([]byte(strings.ToLower(name))); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: want debug, info, warn or error", EnvLogLevel, name)
+		}
+	}
 
 	encoderConfig := zapcore.EncoderConfig{
 		MessageKey:     "msg",
@@ -24,6 +55,15 @@ func New(debug bool) (*zap.Logger, error) {
 		// TimeKey is omitted to remove timestamps
 	}
 
+	if FileConfigured() {
+		return newFileLogger(strings.TrimSpace(os.Getenv(EnvLogFile)), level, debug, encoderConfig)
+	}
+	if w != nil {
+		// 写入非终端目标时不使用颜色
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		return newWriterLogger(zapcore.Lock(zapcore.AddSync(w)), level, debug, encoderConfig), nil
+	}
+
 	config := zap.Config{
 		Level:            zap.NewAtomicLevelAt(level),
 		Development:      debug,
@@ -41,4 +81,26 @@ func New(debug bool) (*zap.Logger, error) {
 	}
 
 	return logger, nil
-}
\ No newline at end of file
+}
+
+// newFileLogger 将日志写入按大小轮转的文件；文件中不使用颜色并记录时间
+func newFileLogger(path string, level zapcore.Level, debug bool, encoderConfig zapcore.EncoderConfig) (*zap.Logger, error) {
+	out, err := openRotatingFile(path, defaultMaxFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", EnvLogFile, err)
+	}
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	encoderConfig.TimeKey = "time"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	return newWriterLogger(out, level, debug, encoderConfig), nil
+}
+
+// newWriterLogger 创建写入 out 的 logger，内部错误也写入 out
+func newWriterLogger(out zapcore.WriteSyncer, level zapcore.Level, debug bool, encoderConfig zapcore.EncoderConfig) *zap.Logger {
+	core := zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), out, zap.NewAtomicLevelAt(level))
+	options := []zap.Option{zap.AddCaller(), zap.ErrorOutput(out)}
+	if debug {
+		options = append(options, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	}
+	return zap.New(core, options...)
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_LogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catmit.log")
+	t.Setenv(EnvLogFile, path)
+	t.Setenv(EnvLogLevel, "warn")
+
+	log, err := New(true)
+	require.NoError(t, err)
+	log.Info("hidden by CATMIT_LOG_LEVEL")
+	log.Warn("written to the file")
+	require.NoError(t, log.Sync())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "WARN")
+	require.Contains(t, string(data), "written to the file")
+	require.NotContains(t, string(data), "hidden")
+	require.NotContains(t, string(data), "\x1b[", "file output must not contain colors")
+}
+
+func TestNew_InvalidLevel(t *testing.T) {
+	t.Setenv(EnvLogLevel, "verbose")
+	_, err := New(false)
+	require.ErrorContains(t, err, `invalid CATMIT_LOG_LEVEL "verbose"`)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catmit.log")
+	r, err := openRotatingFile(path, 20)
+	require.NoError(t, err)
+
+	_, err = r.Write([]byte("first line\n"))
+	require.NoError(t, err)
+	_, err = r.Write([]byte("second line\n")) // 11+12 > 20，先轮转
+	require.NoError(t, err)
+	_, err = r.Write([]byte(strings.Repeat("x", 30) + "\n")) // 超过上限的单条日志完整写入
+	require.NoError(t, err)
+	require.NoError(t, r.Sync())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("x", 30)+"\n", string(current))
+	backup, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	require.Equal(t, "second line\n", string(backup))
+
+	// 重新打开时从已有大小继续计算
+	r, err = openRotatingFile(path, 100)
+	require.NoError(t, err)
+	require.Equal(t, int64(31), r.size)
+}
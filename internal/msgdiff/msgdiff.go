@@ -0,0 +1,90 @@
+// Package msgdiff compares two commit messages line by line, so amending
+// HEAD can show what the regenerated message changes.
+package msgdiff
+
+import "strings"
+
+// Op 表示一行在比较结果中的类型
+type Op int
+
+const (
+	Equal  Op = iota // 两个 message 中都有
+	Delete           // 只在原 message 中
+	Insert           // 只在新 message 中
+)
+
+// Line 是比较结果中的一行
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// String 以 diff 风格渲染一行：" " 表示相同，"-" 表示删除，"+" 表示新增
+func (l Line) String() string {
+	switch l.Op {
+	case Delete:
+		return "- " + l.Text
+	case Insert:
+		return "+ " + l.Text
+	}
+	return "  " + l.Text
+}
+
+// Lines 按行比较 before 与 after（最长公共子序列），删除行排在对应的新增行之前。
+// commit message 行数很少，O(n*m) 的动态规划足够。
+func Lines(before, after string) []Line {
+	a, b := split(before), split(after)
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Op: Delete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Op: Insert, Text: b[j]})
+	}
+	return lines
+}
+
+// Changed 报告两个 message 去掉首尾空白后是否不同
+func Changed(before, after string) bool {
+	return strings.TrimSpace(before) != strings.TrimSpace(after)
+}
+
+// split 去掉首尾空白后按行拆分，空 message 返回 nil
+func split(message string) []string {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	if message == "" {
+		return nil
+	}
+	return strings.Split(message, "\n")
+}
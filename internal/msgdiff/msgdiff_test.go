@@ -0,0 +1,37 @@
+package msgdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func render(lines []Line) []string {
+	var out []string
+	for _, l := range lines {
+		out = append(out, l.String())
+	}
+	return out
+}
+
+func TestLines(t *testing.T) {
+	before := "fix: update stuff\n\nChanged the parser."
+	after := "fix(parser): handle empty input\n\nChanged the parser.\nAdded a regression test."
+	require.Equal(t, []string{
+		"- fix: update stuff",
+		"+ fix(parser): handle empty input",
+		"  ",
+		"  Changed the parser.",
+		"+ Added a regression test.",
+	}, render(Lines(before, after)))
+}
+
+func TestLines_Empty(t *testing.T) {
+	require.Equal(t, []string{"+ feat: add x"}, render(Lines("", "feat: add x\n")))
+	require.Empty(t, Lines("", ""))
+}
+
+func TestChanged(t *testing.T) {
+	require.False(t, Changed("feat: add x\n", "feat: add x"))
+	require.True(t, Changed("feat: add x", "feat: add y"))
+}
@@ -0,0 +1,80 @@
+// Package notify posts a short notification after catmit commits, for
+// example to a Slack incoming webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Event 描述一次完成的提交
+type Event struct {
+	Repo    string `json:"repo"`
+	Branch  string `json:"branch"`
+	Subject string `json:"subject"`
+	SHA     string `json:"commit_sha,omitempty"`
+	PRURL   string `json:"pr_url,omitempty"`
+	// Text 是人类可读的摘要，Slack incoming webhook 直接展示该字段；为空时由 Notify 生成
+	Text string `json:"text"`
+}
+
+// Notifier 发送提交通知
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// Webhook 以 JSON POST 的方式将 Event 发送到指定 URL
+type Webhook struct {
+	url        string
+	httpClient *http.Client // 可注入自定义 http.Client，用于测试
+}
+
+// NewWebhook 创建发送到 url 的 Webhook，超时由调用方的 context 控制
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, httpClient: &http.Client{}}
+}
+
+// Notify 发送 e，非 2xx 响应视为失败
+func (w *Webhook) Notify(ctx context.Context, e Event) error {
+	if e.Text == "" {
+		e.Text = summary(e)
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	// 读完响应体以便复用连接
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// summary 生成 "repo@branch: subject" 形式的摘要，有 PR 时附加其 URL
+func summary(e Event) string {
+	text := e.Repo
+	if e.Branch != "" {
+		text += "@" + e.Branch
+	}
+	text += ": " + e.Subject
+	if e.PRURL != "" {
+		text += " (" + e.PRURL + ")"
+	}
+	return text
+}
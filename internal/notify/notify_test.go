@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	var got Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	err := NewWebhook(server.URL).Notify(context.Background(), Event{
+		Repo:    "catmit",
+		Branch:  "feat/x",
+		Subject: "feat: add x",
+		PRURL:   "https://github.com/o/r/pull/1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "feat: add x", got.Subject)
+	require.Equal(t, "catmit@feat/x: feat: add x (https://github.com/o/r/pull/1)", got.Text)
+}
+
+func TestWebhook_NotifyFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewWebhook(server.URL).Notify(context.Background(), Event{Repo: "catmit", Subject: "fix: y"})
+	require.ErrorContains(t, err, "403")
+
+	err = NewWebhook("://bad").Notify(context.Background(), Event{})
+	require.ErrorContains(t, err, "invalid webhook URL")
+}
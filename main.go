@@ -7,32 +7,75 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/penwyp/catmit/cmd"
 )
 
+const (
+	// exitCodeInterrupted 遵循 shell 约定：128 + SIGINT(2)
+	exitCodeInterrupted = 130
+	// forceExitWindow 内再次收到信号则强制退出
+	forceExitWindow = 3 * time.Second
+)
+
 // main 为 CLI 入口，调用 cmd.Execute。
 func main() {
-	// 使用 signal.NotifyContext 创建可取消的 Context；
-	// 当收到 Ctrl+C (SIGINT) 或 SIGTERM 时，ctx.Done() 会被触发。
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop() // 释放资源
+	// 收到 Ctrl+C (SIGINT) 或 SIGTERM 时取消根 Context，
+	// 让流水线自然收尾（释放资源、刷新日志），而不是立即退出。
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go handleSignals(sigCh, cancel)
 
 	// Execute the root command.
-	if err := cmd.ExecuteContext(ctx); err != nil {
+	err := cmd.ExecuteContext(ctx)
+
+	// 根 Context 被信号取消：视为用户主动中断
+	if ctx.Err() != nil {
+		log.Println("Operation canceled")
+		os.Exit(exitCodeInterrupted)
+	}
+
+	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			// 使用 124 表示超时，符合 CLI 规范
 			log.Println("Timeout exceeded")
 			os.Exit(124)
 		}
-		// Since we are handling SIGINT/SIGTERM separately,
-		// a Canceled error here is likely from another cause.
+		// The root context was not canceled by a signal,
+		// so a Canceled error here is likely from another cause.
 		if errors.Is(err, context.Canceled) {
 			log.Println("Operation canceled")
 			os.Exit(0)
 		}
+		// 子命令指定了退出码（如 catmit lint 发现违规）
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			if !exitErr.Silent {
+				log.Printf("catmit error: %v", err)
+			}
+			os.Exit(exitErr.Code)
+		}
 		// 标准化错误处理：避免 log.Fatalf，使用 log.Println + os.Exit
 		log.Printf("catmit error: %v", err)
 		os.Exit(1)
 	}
 }
+
+// handleSignals 在收到第一个信号时取消 Context；
+// 若在 forceExitWindow 内收到第二个信号，则立即强制退出。
+func handleSignals(sigCh <-chan os.Signal, cancel context.CancelFunc) {
+	for range sigCh {
+		cancel()
+		select {
+		case <-sigCh:
+			log.Println("Forced exit")
+			os.Exit(exitCodeInterrupted)
+		case <-time.After(forceExitWindow):
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package prompt
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// gitmojiTable 按在系统提示词中展示的顺序列出 Conventional Commits 类型与对应的 gitmoji
+var gitmojiTable = []struct {
+	prefix string
+	emoji  string
+}{
+	{"feat", "✨"},
+	{"fix", "🐛"},
+	{"refactor", "♻️"},
+	{"docs", "📝"},
+	{"style", "🎨"},
+	{"test", "✅"},
+	{"perf", "⚡️"},
+	{"chore", "🔧"},
+	{"build", "📦️"},
+	{"ci", "👷"},
+	{"revert", "⏪️"},
+}
+
+// gitmojiFor 返回 Conventional Commits 类型对应的 gitmoji，未知类型返回空串
+func gitmojiFor(prefix string) string {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	for _, g := range gitmojiTable {
+		if g.prefix == prefix {
+			return g.emoji
+		}
+	}
+	return ""
+}
+
+// gitmojiRule 生成系统提示词中的 gitmoji 规则
+func gitmojiRule() string {
+	pairs := make([]string, 0, len(gitmojiTable))
+	for _, g := range gitmojiTable {
+		pairs = append(pairs, g.emoji+" "+g.prefix)
+	}
+	return "5. **Gitmoji**: Prefix the subject line with the gitmoji for its type, e.g. \"✨ feat(api): add endpoint\". Use: " + strings.Join(pairs, ", ")
+}
+
+// ApplyGitmoji 确保 message 的标题行以其类型对应的 gitmoji 开头。
+// 已带有 emoji 或无法识别类型时原样返回，使结果不依赖模型是否遵循指令。
+func ApplyGitmoji(message string) string {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return message
+	}
+	if r, _ := utf8.DecodeRuneInString(message); r >= utf8.RuneSelf {
+		return message
+	}
+
+	subject := message
+	if idx := strings.IndexByte(subject, '\n'); idx >= 0 {
+		subject = subject[:idx]
+	}
+	colon := strings.IndexByte(subject, ':')
+	if colon <= 0 {
+		return message
+	}
+	prefix := subject[:colon]
+	if idx := strings.IndexByte(prefix, '('); idx >= 0 {
+		prefix = prefix[:idx]
+	}
+	prefix = strings.TrimSuffix(prefix, "!")
+
+	emoji := gitmojiFor(prefix)
+	if emoji == "" {
+		return message
+	}
+	return emoji + " " + message
+}
@@ -0,0 +1,104 @@
+package prompt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/penwyp/catmit/collector"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitmojiFor(t *testing.T) {
+	require.Equal(t, "✨", gitmojiFor("feat"))
+	require.Equal(t, "🐛", gitmojiFor("FIX"))
+	require.Equal(t, "♻️", gitmojiFor("refactor"))
+	require.Equal(t, "📝", gitmojiFor(" docs "))
+	require.Empty(t, gitmojiFor("unknown"))
+	require.Empty(t, gitmojiFor(""))
+}
+
+func TestApplyGitmoji(t *testing.T) {
+	cases := map[string]string{
+		"feat(api): add endpoint":       "✨ feat(api): add endpoint",
+		"fix: handle nil\n\nBody text.": "🐛 fix: handle nil\n\nBody text.",
+		"refactor!: drop legacy flag":   "♻️ refactor!: drop legacy flag",
+		"✨ feat: already prefixed":      "✨ feat: already prefixed",
+		"update readme":                 "update readme",
+		"wip: not a conventional type":  "wip: not a conventional type",
+	}
+	for in, want := range cases {
+		require.Equal(t, want, ApplyGitmoji(in), in)
+	}
+}
+
+func TestBuilder_BuildSystemPrompt_Gitmoji(t *testing.T) {
+	b := NewBuilder("en", 0)
+	require.NotContains(t, b.BuildSystemPrompt(), "Gitmoji")
+
+	b.SetGitmoji(true)
+	systemPrompt := b.BuildSystemPrompt()
+	require.Contains(t, systemPrompt, "**Gitmoji**")
+	require.Contains(t, systemPrompt, "🐛 fix")
+}
+
+// analyzingCollector 在 mockCollector 基础上提供变更分析结果
+type analyzingCollector struct {
+	mockCollector
+	prefix string
+	modes  []collector.ModeChange
+}
+
+func (a *analyzingCollector) AnalyzeChanges(ctx context.Context) (*collector.ChangesSummary, error) {
+	return &collector.ChangesSummary{SuggestedPrefix: a.prefix, ModeChanges: a.modes}, nil
+}
+
+func TestBuildUserPromptWithBudget_GitmojiHint(t *testing.T) {
+	col := &analyzingCollector{
+		mockCollector: mockCollector{
+			summary: &collector.FileStatusSummary{
+				BranchName: "main",
+				Files:      []collector.FileStatus{{Path: "main.go", IndexStatus: 'A'}},
+			},
+			diff: "diff --git a/main.go b/main.go",
+		},
+		prefix: "feat",
+	}
+
+	b := NewBuilder("en", 0)
+	userPrompt, err := b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.NotContains(t, userPrompt, "Suggested type")
+
+	b.SetGitmoji(true)
+	userPrompt, err = b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.Contains(t, userPrompt, "Suggested type: feat (gitmoji ✨)")
+}
+
+func TestBuildUserPromptWithBudget_ModeChanges(t *testing.T) {
+	col := &analyzingCollector{
+		mockCollector: mockCollector{
+			summary: &collector.FileStatusSummary{
+				BranchName: "main",
+				Files:      []collector.FileStatus{{Path: "scripts/build.sh", IndexStatus: 'M'}, {Path: "tool.py", IndexStatus: 'M'}},
+			},
+			diff: "diff --git a/scripts/build.sh b/scripts/build.sh\nold mode 100644\nnew mode 100755",
+		},
+		prefix: "chore",
+		modes: []collector.ModeChange{
+			{Path: "scripts/build.sh", OldMode: "100644", NewMode: "100755"},
+			{Path: "tool.py", OldMode: "100755", NewMode: "100644"},
+		},
+	}
+
+	// 权限变更与 gitmoji 设置无关，总是写入变更摘要
+	b := NewBuilder("en", 0)
+	userPrompt, err := b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.Contains(t, userPrompt, "File mode changes:\n- scripts/build.sh: 100644 → 100755 (made executable)\n- tool.py: 100755 → 100644")
+
+	col.modes = nil
+	userPrompt, err = b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.NotContains(t, userPrompt, "File mode changes")
+}
@@ -0,0 +1,234 @@
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PR 模板中可用的占位符：BranchCommits 与 RecentCommits 替换为分支提交标题的列表，
+// CommitCount 替换为提交数量，Date 替换为当天日期（YYYY-MM-DD），
+// DiffStat 替换为相对 PR base 的变更统计（如 "5 files changed, +120/-30"）
+const (
+	BranchCommitsPlaceholder = "{{.BranchCommits}}"
+	RecentCommitsPlaceholder = "{{.RecentCommits}}"
+	CommitCountPlaceholder   = "{{.CommitCount}}"
+	DatePlaceholder          = "{{.Date}}"
+	DiffStatPlaceholder      = "{{.DiffStat}}"
+)
+
+// PRTemplateData 是填充 PR 模板占位符所用的数据
+type PRTemplateData struct {
+	Commits  []string // 分支上的提交标题，旧到新
+	DiffStat string   // 相对 PR base 的变更统计，未知时为空
+}
+
+// now 返回当前时间，测试时可替换
+var now = time.Now
+
+// BuildPRSystemPrompt 构建生成 pull request 描述的系统提示词。
+// template 非空时（通常是仓库的 .github/pull_request_template.md），要求模型按模板的结构填写。
+func (b *Builder) BuildPRSystemPrompt(template string) string {
+	var langInst string
+	switch strings.ToLower(b.lang) {
+	case "zh":
+		langInst = "The description MUST be in Chinese."
+	default:
+		langInst = "The description MUST be in English."
+	}
+
+	rules := "# INSTRUCTIONS & RULES\n" +
+		"1. Start with one or two sentences explaining what the branch changes and why.\n" +
+		"2. Follow with a bulleted list of the notable changes, grouped by area when there are many.\n" +
+		"3. Mention anything reviewers should check carefully, such as migrations or breaking changes.\n" +
+		"4. When the branch commits are listed, end with a \"## Changes in this PR\" section: a bulleted list summarizing them, merging fixups and closely related small commits into one bullet.\n" +
+		"5. Use Markdown, do not repeat the title, and do not invent changes that are not in the diff."
+
+	sections := []string{
+		"You are an expert software engineer who writes clear, well-structured GitHub pull request descriptions.",
+		"Write the body of a pull request for the provided branch changes.",
+		langInst,
+		rules,
+	}
+	if template = strings.TrimSpace(template); template != "" {
+		// TEMPLATE - 仓库约定的 PR 模板，生成内容填入其中
+		sections = append(sections, "# TEMPLATE\nThe description MUST follow this pull request template, keeping its headings and filling in each section:\n```\n"+template+"\n```")
+	}
+	sections = append(sections, "# YOUR RESPONSE\nGenerate ONLY the pull request body text.")
+	return strings.Join(sections, "\n\n")
+}
+
+// BuildPRUserPrompt 构建生成 pull request 描述的用户提示词，commits 为分支上的提交标题（旧到新），
+// diff 超出 token 预算时按头尾保留截断
+func (b *Builder) BuildPRUserPrompt(title string, commits []string, diff string, files []string) string {
+	var parts []string
+	if title != "" {
+		parts = append(parts, "Pull request title: "+title)
+	}
+	if len(commits) > 0 {
+		parts = append(parts, fmt.Sprintf("Commits on this branch (%d, oldest first):\n%s", len(commits), bulletList(commits)))
+	}
+	if len(files) > 0 {
+		parts = append(parts, "Changed files: "+strings.Join(files, ", "))
+	}
+	if diff != "" {
+		parts = append(parts, "Branch diff (may be truncated):\n```diff\n"+b.smartTruncateDiff(diff, b.tokenBudget.AvailableTokens)+"\n```")
+	}
+	if len(parts) == 0 {
+		return "No changes detected."
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// FillPRTemplate 替换模板中的占位符（见 BranchCommitsPlaceholder 等常量），
+// 模板的其余部分保持原样交给模型填写
+func FillPRTemplate(template string, commits []string) string {
+	return FillPRTemplateData(template, PRTemplateData{Commits: commits})
+}
+
+// FillPRTemplateData 与 FillPRTemplate 相同，但使用 data 中的全部字段（如 DiffStat）
+func FillPRTemplateData(template string, data PRTemplateData) string {
+	list := bulletList(data.Commits)
+	return strings.NewReplacer(
+		BranchCommitsPlaceholder, list,
+		RecentCommitsPlaceholder, list,
+		CommitCountPlaceholder, strconv.Itoa(len(data.Commits)),
+		DatePlaceholder, now().Format("2006-01-02"),
+		DiffStatPlaceholder, data.DiffStat,
+	).Replace(template)
+}
+
+// placeholderPatterns 匹配常见的模板变量写法：{{.Foo}}、[Foo] 与 <Foo>。
+// 方括号与尖括号只匹配以大写字母开头、至少两个字符的标识符，避免把复选框 "[X]"、"<details>" 等 HTML 标签当作变量
+var placeholderPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\{\{\s*\.[A-Za-z_][A-Za-z0-9_]*\s*\}\}`),
+	regexp.MustCompile(`\[[A-Z][A-Za-z0-9_]+\]`),
+	regexp.MustCompile(`<[A-Z][A-Za-z0-9_]+>`),
+}
+
+// UnfilledPlaceholders 返回 text 中残留的模板变量，按首次出现的位置排序并去重。
+// 紧跟 "(" 的 [Foo] 是 Markdown 链接，紧接在字母数字之后的 <Foo> 是泛型（如 List<String>），均不视为变量
+func UnfilledPlaceholders(text string) []string {
+	type match struct {
+		start int
+		text  string
+	}
+	var matches []match
+	for _, pattern := range placeholderPatterns {
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if text[start] == '[' && end < len(text) && text[end] == '(' {
+				continue
+			}
+			if text[start] == '<' && start > 0 && isIdentByte(text[start-1]) {
+				continue
+			}
+			matches = append(matches, match{start, text[start:end]})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	var found []string
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if !seen[m.text] {
+			seen[m.text] = true
+			found = append(found, m.text)
+		}
+	}
+	return found
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// bulletList 将每一项渲染为 "- item" 一行
+func bulletList(items []string) string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "- " + item
+	}
+	return strings.Join(lines, "\n")
+}
+
+// htmlComment 匹配模板中的 HTML 注释，判断小节是否为空时忽略
+var htmlComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+// prSection 是 PR 描述中以 "## " 标题开头的一节；第一个标题之前的内容是 header 为空的一节
+type prSection struct {
+	header string
+	lines  []string // 包括标题行
+}
+
+func (s prSection) empty() bool {
+	body := s.lines
+	if s.header != "" {
+		body = body[1:]
+	}
+	return strings.TrimSpace(htmlComment.ReplaceAllString(strings.Join(body, "\n"), "")) == ""
+}
+
+// MergeTemplate 将重新生成的描述 rendered 合并到已有的 PR 描述 existing 中：
+// existing 中有内容的小节（按 "## " 标题匹配，忽略大小写）原样保留，空的小节（只有空白或 HTML 注释）
+// 使用 rendered 中的同名小节填充，rendered 中独有的小节追加到末尾。existing 为空时返回 rendered
+func MergeTemplate(existing, rendered string) string {
+	if strings.TrimSpace(existing) == "" {
+		return rendered
+	}
+	fresh := splitSections(rendered)
+	used := make([]bool, len(fresh))
+	find := func(header string) int {
+		for i, s := range fresh {
+			if !used[i] && strings.EqualFold(s.header, header) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var lines []string
+	for _, s := range splitSections(existing) {
+		if i := find(s.header); i >= 0 {
+			used[i] = true
+			if s.empty() && !fresh[i].empty() {
+				s = fresh[i]
+			}
+		}
+		lines = append(lines, s.lines...)
+	}
+	for i, s := range fresh {
+		if used[i] || s.empty() {
+			continue
+		}
+		if n := len(lines); n > 0 && strings.TrimSpace(lines[n-1]) != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, s.lines...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitSections 按 "## " 标题拆分 body，代码块中的行不视为标题
+func splitSections(body string) []prSection {
+	var sections []prSection
+	current := prSection{}
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(line, "## ") {
+			if current.header != "" || len(current.lines) > 0 {
+				sections = append(sections, current)
+			}
+			current = prSection{header: strings.TrimSpace(strings.TrimPrefix(line, "## "))}
+		}
+		current.lines = append(current.lines, line)
+	}
+	return append(sections, current)
+}
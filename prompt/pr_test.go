@@ -0,0 +1,85 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildPRSystemPrompt(t *testing.T) {
+	out := NewBuilder("zh", 0).BuildPRSystemPrompt("")
+	require.Contains(t, out, "pull request")
+	require.Contains(t, out, "MUST be in Chinese")
+	require.NotContains(t, out, "# TEMPLATE")
+
+	out = NewBuilder("en", 0).BuildPRSystemPrompt("## Summary\n\n## Testing\n")
+	require.Contains(t, out, "# TEMPLATE")
+	require.Contains(t, out, "## Summary\n\n## Testing")
+}
+
+func TestBuildPRUserPrompt(t *testing.T) {
+	b := NewBuilder("en", 0)
+	out := b.BuildPRUserPrompt("feat: add x", []string{"feat: add x", "fix: typo"}, "diff --git a/x.go b/x.go\n+x", []string{"x.go"})
+	require.Contains(t, out, "Pull request title: feat: add x")
+	require.Contains(t, out, "Commits on this branch (2, oldest first):\n- feat: add x\n- fix: typo")
+	require.Contains(t, out, "Changed files: x.go")
+	require.Contains(t, out, "+x")
+
+	require.Equal(t, "No changes detected.", b.BuildPRUserPrompt("", nil, "", nil))
+
+	// 超出 token 预算的 diff 被截断
+	small := NewBuilderWithTokenBudget("en", 0, 100)
+	large := strings.Repeat("+line of code in a large change\n", 200)
+	require.Contains(t, small.BuildPRUserPrompt("", nil, large, nil), "Diff truncated")
+}
+
+func TestFillPRTemplate(t *testing.T) {
+	template := "## Summary\n\n## Commits\n{{.BranchCommits}}\n\n## Recent\n{{.RecentCommits}}\n"
+	require.Equal(t, "## Summary\n\n## Commits\n- feat: a\n- fix: b\n\n## Recent\n- feat: a\n- fix: b\n",
+		FillPRTemplate(template, []string{"feat: a", "fix: b"}))
+	require.Equal(t, "## Summary\n", FillPRTemplate("## Summary\n", []string{"feat: a"}))
+}
+
+func TestFillPRTemplate_CountAndDate(t *testing.T) {
+	orig := now
+	t.Cleanup(func() { now = orig })
+	now = func() time.Time { return time.Date(2024, 3, 9, 23, 0, 0, 0, time.UTC) }
+
+	require.Equal(t, "Released 2024-03-09 with 2 commits",
+		FillPRTemplate("Released {{.Date}} with {{.CommitCount}} commits", []string{"feat: a", "fix: b"}))
+	require.Equal(t, "0 commits", FillPRTemplate("{{.CommitCount}} commits", nil))
+}
+
+func TestFillPRTemplateData_DiffStat(t *testing.T) {
+	template := "## Summary\n\n## Stats\n{{.CommitCount}} commits, {{.DiffStat}}\n"
+	filled := FillPRTemplateData(template, PRTemplateData{Commits: []string{"feat: a"}, DiffStat: "5 files changed, +120/-30"})
+	require.Equal(t, "## Summary\n\n## Stats\n1 commits, 5 files changed, +120/-30\n", filled)
+	require.Empty(t, UnfilledPlaceholders(filled))
+}
+
+func TestUnfilledPlaceholders(t *testing.T) {
+	// 模板引用了 FillPRTemplate 不认识的字段，填充后原样残留
+	filled := FillPRTemplate("## Commits\n{{.BranchCommits}}\n\nTicket: {{ .Ticket }}\n", []string{"feat: a"})
+	require.Equal(t, []string{"{{ .Ticket }}"}, UnfilledPlaceholders(filled))
+
+	body := "Fixes [IssueNumber] for <Component>.\n- [x] tested\n- [X] reviewed\n" +
+		"See [Docs](https://example.com) and List<String>.\n<details>[IssueNumber]</details>"
+	require.Equal(t, []string{"[IssueNumber]", "<Component>"}, UnfilledPlaceholders(body))
+	require.Empty(t, UnfilledPlaceholders("## Summary\nAll good."))
+}
+
+func TestMergeTemplate(t *testing.T) {
+	existing := "## Summary\nHand-written summary.\n\n## Testing\n<!-- how was this tested? -->\n\n## Notes\nKeep me.\n"
+	rendered := "## Summary\nGenerated summary.\n\n## testing\nUnit tests pass.\n\n## Checklist\n- [x] docs\n"
+	require.Equal(t,
+		"## Summary\nHand-written summary.\n\n## testing\nUnit tests pass.\n\n## Notes\nKeep me.\n\n## Checklist\n- [x] docs\n",
+		MergeTemplate(existing, rendered))
+
+	// 代码块中的 "## " 不是标题
+	existing = "## Summary\n```sh\n## not a header\n```\n"
+	require.Equal(t, existing, MergeTemplate(existing, "## Summary\nGenerated."))
+
+	require.Equal(t, "## Summary\nGenerated.", MergeTemplate("  \n", "## Summary\nGenerated."))
+}
@@ -0,0 +1,107 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Preset 是一组预定义的提示词规则，通过 --preset 选择。
+// 语言、标题长度与模板仍由各自的设置决定，可与任意预设组合。
+type Preset struct {
+	Name     string
+	Summary  string   // 一句话说明，用于帮助信息
+	Format   string   // 标题行格式规则
+	Types    string   // 允许的类型说明
+	Body     string   // 需要正文时对正文结构的要求，空串表示只解释 why
+	BodyMode BodyMode // 预设默认的正文要求，--body/--no-body/CATMIT_BODY 优先
+	Gitmoji  bool     // 是否默认添加 gitmoji，--gitmoji/CATMIT_GITMOJI/配置文件优先
+	Example  string   // few-shot 示例中的 commit message
+}
+
+// DefaultPreset 是未指定 --preset 时使用的规则
+var DefaultPreset = Preset{
+	Name:    "conventional",
+	Summary: "Conventional Commits, body only when needed (default)",
+	Format:  "MUST follow Conventional Commits: <type>(<scope>): <subject>",
+	Types:   "Choose from feat, fix, refactor, chore, docs, style, test",
+	Example: "refactor(auth): use sessionStorage for token storage",
+}
+
+// presets 按帮助信息中的展示顺序列出全部预设
+var presets = []Preset{
+	DefaultPreset,
+	{
+		Name:     "angular",
+		Summary:  "Angular convention: type(scope): subject with a structured body",
+		Format:   "MUST follow the Angular commit convention: <type>(<scope>): <subject>. The scope is REQUIRED and names the affected module",
+		Types:    "Choose from build, ci, docs, feat, fix, perf, refactor, style, test",
+		Body:     "describe the motivation for the change and contrast it with the previous behavior; end with a footer for \"BREAKING CHANGE: <description>\" or \"Closes #<issue>\" when applicable",
+		BodyMode: BodyRequired,
+		Example:  "refactor(auth): read the token from sessionStorage\n\n  The token was kept in localStorage and outlived the browser session;\n  sessionStorage drops it when the tab is closed.\n\n  Closes #123",
+	},
+	{
+		Name:    "gitmoji",
+		Summary: "Conventional Commits prefixed with a gitmoji",
+		Format:  "MUST follow Conventional Commits with a leading gitmoji: <gitmoji> <type>(<scope>): <subject>",
+		Types:   "Choose from feat, fix, refactor, chore, docs, style, test, perf, build, ci, revert",
+		Gitmoji: true,
+		Example: "♻️ refactor(auth): use sessionStorage for token storage",
+	},
+	{
+		Name:     "simple",
+		Summary:  "a terse one-line message",
+		Format:   "A single line: <type>: <subject>. Omit the scope",
+		Types:    "Choose from feat, fix, refactor, chore, docs, test",
+		BodyMode: BodyOmitted,
+		Example:  "refactor: store token in sessionStorage",
+	},
+	{
+		Name:     "detailed",
+		Summary:  "Conventional Commits with a bulleted body of key changes",
+		Format:   "MUST follow Conventional Commits: <type>(<scope>): <subject>",
+		Types:    "Choose from feat, fix, refactor, chore, docs, style, test, perf, build, ci",
+		Body:     "start with one sentence on why the change was made, then list the key changes as \"- \" bullet points",
+		BodyMode: BodyRequired,
+		Example:  "refactor(auth): use sessionStorage for token storage\n\n  Tokens no longer survive closing the tab.\n\n  - read and write the token through sessionStorage\n  - drop the localStorage migration shim",
+	},
+}
+
+// PresetNames 返回全部预设名称
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for _, p := range presets {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// LookupPreset 按名称（大小写不敏感）查找预设，空串返回 DefaultPreset
+func LookupPreset(name string) (Preset, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return DefaultPreset, nil
+	}
+	for _, p := range presets {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Preset{}, fmt.Errorf("unknown preset %q: expected one of %s", name, strings.Join(PresetNames(), ", "))
+}
+
+// bodyRule 生成正文规则，mode 为最终生效的正文要求
+func (p Preset) bodyRule(mode BodyMode) string {
+	switch mode {
+	case BodyRequired:
+		if p.Body != "" {
+			return "4. **Body**: REQUIRED. After a blank line, " + p.Body
+		}
+		return "4. **Body**: REQUIRED. After a blank line, add a body paragraph explaining the 'why', not the 'how'"
+	case BodyOmitted:
+		return "4. **Body**: Do NOT add a body. Output the subject line only"
+	}
+	if p.Body != "" {
+		return "4. **Body**: If needed, after a blank line, " + p.Body
+	}
+	return "4. **Body**: If needed, explain the 'why', not the 'how', after a blank line"
+}
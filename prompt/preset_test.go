@@ -0,0 +1,55 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupPreset(t *testing.T) {
+	p, err := LookupPreset("")
+	require.NoError(t, err)
+	require.Equal(t, DefaultPreset.Name, p.Name)
+
+	p, err = LookupPreset(" Angular ")
+	require.NoError(t, err)
+	require.Equal(t, "angular", p.Name)
+	require.Equal(t, BodyRequired, p.BodyMode)
+
+	_, err = LookupPreset("nope")
+	require.ErrorContains(t, err, "conventional, angular, gitmoji, simple, detailed")
+}
+
+func TestBuildSystemPrompt_Preset(t *testing.T) {
+	simple, err := LookupPreset("simple")
+	require.NoError(t, err)
+	b := NewBuilder("zh", 0)
+	b.SetPreset(simple)
+	b.SetBodyMode(simple.BodyMode)
+	sys := b.BuildSystemPrompt()
+	require.Contains(t, sys, "A single line: <type>: <subject>")
+	require.Contains(t, sys, "Do NOT add a body")
+	require.Contains(t, sys, "refactor: store token in sessionStorage")
+	// 预设与语言设置可以组合
+	require.Contains(t, sys, "Chinese")
+
+	detailed, err := LookupPreset("detailed")
+	require.NoError(t, err)
+	b = NewBuilder("en", 0)
+	b.SetPreset(detailed)
+	b.SetBodyMode(BodyRequired)
+	sys = b.BuildSystemPrompt()
+	require.Contains(t, sys, "REQUIRED. After a blank line, start with one sentence on why")
+	require.Contains(t, sys, "- drop the localStorage migration shim")
+
+	// --body/--no-body 可以覆盖预设的正文要求
+	b.SetBodyMode(BodyAuto)
+	require.Contains(t, b.BuildSystemPrompt(), "4. **Body**: If needed, after a blank line, start with one sentence")
+}
+
+// 每个预设的示例 message 都必须描述系统 prompt 中固定的示例 diff（localStorage 改为 sessionStorage）
+func TestPresetExamplesMatchExampleDiff(t *testing.T) {
+	for _, p := range append([]Preset{DefaultPreset}, presets...) {
+		require.Contains(t, p.Example, "sessionStorage", p.Name)
+	}
+}
@@ -3,6 +3,7 @@ package prompt
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	
 	"github.com/penwyp/catmit/collector"
@@ -17,6 +18,35 @@ type CollectorInterface interface {
 	ChangedFiles(ctx context.Context) ([]string, error)
 }
 
+// changeAnalyzer 是可选能力：支持变更分析的 collector 可提供权限变更，并为 gitmoji 提供类型提示
+type changeAnalyzer interface {
+	AnalyzeChanges(ctx context.Context) (*collector.ChangesSummary, error)
+}
+
+// branchDescriber 是可选能力：提供分支描述（branch.<name>.description）作为变更意图的上下文
+type branchDescriber interface {
+	BranchDescription(ctx context.Context) (string, error)
+}
+
+// initialCommitDetector 是可选能力：判断本次是否为仓库的第一次提交
+type initialCommitDetector interface {
+	IsInitialCommit(ctx context.Context) (bool, error)
+}
+
+// dependencyReporter 是可选能力：提供锁文件中的依赖版本变化，锁文件本身不会出现在文件摘要与 diff 中
+type dependencyReporter interface {
+	DependencyChanges(ctx context.Context) ([]collector.DependencyChange, error)
+}
+
+// maxDependencyLines 是 prompt 中最多列出的依赖版本变化数量
+const maxDependencyLines = 20
+
+// maxModeChangeLines 是 prompt 中最多列出的文件权限变更数量
+const maxModeChangeLines = 20
+
+// initialCommitHint 告知模型本次是仓库的第一次提交
+const initialCommitHint = "This is the initial commit of the repository: describe what the new project sets up rather than a change to existing code."
+
 // TokenBudget 定义token预算配置
 type TokenBudget struct {
 	MaxTokens       int // 最大token数
@@ -24,6 +54,39 @@ type TokenBudget struct {
 	AvailableTokens int // 可用于diff内容的token数
 }
 
+// BodyMode 控制是否要求 commit message 包含正文
+type BodyMode int
+
+const (
+	BodyAuto     BodyMode = iota // 由模型决定是否需要正文
+	BodyRequired                 // 必须包含解释性正文
+	BodyOmitted                  // 只输出标题行
+)
+
+// ParseBodyMode 解析 "auto"、"always"、"never"（大小写不敏感），空串视为 auto
+func ParseBodyMode(s string) (BodyMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return BodyAuto, nil
+	case "always":
+		return BodyRequired, nil
+	case "never":
+		return BodyOmitted, nil
+	default:
+		return BodyAuto, fmt.Errorf("invalid body mode %q: expected auto, always or never", s)
+	}
+}
+
+// BodyReminder 是模型在要求正文时只返回标题行后，追加到用户提示词的重试指令
+const BodyReminder = "Your previous answer contained only a subject line. Respond again with the subject line, a blank line, and a body paragraph explaining why the change was made."
+
+// HasBody 报告 message 在标题行之后是否还有非空正文
+func HasBody(message string) bool {
+	message = strings.TrimSpace(message)
+	idx := strings.IndexByte(message, '\n')
+	return idx >= 0 && strings.TrimSpace(message[idx+1:]) != ""
+}
+
 // Builder 负责构建发送给 LLM 的 Prompt 文本。
 // 支持语言注入、token预算控制与智能diff截断。
 type Builder struct {
@@ -31,6 +94,14 @@ type Builder struct {
 	diffLimit   int         // Diff 最大长度（字节），0 表示不限制
 	truncMarker string      // 截断标记，可自定义，便于测试
 	tokenBudget TokenBudget // Token预算配置
+	bodyMode    BodyMode    // 正文要求，默认 BodyAuto
+	gitmoji     bool        // 是否要求在标题前添加 gitmoji
+	maxSubject  int         // 标题行最大长度，0 表示默认的 50
+	template    string      // 可选的 commit message 模板
+	templateNotes string    // 模板中的注释行（# 开头），用于指导填写但不出现在 message 中
+	preset      Preset      // 标题格式、类型、正文结构与示例
+	prefixMap   map[string]string // 团队约定的变更类别到提交类型的映射，nil 表示使用预设的类型
+	strictBudget bool             // 超出预算时返回 *BudgetExceededError 而不是截断 diff
 }
 
 // NewBuilder 创建 Prompt Builder。
@@ -40,6 +111,7 @@ func NewBuilder(lang string, diffLimit int) *Builder {
 		lang:        lang,
 		diffLimit:   diffLimit,
 		truncMarker: "(diff truncated)",
+		preset:      DefaultPreset,
 		tokenBudget: TokenBudget{
 			MaxTokens:       8000, // 默认预算
 			ReservedTokens:  2000, // 为系统prompt和其他信息预留
@@ -55,6 +127,7 @@ func NewBuilderWithTokenBudget(lang string, diffLimit int, maxTokens int) *Build
 		lang:        lang,
 		diffLimit:   diffLimit,
 		truncMarker: "(diff truncated)",
+		preset:      DefaultPreset,
 		tokenBudget: TokenBudget{
 			MaxTokens:       maxTokens,
 			ReservedTokens:  reservedTokens,
@@ -63,6 +136,89 @@ func NewBuilderWithTokenBudget(lang string, diffLimit int, maxTokens int) *Build
 	}
 }
 
+// BudgetExceededError 表示严格预算模式下 prompt 的估算 token 数超出上限
+type BudgetExceededError struct {
+	Estimated int // 估算的 prompt token 数（系统提示词 + 用户提示词）
+	Limit     int // 预算上限
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("estimated prompt size of %d tokens exceeds the limit of %d", e.Estimated, e.Limit)
+}
+
+// SetTokenBudget 设置 token 预算（预留 25% 给系统提示词等信息，与 NewBuilderWithTokenBudget 相同）。
+// strict 为 true 时不截断 diff，完整 prompt 超出 maxTokens 则 BuildUserPromptWithBudget 返回 *BudgetExceededError
+func (b *Builder) SetTokenBudget(maxTokens int, strict bool) {
+	reservedTokens := maxTokens / 4
+	b.tokenBudget = TokenBudget{
+		MaxTokens:       maxTokens,
+		ReservedTokens:  reservedTokens,
+		AvailableTokens: maxTokens - reservedTokens,
+	}
+	b.strictBudget = strict
+}
+
+// SetBodyMode 设置系统提示词中对正文的要求
+func (b *Builder) SetBodyMode(mode BodyMode) {
+	b.bodyMode = mode
+}
+
+// SetPreset 选择提示词预设。预设默认的正文要求与 gitmoji 由调用方解析后
+// 通过 SetBodyMode、SetGitmoji 传入，以便与命令行参数统一处理优先级。
+func (b *Builder) SetPreset(p Preset) {
+	b.preset = p
+}
+
+// SetGitmoji 设置是否要求模型在标题前添加 gitmoji
+func (b *Builder) SetGitmoji(enabled bool) {
+	b.gitmoji = enabled
+}
+
+// SetMaxSubject 设置标题行最大长度，n <= 0 时使用默认的 50
+func (b *Builder) SetMaxSubject(n int) {
+	b.maxSubject = n
+}
+
+// SetPrefixMap 设置团队约定的变更类别到提交类型的映射（如 added → feature），
+// 写入系统提示词的类型规则；nil 表示只使用预设的类型
+func (b *Builder) SetPrefixMap(m map[string]string) {
+	b.prefixMap = m
+}
+
+// typeRule 返回类型规则，配置了 prefixMap 时追加团队的类型词汇
+func (b *Builder) typeRule() string {
+	if len(b.prefixMap) == 0 {
+		return b.preset.Types
+	}
+	kinds := make([]string, 0, len(b.prefixMap))
+	for kind := range b.prefixMap {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	pairs := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		pairs = append(pairs, kind+" → "+b.prefixMap[kind])
+	}
+	return b.preset.Types + " This team uses its own type names; by kind of change: " + strings.Join(pairs, ", ") + "."
+}
+
+// SetTemplate 设置 message 需要遵循的模板，空串表示不使用模板。
+// 与 git 的 commit.template 一致，以 # 开头的行是注释：作为填写说明交给模型，但不属于模板结构
+func (b *Builder) SetTemplate(template string) {
+	var lines, notes []string
+	for _, line := range strings.Split(template, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") {
+			if note := strings.TrimSpace(strings.TrimLeft(trimmed, "#")); note != "" {
+				notes = append(notes, note)
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	b.template = strings.TrimSpace(strings.Join(lines, "\n"))
+	b.templateNotes = strings.Join(notes, "\n")
+}
+
 // estimateTokens 估算文本的token数量
 // 简化算法：1个token约等于4个字符（英文），2个字符（中文）
 func estimateTokens(text string) int {
@@ -71,6 +227,10 @@ func estimateTokens(text string) int {
 	return (charCount + 2) / 3
 }
 
+// EstimateTokens 对外暴露的 token 估算，与内部预算控制使用相同算法
+func EstimateTokens(text string) int {
+	return estimateTokens(text)
+}
 
 // smartTruncateDiff 智能截断单个文件的diff
 // 如果diff太大，使用头尾保留法
@@ -120,23 +280,46 @@ func (b *Builder) BuildSystemPrompt() string {
 		langInst = "The commit message MUST be in English."
 	}
 	
+	// 正文规则随 bodyMode 与预设变化
+	bodyRule := b.preset.bodyRule(b.bodyMode)
+
+	maxSubject := b.maxSubject
+	if maxSubject <= 0 {
+		maxSubject = 50
+	}
+
 	// INSTRUCTIONS & RULES - 格式与规则
-	formatRules := `# INSTRUCTIONS & RULES
-1. **Format**: MUST follow Conventional Commits: <type>(<scope>): <subject>
-2. **Type**: Choose from feat, fix, refactor, chore, docs, style, test
-3. **Subject**: Use imperative mood, max 50 chars, no period at the end
-4. **Body**: If needed, explain the 'why', not the 'how', after a blank line`
+	formatRules := "# INSTRUCTIONS & RULES\n" +
+		"1. **Format**: " + b.preset.Format + "\n" +
+		"2. **Type**: " + b.typeRule() + "\n" +
+		fmt.Sprintf("3. **Subject**: Use the imperative mood (\"add\", not \"added\", \"adds\" or \"adding\"), max %d chars, no period at the end\n", maxSubject) + bodyRule
+	if b.gitmoji {
+		formatRules += "\n" + gitmojiRule()
+	}
 
 	// EXAMPLE - 示例 (Few-Shot Learning)
 	examples := `# EXAMPLE
 - **Diff**: + return sessionStorage.getItem('token'); - return localStorage.getItem('token');
-- **Commit**: refactor(auth): use sessionStorage for token storage`
+- **Commit**: ` + b.preset.Example
 
 	// YOUR RESPONSE - 输出要求
 	outputReq := `# YOUR RESPONSE
 Generate ONLY the commit message text.`
 	
-	return strings.Join([]string{rolePrompt, taskPrompt, langInst, formatRules, examples, outputReq}, "\n\n")
+	sections := []string{rolePrompt, taskPrompt, langInst, formatRules}
+	if b.template != "" || b.templateNotes != "" {
+		// TEMPLATE - 项目约定的 message 模板
+		var template []string
+		if b.template != "" {
+			template = append(template, "The commit message MUST follow this template:\n```\n"+b.template+"\n```")
+		}
+		if b.templateNotes != "" {
+			template = append(template, "Follow these notes from the template, but do NOT include them or any line starting with # in the message:\n"+b.templateNotes)
+		}
+		sections = append(sections, "# TEMPLATE\n"+strings.Join(template, "\n"))
+	}
+	sections = append(sections, examples, outputReq)
+	return strings.Join(sections, "\n\n")
 }
 
 // BuildUserPrompt 构建用户提示词，包含上下文数据（分支、文件、提交历史、diff）。
@@ -201,6 +384,16 @@ func (b *Builder) BuildUserPromptWithBudget(ctx context.Context, collector inter
 	if summary.BranchName != "" {
 		parts = append(parts, "Branch: "+summary.BranchName)
 	}
+	if detector, ok := collector.(initialCommitDetector); ok {
+		if initial, err := detector.IsInitialCommit(ctx); err == nil && initial {
+			parts = append(parts, initialCommitHint)
+		}
+	}
+	if describer, ok := collector.(branchDescriber); ok {
+		if description, err := describer.BranchDescription(ctx); err == nil && description != "" {
+			parts = append(parts, "Branch description (the intent of this branch):\n"+description)
+		}
+	}
 	
 	// 构建文件摘要
 	if len(summary.Files) > 0 {
@@ -218,6 +411,25 @@ func (b *Builder) BuildUserPromptWithBudget(ctx context.Context, collector inter
 		}
 		parts = append(parts, "Summary of Staged Files:\n"+strings.Join(fileSummary, "\n"))
 	}
+	changes := analyzeChanges(ctx, collector)
+	// 权限变更在 diff 中只有 old mode/new mode 两行，单独列出以免被截断或忽略
+	if changes != nil && len(changes.ModeChanges) > 0 {
+		parts = append(parts, modeChangeSummary(changes.ModeChanges))
+	}
+	if reporter, ok := collector.(dependencyReporter); ok {
+		if changes, err := reporter.DependencyChanges(ctx); err == nil && len(changes) > 0 {
+			parts = append(parts, dependencySummary(changes))
+		}
+	}
+	
+	// gitmoji 模式下提供变更分析推断的类型作为提示
+	if b.gitmoji && changes != nil && changes.SuggestedPrefix != "" {
+		hint := "Suggested type: " + changes.SuggestedPrefix
+		if emoji := gitmojiFor(changes.SuggestedPrefix); emoji != "" {
+			hint += " (gitmoji " + emoji + ")"
+		}
+		parts = append(parts, hint)
+	}
 	
 	// 获取最近的提交历史
 	commits, err := col.RecentCommits(ctx, 3)
@@ -239,7 +451,57 @@ func (b *Builder) BuildUserPromptWithBudget(ctx context.Context, collector inter
 		return "No changes detected.", nil
 	}
 	
-	return strings.Join(parts, "\n\n"), nil
+	userPrompt := strings.Join(parts, "\n\n")
+	if b.strictBudget {
+		if estimated := estimateTokens(b.BuildSystemPrompt() + userPrompt); estimated > b.tokenBudget.MaxTokens {
+			return "", &BudgetExceededError{Estimated: estimated, Limit: b.tokenBudget.MaxTokens}
+		}
+	}
+	return userPrompt, nil
+}
+
+// dependencySummary 列出依赖版本变化，并给出依赖更新提交应使用的标题
+func dependencySummary(changes []collector.DependencyChange) string {
+	lines := []string{"Dependency changes (from lockfiles):"}
+	for i, change := range changes {
+		if i == maxDependencyLines {
+			lines = append(lines, fmt.Sprintf("- … and %d more", len(changes)-maxDependencyLines))
+			break
+		}
+		lines = append(lines, "- "+change.String())
+	}
+	lines = append(lines, "If the commit only updates dependencies, use a subject like: chore(deps): "+collector.DependencySubject(changes))
+	return strings.Join(lines, "\n")
+}
+
+// analyzeChanges 返回支持变更分析的 collector 的分析结果，不支持或分析失败时返回 nil
+func analyzeChanges(ctx context.Context, col interface{}) *collector.ChangesSummary {
+	analyzer, ok := col.(changeAnalyzer)
+	if !ok {
+		return nil
+	}
+	changes, err := analyzer.AnalyzeChanges(ctx)
+	if err != nil {
+		return nil
+	}
+	return changes
+}
+
+// modeChangeSummary 列出文件权限位的变化，例如 chmod +x 产生的 100644 → 100755
+func modeChangeSummary(modes []collector.ModeChange) string {
+	lines := []string{"File mode changes:"}
+	for i, mode := range modes {
+		if i == maxModeChangeLines {
+			lines = append(lines, fmt.Sprintf("- … and %d more", len(modes)-maxModeChangeLines))
+			break
+		}
+		line := fmt.Sprintf("- %s: %s → %s", mode.Path, mode.OldMode, mode.NewMode)
+		if mode.MadeExecutable() {
+			line += " (made executable)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
 }
 
 // buildBudgetedDiff 根据token预算和文件优先级构建diff内容
@@ -254,8 +516,8 @@ func (b *Builder) buildBudgetedDiff(ctx context.Context, collector CollectorInte
 		return "", fmt.Errorf("failed to get diff: %w", err)
 	}
 	
-	// 如果diff很小，直接返回
-	if estimateTokens(fullDiff) <= b.tokenBudget.AvailableTokens {
+	// 如果diff很小，或严格预算模式由调用方检查整体大小，直接返回
+	if b.strictBudget || estimateTokens(fullDiff) <= b.tokenBudget.AvailableTokens {
 		return fullDiff, nil
 	}
 	
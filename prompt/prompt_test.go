@@ -52,10 +52,67 @@ func TestBuilder_BuildSystemPrompt_English(t *testing.T) {
 	require.Contains(t, systemPrompt, "Conventional Commits")
 	require.Contains(t, systemPrompt, "MUST be in English")
 	require.Contains(t, systemPrompt, "feat, fix, refactor")
+	require.Contains(t, systemPrompt, `imperative mood ("add", not "added"`)
 	require.Contains(t, systemPrompt, "EXAMPLE")
 	require.Contains(t, systemPrompt, "commit message text")
 }
 
+func TestBuilder_BuildSystemPrompt_BodyMode(t *testing.T) {
+	b := NewBuilder("en", 0)
+	require.Contains(t, b.BuildSystemPrompt(), "If needed, explain the 'why'")
+
+	b.SetBodyMode(BodyRequired)
+	require.Contains(t, b.BuildSystemPrompt(), "**Body**: REQUIRED")
+
+	b.SetBodyMode(BodyOmitted)
+	require.Contains(t, b.BuildSystemPrompt(), "Do NOT add a body")
+}
+
+func TestBuilder_BuildSystemPrompt_MaxSubjectAndTemplate(t *testing.T) {
+	b := NewBuilder("en", 0)
+	systemPrompt := b.BuildSystemPrompt()
+	require.Contains(t, systemPrompt, "max 50 chars")
+	require.NotContains(t, systemPrompt, "# TEMPLATE")
+
+	b.SetMaxSubject(72)
+	b.SetTemplate("<type>(<scope>): <subject>\n\nRefs: <ticket>\n")
+	systemPrompt = b.BuildSystemPrompt()
+	require.Contains(t, systemPrompt, "max 72 chars")
+	require.Contains(t, systemPrompt, "# TEMPLATE")
+	require.Contains(t, systemPrompt, "Refs: <ticket>\n```")
+	require.NotContains(t, systemPrompt, "notes from the template")
+
+	// 注释行只作为说明，不属于模板结构
+	b.SetTemplate("# Why is this change needed?\n<subject>\n\n#   \nReviewed-by: <name>\n")
+	systemPrompt = b.BuildSystemPrompt()
+	require.Contains(t, systemPrompt, "```\n<subject>\n\nReviewed-by: <name>\n```")
+	require.Contains(t, systemPrompt, "do NOT include them or any line starting with # in the message:\nWhy is this change needed?")
+}
+
+func TestBuilder_BuildSystemPrompt_PrefixMap(t *testing.T) {
+	b := NewBuilder("en", 0)
+	require.NotContains(t, b.BuildSystemPrompt(), "own type names")
+
+	b.SetPrefixMap(map[string]string{"modified": "bugfix", "added": "feature"})
+	require.Contains(t, b.BuildSystemPrompt(), "by kind of change: added → feature, modified → bugfix.")
+}
+
+func TestParseBodyMode(t *testing.T) {
+	for input, want := range map[string]BodyMode{"": BodyAuto, "auto": BodyAuto, "Always": BodyRequired, "never": BodyOmitted} {
+		got, err := ParseBodyMode(input)
+		require.NoError(t, err, input)
+		require.Equal(t, want, got, input)
+	}
+	_, err := ParseBodyMode("sometimes")
+	require.Error(t, err)
+}
+
+func TestHasBody(t *testing.T) {
+	require.False(t, HasBody("feat: add x"))
+	require.False(t, HasBody("feat: add x\n\n  \n"))
+	require.True(t, HasBody("feat: add x\n\nExplain why."))
+}
+
 func TestBuilder_BuildSystemPrompt_Chinese(t *testing.T) {
 	b := NewBuilder("zh", 0)
 	systemPrompt := b.BuildSystemPrompt()
@@ -241,6 +298,126 @@ func (m *mockCollector) ChangedFiles(ctx context.Context) ([]string, error) {
 	return []string{}, nil
 }
 
+type describedCollector struct {
+	mockCollector
+	description string
+}
+
+func (d *describedCollector) BranchDescription(ctx context.Context) (string, error) {
+	return d.description, nil
+}
+
+func TestBuildUserPromptWithBudget_BranchDescription(t *testing.T) {
+	t.Parallel()
+
+	col := &describedCollector{
+		mockCollector: mockCollector{summary: &collector.FileStatusSummary{BranchName: "feature/sso"}},
+		description:   "Add SSO login for enterprise tenants",
+	}
+	b := NewBuilder("en", 0)
+	userPrompt, err := b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.Contains(t, userPrompt, "Branch: feature/sso\n\nBranch description (the intent of this branch):\nAdd SSO login for enterprise tenants")
+
+	col.description = ""
+	userPrompt, err = b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.NotContains(t, userPrompt, "Branch description")
+}
+
+type initialCollector struct {
+	mockCollector
+	initial bool
+}
+
+func (i *initialCollector) IsInitialCommit(ctx context.Context) (bool, error) {
+	return i.initial, nil
+}
+
+func TestBuildUserPromptWithBudget_InitialCommit(t *testing.T) {
+	t.Parallel()
+
+	col := &initialCollector{
+		mockCollector: mockCollector{summary: &collector.FileStatusSummary{BranchName: "main"}},
+		initial:       true,
+	}
+	b := NewBuilder("en", 0)
+	userPrompt, err := b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.Contains(t, userPrompt, "This is the initial commit of the repository")
+
+	col.initial = false
+	userPrompt, err = b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.NotContains(t, userPrompt, "initial commit")
+}
+
+type dependencyCollector struct {
+	mockCollector
+	changes []collector.DependencyChange
+}
+
+func (d *dependencyCollector) DependencyChanges(ctx context.Context) ([]collector.DependencyChange, error) {
+	return d.changes, nil
+}
+
+func TestBuildUserPromptWithBudget_DependencyChanges(t *testing.T) {
+	t.Parallel()
+
+	col := &dependencyCollector{
+		mockCollector: mockCollector{summary: &collector.FileStatusSummary{BranchName: "main"}},
+		changes: []collector.DependencyChange{
+			{Name: "lodash", From: "4.17.20", To: "4.17.21"},
+			{Name: "zod", To: "3.23.8"},
+			{Name: "left-pad", From: "1.3.0"},
+		},
+	}
+	b := NewBuilder("en", 0)
+	userPrompt, err := b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.Contains(t, userPrompt, "Dependency changes (from lockfiles):\n"+
+		"- lodash: 4.17.20 → 4.17.21\n- zod: added 3.23.8\n- left-pad: removed 1.3.0\n"+
+		"If the commit only updates dependencies, use a subject like: chore(deps): bump lodash to 4.17.21 and 2 others")
+
+	col.changes = nil
+	userPrompt, err = b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.NotContains(t, userPrompt, "Dependency changes")
+}
+
+func TestBuilder_SetTokenBudget(t *testing.T) {
+	t.Parallel()
+
+	col := &mockCollector{
+		summary: &collector.FileStatusSummary{
+			BranchName: "main",
+			Files:      []collector.FileStatus{{Path: "big.go", IndexStatus: 'M'}},
+		},
+		diff: strings.Repeat("+line of a very large change\n", 2000),
+	}
+
+	// 严格预算：不截断，超出上限时报告估算大小
+	b := NewBuilder("en", 0)
+	b.SetTokenBudget(1000, true)
+	_, err := b.BuildUserPromptWithBudget(context.Background(), col, "")
+	var exceeded *BudgetExceededError
+	require.ErrorAs(t, err, &exceeded)
+	require.Equal(t, 1000, exceeded.Limit)
+	require.Greater(t, exceeded.Estimated, 1000)
+
+	// 非严格预算：截断 diff 以适应上限
+	b.SetTokenBudget(1000, false)
+	userPrompt, err := b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.Less(t, EstimateTokens(userPrompt), EstimateTokens(col.diff))
+
+	// 严格预算且未超出时返回完整 diff
+	b.SetTokenBudget(100000, true)
+	userPrompt, err = b.BuildUserPromptWithBudget(context.Background(), col, "")
+	require.NoError(t, err)
+	require.Contains(t, userPrompt, col.diff)
+}
+
 func TestBuildUserPromptWithBudget(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,24 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuildSummaryUserPrompt 构建将 since 以来所有变更汇总为一条 commit message 的用户提示词，
+// 用于发布说明或 squash merge。commits 为期间的提交标题（旧到新），diff 超出 token 预算时按头尾保留截断。
+func (b *Builder) BuildSummaryUserPrompt(since string, commits []string, diff string, files []string) string {
+	parts := []string{"Summarize every change since " + since + " in a single commit message. " +
+		"The subject describes the overall change; the body lists the notable changes as bullets, " +
+		"merging fixups and closely related commits."}
+	if len(commits) > 0 {
+		parts = append(parts, fmt.Sprintf("Commits since %s (%d, oldest first):\n%s", since, len(commits), bulletList(commits)))
+	}
+	if len(files) > 0 {
+		parts = append(parts, "Changed files: "+strings.Join(files, ", "))
+	}
+	if diff != "" {
+		parts = append(parts, "Git diff (may be truncated):\n```diff\n"+b.smartTruncateDiff(diff, b.tokenBudget.AvailableTokens)+"\n```")
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,15 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSummaryUserPrompt(t *testing.T) {
+	out := NewBuilder("en", 0).BuildSummaryUserPrompt("v1.2.0", []string{"feat: add x", "fix: x typo"}, "diff --git a/x.go b/x.go\n+x", []string{"x.go"})
+	require.Contains(t, out, "Summarize every change since v1.2.0")
+	require.Contains(t, out, "Commits since v1.2.0 (2, oldest first):\n- feat: add x\n- fix: x typo")
+	require.Contains(t, out, "Changed files: x.go")
+	require.Contains(t, out, "+x")
+}
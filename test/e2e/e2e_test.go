@@ -74,6 +74,8 @@ func TestE2E_HappyPathYes(t *testing.T) {
 	cmd.Env = append(os.Environ(),
 		"CATMIT_LLM_API_KEY=dummy",
 		"CATMIT_LLM_API_URL="+server.URL,
+		"CATMIT_LLM_MODEL=test-model",
+		"XDG_CONFIG_HOME="+t.TempDir(),
 	)
 	var out bytes.Buffer
 	cmd.Stdout = &out
@@ -100,9 +102,9 @@ func TestE2E_DryRun(t *testing.T) {
 	_ = os.WriteFile(filepath.Join(repo, "a.go"), []byte("package main"), 0644)
 	_ = exec.Command("git", "-C", repo, "add", "a.go").Run()
 
-	cmd := exec.Command(bin, "--dry-run")
+	cmd := exec.Command(bin, "--dry-run", "--model", "test-model")
 	cmd.Dir = repo
-	cmd.Env = append(os.Environ(), "CATMIT_LLM_API_KEY=dummy", "CATMIT_LLM_API_URL="+server.URL)
+	cmd.Env = append(os.Environ(), "CATMIT_LLM_API_KEY=dummy", "CATMIT_LLM_API_URL="+server.URL, "XDG_CONFIG_HOME="+t.TempDir())
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &out
@@ -132,7 +134,7 @@ func TestE2E_Timeout(t *testing.T) {
 
 	cmd := exec.Command(bin, "-y", "-t", "1", "--push=false")
 	cmd.Dir = repo
-	cmd.Env = append(os.Environ(), "CATMIT_LLM_API_KEY=dummy", "CATMIT_LLM_API_URL="+server.URL)
+	cmd.Env = append(os.Environ(), "CATMIT_LLM_API_KEY=dummy", "CATMIT_LLM_API_URL="+server.URL, "CATMIT_LLM_MODEL=test-model")
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &out
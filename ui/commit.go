@@ -25,6 +25,7 @@ const (
 	CommitStagePRCreated
 	CommitStagePRFailed
 	CommitStageDone
+	CommitStageConfirmPush // 等待用户确认推送到受保护分支
 )
 
 // CommitModel 用于显示commit和push操作的进度，保持与ReviewModel一致的视觉风格
@@ -61,6 +62,8 @@ type commitInterface interface {
 	HasStagedChanges(ctx context.Context) bool
 	CreatePullRequest(ctx context.Context) (string, error)
 	NeedsPush(ctx context.Context) (bool, error)
+	// LastCommitSHA 返回刚完成的提交的 SHA，提交后显示短 SHA；不支持时返回空串
+	LastCommitSHA(ctx context.Context) (string, error)
 }
 
 // NewCommitModel 创建新的CommitModel
@@ -215,8 +218,8 @@ func (m *CommitModel) View() string {
 	
 	// 显示commit message（截断显示）
 	messagePreview := m.message
-	if len(messagePreview) > contentWidth-4 {
-		messagePreview = messagePreview[:contentWidth-7] + "..."
+	if lipgloss.Width(messagePreview) > contentWidth-4 {
+		messagePreview = truncateContent(messagePreview, contentWidth-7) + "..."
 	}
 	contentLines = append(contentLines, renderLine(" "+titleStyle.Render("Message: ")+messagePreview))
 	contentLines = append(contentLines, renderLine("")) // 空行
@@ -267,6 +270,7 @@ func (m *CommitModel) IsDone() (bool, error) {
 
 type commitDoneMsg struct {
 	err error
+	sha string // 提交成功后的 HEAD SHA，未知时为空
 }
 
 type pushDoneMsg struct {
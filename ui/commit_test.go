@@ -45,6 +45,11 @@ func (m *mockCommitter) NeedsPush(ctx context.Context) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+// LastCommitSHA 不报告 SHA；需要 SHA 的测试使用 shaCommitter
+func (m *mockCommitter) LastCommitSHA(ctx context.Context) (string, error) {
+	return "", nil
+}
+
 func TestNewCommitModel(t *testing.T) {
 	ctx := context.Background()
 	committer := &mockCommitter{}
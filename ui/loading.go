@@ -2,6 +2,8 @@ package ui
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -55,6 +57,7 @@ type LoadingModel struct {
 	spinner spinner.Model
 	// injected dependencies
 	ctx         context.Context
+	cancel      context.CancelFunc // Ctrl+C 时取消 ctx，终止仍在运行的 git 子进程
 	collector   collectorInterface
 	promptBuild promptInterface
 	client      clientInterface
@@ -67,6 +70,8 @@ type LoadingModel struct {
 	stageStartTime time.Time
 	minStageDelay  time.Duration
 
+	progress untrackedProgressMsg // 最近一次未跟踪文件读取进度，total 为 0 表示没有进度
+
 	message string
 	err     error
 }
@@ -74,10 +79,12 @@ type LoadingModel struct {
 func NewLoadingModel(ctx context.Context, col collectorInterface, pb promptInterface, cli clientInterface, seed, lang string, apiTimeout time.Duration) *LoadingModel {
 	sp := spinner.New()
 	sp.Spinner = spinner.Line
+	ctx, cancel := context.WithCancel(ctx)
 	return &LoadingModel{
 		stage:          StageCollect,
 		spinner:        sp,
 		ctx:            ctx,
+		cancel:         cancel,
 		collector:      col,
 		promptBuild:    pb,
 		client:         cli,
@@ -91,7 +98,7 @@ func NewLoadingModel(ctx context.Context, col collectorInterface, pb promptInter
 
 // Init 启动第一个阶段
 func (m *LoadingModel) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, collectCmd(m.collector, m.ctx))
+	return tea.Batch(m.spinner.Tick, startCollectCmd(m.collector, m.ctx))
 }
 
 // Update 处理消息
@@ -100,6 +107,7 @@ func (m *LoadingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
+			m.cancel()
 			m.err = context.Canceled
 			return m, tea.Quit
 		}
@@ -107,6 +115,9 @@ func (m *LoadingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
+	case untrackedProgressMsg:
+		m.progress = msg
+		return m, waitProgressCmd(msg.ch)
 	case diffCollectedMsg:
 		// 检查是否需要延迟过渡到预处理阶段
 		elapsed := time.Since(m.stageStartTime)
@@ -174,7 +185,7 @@ func (m *LoadingModel) View() string {
 
 	switch m.stage {
 	case StageCollect:
-		status = "Collecting diff…"
+		status = collectStatus(m.progress)
 		statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("33")) // Orange
 	case StagePreprocess:
 		status = "Preprocessing files…"
@@ -193,6 +204,14 @@ func (m *LoadingModel) View() string {
 	return m.spinner.View() + " " + statusStyle.Render(status)
 }
 
+// collectStatus 返回收集阶段的文案，有未跟踪文件读取进度时显示 "Reading untracked file 12/340…"
+func collectStatus(progress untrackedProgressMsg) string {
+	if progress.total > 0 && progress.done < progress.total {
+		return fmt.Sprintf("Reading untracked file %d/%d…", progress.done, progress.total)
+	}
+	return "Collecting diff…"
+}
+
 // IsDone 返回结果
 func (m *LoadingModel) IsDone() (string, error) {
 	return m.message, m.err
@@ -238,10 +257,88 @@ type errorMsg struct{ err error }
 
 // ---------------- Cmd 实现 --------------------
 
-func collectCmd(col collectorInterface, ctx context.Context) tea.Cmd {
+// progressDiffer 是可选能力：收集 diff 时报告未跟踪文件的读取进度
+type progressDiffer interface {
+	ComprehensiveDiffWithProgress(ctx context.Context, progress func(done, total int)) (string, error)
+}
+
+// untrackedProgressMsg 报告已读取的未跟踪文件数，ch 用于继续等待下一次进度
+type untrackedProgressMsg struct {
+	done, total int
+	ch          <-chan untrackedProgressMsg
+}
+
+// waitProgressCmd 等待下一条进度消息；收集结束（通道关闭）后不再产生消息
+func waitProgressCmd(ch <-chan untrackedProgressMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// startCollectCmd 开始收集 diff；collector 支持进度报告时同时等待进度消息，以便更新 Spinner 文案
+func startCollectCmd(col collectorInterface, ctx context.Context) tea.Cmd {
+	differ, ok := col.(progressDiffer)
+	if !ok {
+		return collectCmd(col, ctx, nil)
+	}
+	ch := make(chan untrackedProgressMsg, 1)
+	report, stop := progressReporter(ch)
+	collect := func() (string, error) {
+		defer stop()
+		return differ.ComprehensiveDiffWithProgress(ctx, report)
+	}
+	return tea.Batch(collectCmd(col, ctx, collect), waitProgressCmd(ch))
+}
+
+// progressReporter 返回向容量为 1 的 ch 发送进度的回调。缓冲区已满时取出旧进度再放入新进度，
+// UI 来不及处理时丢弃中间值，但总能读到最新进度，也不会阻塞读取文件的协程。
+// 并发读取时回调可能乱序到达，落后于已发送进度的值会被忽略。
+// stop 关闭 ch；之后到达的回调（如取消后仍在读取的协程）被丢弃，而不是向已关闭的通道发送
+func progressReporter(ch chan untrackedProgressMsg) (report func(done, total int), stop func()) {
+	var mu sync.Mutex
+	latest := 0
+	closed := false
+	stop = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+	report = func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed || done <= latest {
+			return
+		}
+		latest = done
+		select {
+		case <-ch:
+		default:
+		}
+		// 只有这里发送，取出旧值后缓冲区必有空位
+		ch <- untrackedProgressMsg{done: done, total: total, ch: ch}
+	}
+	return report, stop
+}
+
+// collectCmd 收集 diff、最近提交与分支信息；diff 为 nil 时使用 ComprehensiveDiff
+func collectCmd(col collectorInterface, ctx context.Context, diffFn func() (string, error)) tea.Cmd {
 	return func() tea.Msg {
+		if diffFn == nil {
+			diffFn = func() (string, error) { return col.ComprehensiveDiff(ctx) }
+		}
 		// Use ComprehensiveDiff to include untracked files
-		diff, err := col.ComprehensiveDiff(ctx)
+		diff, err := diffFn()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// 已取消（如 Ctrl+C）时不再回退到 Diff，直接结束
+			return errorMsg{ctxErr}
+		}
 		if err != nil {
 			// Fallback to legacy diff for backward compatibility
 			diff, err = col.Diff(ctx)
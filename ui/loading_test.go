@@ -117,3 +117,63 @@ func TestLoadingModel_Error(t *testing.T) {
 		t.Fatalf("unexpected model type")
 	}
 }
+
+type progressCollector struct {
+	mockCollector
+	total int
+}
+
+func (p progressCollector) ComprehensiveDiffWithProgress(_ context.Context, progress func(done, total int)) (string, error) {
+	for i := 1; i <= p.total; i++ {
+		progress(i, p.total)
+	}
+	return p.diff, p.err
+}
+
+func TestLoadingModel_UntrackedProgress(t *testing.T) {
+	col := progressCollector{mockCollector: mockCollector{diff: "diff"}, total: 3}
+	lm := NewLoadingModel(context.Background(), col, mockPrompt{}, mockClient{msg: "feat: ok"}, "", "en", 20*time.Second)
+	require.Contains(t, lm.View(), "Collecting diff…")
+
+	ch := make(chan untrackedProgressMsg)
+	model, cmd := lm.Update(untrackedProgressMsg{done: 12, total: 340, ch: ch})
+	require.NotNil(t, cmd)
+	require.Contains(t, model.View(), "Reading untracked file 12/340…")
+
+	// 通道关闭后等待命令不再产生消息
+	close(ch)
+	require.Nil(t, cmd())
+
+	// 全部读完后恢复通用文案
+	model, _ = model.Update(untrackedProgressMsg{done: 340, total: 340, ch: ch})
+	require.Contains(t, model.View(), "Collecting diff…")
+
+	finalModel, err := runModel(NewLoadingModel(context.Background(), col, mockPrompt{}, mockClient{msg: "feat: ok"}, "", "en", 20*time.Second))
+	require.NoError(t, err)
+	msg, err := finalModel.(*LoadingModel).IsDone()
+	require.NoError(t, err)
+	require.Equal(t, "feat: ok", msg)
+}
+
+func TestProgressReporter(t *testing.T) {
+	ch := make(chan untrackedProgressMsg, 1)
+	report, stop := progressReporter(ch)
+
+	// UI 未读取时新进度替换旧进度
+	report(1, 3)
+	report(2, 3)
+	require.Equal(t, 2, (<-ch).done)
+
+	// 乱序到达的旧进度被忽略
+	report(3, 3)
+	report(2, 3)
+	require.Equal(t, 3, (<-ch).done)
+	require.Empty(t, ch)
+
+	// stop 关闭通道后到达的进度被丢弃，不会向已关闭的通道发送
+	stop()
+	require.NotPanics(t, func() { report(4, 5) })
+	stop()
+	_, ok := <-ch
+	require.False(t, ok)
+}
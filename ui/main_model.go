@@ -12,6 +12,8 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/penwyp/catmit/collector"
+	"github.com/penwyp/catmit/internal/commitlint"
+	"github.com/penwyp/catmit/internal/msgdiff"
 )
 
 // Phase 表示主模型所处的阶段
@@ -22,6 +24,7 @@ const (
 	PhaseReview
 	PhaseCommit
 	PhaseDone
+	PhaseSelect // 生成 message 前选择要提交的文件，见 SetInteractiveStage
 )
 
 // MainModel 统一的单视图模型，管理整个生命周期
@@ -45,6 +48,7 @@ type MainModel struct {
 
 	// 依赖注入
 	ctx         context.Context
+	cancel      context.CancelFunc // Ctrl+C 时取消 ctx，终止仍在运行的 git 子进程
 	collector   collectorInterface
 	promptBuild promptInterface
 	client      clientInterface
@@ -68,6 +72,26 @@ type MainModel struct {
 	// 内部状态
 	finalStartTime time.Time
 	showDuration   time.Duration
+	onMessage      func(message string, edited bool) // 生成或编辑 message 后的回调，可为 nil
+	protectedPush  string                            // 非空时推送前需确认，值为受保护的当前分支
+	pushSkipped    bool                              // 用户拒绝推送到受保护分支
+	committed      bool                              // 提交已成功
+	pushed         bool                              // 推送已成功
+	keepOpen       bool                              // 完成后停留在摘要界面，按任意键退出
+	awaitingExit   bool                              // 正在显示摘要界面，等待按键
+	copyPRURL      func(url string) error            // 摘要界面按 c 复制 PR URL，可为 nil
+	copyStatus     string                            // 最近一次复制 PR URL 的结果
+	commitSHA      string                            // 提交成功后的 HEAD SHA，未知时为空
+	amendOriginal  string                            // --amend 时 HEAD 原有的 message，Review 阶段与新 message 对比
+	progress       untrackedProgressMsg              // 最近一次未跟踪文件读取进度
+
+	// 文件选择阶段
+	stageFiles   StageFunc              // 非 nil 时先进入文件选择阶段
+	selectFiles  []collector.FileStatus // 候选文件，加载完成前为 nil
+	selected     []bool                 // 与 selectFiles 一一对应的勾选状态
+	selectCursor int
+	selectNotice string // 提示信息，例如未选择任何文件
+	staging      bool   // 正在暂存所选文件
 
 	// UI样式
 	styles UIStyles
@@ -92,6 +116,7 @@ func NewMainModel(
 	ta.CharLimit = 1000
 	ta.ShowLineNumbers = false
 
+	ctx, cancel := context.WithCancel(ctx)
 	return &MainModel{
 		phase:          PhaseLoading,
 		loadingStage:   StageCollect,
@@ -99,6 +124,7 @@ func NewMainModel(
 		textArea:       ta,
 		selectedButton: buttonAccept,
 		ctx:            ctx,
+		cancel:         cancel,
 		collector:      col,
 		promptBuild:    pb,
 		client:         cli,
@@ -116,9 +142,47 @@ func NewMainModel(
 	}
 }
 
+// SetMessageRecorder 注册回调，在 message 生成及每次保存编辑后调用，用于记录历史
+func (m *MainModel) SetMessageRecorder(fn func(message string, edited bool)) {
+	m.onMessage = fn
+}
+
+// SetInitialMessage 跳过生成流程，直接以给定 message 进入 Review 阶段
+func (m *MainModel) SetInitialMessage(message string) {
+	m.message = strings.TrimSpace(message)
+	m.textArea.SetValue(m.message)
+	m.phase = PhaseReview
+}
+
+// SetConfirmPush 要求在推送到受保护分支 branch 前由用户确认，空串表示无需确认
+func (m *MainModel) SetConfirmPush(branch string) {
+	m.protectedPush = branch
+}
+
+// SetKeepOpen 设置完成后是否停留在摘要界面等待按键，而不是在 showDuration 后自动退出
+func (m *MainModel) SetKeepOpen(keepOpen bool) {
+	m.keepOpen = keepOpen
+}
+
+// SetCopyPRURL 注册复制 PR URL 到剪贴板的函数，摘要界面中按 c 调用
+func (m *MainModel) SetCopyPRURL(fn func(url string) error) {
+	m.copyPRURL = fn
+}
+
+// SetAmendOriginal 设置被 amend 的 HEAD 原有 message，Review 阶段会显示与新 message 的逐行对比
+func (m *MainModel) SetAmendOriginal(message string) {
+	m.amendOriginal = strings.TrimSpace(message)
+}
+
 // Init 启动第一个阶段
 func (m *MainModel) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, collectCmd(m.collector, m.ctx))
+	switch m.phase {
+	case PhaseReview:
+		return m.spinner.Tick
+	case PhaseSelect:
+		return tea.Batch(m.spinner.Tick, loadFilesCmd(m.collector, m.ctx))
+	}
+	return tea.Batch(m.spinner.Tick, startCollectCmd(m.collector, m.ctx))
 }
 
 // Update 处理消息
@@ -132,8 +196,22 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		// 摘要界面中任意键（包括 Ctrl+C）正常退出，操作已全部完成
+		if m.awaitingExit {
+			if msg.String() == "c" && m.canCopyPRURL() {
+				if err := m.copyPRURL(m.prURL); err != nil {
+					m.copyStatus = "Copy failed: " + err.Error()
+				} else {
+					m.copyStatus = "Copied PR URL to clipboard"
+				}
+				return m, nil
+			}
+			m.done = true
+			return m, tea.Quit
+		}
 		// 全局快捷键处理
 		if msg.String() == "ctrl+c" {
+			m.cancel()
 			m.err = context.Canceled
 			m.done = true
 			return m, tea.Quit
@@ -141,8 +219,14 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// 根据phase处理不同的键盘输入
 		switch m.phase {
+		case PhaseSelect:
+			return m.updateSelect(msg)
 		case PhaseReview:
 			return m.updateReview(msg)
+		case PhaseCommit:
+			if m.commitStage == CommitStageConfirmPush {
+				return m.updateConfirmPush(msg)
+			}
 		}
 
 	case spinner.TickMsg:
@@ -150,7 +234,36 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
 
+	// 文件选择阶段的消息处理
+	case filesLoadedMsg:
+		if msg.err == nil && len(msg.files) == 0 {
+			msg.err = collector.ErrNoDiff
+		}
+		if msg.err != nil {
+			m.err = msg.err
+			m.done = true
+			return m, tea.Quit
+		}
+		m.selectFiles = msg.files
+		m.selected = make([]bool, len(msg.files))
+		for i := range m.selected {
+			m.selected[i] = true
+		}
+		return m, nil
+
+	case filesStagedMsg:
+		if msg.err != nil {
+			m.err = fmt.Errorf("staging failed: %w", msg.err)
+			m.done = true
+			return m, tea.Quit
+		}
+		return m, m.finishStaging()
+
 	// Loading阶段的消息处理
+	case untrackedProgressMsg:
+		m.progress = msg
+		return m, waitProgressCmd(msg.ch)
+
 	case diffCollectedMsg:
 		m.loadingStage = StagePreprocess
 		return m, preprocessCmd(m.collector, m.ctx)
@@ -167,6 +280,9 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.message = strings.TrimSpace(strings.ReplaceAll(msg.message, "\r", ""))
 		m.phase = PhaseReview
 		m.textArea.SetValue(m.message)
+		if m.onMessage != nil {
+			m.onMessage(m.message, false)
+		}
 		return m, nil
 
 	// Commit阶段的消息处理
@@ -177,6 +293,12 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 		m.commitStage = CommitStageCommitted
+		m.committed = true
+		m.commitSHA = msg.sha
+		if m.enablePush && m.protectedPush != "" {
+			m.commitStage = CommitStageConfirmPush
+			return m, nil
+		}
 		if m.enablePush {
 			// 添加延迟以确保CommitStageCommitted状态有时间完整渲染
 			return m, tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
@@ -207,6 +329,7 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return finalTimeoutMsg{}
 			})
 		}
+		m.pushed = true
 		m.commitStage = CommitStageDone
 		m.finalStartTime = time.Now()
 		return m, tea.Tick(m.showDuration, func(time.Time) tea.Msg {
@@ -214,6 +337,10 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		})
 
 	case finalTimeoutMsg:
+		if m.keepOpen {
+			m.awaitingExit = true
+			return m, nil
+		}
 		m.done = true
 		return m, tea.Quit
 
@@ -284,7 +411,11 @@ func (m *MainModel) updateReview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.textArea.Blur()
 			return m, nil
 		case "ctrl+s":
-			m.message = strings.TrimSpace(m.textArea.Value())
+			edited := strings.TrimSpace(m.textArea.Value())
+			if edited != m.message && m.onMessage != nil {
+				m.onMessage(edited, true)
+			}
+			m.message = edited
 			m.editing = false
 			m.textArea.Blur()
 			return m, nil
@@ -343,6 +474,26 @@ func (m *MainModel) updateReview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// updateConfirmPush 处理推送到受保护分支的确认：y 推送，n/Esc/Enter 跳过推送（默认拒绝）
+func (m *MainModel) updateConfirmPush(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		m.commitStage = CommitStagePushing
+		return m, m.startPush()
+	case "n", "N", "esc", "enter", "q", "Q":
+		// 未推送时无法创建 PR，一并跳过
+		m.enablePush = false
+		m.createPR = false
+		m.pushSkipped = true
+		m.commitStage = CommitStageDone
+		m.finalStartTime = time.Now()
+		return m, tea.Tick(m.showDuration, func(time.Time) tea.Msg {
+			return finalTimeoutMsg{}
+		})
+	}
+	return m, nil
+}
+
 // View 渲染统一的界面
 func (m *MainModel) View() string {
 	contentWidth := CalculateContentWidth(m.terminalWidth)
@@ -350,6 +501,8 @@ func (m *MainModel) View() string {
 	// 统一的边框容器
 	content := m.renderContainer(func() string {
 		switch m.phase {
+		case PhaseSelect:
+			return m.renderSelectContent()
 		case PhaseLoading:
 			return m.renderLoadingContent()
 		case PhaseReview:
@@ -424,7 +577,7 @@ func (m *MainModel) renderLoadingContent() string {
 
 	switch m.loadingStage {
 	case StageCollect:
-		status = "Collecting diff…"
+		status = collectStatus(m.progress)
 		statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("33"))
 	case StagePreprocess:
 		status = "Preprocessing files…"
@@ -473,6 +626,15 @@ func (m *MainModel) renderReviewContent() string {
 		}
 	}
 
+	// 非阻塞提示：标题未使用祈使语气时给出建议，不影响提交
+	if hint := commitlint.ImperativeHint(m.message); hint != "" {
+		content.WriteString("\n " + m.styles.Warning.Render("💡 "+hint) + "\n")
+	}
+
+	if m.amendOriginal != "" {
+		content.WriteString(m.renderAmendComparison())
+	}
+
 	content.WriteString("\n")
 
 	// 渲染按钮
@@ -482,6 +644,30 @@ func (m *MainModel) renderReviewContent() string {
 	return content.String()
 }
 
+// renderAmendComparison 渲染 HEAD 原有 message 与新 message 的逐行对比
+func (m *MainModel) renderAmendComparison() string {
+	var content strings.Builder
+	content.WriteString("\n " + m.styles.Title.Render("Compared with HEAD:") + "\n")
+	if !msgdiff.Changed(m.amendOriginal, m.message) {
+		content.WriteString(" " + m.styles.Warning.Render("The new message is identical to the current HEAD message") + "\n")
+		return content.String()
+	}
+	maxWidth := CalculateContentWidth(m.terminalWidth) - 2
+	for _, line := range msgdiff.Lines(m.amendOriginal, m.message) {
+		text := truncateContent(line.String(), maxWidth)
+		switch line.Op {
+		case msgdiff.Delete:
+			text = m.styles.Error.Render(text)
+		case msgdiff.Insert:
+			text = m.styles.Success.Render(text)
+		default:
+			text = m.styles.CommitBody.Render(text)
+		}
+		content.WriteString(" " + text + "\n")
+	}
+	return content.String()
+}
+
 // renderEditingContent 渲染编辑模式的内容
 func (m *MainModel) renderEditingContent() string {
 	promptStyle := lipgloss.NewStyle().Foreground(m.styles.Colors.Yellow)
@@ -509,8 +695,9 @@ func (m *MainModel) renderCommitContent() string {
 	// 显示commit message预览
 	messagePreview := m.message
 	maxWidth := CalculateContentWidth(m.terminalWidth) - 4
-	if len(messagePreview) > maxWidth {
-		messagePreview = messagePreview[:maxWidth-3] + "..."
+	// 按显示宽度截断，避免切断中日文等多字节字符
+	if lipgloss.Width(messagePreview) > maxWidth {
+		messagePreview = truncateContent(messagePreview, maxWidth-3) + "..."
 	}
 	content.WriteString(" " + m.styles.Title.Render("Message: ") + messagePreview + "\n\n")
 
@@ -519,15 +706,15 @@ func (m *MainModel) renderCommitContent() string {
 	case CommitStageInit, CommitStageCommitting:
 		content.WriteString(" " + m.spinner.View() + " " + m.styles.Progress.Render("Committing changes..."))
 	case CommitStageCommitted:
-		content.WriteString(" ✓ " + m.styles.Success.Render("Committed successfully"))
+		content.WriteString(" ✓ " + m.styles.Success.Render(m.committedLabel()))
 		if m.enablePush {
 			content.WriteString("\n " + m.spinner.View() + " " + m.styles.Progress.Render("Preparing to push..."))
 		}
 	case CommitStagePushing:
-		content.WriteString(" ✓ " + m.styles.Success.Render("Committed successfully"))
+		content.WriteString(" ✓ " + m.styles.Success.Render(m.committedLabel()))
 		content.WriteString("\n " + m.spinner.View() + " " + m.styles.Progress.Render("Pushing to remote..."))
 	case CommitStagePushFailed:
-		content.WriteString(" ✓ " + m.styles.Success.Render("Committed successfully"))
+		content.WriteString(" ✓ " + m.styles.Success.Render(m.committedLabel()))
 		if m.enablePush {
 			errorText := "Push failed"
 			if m.err != nil {
@@ -541,7 +728,7 @@ func (m *MainModel) renderCommitContent() string {
 			content.WriteString("\n ✗ " + m.styles.Error.Render(errorText))
 		}
 	case CommitStagePushed:
-		content.WriteString(" ✓ " + m.styles.Success.Render("Committed successfully"))
+		content.WriteString(" ✓ " + m.styles.Success.Render(m.committedLabel()))
 		if m.enablePush {
 			content.WriteString("\n ✓ " + m.styles.Success.Render("Pushed successfully"))
 		}
@@ -549,13 +736,13 @@ func (m *MainModel) renderCommitContent() string {
 			content.WriteString("\n " + m.spinner.View() + " " + m.styles.Progress.Render("Preparing to create PR..."))
 		}
 	case CommitStageCreatingPR:
-		content.WriteString(" ✓ " + m.styles.Success.Render("Committed successfully"))
+		content.WriteString(" ✓ " + m.styles.Success.Render(m.committedLabel()))
 		if m.enablePush {
 			content.WriteString("\n ✓ " + m.styles.Success.Render("Pushed successfully"))
 		}
 		content.WriteString("\n " + m.spinner.View() + " " + m.styles.Progress.Render("Creating pull request..."))
 	case CommitStagePRFailed:
-		content.WriteString(" ✓ " + m.styles.Success.Render("Committed successfully"))
+		content.WriteString(" ✓ " + m.styles.Success.Render(m.committedLabel()))
 		if m.enablePush {
 			content.WriteString("\n ✓ " + m.styles.Success.Render("Pushed successfully"))
 		}
@@ -571,11 +758,17 @@ func (m *MainModel) renderCommitContent() string {
 			}
 			content.WriteString("\n ✗ " + m.styles.Error.Render(errorText))
 		}
+	case CommitStageConfirmPush:
+		content.WriteString(" ✓ " + m.styles.Success.Render(m.committedLabel()))
+		content.WriteString("\n ? " + m.styles.Warning.Render(fmt.Sprintf("Push to protected branch %s? [y/N]", m.protectedPush)))
 	case CommitStagePRCreated, CommitStageDone:
-		content.WriteString(" ✓ " + m.styles.Success.Render("Committed successfully"))
+		content.WriteString(" ✓ " + m.styles.Success.Render(m.committedLabel()))
 		if m.enablePush {
 			content.WriteString("\n ✓ " + m.styles.Success.Render("Pushed successfully"))
 		}
+		if m.pushSkipped {
+			content.WriteString("\n - " + m.styles.Warning.Render(fmt.Sprintf("Push skipped: %s is a protected branch", m.protectedPush)))
+		}
 		if m.createPR {
 			content.WriteString("\n ✓ " + m.styles.Success.Render("Pull request created successfully"))
 			if m.prURL != "" {
@@ -584,9 +777,37 @@ func (m *MainModel) renderCommitContent() string {
 		}
 	}
 
+	if m.awaitingExit {
+		content.WriteString("\n\n" + m.renderSummary())
+	}
+
 	return content.String()
 }
 
+// renderSummary 渲染 --keep-open 的摘要界面，显示完整的 commit SHA 与 PR URL
+func (m *MainModel) renderSummary() string {
+	var summary strings.Builder
+	if m.commitSHA != "" {
+		summary.WriteString(" " + m.styles.Title.Render("Commit: ") + m.commitSHA + "\n")
+	}
+	if m.prURL != "" {
+		summary.WriteString(" " + m.styles.Title.Render("PR: ") + m.prURL + "\n")
+	}
+	if m.copyStatus != "" {
+		summary.WriteString(" " + m.styles.CommitDesc.Render(m.copyStatus) + "\n")
+	}
+	if m.canCopyPRURL() {
+		summary.WriteString(" " + m.styles.Progress.Render("Press c to copy the PR URL, any other key to exit"))
+	} else {
+		summary.WriteString(" " + m.styles.Progress.Render("Press any key to exit"))
+	}
+	return summary.String()
+}
+
+func (m *MainModel) canCopyPRURL() bool {
+	return m.copyPRURL != nil && m.prURL != ""
+}
+
 // renderButtons 渲染按钮组
 func (m *MainModel) renderButtons() string {
 	colors := m.styles.Colors
@@ -627,6 +848,8 @@ func (m *MainModel) renderButtons() string {
 // getPhaseTitle 获取当前阶段的标题
 func (m *MainModel) getPhaseTitle() string {
 	switch m.phase {
+	case PhaseSelect:
+		return "Select Files"
 	case PhaseLoading:
 		return "Generating Message"
 	case PhaseReview:
@@ -651,8 +874,12 @@ func (m *MainModel) startCommit() tea.Cmd {
 				return commitDoneMsg{err: fmt.Errorf("staging failed: %w", err)}
 			}
 		}
-		err := m.committer.Commit(m.ctx, m.message)
-		return commitDoneMsg{err: err}
+		if err := m.committer.Commit(m.ctx, m.message); err != nil {
+			return commitDoneMsg{err: err}
+		}
+		// 查询失败时不显示 SHA，不影响提交结果
+		sha, _ := m.committer.LastCommitSHA(m.ctx)
+		return commitDoneMsg{sha: sha}
 	}
 }
 
@@ -677,6 +904,75 @@ func (m *MainModel) IsDone() (bool, Decision, string, error) {
 	return m.done, m.reviewDecision, m.message, m.err
 }
 
+// PushStatus 描述本次运行的推送结果
+type PushStatus string
+
+const (
+	PushNotRequested PushStatus = "not_requested" // 未启用推送，或提交前已结束
+	PushSkipped      PushStatus = "skipped"       // 用户拒绝推送到受保护分支
+	PushSucceeded    PushStatus = "pushed"
+	PushFailed       PushStatus = "failed"
+)
+
+// Result 汇总 MainModel 结束后的结果，供调用方打印摘要或输出 JSON
+type Result struct {
+	Decision  Decision   `json:"decision"`
+	Message   string     `json:"message"`
+	Committed bool       `json:"committed"`
+	CommitSHA string     `json:"commit_sha,omitempty"`
+	Push      PushStatus `json:"push"`
+	PRURL     string     `json:"pr_url,omitempty"`
+	Err       error      `json:"-"` // 与 IsDone 返回的错误相同
+}
+
+// Result 返回本次运行的结构化结果，应在程序退出后调用
+func (m *MainModel) Result() Result {
+	push := PushNotRequested
+	switch {
+	case m.pushed:
+		push = PushSucceeded
+	case m.pushSkipped:
+		push = PushSkipped
+	case m.commitStage == CommitStagePushFailed:
+		push = PushFailed
+	}
+	return Result{
+		Decision:  m.reviewDecision,
+		Message:   m.message,
+		Committed: m.committed,
+		CommitSHA: m.commitSHA,
+		Push:      push,
+		PRURL:     m.prURL,
+		Err:       m.err,
+	}
+}
+
+// CommitSHA 返回本次提交的 SHA，尚未提交或无法获取时为空串
+func (m *MainModel) CommitSHA() string {
+	return m.commitSHA
+}
+
+// committedLabel 返回提交完成的状态文本，已知 SHA 时显示短 SHA
+func (m *MainModel) committedLabel() string {
+	if m.commitSHA == "" {
+		return "Committed successfully"
+	}
+	return "Committed " + shortSHA(m.commitSHA)
+}
+
+// shortSHA 截取 SHA 的前 7 位用于显示
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// PRURL 返回本次创建（或已存在）的 PR URL，未创建时为空串
+func (m *MainModel) PRURL() string {
+	return m.prURL
+}
+
 // GetError 返回错误信息
 func (m *MainModel) GetError() error {
 	if m.err == collector.ErrNoDiff {
@@ -2,11 +2,15 @@ package ui
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/penwyp/catmit/collector"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -129,6 +133,11 @@ func (m *MockCommitter) NeedsPush(ctx context.Context) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+// LastCommitSHA 不报告 SHA；需要 SHA 的测试使用 shaCommitter
+func (m *MockCommitter) LastCommitSHA(ctx context.Context) (string, error) {
+	return "", nil
+}
+
 func TestMainModel_NewMainModel(t *testing.T) {
 	ctx := context.Background()
 	mockCollector := new(MockCollector)
@@ -243,6 +252,56 @@ func TestMainModel_Update_CtrlC(t *testing.T) {
 	assert.Equal(t, context.Canceled, m.err)
 }
 
+// blockingCollector 模拟卡住的 git 命令，直到 ctx 被取消才返回
+type blockingCollector struct {
+	mockCollector
+}
+
+func (blockingCollector) ComprehensiveDiff(ctx context.Context) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestMainModel_CtrlCCancelsCollect(t *testing.T) {
+	model := NewMainModel(
+		context.Background(),
+		blockingCollector{},
+		new(MockPromptBuilder),
+		new(MockClient),
+		new(MockCommitter),
+		"",
+		"en",
+		30*time.Second,
+		false,
+		false,
+		false,
+	)
+
+	result := make(chan tea.Msg, 1)
+	collect := collectCmd(model.collector, model.ctx, nil)
+	go func() { result <- collect() }()
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	assert.NotNil(t, cmd)
+	assert.ErrorIs(t, model.ctx.Err(), context.Canceled)
+
+	var msg tea.Msg
+	select {
+	case msg = <-result:
+	case <-time.After(time.Second):
+		t.Fatal("collectCmd did not return after cancellation")
+	}
+	errMsg, ok := msg.(errorMsg)
+	assert.True(t, ok, "expected errorMsg, got %T", msg)
+	assert.ErrorIs(t, errMsg.err, context.Canceled)
+
+	newModel, cmd := model.Update(errMsg)
+	m := newModel.(*MainModel)
+	assert.NotNil(t, cmd)
+	assert.True(t, m.done)
+	assert.ErrorIs(t, m.err, context.Canceled)
+}
+
 func TestMainModel_View_LoadingPhase(t *testing.T) {
 	ctx := context.Background()
 	mockCollector := new(MockCollector)
@@ -299,6 +358,40 @@ func TestMainModel_View_ReviewPhase(t *testing.T) {
 	assert.Contains(t, view, "Accept")
 	assert.Contains(t, view, "Edit")
 	assert.Contains(t, view, "Cancel")
+	assert.NotContains(t, view, "imperative mood")
+
+	// 非祈使语气的标题显示提示，但不阻止提交
+	model.message = "feat: Added new feature"
+	view = model.View()
+	assert.Contains(t, view, "imperative mood")
+	assert.Contains(t, view, "Accept")
+}
+
+func TestMainModel_View_AmendComparison(t *testing.T) {
+	model := NewMainModel(
+		context.Background(),
+		new(MockCollector),
+		new(MockPromptBuilder),
+		new(MockClient),
+		new(MockCommitter),
+		"",
+		"en",
+		30*time.Second,
+		false,
+		false,
+		false,
+	)
+	model.SetAmendOriginal("fix: update stuff\n")
+	model.SetInitialMessage("fix(parser): handle empty input")
+
+	view := model.View()
+	assert.Contains(t, view, "Compared with HEAD:")
+	assert.Contains(t, view, "- fix: update stuff")
+	assert.Contains(t, view, "+ fix(parser): handle empty input")
+	assert.Contains(t, view, "Accept")
+
+	model.message = "fix: update stuff"
+	assert.Contains(t, model.View(), "identical to the current HEAD message")
 }
 
 func TestMainModel_View_CommitPhase(t *testing.T) {
@@ -402,4 +495,418 @@ func TestMainModel_ErrorHandling(t *testing.T) {
 	assert.NotNil(t, cmd)
 	assert.True(t, m.done)
 	assert.Equal(t, testErr, m.err)
-}
\ No newline at end of file
+}
+func TestMainModel_MessageRecorder(t *testing.T) {
+	model := NewMainModel(
+		context.Background(),
+		new(MockCollector),
+		new(MockPromptBuilder),
+		new(MockClient),
+		new(MockCommitter),
+		"",
+		"en",
+		30*time.Second,
+		false,
+		false,
+		false,
+	)
+
+	type record struct {
+		message string
+		edited  bool
+	}
+	var records []record
+	model.SetMessageRecorder(func(message string, edited bool) {
+		records = append(records, record{message, edited})
+	})
+
+	model.Update(queryDoneMsg{message: "feat: generated\n"})
+	assert.Equal(t, PhaseReview, model.phase)
+
+	model.editing = true
+	model.textArea.SetValue("feat: edited")
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+
+	assert.Equal(t, []record{{"feat: generated", false}, {"feat: edited", true}}, records)
+	assert.Equal(t, "feat: edited", model.message)
+}
+
+func TestMainModel_SetInitialMessage(t *testing.T) {
+	model := NewMainModel(
+		context.Background(),
+		new(MockCollector),
+		new(MockPromptBuilder),
+		nil,
+		new(MockCommitter),
+		"",
+		"en",
+		30*time.Second,
+		false,
+		false,
+		false,
+	)
+
+	model.SetInitialMessage("  fix: reused  ")
+	assert.Equal(t, PhaseReview, model.phase)
+	assert.Equal(t, "fix: reused", model.message)
+	assert.Equal(t, "fix: reused", model.textArea.Value())
+	assert.NotNil(t, model.Init())
+}
+
+func TestMainModel_View_CommitPhase_CJKTruncation(t *testing.T) {
+	model := NewMainModel(
+		context.Background(),
+		new(MockCollector),
+		new(MockPromptBuilder),
+		new(MockClient),
+		new(MockCommitter),
+		"",
+		"zh",
+		30*time.Second,
+		false,
+		false,
+		false,
+	)
+
+	model.terminalWidth = 40 // 窄终端，内容宽度取最小值
+	model.phase = PhaseCommit
+	model.commitStage = CommitStageCommitting
+	model.message = "feat(ui): " + strings.Repeat("修复提交预览中的中文截断问题", 4)
+
+	content := model.renderCommitContent()
+	assert.True(t, utf8.ValidString(content))
+	assert.NotContains(t, content, "�")
+
+	firstLine := strings.SplitN(content, "\n", 2)[0]
+	preview := firstLine[strings.Index(firstLine, "feat"):]
+	assert.True(t, strings.HasSuffix(preview, "..."))
+	assert.LessOrEqual(t, lipgloss.Width(preview), CalculateContentWidth(model.terminalWidth)-4)
+}
+
+func TestMainModel_ConfirmPushToProtectedBranch(t *testing.T) {
+	newModel := func(com *MockCommitter) *MainModel {
+		model := NewMainModel(
+			context.Background(),
+			new(MockCollector),
+			new(MockPromptBuilder),
+			new(MockClient),
+			com,
+			"",
+			"en",
+			30*time.Second,
+			true,
+			false,
+			false,
+		)
+		model.SetConfirmPush("main")
+		model.phase = PhaseCommit
+		model.message = "fix: guarded"
+		return model
+	}
+
+	t.Run("confirm pushes", func(t *testing.T) {
+		com := new(MockCommitter)
+		com.On("Push", mock.Anything).Return(nil)
+		model := newModel(com)
+
+		_, cmd := model.Update(commitDoneMsg{})
+		assert.Nil(t, cmd)
+		assert.Equal(t, CommitStageConfirmPush, model.commitStage)
+		assert.Contains(t, model.View(), "Push to protected branch main? [y/N]")
+
+		_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+		assert.Equal(t, CommitStagePushing, model.commitStage)
+		assert.IsType(t, pushDoneMsg{}, cmd())
+		com.AssertCalled(t, "Push", mock.Anything)
+	})
+
+	t.Run("decline skips push", func(t *testing.T) {
+		com := new(MockCommitter)
+		model := newModel(com)
+
+		model.Update(commitDoneMsg{})
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.NotNil(t, cmd)
+		assert.Equal(t, CommitStageDone, model.commitStage)
+		assert.Contains(t, model.View(), "Push skipped: main is a protected branch")
+		com.AssertNotCalled(t, "Push", mock.Anything)
+	})
+}
+
+// shaCommitter 在 MockCommitter 基础上报告提交后的 SHA
+type shaCommitter struct {
+	MockCommitter
+	sha string
+}
+
+func (s *shaCommitter) LastCommitSHA(ctx context.Context) (string, error) {
+	return s.sha, nil
+}
+
+func TestMainModel_ShowsCommitSHA(t *testing.T) {
+	com := &shaCommitter{sha: "0123456789abcdef0123456789abcdef01234567"}
+	com.On("Commit", mock.Anything, "fix: sha").Return(nil)
+	model := NewMainModel(
+		context.Background(),
+		new(MockCollector),
+		new(MockPromptBuilder),
+		new(MockClient),
+		com,
+		"",
+		"en",
+		30*time.Second,
+		false,
+		false,
+		false,
+	)
+	model.phase = PhaseCommit
+	model.message = "fix: sha"
+
+	msg := model.startCommit()()
+	assert.Equal(t, commitDoneMsg{sha: com.sha}, msg)
+	model.Update(msg)
+	assert.Equal(t, com.sha, model.CommitSHA())
+	assert.Contains(t, model.View(), "Committed 0123456")
+}
+
+func TestMainModel_Result(t *testing.T) {
+	newModel := func(enablePush bool) *MainModel {
+		model := NewMainModel(
+			context.Background(),
+			new(MockCollector),
+			new(MockPromptBuilder),
+			new(MockClient),
+			new(MockCommitter),
+			"",
+			"en",
+			30*time.Second,
+			enablePush,
+			false,
+			false,
+		)
+		model.phase = PhaseCommit
+		model.reviewDecision = DecisionAccept
+		model.message = "fix: result"
+		return model
+	}
+
+	t.Run("pushed", func(t *testing.T) {
+		model := newModel(true)
+		model.Update(commitDoneMsg{sha: "abc1234"})
+		model.Update(pushDoneMsg{})
+
+		assert.Equal(t, Result{
+			Decision:  DecisionAccept,
+			Message:   "fix: result",
+			Committed: true,
+			CommitSHA: "abc1234",
+			Push:      PushSucceeded,
+		}, model.Result())
+	})
+
+	t.Run("push failed", func(t *testing.T) {
+		model := newModel(true)
+		pushErr := errors.New("rejected")
+		model.Update(commitDoneMsg{})
+		model.Update(pushDoneMsg{err: pushErr})
+
+		result := model.Result()
+		assert.True(t, result.Committed)
+		assert.Equal(t, PushFailed, result.Push)
+		assert.Equal(t, pushErr, result.Err)
+	})
+
+	t.Run("commit failed", func(t *testing.T) {
+		model := newModel(false)
+		model.Update(commitDoneMsg{err: errors.New("hook failed")})
+
+		result := model.Result()
+		assert.False(t, result.Committed)
+		assert.Equal(t, PushNotRequested, result.Push)
+		assert.Error(t, result.Err)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(Result{Decision: DecisionCancel, Message: "fix: result", Push: PushNotRequested, Err: errors.New("ignored")})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"decision":"cancel","message":"fix: result","committed":false,"push":"not_requested"}`, string(data))
+	})
+}
+
+func TestMainModel_KeepOpen(t *testing.T) {
+	newModel := func(keepOpen bool) *MainModel {
+		model := NewMainModel(
+			context.Background(),
+			new(MockCollector),
+			new(MockPromptBuilder),
+			new(MockClient),
+			new(MockCommitter),
+			"",
+			"en",
+			30*time.Second,
+			false,
+			false,
+			false,
+		)
+		model.SetKeepOpen(keepOpen)
+		model.phase = PhaseCommit
+		model.message = "fix: keep open"
+		model.commitStage = CommitStageDone
+		model.commitSHA = "0123456789abcdef0123456789abcdef01234567"
+		return model
+	}
+
+	t.Run("default exits after timeout", func(t *testing.T) {
+		model := newModel(false)
+		_, cmd := model.Update(finalTimeoutMsg{})
+		assert.True(t, model.done)
+		assert.IsType(t, tea.QuitMsg{}, cmd())
+	})
+
+	t.Run("waits for a key on the summary", func(t *testing.T) {
+		model := newModel(true)
+		_, cmd := model.Update(finalTimeoutMsg{})
+		assert.Nil(t, cmd)
+		assert.False(t, model.done)
+		view := model.View()
+		assert.Contains(t, view, "0123456789abcdef0123456789abcdef01234567")
+		assert.Contains(t, view, "Press any key to exit")
+
+		_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+		assert.True(t, model.done)
+		assert.NoError(t, model.Result().Err, "exiting the summary is not a cancellation")
+		assert.IsType(t, tea.QuitMsg{}, cmd())
+	})
+	t.Run("c copies the PR URL", func(t *testing.T) {
+		model := newModel(true)
+		model.prURL = "https://github.com/o/r/pull/1"
+		var copied string
+		model.SetCopyPRURL(func(url string) error {
+			copied = url
+			return nil
+		})
+		model.Update(finalTimeoutMsg{})
+		assert.Contains(t, model.View(), "Press c to copy the PR URL")
+
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+		assert.Nil(t, cmd)
+		assert.False(t, model.done)
+		assert.Equal(t, "https://github.com/o/r/pull/1", copied)
+		assert.Contains(t, model.View(), "Copied PR URL to clipboard")
+	})
+}
+
+// stagedOnlyMockCollector 记录文件选择完成后对 collector 的切换
+type stagedOnlyMockCollector struct {
+	MockCollector
+	stagedOnly bool
+	cleared    bool
+}
+
+func (c *stagedOnlyMockCollector) SetStagedOnly(stagedOnly bool) { c.stagedOnly = stagedOnly }
+
+func (c *stagedOnlyMockCollector) ClearCache() { c.cleared = true }
+
+func TestMainModel_InteractiveStage(t *testing.T) {
+	files := []collector.FileStatus{
+		{Path: "a.go", IndexStatus: 'M', WorkStatus: ' '},
+		{Path: "b.go", IndexStatus: ' ', WorkStatus: 'M'},
+		{Path: "new.txt", IndexStatus: '?', WorkStatus: '?', IsUntracked: true},
+	}
+	newModel := func(col collectorInterface, stage StageFunc) *MainModel {
+		model := NewMainModel(
+			context.Background(),
+			col,
+			new(MockPromptBuilder),
+			new(MockClient),
+			new(MockCommitter),
+			"",
+			"en",
+			30*time.Second,
+			false,
+			true,
+			false,
+		)
+		model.SetInteractiveStage(stage)
+		return model
+	}
+	key := func(s string) tea.KeyMsg {
+		switch s {
+		case "enter":
+			return tea.KeyMsg{Type: tea.KeyEnter}
+		case "down":
+			return tea.KeyMsg{Type: tea.KeyDown}
+		}
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	}
+
+	t.Run("stages the selection", func(t *testing.T) {
+		col := new(stagedOnlyMockCollector)
+		col.On("AnalyzeChanges", mock.Anything).Return(&collector.ChangesSummary{FilesByPriority: files}, nil)
+		var add, reset []string
+		model := newModel(col, func(_ context.Context, a, r []string) error {
+			add, reset = a, r
+			return nil
+		})
+		assert.Equal(t, PhaseSelect, model.phase)
+		assert.NotNil(t, model.Init())
+		assert.Contains(t, model.View(), "Loading changed files")
+
+		model.Update(loadFilesCmd(col, context.Background())())
+		view := model.View()
+		assert.Contains(t, view, "Select Files")
+		assert.Contains(t, view, "Select the files to commit (3/3)")
+		assert.Contains(t, view, "?? new.txt")
+
+		// 取消勾选已暂存的 a.go
+		model.Update(key(" "))
+		model.Update(key("down"))
+		assert.Contains(t, model.View(), "(2/3)")
+		_, cmd := model.Update(key("enter"))
+		assert.Contains(t, model.View(), "Staging 2 files")
+		msg := cmd()
+		assert.Equal(t, []string{"b.go", "new.txt"}, add)
+		assert.Equal(t, []string{"a.go"}, reset)
+
+		_, cmd = model.Update(msg)
+		assert.NotNil(t, cmd)
+		assert.Equal(t, PhaseLoading, model.phase)
+		assert.False(t, model.stageAll, "only the selected files are committed")
+		assert.True(t, col.stagedOnly)
+		assert.True(t, col.cleared)
+	})
+
+	t.Run("requires a selection", func(t *testing.T) {
+		called := false
+		model := newModel(new(MockCollector), func(context.Context, []string, []string) error {
+			called = true
+			return nil
+		})
+		model.Update(filesLoadedMsg{files: files})
+		model.Update(key("a")) // 已全选，全部取消
+		_, cmd := model.Update(key("enter"))
+		assert.Nil(t, cmd)
+		assert.False(t, called)
+		assert.Contains(t, model.View(), "Select at least one file to commit")
+
+		_, cmd = model.Update(key("esc"))
+		assert.True(t, model.done)
+		assert.Equal(t, DecisionCancel, model.Result().Decision)
+		assert.IsType(t, tea.QuitMsg{}, cmd())
+	})
+
+	t.Run("nothing to select", func(t *testing.T) {
+		model := newModel(new(MockCollector), nil)
+		model.Update(filesLoadedMsg{})
+		assert.True(t, model.done)
+		assert.ErrorIs(t, model.Result().Err, collector.ErrNoDiff)
+	})
+
+	t.Run("staging failure", func(t *testing.T) {
+		model := newModel(new(MockCollector), nil)
+		model.Update(filesLoadedMsg{files: files})
+		model.Update(filesStagedMsg{err: errors.New("index.lock exists")})
+		assert.True(t, model.done)
+		assert.EqualError(t, model.Result().Err, "staging failed: index.lock exists")
+	})
+}
@@ -21,6 +21,18 @@ const (
 	DecisionCancel
 )
 
+// MarshalText 以 "none"、"accept"、"cancel" 表示 Decision，便于 JSON 输出
+func (d Decision) MarshalText() ([]byte, error) {
+	switch d {
+	case DecisionAccept:
+		return []byte("accept"), nil
+	case DecisionCancel:
+		return []byte("cancel"), nil
+	default:
+		return []byte("none"), nil
+	}
+}
+
 const (
 	buttonAccept buttonState = iota
 	buttonEdit
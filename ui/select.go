@@ -0,0 +1,204 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/penwyp/catmit/collector"
+)
+
+// StageFunc 暂存 add 中的文件，并将 reset 中已暂存的文件移出暂存区
+type StageFunc func(ctx context.Context, add, reset []string) error
+
+// stagedOnlyCollector 是可选能力：文件选择完成后只收集已暂存的变更
+type stagedOnlyCollector interface {
+	SetStagedOnly(stagedOnly bool)
+	ClearCache()
+}
+
+// filesLoadedMsg 携带文件选择阶段的候选文件
+type filesLoadedMsg struct {
+	files []collector.FileStatus
+	err   error
+}
+
+// filesStagedMsg 表示所选文件已暂存
+type filesStagedMsg struct {
+	err error
+}
+
+// SetInteractiveStage 在生成 message 之前加入文件选择阶段：列出所有变更文件（默认全选），
+// 确认后通过 stage 只暂存所选文件，之后只根据暂存区生成与提交，不再自动暂存全部变更
+func (m *MainModel) SetInteractiveStage(stage StageFunc) {
+	m.stageFiles = stage
+	m.phase = PhaseSelect
+}
+
+// loadFilesCmd 按优先级列出所有变更文件（已暂存、未暂存与未跟踪）
+func loadFilesCmd(col collectorInterface, ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		changes, err := col.AnalyzeChanges(ctx)
+		if err != nil {
+			return filesLoadedMsg{err: err}
+		}
+		return filesLoadedMsg{files: changes.FilesByPriority}
+	}
+}
+
+// updateSelect 处理文件选择阶段的键盘输入
+func (m *MainModel) updateSelect(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.selectFiles == nil || m.staging {
+		return m, nil
+	}
+	switch msg.String() {
+	case "up", "k":
+		if m.selectCursor > 0 {
+			m.selectCursor--
+		}
+	case "down", "j":
+		if m.selectCursor < len(m.selectFiles)-1 {
+			m.selectCursor++
+		}
+	case " ", "x":
+		m.selected[m.selectCursor] = !m.selected[m.selectCursor]
+		m.selectNotice = ""
+	case "a", "A":
+		// 已全选时全部取消，否则全选
+		all := m.selectedCount() < len(m.selected)
+		for i := range m.selected {
+			m.selected[i] = all
+		}
+		m.selectNotice = ""
+	case "enter":
+		if m.selectedCount() == 0 {
+			m.selectNotice = "Select at least one file to commit"
+			return m, nil
+		}
+		m.staging = true
+		return m, m.stageSelected()
+	case "q", "Q", "esc":
+		m.reviewDecision = DecisionCancel
+		m.done = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *MainModel) selectedCount() int {
+	n := 0
+	for _, s := range m.selected {
+		if s {
+			n++
+		}
+	}
+	return n
+}
+
+// stageSelected 暂存所选文件中工作区有变更的部分，并将未选中但已暂存的文件移出暂存区
+func (m *MainModel) stageSelected() tea.Cmd {
+	var add, reset []string
+	for i, f := range m.selectFiles {
+		switch {
+		case m.selected[i] && hasStatus(f.WorkStatus):
+			add = append(add, f.Path)
+		case !m.selected[i] && hasStatus(f.IndexStatus):
+			reset = append(reset, f.Path)
+			if f.IsRenamed && f.OldPath != "" {
+				reset = append(reset, f.OldPath)
+			}
+		}
+	}
+	stage, ctx := m.stageFiles, m.ctx
+	return func() tea.Msg {
+		return filesStagedMsg{err: stage(ctx, add, reset)}
+	}
+}
+
+// hasStatus 判断 git status 的某一列是否表示有变更
+func hasStatus(status rune) bool {
+	return status != ' ' && status != 0
+}
+
+// finishStaging 在所选文件暂存后继续：已有 message 时直接进入 Review，否则开始收集暂存区的变更
+func (m *MainModel) finishStaging() tea.Cmd {
+	m.staging = false
+	m.stageAll = false
+	if col, ok := m.collector.(stagedOnlyCollector); ok {
+		// 暂存前的 git 输出已缓存，需要重新读取
+		col.ClearCache()
+		col.SetStagedOnly(true)
+	}
+	if m.message != "" {
+		m.phase = PhaseReview
+		return nil
+	}
+	m.phase = PhaseLoading
+	return startCollectCmd(m.collector, m.ctx)
+}
+
+// renderSelectContent 渲染文件选择阶段的内容
+func (m *MainModel) renderSelectContent() string {
+	if m.selectFiles == nil {
+		return " " + m.spinner.View() + " " + m.styles.Progress.Render("Loading changed files…")
+	}
+	if m.staging {
+		return " " + m.spinner.View() + " " + m.styles.Progress.Render(fmt.Sprintf("Staging %d files…", m.selectedCount()))
+	}
+
+	var content strings.Builder
+	content.WriteString(" " + m.styles.Title.Render(fmt.Sprintf("Select the files to commit (%d/%d):", m.selectedCount(), len(m.selectFiles))) + "\n\n")
+
+	// 文件较多时只显示光标附近的一屏
+	visible := m.terminalHeight - 8
+	if visible < 5 {
+		visible = 5
+	}
+	start := 0
+	if m.selectCursor >= visible {
+		start = m.selectCursor - visible + 1
+	}
+	end := start + visible
+	if end > len(m.selectFiles) {
+		end = len(m.selectFiles)
+	}
+	for i := start; i < end; i++ {
+		cursor, box := "  ", "[ ]"
+		if i == m.selectCursor {
+			cursor = m.styles.Progress.Render("› ")
+		}
+		if m.selected[i] {
+			box = m.styles.Success.Render("[x]")
+		}
+		f := m.selectFiles[i]
+		path := f.Path
+		if f.IsRenamed && f.OldPath != "" {
+			path = f.OldPath + " → " + f.Path
+		}
+		content.WriteString(" " + cursor + box + " " + m.styles.CommitType.Render(statusLabel(f)) + " " + path + "\n")
+	}
+	if start > 0 || end < len(m.selectFiles) {
+		content.WriteString(" " + m.styles.CommitBody.Render(fmt.Sprintf("  … showing %d-%d of %d", start+1, end, len(m.selectFiles))) + "\n")
+	}
+
+	if m.selectNotice != "" {
+		content.WriteString("\n " + m.styles.Warning.Render(m.selectNotice) + "\n")
+	}
+	content.WriteString("\n " + m.styles.CommitBody.Render("[↑/↓] Move  [Space] Toggle  [A] All  [Enter] Continue  [Esc] Cancel"))
+	return content.String()
+}
+
+// statusLabel 返回 git status --short 风格的两列状态，例如 "M "、" M"、"??"
+func statusLabel(f collector.FileStatus) string {
+	if f.IsUntracked {
+		return "??"
+	}
+	column := func(r rune) string {
+		if r == 0 {
+			return " "
+		}
+		return string(r)
+	}
+	return column(f.IndexStatus) + column(f.WorkStatus)
+}